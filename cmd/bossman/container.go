@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/events"
+	"procdexeh/bossman/internal/http"
+	"procdexeh/bossman/internal/mcp"
+	"procdexeh/bossman/internal/tools"
+)
+
+// Container mode is configured by these variables alone, on top of the
+// ones every command reads (BOSSMAN_ADMIN_TOKEN, BOSSMAN_SLOW_*, ...).
+const (
+	envDataDir      = "BOSSMAN_DATA_DIR"      // mounted volume holding bossman.db; default /data
+	envHTTPAddr     = "BOSSMAN_HTTP_ADDR"     // HTTP API and probes; default :8080
+	envDrainTimeout = "BOSSMAN_DRAIN_TIMEOUT" // wait for running tool calls on SIGTERM; default 25s
+	envPreStart     = "BOSSMAN_PRE_START"     // shell command run before the database opens
+	envPostStop     = "BOSSMAN_POST_STOP"     // shell command run after it closes
+	envLogLevel     = "BOSSMAN_LOG_LEVEL"     // debug, info, warn or error; default info
+)
+
+// containerLogger writes JSON lines to stdout for the cluster's log
+// collector. That is only safe because container mode never runs MCP
+// over stdio.
+func containerLogger() *slog.Logger {
+	var level slog.Level
+	if v := os.Getenv(envLogLevel); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v; using info\n", envLogLevel, err)
+		}
+	}
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// runContainerMode opens the database on the data volume between the
+// pre-start and post-stop hooks, which is where a litestream restore or a
+// final replication flush belongs.
+func runContainerMode(opts options, args []string) error {
+	opts.path = filepath.Join(containerDataDir(), "bossman.db")
+	if err := runHook(envPreStart, opts.path); err != nil {
+		return err
+	}
+	err := run(runContainer, opts, args)
+	if herr := runHook(envPostStop, opts.path); herr != nil && err == nil {
+		err = herr
+	}
+	return err
+}
+
+func containerDataDir() string {
+	if dir := os.Getenv(envDataDir); dir != "" {
+		return dir
+	}
+	return "/data"
+}
+
+// runHook runs the shell command in the named variable, if set, with
+// BOSSMAN_DB_PATH pointing at the database. Its output goes to stderr so
+// stdout stays valid JSON.
+func runHook(name, path string) error {
+	command := os.Getenv(name)
+	if command == "" {
+		return nil
+	}
+	slog.Info("running hook", "hook", name, "command", command)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "BOSSMAN_DB_PATH="+path)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// runContainer serves the HTTP API with /livez and /readyz, and MCP on
+// BOSSMAN_LISTEN when set, until SIGTERM. Then /readyz fails, new tool
+// calls are refused as retryable, and running ones get up to
+// BOSSMAN_DRAIN_TIMEOUT to finish before the servers stop.
+func runContainer(ctx context.Context, conn *sqlx.DB, args []string) error {
+	if len(args) > 0 {
+		return errors.New("container mode takes no arguments; configure it through the environment")
+	}
+	addr := os.Getenv(envHTTPAddr)
+	if addr == "" {
+		addr = ":8080"
+	}
+	drainTimeout := envDuration(envDrainTimeout, 25*time.Second)
+
+	registry := tools.NewRegistry(conn)
+	registry.SetSlowLog(slowLog)
+	registry.SetAnomalyConfig(anomalyConfig)
+	registry.SetReplayer(scheduler)
+	registry.SetBackupDir(backupDir)
+	sessions := mcp.NewSessionRegistry()
+	sessions.OnEnd(registry.EndSession)
+
+	// ctx ends on SIGTERM; serving has to outlast it until the drain is done
+	serve, stop := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer stop(nil)
+	registry.SetFatalHandler(stop)
+
+	http.RegisterProbes(&http.Probes{
+		DB:        conn,
+		LiteFSDir: containerDataDir(),
+		Ready: func() error {
+			if registry.Draining() {
+				return errors.New("draining")
+			}
+			return nil
+		},
+		Degraded: registry.Degraded,
+	})
+	http.RegisterAdmin(&http.Admin{Token: os.Getenv(http.AdminTokenEnv), Sessions: sessions, Jobs: scheduler, Slow: slowLog,
+		DB: conn, BackupDir: backupDir})
+
+	httpLn, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	errc := make(chan error, 2)
+	servers := 1
+	go func() { errc <- http.Serve(serve, httpLn, &db.SQLStore{DB: conn}, 5*time.Second) }()
+
+	if listen := os.Getenv("BOSSMAN_LISTEN"); listen != "" {
+		ln, err := mcp.Listen(listen)
+		if err != nil {
+			stop(nil)
+			<-errc
+			return err
+		}
+		registry.SetNotifier(sessions)
+		bus := events.NewBus()
+		if err := bus.Watch(serve, conn, 500*time.Millisecond); err != nil {
+			ln.Close()
+			return err
+		}
+		go events.Forward(serve, bus, sessions)
+		newServer := mcpServerFactory(serve, conn, registry, sessions, mcpSettings{
+			instructions: os.Getenv("BOSSMAN_INSTRUCTIONS"),
+			maxMessage:   mcp.DefaultMaxMessageSize,
+		})
+		servers++
+		go func() { errc <- mcp.Serve(serve, ln, newServer) }()
+	}
+
+	select {
+	case <-ctx.Done():
+		slog.Info("draining", "timeout", drainTimeout.String())
+		drain, cancel := context.WithTimeout(serve, drainTimeout)
+		if derr := registry.Drain(drain); derr != nil {
+			slog.Warn("drain timed out; cancelling the tool calls still running")
+		}
+		cancel()
+	case err = <-errc:
+		servers--
+	case <-serve.Done():
+	}
+	stop(nil)
+	for range servers {
+		if serr := <-errc; err == nil {
+			err = serr
+		}
+	}
+	if cause := context.Cause(serve); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/desktop"
+	"procdexeh/bossman/internal/events"
+	"procdexeh/bossman/internal/http"
+)
+
+// runDesktop serves the HTTP API and dashboard on localhost for someone
+// running a personal agent on their workstation, opens the dashboard, and
+// raises OS notifications for due and failed tasks and new questions. Built
+// with the tray tag it also shows a tray icon with the pending and blocked
+// counts, and quitting from the tray stops it.
+func runDesktop(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("desktop", flag.ContinueOnError)
+	addr := fs.String("addr", "127.0.0.1:6969", "serve the dashboard on this address")
+	remindBefore := fs.Duration("remind-before", 15*time.Minute, "notify this long before a task is due")
+	noBrowser := fs.Bool("no-browser", false, "don't open the dashboard on start")
+	noNotify := fs.Bool("no-notify", false, "don't raise OS notifications")
+	noTray := fs.Bool("no-tray", false, "don't show a tray icon (builds with the tray tag only)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	tray := desktop.TrayAvailable && !*noTray
+	var bus *events.Bus
+	if !*noNotify || tray {
+		bus = events.NewBus()
+		if err := bus.Watch(ctx, conn, time.Second); err != nil {
+			ln.Close()
+			return err
+		}
+	}
+	if !*noNotify {
+		go desktop.NewWatcher(conn, desktop.SystemNotifier(), *remindBefore).Run(ctx, bus)
+	}
+	url := "http://" + ln.Addr().String() + "/dashboard"
+	if !*noBrowser {
+		if err := openBrowser(url); err != nil {
+			slog.Warn("open dashboard", "url", url, "err", err)
+		}
+	}
+	slog.Info("dashboard", "url", url)
+	if !tray {
+		return http.Serve(ctx, ln, &db.SQLStore{DB: conn}, 5*time.Second)
+	}
+
+	// the tray owns the main goroutine, so the server runs beside it and
+	// either one stopping stops the other
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- http.Serve(ctx, ln, &db.SQLStore{DB: conn}, 5*time.Second)
+		cancel()
+	}()
+	desktop.RunTray(ctx, conn, bus, url, openBrowser, cancel)
+	return <-served
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Start()
+}
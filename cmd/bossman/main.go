@@ -0,0 +1,827 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/diagram"
+	"procdexeh/bossman/internal/events"
+	"procdexeh/bossman/internal/guard"
+	"procdexeh/bossman/internal/http"
+	"procdexeh/bossman/internal/maintenance"
+	"procdexeh/bossman/internal/mcp"
+	"procdexeh/bossman/internal/taskwarrior"
+	"procdexeh/bossman/internal/tools"
+)
+
+const dbPath = "./bossman.db"
+
+type command func(ctx context.Context, conn *sqlx.DB, args []string) error
+
+// scheduler is the process's maintenance runner, set by run before the
+// command starts, for the admin endpoints to report on.
+var scheduler *maintenance.Runner
+
+// slowLog logs and counts slow queries and tool calls, set by run.
+var slowLog *guard.SlowLog
+
+// anomalyConfig tunes the write and delete spike detector, set by run.
+var anomalyConfig guard.Config
+
+// databasePath is the default workspace's database file, set by run; the
+// other workspaces and the default -listen socket sit beside it.
+var databasePath string
+
+// workspace is the workspace run opened; "" with -ephemeral, which has none.
+var workspace string
+
+// dbOptions are the connection options run opened the database with.
+var dbOptions db.Options
+
+// backupDir is where backups go, set by run: BOSSMAN_BACKUP_DIR or the
+// backups directory beside the database. Empty for an in-memory database.
+var backupDir string
+
+var commands = map[string]command{
+	"mcp":       runMCP,
+	"serve":     runServe,
+	"desktop":   runDesktop,
+	"unlock":    runUnlock,
+	"export":    runExport,
+	"import":    runImport,
+	"metrics":   runMetrics,
+	"questions": runQuestions,
+	"answer":    runAnswer,
+	"gc":        runGC,
+}
+
+// options are the flags given before the command.
+type options struct {
+	ephemeral bool   // keep the database in memory instead of path
+	path      string // database file: -db, BOSSMAN_DB or dbPath
+	workspace string // workspace beside path to open instead
+	db        db.Options
+	seed      string // fixture file to load at startup
+	slow      guard.SlowConfig
+	anomaly   guard.Config
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `usage: bossman [-db path] [-workspace name] [-read-only] [-ephemeral] [-seed fixture.json] [-slow-query 250ms] [-slow-tool 2s] [-auto-read-only] <command>
+
+flags:
+  -db            database file (env BOSSMAN_DB; default ./bossman.db)
+  -workspace     open this workspace's database beside the -db file instead, e.g. work (env BOSSMAN_WORKSPACE)
+  -busy-timeout  how long to wait for another process's lock (env BOSSMAN_BUSY_TIMEOUT; default 5s)
+  -journal-mode  SQLite journal mode, e.g. delete on network filesystems (env BOSSMAN_JOURNAL_MODE; default wal)
+  -read-only     open the database read-only; every write fails and maintenance doesn't run (env BOSSMAN_READ_ONLY)
+  -ephemeral   keep everything in memory; nothing is written to bossman.db
+  -seed        load a JSON array of fixture tasks (db.SeedTask) at startup
+  -slow-query  log queries at least this slow, parameters redacted (env BOSSMAN_SLOW_QUERY; 0 disables)
+  -slow-tool   log tool calls at least this slow, arguments redacted (env BOSSMAN_SLOW_TOOL; 0 disables)
+               BOSSMAN_REDACT_KEYS replaces the argument keys hidden in those logs (comma-separated)
+  -write-limit     tool writes within a window before a spike warning, COUNT/WINDOW (env BOSSMAN_WRITE_LIMIT; default 500/1m; 0 disables)
+  -delete-limit    deletes within a window before a spike warning (env BOSSMAN_DELETE_LIMIT; default 50/1m; 0 disables)
+  -auto-read-only  on a spike, also suspend writes until bossman unlock (env BOSSMAN_AUTO_READ_ONLY)
+
+commands:
+  mcp        run the MCP server over stdio (-listen unix:PATH|pipe:NAME|local for several clients)
+  connect    bridge stdio to a server started with mcp -listen (default: local)
+  serve      run the HTTP server (-dsn postgres://... for a shared database)
+  desktop    dashboard on localhost with OS notifications for due and failed tasks and new questions (-addr, -remind-before, -no-tray)
+  container  HTTP API, probes and optional MCP socket for Kubernetes; env-only config, JSON logs on stdout
+  unlock     resume writes after an anomaly put bossman in read-only mode
+  export     write all tasks to stdout or a file (-format taskwarrior|json, -project)
+  import     read tasks from a file or stdin (-format taskwarrior|json|mermaid|dot, -project for diagrams)
+  metrics    write event, time and maintenance history plus a task snapshot as day-partitioned CSV (-o DIR, -since)
+  questions  list the questions agents are waiting on you to answer (-answered for past ones)
+  answer     answer a question: bossman answer <id> <answer...>
+  gc         purge finished tasks past retention with their comments and events (-completed 2160h, -failed, -dry-run)`)
+}
+
+func main() {
+	opts := options{slow: guard.DefaultSlowConfig(), anomaly: guard.DefaultConfig()}
+	if keys := os.Getenv("BOSSMAN_REDACT_KEYS"); keys != "" {
+		opts.slow.Redact.Keys = strings.Split(keys, ",")
+	}
+	global := flag.NewFlagSet("bossman", flag.ContinueOnError)
+	global.Usage = printUsage
+	global.StringVar(&opts.path, "db", cmp.Or(os.Getenv("BOSSMAN_DB"), dbPath), "")
+	global.StringVar(&opts.workspace, "workspace", os.Getenv("BOSSMAN_WORKSPACE"), "")
+	global.DurationVar(&opts.db.BusyTimeout, "busy-timeout", envDuration("BOSSMAN_BUSY_TIMEOUT", 5*time.Second), "")
+	global.StringVar(&opts.db.JournalMode, "journal-mode", cmp.Or(os.Getenv("BOSSMAN_JOURNAL_MODE"), "wal"), "")
+	global.BoolVar(&opts.db.ReadOnly, "read-only", envBool("BOSSMAN_READ_ONLY"), "")
+	global.BoolVar(&opts.ephemeral, "ephemeral", false, "")
+	global.StringVar(&opts.seed, "seed", "", "")
+	global.DurationVar(&opts.slow.Query, "slow-query", envDuration("BOSSMAN_SLOW_QUERY", opts.slow.Query), "")
+	global.DurationVar(&opts.slow.Tool, "slow-tool", envDuration("BOSSMAN_SLOW_TOOL", opts.slow.Tool), "")
+	writes, deletes := opts.anomaly.Limits[guard.KindWrite], opts.anomaly.Limits[guard.KindDelete]
+	for name, l := range map[string]*guard.Limit{"BOSSMAN_WRITE_LIMIT": &writes, "BOSSMAN_DELETE_LIMIT": &deletes} {
+		if err := envLimit(name, l); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	global.Var(&writes, "write-limit", "")
+	global.Var(&deletes, "delete-limit", "")
+	global.BoolVar(&opts.anomaly.AutoReadOnly, "auto-read-only", envBool("BOSSMAN_AUTO_READ_ONLY"), "")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	opts.anomaly.Limits[guard.KindWrite], opts.anomaly.Limits[guard.KindDelete] = writes, deletes
+	if global.NArg() == 0 {
+		printUsage()
+		return
+	}
+	name := global.Arg(0)
+	if name == "connect" {
+		// a bridge only, so no database of its own
+		if err := runConnect(filepath.Dir(opts.path), global.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "connect:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if name == "container" {
+		slog.SetDefault(containerLogger())
+		if err := runContainerMode(opts, global.Args()[1:]); err != nil {
+			slog.Error(name, "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	// stdout belongs to the MCP transport, so logs always go to stderr
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	slog.SetDefault(logger)
+
+	if err := run(cmd, opts, global.Args()[1:]); err != nil {
+		logger.Error(name, "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd command, opts options, args []string) error {
+	slowLog = guard.NewSlowLog(opts.slow, slog.Default())
+	anomalyConfig = opts.anomaly
+	db.SetSlowQueryHook(opts.slow.Query, func(ctx context.Context, query string, args []any, took time.Duration) {
+		slowLog.Query(db.ActorFromContext(ctx), query, args, took)
+	})
+
+	path, err := db.WorkspacePath(opts.path, opts.workspace)
+	if err != nil {
+		return err
+	}
+	databasePath, workspace = opts.path, cmp.Or(opts.workspace, db.DefaultWorkspace)
+	if opts.ephemeral {
+		path, workspace = db.MemoryPath, ""
+		slog.Warn("ephemeral mode: the database is in memory and discarded on exit")
+	}
+	if opts.db.ReadOnly && opts.seed != "" {
+		return errors.New("-seed writes to the database, which -read-only forbids")
+	}
+	if err := loadEncryptionKey(); err != nil {
+		return err
+	}
+	conn, err := db.InitDB(path, opts.db)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	dbOptions = opts.db
+	if opts.db.ReadOnly {
+		slog.Warn("read-only mode: writes fail and maintenance jobs don't run", "db", path)
+	}
+	if workspace != db.DefaultWorkspace && workspace != "" {
+		slog.Info("workspace", "name", workspace, "db", path)
+	}
+
+	// SIGINT/SIGTERM cancel ctx, which every subsystem tears down on.
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	// Simulation swaps in a virtual clock before anything reads the time.
+	if start := os.Getenv("BOSSMAN_SIMULATE"); start != "" {
+		fake, err := simulationClock(start)
+		if err != nil {
+			return err
+		}
+		slog.Warn("simulation mode: virtual time, advance with the advance_time tool",
+			"start", db.FormatTime(fake.Now()))
+		ctx = clock.With(ctx, fake)
+	}
+
+	if opts.seed != "" {
+		if err := seed(ctx, conn, opts.seed); err != nil {
+			return err
+		}
+	}
+
+	// The rest of startup writes, so a read-only open skips it.
+	if !opts.db.ReadOnly {
+		// Features listed here get their tables now rather than on first use.
+		for name := range strings.FieldsFuncSeq(os.Getenv("BOSSMAN_FEATURES"), func(r rune) bool { return r == ',' || r == ' ' }) {
+			if err := db.EnableExtension(ctx, conn, name); err != nil {
+				return err
+			}
+		}
+
+		// With a key set, rows written without one are encrypted now.
+		if n, err := db.SealTasks(ctx, conn); err != nil {
+			return fmt.Errorf("encrypt existing tasks: %w", err)
+		} else if n > 0 {
+			slog.Info("encrypted existing tasks", "count", n)
+		}
+
+		if vacuum, analyze := envBool("BOSSMAN_VACUUM_ON_START"), envBool("BOSSMAN_ANALYZE_ON_START"); vacuum || analyze {
+			info, err := db.Optimize(ctx, conn, db.OptimizeOpts{Vacuum: vacuum, Analyze: analyze})
+			if err != nil {
+				slog.Error("optimize on start", "err", err)
+			} else {
+				slog.Info("optimized database", "vacuum", vacuum, "analyze", analyze,
+					"bytes_before", info.BytesBefore, "bytes_after", info.BytesAfter, "took", info.Took)
+			}
+		}
+	}
+
+	jobs := maintenance.DefaultJobs()
+	backupDir = os.Getenv("BOSSMAN_BACKUP_DIR")
+	if backupDir == "" && !opts.ephemeral {
+		if backupDir, err = db.DefaultBackupDir(ctx, conn); err != nil {
+			return err
+		}
+	}
+	if every := envDuration("BOSSMAN_BACKUP_INTERVAL", 0); every > 0 && backupDir != "" {
+		keep := envInt("BOSSMAN_BACKUP_KEEP", db.DefaultBackupKeep)
+		jobs = append(jobs, maintenance.BackupJob(backupDir, every, keep))
+		slog.Info("periodic backups", "dir", backupDir, "every", every, "keep", keep)
+	}
+	if policy := retentionPolicy(db.RetentionPolicy{}); policy.Enabled() {
+		jobs = append(jobs, maintenance.RetentionJob(policy))
+		slog.Info("retention", "completed", policy.Completed, "failed", policy.Failed)
+	}
+	if opts.db.ReadOnly {
+		jobs = nil
+	}
+	scheduler = maintenance.NewRunner(conn, jobs, slog.Default())
+	if err := scheduler.Start(ctx); err != nil {
+		slog.Error("start maintenance", "err", err)
+	}
+
+	return cmd(ctx, conn, args)
+}
+
+// loadEncryptionKey turns on encryption of task context and result with
+// the key in BOSSMAN_ENCRYPTION_KEY, or the file BOSSMAN_ENCRYPTION_KEYFILE
+// names.
+func loadEncryptionKey() error {
+	text := os.Getenv("BOSSMAN_ENCRYPTION_KEY")
+	if file := os.Getenv("BOSSMAN_ENCRYPTION_KEYFILE"); file != "" && text == "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("read encryption key: %w", err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return nil
+	}
+	key, err := db.ParseEncryptionKey(text)
+	if err != nil {
+		return err
+	}
+	return db.SetEncryptionKey(key)
+}
+
+// retentionPolicy reads BOSSMAN_RETAIN_COMPLETED and BOSSMAN_RETAIN_FAILED
+// over def.
+func retentionPolicy(def db.RetentionPolicy) db.RetentionPolicy {
+	return db.RetentionPolicy{
+		Completed: envDuration("BOSSMAN_RETAIN_COMPLETED", def.Completed),
+		Failed:    envDuration("BOSSMAN_RETAIN_FAILED", def.Failed),
+	}
+}
+
+// envDuration reads a duration from the environment, falling back to def
+// when unset or unparseable.
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v; using %s\n", name, err, def)
+		return def
+	}
+	return d
+}
+
+// envInt is envDuration for a count.
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v; using %d\n", name, err, def)
+		return def
+	}
+	return n
+}
+
+// envLimit sets l from a COUNT/WINDOW limit, e.g. 500/1m, or 0, when name
+// is set. Unlike the other env readers it doesn't fall back to the
+// default on a bad value: a limit someone meant to turn off would
+// silently stay on.
+func envLimit(name string, l *guard.Limit) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	if err := l.Set(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// envBool is envDuration for a switch, off unless set to something
+// strconv.ParseBool takes for true.
+func envBool(name string) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v; using false\n", name, err)
+		return false
+	}
+	return b
+}
+
+// seed loads a fixture file: a JSON array of db.SeedTask.
+func seed(ctx context.Context, conn *sqlx.DB, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read fixture: %w", err)
+	}
+	var tasks []db.SeedTask
+	if err := json.Unmarshal(data, &tasks); err != nil {
+		return fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	ids, err := db.Seed(ctx, conn, tasks)
+	if err != nil {
+		return err
+	}
+	slog.Info("seeded fixture", "file", path, "tasks", len(ids))
+	return nil
+}
+
+// simulationClock parses BOSSMAN_SIMULATE: "now" or an RFC 3339 start time.
+func simulationClock(start string) (*clock.Fake, error) {
+	if start == "now" || start == "1" {
+		return clock.NewFake(time.Now()), nil
+	}
+	t, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return nil, fmt.Errorf("BOSSMAN_SIMULATE: want \"now\" or an RFC 3339 time: %w", err)
+	}
+	return clock.NewFake(t), nil
+}
+
+func runMCP(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	instructions := fs.String("instructions", os.Getenv("BOSSMAN_INSTRUCTIONS"),
+		"usage guidance sent to agents on initialize (env BOSSMAN_INSTRUCTIONS)")
+	instructionsFile := fs.String("instructions-file", os.Getenv("BOSSMAN_INSTRUCTIONS_FILE"),
+		"read instructions from a file (env BOSSMAN_INSTRUCTIONS_FILE)")
+	maxMessage := fs.Int("max-message-size", mcp.DefaultMaxMessageSize,
+		"largest accepted JSON-RPC message in bytes")
+	pingInterval := fs.Duration("ping-interval", 0, "ping the client this often (0 disables)")
+	idleTimeout := fs.Duration("idle-timeout", 0,
+		"exit after this long without client messages if no task is in progress (0 disables)")
+	listen := fs.String("listen", os.Getenv("BOSSMAN_LISTEN"),
+		"accept several clients on unix:PATH or pipe:NAME instead of stdio; \"local\" picks the platform default (env BOSSMAN_LISTEN)")
+	adminAddr := fs.String("admin-addr", os.Getenv("BOSSMAN_ADMIN_ADDR"),
+		"serve the admin endpoints on this address, e.g. 127.0.0.1:6970 (env BOSSMAN_ADMIN_ADDR; needs "+http.AdminTokenEnv+")")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *instructionsFile != "" {
+		data, err := os.ReadFile(*instructionsFile)
+		if err != nil {
+			return fmt.Errorf("read instructions: %w", err)
+		}
+		*instructions = strings.TrimSpace(string(data))
+	}
+
+	registry := tools.NewRegistry(conn)
+	registry.SetSlowLog(slowLog)
+	registry.SetAnomalyConfig(anomalyConfig)
+	registry.SetReplayer(scheduler)
+	registry.SetBackupDir(backupDir)
+	if workspace != "" {
+		registry.SetWorkspaces(databasePath, workspace, dbOptions)
+		defer registry.CloseWorkspaces()
+	}
+	sessions := mcp.NewSessionRegistry()
+	sessions.OnEnd(registry.EndSession)
+
+	// a database that can't be recovered ends every session with its error
+	ctx, fail := context.WithCancelCause(ctx)
+	defer fail(nil)
+	registry.SetFatalHandler(fail)
+
+	newServer := mcpServerFactory(ctx, conn, registry, sessions, mcpSettings{
+		instructions: *instructions,
+		maxMessage:   *maxMessage,
+		pingInterval: *pingInterval,
+		idleTimeout:  *idleTimeout,
+	})
+
+	bus := events.NewBus()
+	if err := bus.Watch(ctx, conn, 500*time.Millisecond); err != nil {
+		return err
+	}
+	go events.Forward(ctx, bus, sessions)
+
+	if *adminAddr != "" {
+		admin := &http.Admin{Token: os.Getenv(http.AdminTokenEnv), Sessions: sessions, Jobs: scheduler, Slow: slowLog,
+			DB: conn, BackupDir: backupDir}
+		go func() {
+			if err := http.ServeAdmin(ctx, *adminAddr, admin); err != nil {
+				slog.Error("admin endpoints", "err", err)
+			}
+		}()
+	}
+
+	var err error
+	if *listen != "" {
+		if *listen == "local" {
+			*listen = mcp.DefaultListenAddr(filepath.Dir(databasePath))
+		}
+		ln, lerr := mcp.Listen(*listen)
+		if lerr != nil {
+			return lerr
+		}
+		registry.SetNotifier(sessions)
+		err = mcp.Serve(ctx, ln, newServer)
+	} else {
+		srv := newServer(mcp.NewTransport(os.Stdin, os.Stdout))
+		registry.SetNotifier(srv)
+		err = srv.Run(ctx)
+	}
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return err
+}
+
+// mcpSettings configure every session's server.
+type mcpSettings struct {
+	instructions string
+	maxMessage   int
+	pingInterval time.Duration
+	idleTimeout  time.Duration
+}
+
+// mcpServerFactory returns the constructor for session servers: each gets
+// its own server and rate limits around the shared registry. It switches
+// the registry to virtual time when ctx carries a simulation clock.
+func mcpServerFactory(ctx context.Context, conn *sqlx.DB, registry *tools.Registry,
+	sessions *mcp.SessionRegistry, s mcpSettings) func(*mcp.Transport) *mcp.Server {
+	fake, simulated := clock.From(ctx).(*clock.Fake)
+	if simulated {
+		registry.EnableSimulation(fake)
+	}
+	return func(t *mcp.Transport) *mcp.Server {
+		srv := mcp.NewServerWithTransport(registry, t)
+		srv.SetInstructions(s.instructions)
+		srv.SetMaxMessageSize(s.maxMessage)
+		limiter := mcp.NewRateLimiter(mcp.DefaultRateLimit, mcp.DefaultToolRateLimits)
+		if simulated {
+			limiter.SetClock(fake)
+		}
+		srv.SetRateLimiter(limiter)
+		srv.SetKeepalive(s.pingInterval)
+		srv.SetIdleTimeout(s.idleTimeout, func() bool {
+			n, err := db.CountTasks(ctx, conn, "in_progress")
+			if err != nil {
+				slog.Error("count in-progress tasks", "err", err)
+				return false
+			}
+			return n == 0
+		})
+		srv.SetSessions(sessions)
+		return srv
+	}
+}
+
+// runConnect bridges stdio to a server started with mcp -listen, for MCP
+// clients that can only launch a command.
+func runConnect(dir string, args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	addr := fs.Arg(0)
+	if addr == "" || addr == "local" {
+		addr = mcp.DefaultListenAddr(dir)
+	}
+	conn, err := mcp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		io.Copy(conn, os.Stdin)
+		// the client is done: let the server see EOF and end the session
+		if c, ok := conn.(interface{ CloseWrite() error }); ok {
+			c.CloseWrite()
+		} else {
+			conn.Close()
+		}
+	}()
+	_, err = io.Copy(os.Stdout, conn)
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func runServe(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	dsn := fs.String("dsn", os.Getenv("BOSSMAN_DSN"),
+		"serve from this database instead, e.g. postgres://host/bossman (env BOSSMAN_DSN)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var store db.Store = &db.SQLStore{DB: conn}
+	admin := &http.Admin{Token: os.Getenv(http.AdminTokenEnv), Jobs: scheduler, Slow: slowLog, DB: conn, BackupDir: backupDir}
+	if *dsn != "" {
+		s, err := db.Open(*dsn, dbOptions)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		store = s
+		admin.DB = nil // the local file isn't what's being served
+	}
+	http.RegisterAdmin(admin)
+	http.Run(store)
+	return nil
+}
+
+func runUnlock(ctx context.Context, conn *sqlx.DB, args []string) error {
+	reason, locked, err := db.GetSetting(ctx, conn, db.SettingReadOnly)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		fmt.Println("bossman is not read-only")
+		return nil
+	}
+	if err := db.DeleteSetting(ctx, conn, db.SettingReadOnly); err != nil {
+		return err
+	}
+	fmt.Printf("writes resumed (was: %s)\n", reason)
+	return nil
+}
+
+func runExport(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "taskwarrior", "output format: taskwarrior or json")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	project := fs.String("project", "", "only export this project's tasks (ID or name)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "taskwarrior" && *format != "json" {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+	var projectID string
+	if *project != "" {
+		p, err := db.GetProject(ctx, conn, *project)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("project not found: %s", *project)
+		}
+		if err != nil {
+			return err
+		}
+		projectID = p.ID
+	}
+
+	w := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	var n int
+	if *format == "json" {
+		doc, err := db.ExportAll(ctx, conn, projectID)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		n = len(doc.Tasks)
+	} else {
+		var err error
+		if n, err = taskwarrior.Export(ctx, conn, w, projectID); err != nil {
+			return err
+		}
+	}
+	slog.Info("exported", "tasks", n, "format", *format)
+	return nil
+}
+
+func runMetrics(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("metrics", flag.ContinueOnError)
+	output := fs.String("o", "bossman-metrics", "directory to write into; must be empty or absent")
+	since := fs.String("since", "", "only rows from this time or date on (RFC 3339 or YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var from string
+	if *since != "" {
+		var err error
+		if from, err = db.ParseTime(*since); err != nil {
+			return err
+		}
+	}
+	// stale partitions from an earlier run would be read as current
+	if entries, err := os.ReadDir(*output); err == nil && len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", *output)
+	}
+	counts, err := db.ExportMetrics(ctx, conn, *output, from)
+	if err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Printf("%-16s %d rows\n", name, counts[name])
+	}
+	fmt.Printf("wrote %s; in DuckDB: SELECT * FROM read_csv('%s/events/*/*.csv', hive_partitioning = true)\n",
+		*output, filepath.ToSlash(*output))
+	return nil
+}
+
+func runGC(ctx context.Context, conn *sqlx.DB, args []string) error {
+	def := retentionPolicy(db.DefaultRetention)
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	completed := fs.Duration("completed", def.Completed, "purge tasks completed longer ago than this; 0 keeps them (env BOSSMAN_RETAIN_COMPLETED)")
+	failed := fs.Duration("failed", def.Failed, "purge failed tasks unchanged for longer than this; 0 keeps them (env BOSSMAN_RETAIN_FAILED)")
+	dryRun := fs.Bool("dry-run", false, "report what would be deleted without deleting it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	res, err := db.Purge(ctx, conn, db.RetentionPolicy{Completed: *completed, Failed: *failed}, *dryRun)
+	if err != nil {
+		return err
+	}
+	verb := "purged"
+	if res.DryRun {
+		verb = "would purge"
+		for _, id := range res.Tasks {
+			fmt.Println(id)
+		}
+	}
+	fmt.Printf("%s %d tasks, %d comments, %d events, %d changes\n", verb, len(res.Tasks), res.Comments, res.Events, res.Changes)
+	return nil
+}
+
+func runQuestions(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("questions", flag.ContinueOnError)
+	answered := fs.Bool("answered", false, "list answered questions instead")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	questions, err := db.ListQuestions(ctx, conn, *answered)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		fmt.Println("no questions")
+		return nil
+	}
+	for _, q := range questions {
+		fmt.Printf("%s  [%s]  %s\n", q.ID, q.PriorityLabel, q.Description)
+		if q.Context != "" {
+			fmt.Printf("    %s\n", strings.ReplaceAll(string(q.Context), "\n", "\n    "))
+		}
+		if q.Answer != nil {
+			fmt.Printf("    answer: %s\n", *q.Answer)
+		}
+	}
+	if !*answered {
+		fmt.Println("\nanswer with: bossman answer <id> <answer>")
+	}
+	return nil
+}
+
+func runAnswer(ctx context.Context, conn *sqlx.DB, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: bossman answer <id> <answer...>")
+	}
+	by := "cli"
+	if user := os.Getenv("USER"); user != "" {
+		by = user
+	}
+	ctx = db.WithActor(ctx, "cli/"+by)
+	if err := db.AnswerQuestion(ctx, conn, args[0], strings.Join(args[1:], " "), by); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("question not found: %s", args[0])
+		}
+		return err
+	}
+	fmt.Println("answered", args[0])
+	return nil
+}
+
+func runImport(ctx context.Context, conn *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "taskwarrior", "input format: taskwarrior, json, mermaid or dot")
+	project := fs.String("project", "", "project name for tasks from a diagram")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	switch *format {
+	case "taskwarrior", "json", diagram.Mermaid, diagram.DOT:
+	default:
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	r := os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	ctx = db.WithActor(ctx, "cli/import")
+	if *format == "json" {
+		var doc db.ExportDocument
+		if err := json.NewDecoder(r).Decode(&doc); err != nil {
+			return fmt.Errorf("read document: %w", err)
+		}
+		res, err := db.ImportAll(ctx, conn, &doc)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d tasks (%d renumbered)\n", res.Tasks, len(res.Remapped))
+		return nil
+	}
+	if *format == diagram.Mermaid || *format == diagram.DOT {
+		src, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		ids, err := diagram.Import(ctx, conn, *format, string(src), *project)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d tasks\n", len(ids))
+		return nil
+	}
+	n, err := taskwarrior.Import(ctx, conn, r)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d tasks\n", n)
+	return nil
+}
@@ -0,0 +1,584 @@
+// Command genclient generates the Go and TypeScript HTTP clients from the
+// OpenAPI spec served by bossman. Run it through go generate:
+//
+//	go generate ./internal/http
+//
+// With -check it writes nothing and fails if a client is out of date.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type spec struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []parameter          `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+
+	// filled in by load
+	method, path string
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type response struct {
+	Description string `json:"description"`
+	Content     map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Type        string             `json:"type"`
+	Ref         string             `json:"$ref"`
+	Description string             `json:"description"`
+	Format      string             `json:"format"`
+	Enum        []string           `json:"enum"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+}
+
+func main() {
+	specPath := flag.String("spec", "internal/http/openapi.json", "OpenAPI spec to read")
+	root := flag.String("root", ".", "repository root the outputs are written under")
+	check := flag.Bool("check", false, "fail if the generated files are out of date instead of writing them")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("genclient: ")
+
+	s, err := load(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	goSrc, err := genGo(s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	outputs := map[string][]byte{
+		"pkg/client/client.go":     goSrc,
+		"dist/client/bossman.ts":   genTS(s),
+		"dist/client/package.json": genPackageJSON(s),
+	}
+
+	stale := false
+	for _, name := range sortedKeys(outputs) {
+		path := filepath.Join(*root, name)
+		if *check {
+			if old, err := os.ReadFile(path); err != nil || !bytes.Equal(old, outputs[name]) {
+				fmt.Fprintf(os.Stderr, "%s is out of date; run go generate ./internal/http\n", name)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(path, outputs[name], 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
+
+func load(path string) (*spec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s spec
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for p, methods := range s.Paths {
+		for m, op := range methods {
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s: missing operationId", strings.ToUpper(m), p)
+			}
+			if op.RequestBody != nil {
+				if c, ok := op.RequestBody.Content["application/json"]; !ok || c.Schema == nil || c.Schema.Ref == "" {
+					return nil, fmt.Errorf("%s %s: request body must be a JSON schema reference", strings.ToUpper(m), p)
+				}
+			}
+			op.method, op.path = strings.ToUpper(m), p
+		}
+	}
+	return &s, nil
+}
+
+// operations returns every operation ordered by operationId so output is stable.
+func (s *spec) operations() []*operation {
+	var ops []*operation
+	for _, methods := range s.Paths {
+		for _, op := range methods {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+// result returns the success response's schema and whether it is JSON.
+func (op *operation) result() (*schema, bool) {
+	r := op.Responses["200"]
+	if r == nil {
+		return nil, false
+	}
+	if c, ok := r.Content["application/json"]; ok {
+		return c.Schema, true
+	}
+	return nil, false
+}
+
+// body returns the name of the JSON request body's schema, or "" if the
+// operation takes none. load has checked it is a reference.
+func (op *operation) body() string {
+	if op.RequestBody == nil {
+		return ""
+	}
+	return refName(op.RequestBody.Content["application/json"].Schema.Ref)
+}
+
+func (op *operation) params(in string) []parameter {
+	var out []parameter
+	for _, p := range op.Parameters {
+		if p.In == in {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API", "uri": "URI"}
+
+// exported turns snake_case or camelCase into a Go exported name.
+func exported(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if up, ok := initialisms[part]; ok {
+			b.WriteString(up)
+		} else if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const header = "Code generated by genclient from internal/http/openapi.json; DO NOT EDIT."
+
+// --- Go ---
+
+func goType(s *schema) string {
+	switch {
+	case s.Ref != "":
+		return refName(s.Ref)
+	case s.Type == "array":
+		return "[]" + goType(s.Items)
+	case s.Type == "integer":
+		return "int64"
+	case s.Type == "number":
+		return "float64"
+	case s.Type == "boolean":
+		return "bool"
+	case s.Type == "object":
+		return "map[string]any"
+	default:
+		return "string"
+	}
+}
+
+func genGo(s *spec) ([]byte, error) {
+	var b bytes.Buffer
+	w := func(format string, args ...any) { fmt.Fprintf(&b, format, args...) }
+	w("// %s\n\n", header)
+	w("// Package client is a Go client for the bossman HTTP API.\n")
+	w("package client\n\n")
+	w("import (\n\t\"bytes\"\n\t\"context\"\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"io\"\n\t\"net/http\"\n\t\"net/url\"\n\t\"strconv\"\n\t\"strings\"\n)\n\n")
+	w("// Version is the API version this client was generated for.\n")
+	w("const Version = %q\n\n", s.Info.Version)
+
+	for _, name := range sortedKeys(s.Components.Schemas) {
+		sc := s.Components.Schemas[name]
+		w("// %s is %s.\n", name, lowerFirst(strings.TrimSuffix(sc.Description, ".")))
+		w("type %s struct {\n", name)
+		for _, prop := range sortedKeys(sc.Properties) {
+			p := sc.Properties[prop]
+			tag := prop
+			if !slices.Contains(sc.Required, prop) {
+				tag += ",omitempty"
+			}
+			w("\t// %s\n", p.Description)
+			w("\t%s %s `json:\"%s\"`\n", exported(prop), goType(p), tag)
+		}
+		w("}\n\n")
+	}
+
+	b.WriteString(goRuntime)
+
+	for _, op := range s.operations() {
+		name := exported(op.OperationID)
+		query := op.params("query")
+		if len(query) > 0 {
+			w("// %sParams are the query parameters of %s; zero values are left out.\n", name, name)
+			w("type %sParams struct {\n", name)
+			for _, p := range query {
+				w("\t// %s\n", p.Description)
+				w("\t%s %s\n", exported(p.Name), goType(p.Schema))
+			}
+			w("}\n\n")
+		}
+
+		args := []string{"ctx context.Context"}
+		for _, p := range op.params("path") {
+			args = append(args, p.Name+" string")
+		}
+		if len(query) > 0 {
+			args = append(args, "params "+name+"Params")
+		}
+		body := "nil"
+		if t := op.body(); t != "" {
+			args = append(args, "body "+t)
+			body = "body"
+		}
+		out := "string"
+		res, isJSON := op.result()
+		if isJSON {
+			out = goType(res)
+			if res.Ref != "" {
+				out = "*" + out
+			}
+		}
+		w("// %s calls %s %s: %s.\n", name, op.method, op.path, lowerFirst(op.Summary))
+		w("func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), out)
+		path := op.path
+		for _, p := range op.params("path") {
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", `" + url.PathEscape(`+p.Name+`) + "`)
+		}
+		w("\tq := url.Values{}\n")
+		for _, p := range query {
+			field := "params." + exported(p.Name)
+			switch p.Schema.Type {
+			case "array":
+				w("\tfor _, v := range %s {\n\t\tq.Add(%q, v)\n\t}\n", field, p.Name)
+			case "integer":
+				w("\tif %s != 0 {\n\t\tq.Set(%q, strconv.FormatInt(%s, 10))\n\t}\n", field, p.Name, field)
+			case "boolean":
+				w("\tif %s {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, p.Name)
+			default:
+				w("\tif %s != \"\" {\n\t\tq.Set(%q, %s)\n\t}\n", field, p.Name, field)
+			}
+		}
+		switch {
+		case !isJSON:
+			w("\treturn c.text(ctx, %q, %s, q, %s)\n", op.method, strings.ReplaceAll(`"`+path+`"`, ` + ""`, ""), body)
+		case res.Ref != "":
+			w("\tvar out %s\n", goType(res))
+			w("\tif err := c.do(ctx, %q, %s, q, %s, &out); err != nil {\n\t\treturn nil, err\n\t}\n", op.method, strings.ReplaceAll(`"`+path+`"`, ` + ""`, ""), body)
+			w("\treturn &out, nil\n")
+		default:
+			w("\tvar out %s\n", out)
+			w("\terr := c.do(ctx, %q, %s, q, %s, &out)\n", op.method, strings.ReplaceAll(`"`+path+`"`, ` + ""`, ""), body)
+			w("\treturn out, err\n")
+		}
+		w("}\n\n")
+	}
+	return format.Source(b.Bytes())
+}
+
+const goRuntime = `// Client calls a bossman HTTP server.
+type Client struct {
+	BaseURL    string // e.g. http://localhost:6969
+	HTTPClient *http.Client
+}
+
+// New returns a client for the server at baseURL using http.DefaultClient.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// Error is a non-2xx response.
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bossman: %d %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// send makes the request; a non-nil body is sent as JSON.
+func (c *Client) send(ctx context.Context, method, path string, q url.Values, body any, accept string) ([]byte, error) {
+	u := c.BaseURL + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "bossman-go-client/"+Version)
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, &Error{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, q url.Values, body, out any) error {
+	respBody, err := c.send(ctx, method, path, q, body, "application/json")
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func (c *Client) text(ctx context.Context, method, path string, q url.Values, body any) (string, error) {
+	respBody, err := c.send(ctx, method, path, q, body, "text/plain")
+	return string(respBody), err
+}
+
+`
+
+// --- TypeScript ---
+
+func tsType(s *schema) string {
+	switch {
+	case s.Ref != "":
+		return refName(s.Ref)
+	case len(s.Enum) > 0:
+		quoted := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			quoted[i] = fmt.Sprintf("%q", e)
+		}
+		return strings.Join(quoted, " | ")
+	case s.Type == "array":
+		inner := tsType(s.Items)
+		if strings.Contains(inner, "|") {
+			inner = "(" + inner + ")"
+		}
+		return inner + "[]"
+	case s.Type == "integer" || s.Type == "number":
+		return "number"
+	case s.Type == "boolean":
+		return "boolean"
+	case s.Type == "object":
+		return "Record<string, unknown>"
+	default:
+		return "string"
+	}
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func genTS(s *spec) []byte {
+	var b bytes.Buffer
+	w := func(format string, args ...any) { fmt.Fprintf(&b, format, args...) }
+	w("// %s\n\n", header)
+	w("/** API version this client was generated for. */\n")
+	w("export const VERSION = %q;\n\n", s.Info.Version)
+
+	for _, name := range sortedKeys(s.Components.Schemas) {
+		sc := s.Components.Schemas[name]
+		w("/** %s */\n", sc.Description)
+		w("export interface %s {\n", name)
+		for _, prop := range sortedKeys(sc.Properties) {
+			p := sc.Properties[prop]
+			opt := "?"
+			if slices.Contains(sc.Required, prop) {
+				opt = ""
+			}
+			w("  /** %s */\n", p.Description)
+			w("  %s%s: %s;\n", prop, opt, tsType(p))
+		}
+		w("}\n\n")
+	}
+
+	for _, op := range s.operations() {
+		query := op.params("query")
+		if len(query) == 0 {
+			continue
+		}
+		w("export interface %sParams {\n", exported(op.OperationID))
+		for _, p := range query {
+			opt := "?"
+			if p.Required {
+				opt = ""
+			}
+			w("  /** %s */\n", p.Description)
+			w("  %s%s: %s;\n", p.Name, opt, tsType(p.Schema))
+		}
+		w("}\n\n")
+	}
+
+	b.WriteString(tsRuntime)
+	for _, op := range s.operations() {
+		name := lowerFirst(op.OperationID)
+		var args []string
+		path := op.path
+		for _, p := range op.params("path") {
+			args = append(args, p.Name+": string")
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", "${encodeURIComponent("+p.Name+")}")
+		}
+		query := "undefined"
+		if params := op.params("query"); len(params) > 0 {
+			arg := "params: " + exported(op.OperationID) + "Params"
+			if !slices.ContainsFunc(params, func(p parameter) bool { return p.Required }) {
+				arg += " = {}"
+			}
+			args = append(args, arg)
+			query = "params"
+		}
+		body := "undefined"
+		if t := op.body(); t != "" {
+			args = append(args, "body: "+t)
+			body = "body"
+		}
+		res, isJSON := op.result()
+		out := "string"
+		if isJSON {
+			out = tsType(res)
+		}
+		w("\n  /** %s (%s %s). */\n", op.Summary, op.method, op.path)
+		w("  %s(%s): Promise<%s> {\n", name, strings.Join(args, ", "), out)
+		w("    return this.request<%s>(%q, `%s`, %s, %s, %t);\n", out, op.method, path, query, body, isJSON)
+		w("  }\n")
+	}
+	w("}\n")
+	return b.Bytes()
+}
+
+const tsRuntime = `/** A non-2xx response. */
+export class BossmanError extends Error {
+  constructor(public readonly status: number, public readonly body: string) {
+    super(` + "`bossman: ${status} ${body.trim()}`" + `);
+  }
+}
+
+type Query = Record<string, string | number | string[] | undefined>;
+
+/** Calls a bossman HTTP server, e.g. new BossmanClient("http://localhost:6969"). */
+export class BossmanClient {
+  constructor(
+    private readonly baseUrl: string,
+    private readonly fetchImpl: typeof fetch = fetch,
+  ) {
+    this.baseUrl = baseUrl.replace(/\/$/, "");
+  }
+
+  private async request<T>(
+    method: string,
+    path: string,
+    query: object | undefined,
+    body: object | undefined,
+    json: boolean,
+  ): Promise<T> {
+    const qs = new URLSearchParams();
+    for (const [key, value] of Object.entries((query ?? {}) as Query)) {
+      if (value === undefined || value === "") continue;
+      for (const v of Array.isArray(value) ? value : [value]) qs.append(key, String(v));
+    }
+    const search = qs.toString();
+    const url = this.baseUrl + path + (search ? "?" + search : "");
+    const headers: Record<string, string> = { Accept: json ? "application/json" : "text/plain" };
+    if (body !== undefined) headers["Content-Type"] = "application/json";
+    const resp = await this.fetchImpl(url, {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await resp.text();
+    if (!resp.ok) throw new BossmanError(resp.status, text);
+    return (json ? JSON.parse(text) : text) as T;
+  }
+`
+
+func genPackageJSON(s *spec) []byte {
+	pkg := map[string]any{
+		"name":        "@bossman/client",
+		"version":     s.Info.Version,
+		"description": "Generated client for the bossman HTTP API",
+		"main":        "bossman.ts",
+		"types":       "bossman.ts",
+		"private":     true,
+	}
+	out, _ := json.MarshalIndent(pkg, "", "  ")
+	return append(out, '\n')
+}
@@ -0,0 +1,318 @@
+// Code generated by genclient from internal/http/openapi.json; DO NOT EDIT.
+
+/** API version this client was generated for. */
+export const VERSION = "1.0.0";
+
+/** One group of an aggregate */
+export interface AggregateRow {
+  /** Dimension values of the group */
+  group: Record<string, unknown>;
+  /** The metric for the group */
+  value: number;
+}
+
+/** A person's answer to a question */
+export interface AnswerRequest {
+  /** The answer */
+  answer: string;
+  /** Who answered; defaults to the caller's address */
+  answered_by?: string;
+}
+
+/** One recorded change */
+export interface Change {
+  /** When it happened */
+  created_at: string;
+  /** What changed */
+  entity: "task" | "blocker";
+  /** Kind of change */
+  op: "insert" | "update" | "delete";
+  /** Sequence number */
+  seq: number;
+  /** Task concerned */
+  task_id: string;
+}
+
+/** A page of changes */
+export interface ChangesPage {
+  /** Changes, oldest first */
+  changes: Change[];
+  /** Pass as since on the next call */
+  next: number;
+}
+
+/** The longest-waiting pending task */
+export interface OldestTask {
+  /** Seconds since it was created */
+  age_seconds: number;
+  /** When it was created */
+  created_at: string;
+  /** Task description */
+  description: string;
+  /** Task ID */
+  id: string;
+}
+
+/** One search result */
+export interface SearchHit {
+  /** Entity ID */
+  id: string;
+  /** Entity kind */
+  kind: "task" | "comment" | "attachment" | "project";
+  /** Lower is better */
+  rank: number;
+  /** Matching fragment with hits in [brackets] */
+  snippet?: string;
+  /** Owning task of a comment or attachment */
+  task_id?: string;
+  /** Task description, comment author or name */
+  title: string;
+}
+
+/** A summary of the task list, system tasks left out */
+export interface Stats {
+  /** Mean time from start, or creation if never started, to completion; absent before anything completes */
+  avg_completion_seconds?: number;
+  /** Pending tasks waiting on an unfinished blocker */
+  blocked: number;
+  /** Open task count per priority label */
+  by_priority: Record<string, unknown>;
+  /** Task count per status */
+  by_status: Record<string, unknown>;
+  /** Absent when nothing is pending */
+  oldest_pending?: OldestTask;
+  /** Pending tasks not blocked */
+  ready: number;
+  /** Every task */
+  total: number;
+}
+
+/** A unit of work */
+export interface Task {
+  /** Seconds since creation */
+  age_seconds: number;
+  /** A question's answer, for the tasks it blocked */
+  answer?: string;
+  /** Who answered the question */
+  answered_by?: string;
+  /** When the task was archived out of default listings */
+  archived_at?: string;
+  /** Worker holding the task */
+  assigned_to?: string;
+  /** Whether a due date downstream can no longer be met */
+  at_risk?: boolean;
+  /** Times the task was failed with fail_task */
+  attempts?: number;
+  /** Number of direct subtasks */
+  children_count: number;
+  /** When the task finished */
+  completed_at?: string;
+  /** Background for whoever works on it */
+  context?: string;
+  /** Creation time (RFC 3339) */
+  created_at: string;
+  /** What the task is */
+  description: string;
+  /** Deadline */
+  due_at?: string;
+  /** Estimated effort */
+  estimate_minutes?: number;
+  /** Repo-relative paths the task touches */
+  files?: string[];
+  /** Task ID */
+  id: string;
+  /** Earliest deadline from its own due date or due work it blocks */
+  implied_deadline?: string;
+  /** Whether an unfinished task blocks this one */
+  is_blocked: boolean;
+  /** task, or question for a decision put to a person */
+  kind?: "task" | "question";
+  /** Implied deadline less the estimate */
+  latest_start?: string;
+  /** Caller-defined JSON object */
+  metadata?: Record<string, unknown>;
+  /** Parent task ID */
+  parent_id?: string;
+  /** Priority label on the project's scale, e.g. high */
+  priority: string;
+  /** Sort weight of the priority, 0-100, higher first */
+  priority_weight: number;
+  /** Project the task belongs to */
+  project_id?: string;
+  /** Estimates of unfinished tasks in the subtree */
+  remaining_estimate_minutes?: number;
+  /** Shared resources locked while in progress */
+  resources?: string[];
+  /** Outcome recorded when finished */
+  result?: string;
+  /** Review state */
+  review_status?: "requested" | "approved" | "changes_requested";
+  /** Who reviews the task */
+  reviewer?: string;
+  /** Times a reviewer sent the task back */
+  revision?: number;
+  /** Private to the MCP session that made it until promoted */
+  scratch?: boolean;
+  /** When work started */
+  started_at?: string;
+  /** Lifecycle state */
+  status: "pending" | "in_progress" | "completed" | "failed";
+  /** Estimates summed over the subtree */
+  subtree_estimate_minutes?: number;
+  /** Tags on the task */
+  tags?: string[];
+  /** Tracked work on the task and its subtasks */
+  time_spent_seconds?: number;
+  /** Last change */
+  updated_at: string;
+}
+
+export interface AggregateParams {
+  /** Comma-separated dimensions, e.g. status,day */
+  group_by?: string;
+  /** What to compute per group (default count) */
+  metric?: string;
+  /** Only tasks in this status */
+  status?: string;
+}
+
+export interface GetTaskParams {
+  /** Comma-separated fields to return */
+  fields?: string;
+}
+
+export interface ListChangesParams {
+  /** Last sequence number seen */
+  since?: number;
+  /** How long to wait for a change, e.g. 30s (max 60s) */
+  wait?: string;
+}
+
+export interface ListQuestionsParams {
+  /** List answered questions instead, latest first */
+  answered?: boolean;
+}
+
+export interface ListTasksParams {
+  /** Only tasks in this status */
+  status?: "pending" | "in_progress" | "completed" | "failed";
+  /** Only ordinary tasks or only questions */
+  kind?: "task" | "question";
+  /** Only subtasks of this task */
+  parent_id?: string;
+  /** Only tasks assigned to this worker */
+  assigned_to?: string;
+  /** Only tasks in this project */
+  project_id?: string;
+  /** Only tasks carrying every one of these tags */
+  tag?: string[];
+  /** Maximum number of tasks; when more match, the X-Next-Cursor header continues the listing */
+  limit?: number;
+  /** Order by priority (default; newest first among equals), created_at, updated_at or due_at (tasks without one last) */
+  sort?: "priority" | "created_at" | "updated_at" | "due_at";
+  /** Sort direction (default: desc for priority, asc otherwise) */
+  sort_dir?: "asc" | "desc";
+  /** X-Next-Cursor from the previous page, with the same filters and sort */
+  cursor?: string;
+  /** Comma-separated fields to return */
+  fields?: string;
+}
+
+export interface SearchParams {
+  /** Words to find */
+  q: string;
+  /** Only these kinds of result */
+  kind?: ("task" | "comment" | "attachment" | "project")[];
+  /** Maximum number of hits */
+  limit?: number;
+}
+
+/** A non-2xx response. */
+export class BossmanError extends Error {
+  constructor(public readonly status: number, public readonly body: string) {
+    super(`bossman: ${status} ${body.trim()}`);
+  }
+}
+
+type Query = Record<string, string | number | string[] | undefined>;
+
+/** Calls a bossman HTTP server, e.g. new BossmanClient("http://localhost:6969"). */
+export class BossmanClient {
+  constructor(
+    private readonly baseUrl: string,
+    private readonly fetchImpl: typeof fetch = fetch,
+  ) {
+    this.baseUrl = baseUrl.replace(/\/$/, "");
+  }
+
+  private async request<T>(
+    method: string,
+    path: string,
+    query: object | undefined,
+    body: object | undefined,
+    json: boolean,
+  ): Promise<T> {
+    const qs = new URLSearchParams();
+    for (const [key, value] of Object.entries((query ?? {}) as Query)) {
+      if (value === undefined || value === "") continue;
+      for (const v of Array.isArray(value) ? value : [value]) qs.append(key, String(v));
+    }
+    const search = qs.toString();
+    const url = this.baseUrl + path + (search ? "?" + search : "");
+    const headers: Record<string, string> = { Accept: json ? "application/json" : "text/plain" };
+    if (body !== undefined) headers["Content-Type"] = "application/json";
+    const resp = await this.fetchImpl(url, {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await resp.text();
+    if (!resp.ok) throw new BossmanError(resp.status, text);
+    return (json ? JSON.parse(text) : text) as T;
+  }
+
+  /** Count or sum tasks by dimension (GET /aggregate). */
+  aggregate(params: AggregateParams = {}): Promise<AggregateRow[]> {
+    return this.request<AggregateRow[]>("GET", `/aggregate`, params, undefined, true);
+  }
+
+  /** Answer an open question (POST /questions/{id}/answer). */
+  answerQuestion(id: string, body: AnswerRequest): Promise<Task> {
+    return this.request<Task>("POST", `/questions/${encodeURIComponent(id)}/answer`, undefined, body, true);
+  }
+
+  /** Task statistics for dashboards (GET /stats). */
+  getStats(): Promise<Stats> {
+    return this.request<Stats>("GET", `/stats`, undefined, undefined, true);
+  }
+
+  /** Get one task (GET /tasks/{id}). */
+  getTask(id: string, params: GetTaskParams = {}): Promise<Task> {
+    return this.request<Task>("GET", `/tasks/${encodeURIComponent(id)}`, params, undefined, true);
+  }
+
+  /** Health check (GET /health). */
+  health(): Promise<string> {
+    return this.request<string>("GET", `/health`, undefined, undefined, false);
+  }
+
+  /** Changes after a sequence number, long-polling up to wait (GET /api/v1/changes). */
+  listChanges(params: ListChangesParams = {}): Promise<ChangesPage> {
+    return this.request<ChangesPage>("GET", `/api/v1/changes`, params, undefined, true);
+  }
+
+  /** Questions waiting for a person (GET /questions). */
+  listQuestions(params: ListQuestionsParams = {}): Promise<Task[]> {
+    return this.request<Task[]>("GET", `/questions`, params, undefined, true);
+  }
+
+  /** List tasks, optionally filtered (GET /tasks). */
+  listTasks(params: ListTasksParams = {}): Promise<Task[]> {
+    return this.request<Task[]>("GET", `/tasks`, params, undefined, true);
+  }
+
+  /** Search tasks, comments, attachments and projects (GET /search). */
+  search(params: SearchParams): Promise<SearchHit[]> {
+    return this.request<SearchHit[]>("GET", `/search`, params, undefined, true);
+  }
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+	"procdexeh/bossman/internal/tools"
+)
+
+// pipeConn is the client end of an in-memory connection to a server.
+type pipeConn struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (p pipeConn) Close() error {
+	p.PipeWriter.Close()
+	return p.PipeReader.Close()
+}
+
+// TestAgents runs the example fleet against servers sharing one registry
+// and database, as `bossman mcp -listen` serves several clients, and
+// checks the worker-facing promises held and the plan ran in order.
+func TestAgents(t *testing.T) {
+	conn, err := db.InitDB(db.MemoryPath, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	registry := tools.NewRegistry(conn)
+	sessions := mcp.NewSessionRegistry()
+	sessions.OnEnd(registry.EndSession)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	connect := func(string) (io.ReadWriteCloser, error) {
+		serverIn, clientOut := io.Pipe()
+		clientIn, serverOut := io.Pipe()
+		srv := mcp.NewServerWithTransport(registry, mcp.NewTransport(serverIn, serverOut))
+		srv.SetSessions(sessions)
+		go func() {
+			srv.Run(ctx)
+			serverOut.Close()
+		}()
+		return pipeConn{clientIn, clientOut}, nil
+	}
+
+	report, err := Run(ctx, connect, Config{Workers: 3, Work: 5 * time.Millisecond, Poll: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range report.Violations {
+		t.Error(v)
+	}
+	if report.Retries != 1 {
+		t.Errorf("%d retries, want 1", report.Retries)
+	}
+
+	var order []string
+	for _, c := range report.Completed {
+		order = append(order, c.Task)
+	}
+	if len(order) != 6 {
+		t.Fatalf("completed %q, want all 6 tasks", order)
+	}
+	at := func(task string) int { return slices.Index(order, task) }
+	for _, before := range [][2]string{
+		{"Write the design doc", "Implement the feature"},
+		{"Write the design doc", "Write the tests"},
+		{"Implement the feature", "Publish the release"},
+		{"Write the tests", "Publish the release"},
+		{"Update the user guide", "Publish the release"},
+		{"Publish the release", "Ship release 1.0"},
+	} {
+		if at(before[0]) > at(before[1]) {
+			t.Errorf("%q finished before %q", before[1], before[0])
+		}
+	}
+}
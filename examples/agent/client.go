@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// client speaks MCP's JSON-RPC over one connection: one JSON message per
+// line, the framing bossman's stdio and socket transports use. Calls are
+// made one at a time, as a single agent makes them.
+type client struct {
+	name   string
+	conn   io.ReadWriteCloser
+	lines  *bufio.Scanner
+	mu     sync.Mutex // one call on the wire at a time
+	nextID int64
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// toolError is a tool call that came back with ok: false.
+type toolError struct {
+	Tool    string
+	Code    string
+	Message string
+}
+
+func (e *toolError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %s (%s)", e.Tool, e.Message, e.Code)
+	}
+	return e.Tool + ": " + e.Message
+}
+
+// dial connects as name: the initialize handshake, whose clientInfo.name
+// is who bossman assigns tasks to and records in the audit log.
+func dial(ctx context.Context, conn io.ReadWriteCloser, name string) (*client, error) {
+	c := &client{name: name, conn: conn, lines: bufio.NewScanner(conn)}
+	c.lines.Buffer(make([]byte, 64<<10), 16<<20)
+	params := map[string]any{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": name, "version": "0.1.0"},
+	}
+	if _, err := c.request(ctx, "initialize", params); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("initialize %s: %w", name, err)
+	}
+	if err := c.send(rpcRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *client) Close() error { return c.conn.Close() }
+
+func (c *client) send(req rpcRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(append(b, '\n'))
+	return err
+}
+
+// request sends one request and reads until its response, answering the
+// server's pings and skipping its notifications on the way.
+func (c *client) request(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.nextID++
+	id := c.nextID
+	if err := c.send(rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return nil, err
+	}
+	want := fmt.Sprint(id)
+	for c.lines.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(c.lines.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("bad message from server: %w", err)
+		}
+		switch {
+		case msg.Method == "ping" && msg.ID != nil:
+			b, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": map[string]any{}})
+			if _, err := c.conn.Write(append(b, '\n')); err != nil {
+				return nil, err
+			}
+		case msg.Method != "":
+			// a notification, or a request this client doesn't serve
+		case string(msg.ID) == want:
+			if msg.Error != nil {
+				return nil, fmt.Errorf("%s: %s (%d)", method, msg.Error.Message, msg.Error.Code)
+			}
+			return msg.Result, nil
+		}
+	}
+	if err := c.lines.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// call runs a tool and decodes its envelope's data into out. A throttled
+// or busy call is retried after the wait the server asks for.
+func (c *client) call(ctx context.Context, tool string, args, out any) error {
+	if args == nil {
+		args = map[string]any{}
+	}
+	for {
+		raw, err := c.request(ctx, "tools/call", map[string]any{"name": tool, "arguments": args})
+		if err != nil {
+			return err
+		}
+		var result struct {
+			Content []struct {
+				Type string `json:"type"`
+				Text string `json:"text"`
+			} `json:"content"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return err
+		}
+		if len(result.Content) == 0 || result.Content[0].Type != "text" {
+			return fmt.Errorf("%s: no envelope in result", tool)
+		}
+		var env struct {
+			OK    bool            `json:"ok"`
+			Data  json.RawMessage `json:"data"`
+			Error *struct {
+				Message      string `json:"message"`
+				Code         string `json:"code"`
+				RetryAfterMs int64  `json:"retry_after_ms"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &env); err != nil {
+			return fmt.Errorf("%s: %w", tool, err)
+		}
+		if env.OK {
+			if out == nil {
+				return nil
+			}
+			return json.Unmarshal(env.Data, out)
+		}
+		if env.Error == nil {
+			return errors.New(tool + ": failed without an error")
+		}
+		if env.Error.RetryAfterMs <= 0 {
+			return &toolError{Tool: tool, Code: env.Error.Code, Message: env.Error.Message}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(env.Error.RetryAfterMs) * time.Millisecond):
+		}
+	}
+}
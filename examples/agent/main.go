@@ -0,0 +1,108 @@
+// Command agent is an example fleet of worker agents driving bossman over
+// MCP. A planner lays out a small release with blockers and shared
+// resources; workers then claim ready tasks with assign_task, start them,
+// pretend to work and complete them, and one flaky step hands its first
+// attempt back for another try. Along the way each worker checks what the
+// server promises: nothing is offered before its blockers are done, a
+// claimed task's resources are locked to its worker, and finishing frees
+// them. It exits non-zero when a promise is broken.
+//
+//	go build -o bossman ./cmd/bossman
+//	go run ./examples/agent -bossman ./bossman -workers 3
+//
+// By default each agent starts its own `bossman mcp` over stdio, all on
+// one database in a scratch directory. To watch a running server instead,
+// start it with `bossman mcp -listen unix:/tmp/bossman.sock` and pass
+// -addr unix:/tmp/bossman.sock; the plan goes into that server's database.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"procdexeh/bossman/internal/mcp"
+)
+
+func main() {
+	bossman := flag.String("bossman", "bossman", "bossman binary to start for each agent")
+	addr := flag.String("addr", "", "connect to a server started with mcp -listen instead (unix:PATH or pipe:NAME)")
+	dir := flag.String("dir", "", "directory for the spawned servers' bossman.db (default: a new temporary one)")
+	workers := flag.Int("workers", 3, "number of worker agents")
+	work := flag.Duration("work", 100*time.Millisecond, "how long each task pretends to take")
+	verbose := flag.Bool("v", false, "pass the spawned servers' logs through to stderr")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	connect := func(string) (io.ReadWriteCloser, error) { return mcp.Dial(*addr) }
+	if *addr == "" {
+		if *dir == "" {
+			tmp, err := os.MkdirTemp("", "bossman-agents-")
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			defer os.RemoveAll(tmp)
+			*dir = tmp
+		}
+		connect = func(string) (io.ReadWriteCloser, error) { return spawn(ctx, *bossman, *dir, *verbose) }
+	}
+
+	report, err := Run(ctx, connect, Config{Workers: *workers, Work: *work, Poll: *work / 2})
+	if report != nil {
+		for _, c := range report.Completed {
+			fmt.Printf("%-8s completed %s\n", c.Worker, c.Task)
+		}
+		fmt.Printf("%d tasks, %d lost claims, %d retries\n", len(report.Completed), report.LostClaims, report.Retries)
+		for _, v := range report.Violations {
+			fmt.Println("VIOLATION:", v)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(report.Violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// process is a `bossman mcp` child: its stdout is read, its stdin written.
+type process struct {
+	io.Reader
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+// Close hangs up, which ends the server's session and the process.
+func (p *process) Close() error {
+	p.WriteCloser.Close()
+	return p.cmd.Wait()
+}
+
+func spawn(ctx context.Context, bossman, dir string, verbose bool) (*process, error) {
+	cmd := exec.CommandContext(ctx, bossman, "mcp")
+	cmd.Dir = dir
+	if verbose {
+		cmd.Stderr = os.Stderr
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", bossman, err)
+	}
+	return &process{Reader: stdout, WriteCloser: stdin, cmd: cmd}, nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// task is the part of bossman's task JSON the agents read.
+type task struct {
+	ID          string          `json:"id"`
+	Description string          `json:"description"`
+	Status      string          `json:"status"`
+	AssignedTo  string          `json:"assigned_to"`
+	Resources   []string        `json:"resources"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
+
+type lock struct {
+	Resource string `json:"resource"`
+	TaskID   string `json:"task_id"`
+	Holder   string `json:"holder"`
+}
+
+// Config shapes a run.
+type Config struct {
+	Workers int
+	Work    time.Duration // how long each task pretends to take
+	Poll    time.Duration // wait before asking again when nothing is ready
+}
+
+// Completion is one task finished by one worker.
+type Completion struct {
+	Task   string
+	Worker string
+}
+
+// Report is what a run saw. Violations are broken promises of the worker
+// API: a task started before its blockers finished, a lock not held by
+// the worker doing the task or not freed after it, a task done twice.
+type Report struct {
+	Completed  []Completion // in order
+	LostClaims int          // assign_task refused: someone else's task, or its resource was held
+	Retries    int          // attempts handed back for another try
+	Violations []string
+}
+
+// connectFunc opens one MCP connection for the named agent.
+type connectFunc func(name string) (io.ReadWriteCloser, error)
+
+// Run plans a small release as "planner", then lets cfg.Workers agents
+// claim, work and finish its tasks until the release itself is done.
+func Run(ctx context.Context, connect connectFunc, cfg Config) (*Report, error) {
+	open := func(name string) (*client, error) {
+		conn, err := connect(name)
+		if err != nil {
+			return nil, err
+		}
+		return dial(ctx, conn, name)
+	}
+	planner, err := open("planner")
+	if err != nil {
+		return nil, err
+	}
+	defer planner.Close()
+	root, err := plan(ctx, planner)
+	if err != nil {
+		return nil, fmt.Errorf("plan: %w", err)
+	}
+
+	report := &Report{}
+	var mu sync.Mutex
+	errs := make([]error, cfg.Workers)
+	var wg sync.WaitGroup
+	for i := range cfg.Workers {
+		c, err := open(fmt.Sprintf("agent-%d", i+1))
+		if err != nil {
+			return nil, err
+		}
+		defer c.Close()
+		w := &worker{c: c, cfg: cfg, root: root, report: report, mu: &mu}
+		wg.Go(func() { errs[i] = w.run(ctx) })
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return report, err
+	}
+
+	// nothing may be left running or locked
+	var locks []lock
+	if err := planner.call(ctx, "list_locks", nil, &locks); err != nil {
+		return report, err
+	}
+	for _, l := range locks {
+		report.Violations = append(report.Violations, fmt.Sprintf("%s still locked by %s", l.Resource, l.Holder))
+	}
+	return report, nil
+}
+
+// plan creates the release and its steps, returning the release's id.
+// The implementation and the tests share the repo, so only one of them
+// can be claimed at a time; publishing fails on its first attempt.
+func plan(ctx context.Context, c *client) (string, error) {
+	var root task
+	if err := c.call(ctx, "create_task", map[string]any{
+		"description": "Ship release 1.0",
+	}, &root); err != nil {
+		return "", err
+	}
+	ids := map[string]string{}
+	steps := []struct {
+		key, description string
+		resources        []string
+		blockedBy        []string
+		metadata         map[string]any
+	}{
+		{"design", "Write the design doc", []string{"doc:design"}, nil, nil},
+		{"impl", "Implement the feature", []string{"repo:main"}, []string{"design"}, nil},
+		{"tests", "Write the tests", []string{"repo:main"}, []string{"design"}, nil},
+		{"guide", "Update the user guide", []string{"doc:guide"}, nil, nil},
+		{"publish", "Publish the release", nil, []string{"impl", "tests", "guide"},
+			map[string]any{"fail_first_attempt": true}},
+	}
+	for _, s := range steps {
+		args := map[string]any{"description": s.description, "parent_id": root.ID}
+		if s.resources != nil {
+			args["resources"] = s.resources
+		}
+		if s.metadata != nil {
+			args["metadata"] = s.metadata
+		}
+		var blockers []string
+		for _, key := range s.blockedBy {
+			blockers = append(blockers, ids[key])
+		}
+		if blockers != nil {
+			args["blocked_by"] = blockers
+		}
+		var t task
+		if err := c.call(ctx, "create_task", args, &t); err != nil {
+			return "", err
+		}
+		ids[s.key] = t.ID
+	}
+	return root.ID, nil
+}
+
+type worker struct {
+	c      *client
+	cfg    Config
+	root   string
+	report *Report
+	mu     *sync.Mutex // guards report
+}
+
+func (w *worker) violation(format string, args ...any) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.report.Violations = append(w.report.Violations, w.c.name+": "+fmt.Sprintf(format, args...))
+}
+
+// run claims ready work until the release is completed.
+func (w *worker) run(ctx context.Context) error {
+	for {
+		var ready []task
+		if err := w.c.call(ctx, "get_ready_tasks", map[string]any{"limit": w.cfg.Workers + 1}, &ready); err != nil {
+			return err
+		}
+		claimed := false
+		for _, t := range ready {
+			// assign_task is the claim: it fails if another agent got
+			// there first or one of the task's resources is locked
+			err := w.c.call(ctx, "assign_task", map[string]any{"id": t.ID}, nil)
+			var refused *toolError
+			if errors.As(err, &refused) {
+				w.mu.Lock()
+				w.report.LostClaims++
+				w.mu.Unlock()
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := w.work(ctx, t); err != nil {
+				return fmt.Errorf("%s on %q: %w", w.c.name, t.Description, err)
+			}
+			claimed = true
+			break
+		}
+		if claimed {
+			continue
+		}
+		var root task
+		if err := w.c.call(ctx, "get_task", map[string]any{"id": w.root}, &root); err != nil {
+			return err
+		}
+		if root.Status == "completed" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.cfg.Poll):
+		}
+	}
+}
+
+// work does one claimed task: start it, check what the server promised,
+// pretend to work, and finish it or hand it back.
+func (w *worker) work(ctx context.Context, t task) error {
+	var blockers []task
+	if err := w.c.call(ctx, "get_blockers", map[string]any{"task_id": t.ID}, &blockers); err != nil {
+		return err
+	}
+	for _, b := range blockers {
+		if b.Status != "completed" {
+			w.violation("%q offered while %q is %s", t.Description, b.Description, b.Status)
+		}
+	}
+	if err := w.c.call(ctx, "update_task", map[string]any{"id": t.ID, "status": "in_progress"}, nil); err != nil {
+		return err
+	}
+	locks, err := w.locks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range t.Resources {
+		i := slices.IndexFunc(locks, func(l lock) bool { return l.Resource == r })
+		if i < 0 || locks[i].TaskID != t.ID || locks[i].Holder != w.c.name {
+			w.violation("working on %q without holding %s", t.Description, r)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(w.cfg.Work):
+	}
+
+	var meta struct {
+		FailFirstAttempt bool `json:"fail_first_attempt"`
+		Attempts         int  `json:"attempts"`
+	}
+	if len(t.Metadata) > 0 {
+		if err := json.Unmarshal(t.Metadata, &meta); err != nil {
+			return err
+		}
+	}
+	if meta.FailFirstAttempt && meta.Attempts == 0 {
+		return w.giveBack(ctx, t)
+	}
+	if err := w.c.call(ctx, "update_task", map[string]any{
+		"id": t.ID, "status": "completed", "result": "done by " + w.c.name,
+	}, nil); err != nil {
+		return err
+	}
+
+	if locks, err = w.locks(ctx); err != nil {
+		return err
+	}
+	for _, l := range locks {
+		if l.TaskID == t.ID {
+			w.violation("%s still locked after %q completed", l.Resource, t.Description)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, c := range w.report.Completed {
+		if c.Task == t.Description {
+			w.report.Violations = append(w.report.Violations,
+				fmt.Sprintf("%s: %q completed again, first by %s", w.c.name, t.Description, c.Worker))
+		}
+	}
+	w.report.Completed = append(w.report.Completed, Completion{Task: t.Description, Worker: w.c.name})
+	return nil
+}
+
+// giveBack hands a task that didn't work out back to the queue: in
+// progress goes back to pending, noting the attempt, and unassigning it
+// lets any worker retry it. Failing it instead would close it, and its
+// parent could be completed before anyone reopened it.
+func (w *worker) giveBack(ctx context.Context, t task) error {
+	if err := w.c.call(ctx, "update_task", map[string]any{
+		"id": t.ID, "status": "pending", "result": "flaky: first attempt always fails",
+		"metadata": map[string]any{"attempts": 1},
+	}, nil); err != nil {
+		return err
+	}
+	if err := w.c.call(ctx, "unassign_task", map[string]any{"id": t.ID}, nil); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.report.Retries++
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *worker) locks(ctx context.Context) ([]lock, error) {
+	var locks []lock
+	err := w.c.call(ctx, "list_locks", nil, &locks)
+	return locks, err
+}
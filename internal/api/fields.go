@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// taskFields maps each JSON field name to its index in Task.
+var taskFields = func() map[string]int {
+	fields := make(map[string]int)
+	t := reflect.TypeOf(Task{})
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// ValidateFields rejects names that aren't Task fields.
+func ValidateFields(fields []string) error {
+	for _, f := range fields {
+		if _, ok := taskFields[f]; !ok {
+			return fmt.Errorf("unknown field: %s", f)
+		}
+	}
+	return nil
+}
+
+// Project returns only the named fields of t. Unlike the full Task,
+// requested fields are always present even when empty.
+// Call ValidateFields first.
+func (t Task) Project(fields []string) map[string]any {
+	v := reflect.ValueOf(t)
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		out[f] = v.Field(taskFields[f]).Interface()
+	}
+	return out
+}
+
+// ProjectTasks applies Project to each task.
+func ProjectTasks(tasks []Task, fields []string) []map[string]any {
+	out := make([]map[string]any, len(tasks))
+	for i := range tasks {
+		out[i] = tasks[i].Project(fields)
+	}
+	return out
+}
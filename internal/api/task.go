@@ -0,0 +1,223 @@
+// Package api defines the public JSON shape of bossman entities. Tools,
+// REST handlers and exports convert db rows through here so every surface
+// speaks the same field names.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+)
+
+type Task struct {
+	ID          string `json:"id"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Description string `json:"description"`
+	Context     string `json:"context,omitempty"`
+	Priority    string `json:"priority"` // label on the project's scale
+	Status      string `json:"status"`
+	Result      string `json:"result,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	StartedAt   string `json:"started_at,omitempty"`
+	CompletedAt string `json:"completed_at,omitempty"`
+	UpdatedAt   string `json:"updated_at"`
+	// ArchivedAt is set on finished work moved out of default listings.
+	ArchivedAt string `json:"archived_at,omitempty"`
+
+	EstimateMinutes int    `json:"estimate_minutes,omitempty"`
+	AssignedTo      string `json:"assigned_to,omitempty"`
+	DueAt           string `json:"due_at,omitempty"`
+	ProjectID       string `json:"project_id,omitempty"`
+	// PriorityWeight orders tasks, 0-100 with higher first.
+	PriorityWeight int `json:"priority_weight"`
+
+	// Metadata is the caller's free-form JSON object, returned verbatim.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+
+	ReviewStatus string `json:"review_status,omitempty"`
+	Reviewer     string `json:"reviewer,omitempty"`
+	Revision     int    `json:"revision,omitempty"`
+	// Attempts counts the times the task was failed with fail_task.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Kind is "task", or "question" for a decision put to a person; the
+	// answer is there for the tasks it blocked once it's given.
+	Kind       string `json:"kind"`
+	Answer     string `json:"answer,omitempty"`
+	AnsweredBy string `json:"answered_by,omitempty"`
+	// Scratch marks a task private to the session that made it; it goes
+	// away with the session unless promoted.
+	Scratch bool `json:"scratch,omitempty"`
+
+	Tags []string `json:"tags,omitempty"`
+	// Resources are the shared locks the task takes while in progress.
+	Resources []string `json:"resources,omitempty"`
+	// Files are the repo-relative paths the task touches.
+	Files []string `json:"files,omitempty"`
+
+	// TimeSpentSeconds rolls up tracked work on the task and its subtasks.
+	TimeSpentSeconds int64 `json:"time_spent_seconds,omitempty"`
+	// Estimate rollups over the task and its subtasks; remaining excludes
+	// completed and failed ones.
+	SubtreeEstimateMinutes   int `json:"subtree_estimate_minutes,omitempty"`
+	RemainingEstimateMinutes int `json:"remaining_estimate_minutes,omitempty"`
+	// Deadlines implied by due work this task blocks, and whether the
+	// chain to it can no longer make its due date; see db.DeadlineRisk.
+	ImpliedDeadline string `json:"implied_deadline,omitempty"`
+	LatestStart     string `json:"latest_start,omitempty"`
+	AtRisk          bool   `json:"at_risk,omitempty"`
+
+	// Derived; not stored.
+	IsBlocked     bool  `json:"is_blocked"`
+	AgeSeconds    int64 `json:"age_seconds"`
+	ChildrenCount int   `json:"children_count"`
+}
+
+func deref[T ~string](s *T) string {
+	if s == nil {
+		return ""
+	}
+	return string(*s)
+}
+
+func derefTime(t *db.Timestamp) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// FromDB converts a row plus its relation counts. now anchors AgeSeconds.
+func FromDB(t *db.Task, rel db.TaskRelations, now time.Time) Task {
+	out := Task{
+		ID:             t.ID,
+		ParentID:       deref(t.ParentID),
+		Description:    t.Description,
+		Context:        string(t.Context),
+		Priority:       t.PriorityLabel,
+		Status:         t.Status,
+		Result:         deref(t.Result),
+		CreatedAt:      t.CreatedAt.String(),
+		StartedAt:      derefTime(t.StartedAt),
+		CompletedAt:    derefTime(t.CompletedAt),
+		UpdatedAt:      t.UpdatedAt.String(),
+		ArchivedAt:     derefTime(t.ArchivedAt),
+		AssignedTo:     deref(t.AssignedTo),
+		DueAt:          derefTime(t.DueAt),
+		ProjectID:      deref(t.ProjectID),
+		PriorityWeight: t.PriorityWeight,
+		ReviewStatus:   deref(t.ReviewStatus),
+		Reviewer:       deref(t.Reviewer),
+		Revision:       t.Revision,
+		Attempts:       t.Attempts,
+		Kind:           t.Kind,
+		Answer:         deref(t.Answer),
+		AnsweredBy:     deref(t.AnsweredBy),
+		Scratch:        t.SessionID != nil,
+		IsBlocked:      rel.OpenBlockers > 0,
+		ChildrenCount:  rel.Children,
+	}
+	if t.EstimateMinutes != nil {
+		out.EstimateMinutes = *t.EstimateMinutes
+	}
+	if t.Metadata != nil {
+		out.Metadata = json.RawMessage(*t.Metadata)
+	}
+	out.AgeSeconds = int64(t.Age(now).Seconds())
+	return out
+}
+
+// Tasks converts rows, loading relation counts in one query.
+func Tasks(ctx context.Context, conn *sqlx.DB, tasks []db.Task) ([]Task, error) {
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	rels, err := db.GetTaskRelations(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load relations: %w", err)
+	}
+	tags, err := db.GetTagsForTasks(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load tags: %w", err)
+	}
+	resources, err := db.GetResourcesForTasks(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load resources: %w", err)
+	}
+	files, err := db.GetFilesForTasks(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load files: %w", err)
+	}
+	spent, err := db.GetTimeSpent(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load time spent: %w", err)
+	}
+	estimates, err := db.GetEstimateRollups(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load estimates: %w", err)
+	}
+	risks, err := db.GetDeadlineRisks(ctx, conn, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load deadlines: %w", err)
+	}
+	now := clock.From(ctx).Now()
+	out := make([]Task, len(tasks))
+	for i := range tasks {
+		out[i] = FromDB(&tasks[i], rels[tasks[i].ID], now)
+		out[i].Tags = tags[tasks[i].ID]
+		out[i].Resources = resources[tasks[i].ID]
+		out[i].Files = files[tasks[i].ID]
+		out[i].TimeSpentSeconds = spent[tasks[i].ID]
+		rollup := estimates[tasks[i].ID]
+		out[i].SubtreeEstimateMinutes = rollup.Total
+		out[i].RemainingEstimateMinutes = rollup.Remaining
+		risk := risks[tasks[i].ID]
+		out[i].ImpliedDeadline = risk.ImpliedDeadline
+		out[i].LatestStart = risk.LatestStart
+		out[i].AtRisk = risk.AtRisk
+	}
+	return out, nil
+}
+
+// One converts a single row.
+func One(ctx context.Context, conn *sqlx.DB, t *db.Task) (Task, error) {
+	out, err := Tasks(ctx, conn, []db.Task{*t})
+	if err != nil {
+		return Task{}, err
+	}
+	return out[0], nil
+}
+
+// FromStore converts rows read through a Store. SQLite gets every derived
+// field via Tasks; other backends fill relation counts and tags only.
+func FromStore(ctx context.Context, s db.Store, tasks []db.Task) ([]Task, error) {
+	if conn := db.SQLite(s); conn != nil {
+		return Tasks(ctx, conn, tasks)
+	}
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	rels, err := s.TaskRelations(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load relations: %w", err)
+	}
+	tags, err := s.TagsForTasks(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load tags: %w", err)
+	}
+	now := clock.From(ctx).Now()
+	out := make([]Task, len(tasks))
+	for i := range tasks {
+		out[i] = FromDB(&tasks[i], rels[tasks[i].ID], now)
+		out[i].Tags = tags[tasks[i].ID]
+	}
+	return out, nil
+}
@@ -0,0 +1,186 @@
+// Package clock lets time-based behaviour run against a controllable clock.
+// Code with a context reads the clock from it; long-lived components take
+// one through a SetClock method. Both default to the wall clock.
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker mirrors time.Ticker behind an interface so fakes can drive it.
+// A receiver calls Done once it has handled a tick, so Fake.Run knows the
+// work the tick started is over; real tickers ignore it.
+type Ticker interface {
+	C() <-chan time.Time
+	Done()
+	Stop()
+}
+
+// Real is the wall clock.
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Done()               {}
+func (r realTicker) Stop()               { r.t.Stop() }
+
+type ctxKey struct{}
+
+func With(ctx context.Context, c Clock) context.Context {
+	return context.WithValue(ctx, ctxKey{}, c)
+}
+
+// From returns the clock in ctx, or the wall clock if there is none.
+func From(ctx context.Context) Clock {
+	if c, ok := ctx.Value(ctxKey{}).(Clock); ok {
+		return c
+	}
+	return Real{}
+}
+
+// Fake only moves when told to. Advancing it fires any tickers that came
+// due, at most one tick each, like a slow receiver on a real ticker.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{clock: f, period: d, next: f.now.Add(d), c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and fires due tickers.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set jumps the clock to t. Moving backwards fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.set(t)
+}
+
+// set moves the clock and returns the tickers it fired.
+func (f *Fake) set(t time.Time) []*fakeTicker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+	var fired []*fakeTicker
+	for _, tk := range f.tickers {
+		if tk.next.After(t) {
+			continue
+		}
+		for !tk.next.After(t) {
+			tk.next = tk.next.Add(tk.period)
+		}
+		tk.handled.Add(1)
+		select {
+		case tk.c <- t:
+			fired = append(fired, tk)
+		default:
+			tk.handled.Done()
+		}
+	}
+	return fired
+}
+
+// Run advances the clock by d one due tick at a time, in order, waiting
+// after each until its receivers call Done, so a long jump delivers every
+// scheduled tick instead of one and returns only once the work they
+// started has finished. Returns the number of ticks delivered.
+func (f *Fake) Run(ctx context.Context, d time.Duration) (int, error) {
+	target := f.Now().Add(d)
+	ticks := 0
+	for {
+		next, ok := f.nextTick()
+		if !ok || next.After(target) {
+			f.set(target)
+			return ticks, nil
+		}
+		fired := f.set(next)
+		ticks += len(fired)
+		for _, tk := range fired {
+			handled := make(chan struct{})
+			go func() {
+				tk.handled.Wait()
+				close(handled)
+			}()
+			select {
+			case <-handled:
+			case <-ctx.Done():
+				return ticks, ctx.Err()
+			}
+		}
+	}
+}
+
+func (f *Fake) nextTick() (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var next time.Time
+	for i, tk := range f.tickers {
+		if i == 0 || tk.next.Before(next) {
+			next = tk.next
+		}
+	}
+	return next, len(f.tickers) > 0
+}
+
+type fakeTicker struct {
+	clock   *Fake
+	period  time.Duration
+	next    time.Time
+	c       chan time.Time
+	handled sync.WaitGroup // ticks delivered and not yet Done
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Done() { t.handled.Done() }
+
+func (t *fakeTicker) Stop() {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, tk := range f.tickers {
+		if tk == t {
+			f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+			break
+		}
+	}
+	// a tick nobody received will never be handled
+	select {
+	case <-t.c:
+		t.handled.Done()
+	default:
+	}
+}
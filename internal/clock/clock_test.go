@@ -0,0 +1,39 @@
+package clock
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFakeRunWaitsForReceivers runs a fake clock past several ticks of a
+// receiver that is slow to handle each, and wants every tick's work done
+// by the time Run returns.
+func TestFakeRunWaitsForReceivers(t *testing.T) {
+	fake := NewFake(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	ticker := fake.NewTicker(time.Hour)
+	var handled atomic.Int32
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C():
+				time.Sleep(10 * time.Millisecond)
+				handled.Add(1)
+				ticker.Done()
+			}
+		}
+	}()
+
+	ticks, err := fake.Run(context.Background(), 3*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticks != 3 || handled.Load() != 3 {
+		t.Errorf("delivered %d ticks, handled %d; want 3 and 3", ticks, handled.Load())
+	}
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// groupByExprs whitelists what callers may group by. Keys are the public
+// names; values are SQL expressions over tasks (aliased t).
+var groupByExprs = map[string]string{
+	"status":        "t.status",
+	"priority":      "t.priority_label",
+	"parent":        "t.parent_id",
+	"day":           "substr(t.created_at, 1, 10)",
+	"completed_day": "substr(t.completed_at, 1, 10)",
+	"tag":           "tg.tag",
+	"assignee":      "t.assigned_to",
+	"project":       "t.project_id",
+}
+
+// groupByJoins adds the joins some group-by dimensions need. A task with
+// several tags counts once per tag; untagged tasks group under null.
+var groupByJoins = map[string]string{
+	"tag": "LEFT JOIN task_tags tg ON tg.task_id = t.id",
+}
+
+// metricExprs whitelists the aggregate computed per group.
+var metricExprs = map[string]string{
+	"count":            "COUNT(*)",
+	"estimate_minutes": "COALESCE(SUM(t.estimate_minutes), 0)",
+}
+
+type AggregateOpts struct {
+	GroupBy []string
+	Metric  string // defaults to count
+	Status  *string
+	// ProjectID limits the tasks counted; "" means tasks outside any project.
+	ProjectID *string
+}
+
+type AggregateRow struct {
+	Group map[string]any `json:"group"`
+	Value float64        `json:"value"`
+}
+
+// GroupByNames and MetricNames list the accepted values, for schemas and errors.
+func GroupByNames() []string { return sortedKeys(groupByExprs) }
+func MetricNames() []string  { return sortedKeys(metricExprs) }
+
+func Aggregate(ctx context.Context, db *sqlx.DB, opts AggregateOpts) ([]AggregateRow, error) {
+	if opts.Metric == "" {
+		opts.Metric = "count"
+	}
+	metric, ok := metricExprs[opts.Metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric: %s (want one of %s)", opts.Metric, strings.Join(MetricNames(), ", "))
+	}
+
+	cols := make([]string, 0, len(opts.GroupBy)+1)
+	for i, g := range opts.GroupBy {
+		expr, ok := groupByExprs[g]
+		if !ok {
+			return nil, fmt.Errorf("unknown group_by: %s (want one of %s)", g, strings.Join(GroupByNames(), ", "))
+		}
+		cols = append(cols, fmt.Sprintf("%s AS g%d", expr, i))
+	}
+	cols = append(cols, metric+" AS value")
+
+	query := "SELECT " + strings.Join(cols, ", ") + " FROM tasks t"
+	for _, g := range opts.GroupBy {
+		if join, ok := groupByJoins[g]; ok {
+			query += " " + join
+		}
+	}
+	query += " WHERE 1=1"
+	var args []any
+	if opts.Status != nil {
+		query += " AND t.status = ?"
+		args = append(args, *opts.Status)
+	}
+	if opts.ProjectID != nil {
+		if *opts.ProjectID == "" {
+			query += " AND t.project_id IS NULL"
+		} else {
+			query += " AND t.project_id = ?"
+			args = append(args, *opts.ProjectID)
+		}
+	}
+	if len(opts.GroupBy) > 0 {
+		groups := make([]string, len(opts.GroupBy))
+		for i := range opts.GroupBy {
+			groups[i] = fmt.Sprintf("g%d", i)
+		}
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AggregateRow
+	for rows.Next() {
+		vals, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		row := AggregateRow{Group: make(map[string]any, len(opts.GroupBy))}
+		for i, g := range opts.GroupBy {
+			row.Group[g] = vals[i]
+		}
+		switch v := vals[len(vals)-1].(type) {
+		case int64:
+			row.Value = float64(v)
+		case float64:
+			row.Value = v
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Archived tasks are finished work moved out of the way. They keep their
+// row, history and ID, so GetTask, parents and blocker edges still find
+// them, but QueryTasks leaves them out unless ListOpts.Archived asks for
+// them instead. Reopening an archived task unarchives it.
+
+// ArchiveOpts selects what ArchiveCompleted archives.
+type ArchiveOpts struct {
+	// Before archives tasks completed before this time.
+	Before time.Time
+	// ProjectID limits archiving to one project; "" for tasks outside any.
+	ProjectID *string
+}
+
+// ArchiveCompleted archives the tasks completed before opts.Before and
+// returns them. A task goes only with its whole subtree, so a parent stays
+// while a subtask is open, failed or completed too recently. System and
+// scratch tasks are never archived.
+func ArchiveCompleted(ctx context.Context, db *sqlx.DB, opts ArchiveOpts) ([]Task, error) {
+	query := `UPDATE tasks SET archived_at = :now, updated_at = :now
+		WHERE status = 'completed' AND archived_at IS NULL AND completed_at < :before
+		  AND session_id IS NULL AND id != :system AND (parent_id IS NULL OR parent_id != :system)
+		  AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = tasks.id AND c.archived_at IS NULL)`
+	args := map[string]any{"before": FormatTime(opts.Before), "system": SystemTaskID}
+	if opts.ProjectID != nil {
+		if *opts.ProjectID == "" {
+			query += " AND project_id IS NULL"
+		} else {
+			query += " AND project_id = :project_id"
+			args["project_id"] = *opts.ProjectID
+		}
+	}
+	query += " RETURNING *"
+
+	var archived []Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		args["now"] = now(ctx)
+		bound, bindArgs, err := tx.BindNamed(query, args)
+		if err != nil {
+			return err
+		}
+		// each pass takes the tasks whose subtasks are all archived, so
+		// subtrees go leaves first, one level per pass
+		for {
+			var level []Task
+			if err := tx.SelectContext(ctx, &level, bound, bindArgs...); err != nil {
+				return err
+			}
+			if len(level) == 0 {
+				break
+			}
+			events := make([]taskEvent, len(level))
+			for i, t := range level {
+				events[i] = taskEvent{TaskID: t.ID, Entity: "task", Op: "update",
+					Old: map[string]any{"archived_at": nil}, New: map[string]any{"archived_at": t.ArchivedAt}}
+			}
+			if err := recordEventsTx(ctx, tx, events); err != nil {
+				return err
+			}
+			archived = append(archived, level...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
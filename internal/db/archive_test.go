@@ -0,0 +1,96 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// TestArchiveCompleted archives a finished subtree while a parent with an
+// open subtask stays, then reopens an archived task.
+func TestArchiveCompleted(t *testing.T) {
+	conn, ctx := newTestDB(t)
+	fake := clock.NewFake(time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC))
+	ctx = clock.With(ctx, fake)
+
+	add := func(description string, parent *Task) *Task {
+		task := &Task{ID: NewTaskID(), Description: description}
+		if parent != nil {
+			task.ParentID = &parent.ID
+		}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	setStatus := func(task *Task, status string, reopen bool) {
+		if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Status: &status, Reopen: reopen}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := add("release 1.0", nil)
+	step := add("tag the release", done)
+	open := add("release 1.1", nil)
+	finished := add("bump the version", open)
+	add("write the changelog", open)
+	complete := func(task *Task) {
+		setStatus(task, StatusInProgress, false)
+		setStatus(task, StatusCompleted, false)
+	}
+	for _, task := range []*Task{step, done, finished} {
+		complete(task)
+	}
+	recent := add("fix the typo", nil)
+	fake.Advance(10 * 24 * time.Hour)
+	complete(recent)
+
+	archived, err := ArchiveCompleted(ctx, conn, ArchiveOpts{Before: fake.Now().Add(-7 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range archived {
+		got = append(got, task.Description)
+		if task.ArchivedAt == nil {
+			t.Errorf("%s: no archived_at", task.Description)
+		}
+	}
+	want := []string{"tag the release", "bump the version", "release 1.0"}
+	if len(got) != len(want) {
+		t.Fatalf("archived %q, want %q", got, want)
+	}
+	for _, description := range want {
+		if !slices.Contains(got, description) {
+			t.Errorf("archived %q, want %q", got, want)
+		}
+	}
+	if got[len(got)-1] != "release 1.0" {
+		t.Errorf("archived %q: parent before its subtask", got)
+	}
+
+	listed, err := QueryTasks(ctx, conn, ListOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 3 {
+		t.Errorf("listed %d tasks, want the 3 not archived", len(listed))
+	}
+	inArchive, err := QueryTasks(ctx, conn, ListOpts{Archived: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inArchive) != 3 {
+		t.Errorf("archive lists %d tasks, want 3", len(inArchive))
+	}
+
+	setStatus(step, StatusPending, true)
+	reopened, err := GetTask(ctx, conn, step.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.ArchivedAt != nil {
+		t.Errorf("reopened task still archived at %s", reopened.ArchivedAt)
+	}
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+// MaxAttachmentBytes caps inline attachments; larger artifacts should be
+// attached by path or URL.
+const MaxAttachmentBytes = 1 << 20
+
+// Attachment is an artifact produced while working a task: inline bytes,
+// a local file path or a URL. Exactly one of Data, Path and URL is set.
+type Attachment struct {
+	ID        string  `db:"id" json:"id"`
+	TaskID    string  `db:"task_id" json:"task_id"`
+	Name      string  `db:"name" json:"name"`
+	MimeType  string  `db:"mime_type" json:"mime_type"`
+	Data      []byte  `db:"data" json:"-"`
+	Path      *string `db:"path" json:"path,omitempty"`
+	URL       *string `db:"url" json:"url,omitempty"`
+	Size      int64   `db:"size" json:"size"`
+	Author    string  `db:"author" json:"author"`
+	CreatedAt string  `db:"created_at" json:"created_at"`
+}
+
+// ExtAttachments is the extension holding attachments.
+const ExtAttachments = "attachments"
+
+func init() {
+	RegisterExtension(Extension{
+		Name: ExtAttachments,
+		Schema: `
+CREATE TABLE IF NOT EXISTS task_attachments (
+    id         TEXT PRIMARY KEY,
+    task_id    TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    mime_type  TEXT NOT NULL,
+    data       BLOB,    -- inline content, or
+    path       TEXT,    -- a local file, or
+    url        TEXT,    -- a link; exactly one is set
+    size       INTEGER NOT NULL DEFAULT 0,
+    author     TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    CHECK ((data IS NOT NULL) + (path IS NOT NULL) + (url IS NOT NULL) = 1)
+);
+CREATE INDEX IF NOT EXISTS idx_task_attachments_task ON task_attachments(task_id, created_at);
+`,
+		Tables: []string{"task_attachments"},
+	})
+}
+
+func NewAttachmentID() string {
+	return "attachment_" + xid.New().String()
+}
+
+// attachmentColumns leaves out data so listings stay small.
+const attachmentColumns = "id, task_id, name, mime_type, path, url, size, author, created_at"
+
+// AddAttachment assigns an ID if missing, stamps CreatedAt and sets Size
+// for inline data.
+func AddAttachment(ctx context.Context, db *sqlx.DB, a *Attachment) error {
+	if err := ensureExtension(ctx, db, ExtAttachments); err != nil {
+		return err
+	}
+	if a.ID == "" {
+		a.ID = NewAttachmentID()
+	}
+	if a.Data != nil {
+		a.Size = int64(len(a.Data))
+	}
+	a.CreatedAt = now(ctx)
+	_, err := db.NamedExecContext(ctx,
+		`INSERT INTO task_attachments (id, task_id, name, mime_type, data, path, url, size, author, created_at)
+		 VALUES (:id, :task_id, :name, :mime_type, :data, :path, :url, :size, :author, :created_at)`, a)
+	return err
+}
+
+// ListAttachments returns a task's attachments oldest first, without data.
+func ListAttachments(ctx context.Context, db *sqlx.DB, taskID string) ([]Attachment, error) {
+	if err := ensureExtension(ctx, db, ExtAttachments); err != nil {
+		return nil, err
+	}
+	var out []Attachment
+	err := db.SelectContext(ctx, &out,
+		"SELECT "+attachmentColumns+" FROM task_attachments WHERE task_id = ? ORDER BY created_at, id", taskID)
+	return out, err
+}
+
+// RecentAttachments returns the newest attachments across all tasks,
+// without data.
+func RecentAttachments(ctx context.Context, db *sqlx.DB, limit int) ([]Attachment, error) {
+	if err := ensureExtension(ctx, db, ExtAttachments); err != nil {
+		return nil, err
+	}
+	var out []Attachment
+	err := db.SelectContext(ctx, &out,
+		"SELECT "+attachmentColumns+" FROM task_attachments ORDER BY created_at DESC, id DESC LIMIT ?", limit)
+	return out, err
+}
+
+// GetAttachment returns one attachment including its data.
+func GetAttachment(ctx context.Context, db *sqlx.DB, id string) (*Attachment, error) {
+	if err := ensureExtension(ctx, db, ExtAttachments); err != nil {
+		return nil, err
+	}
+	var a Attachment
+	if err := db.GetContext(ctx, &a, "SELECT * FROM task_attachments WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskEvent is one row of the audit log. Values are JSON objects holding
+// only the fields that changed (updates) or the whole row (insert/delete).
+type TaskEvent struct {
+	ID        int64   `db:"id"`
+	TaskID    string  `db:"task_id"`
+	Entity    string  `db:"entity"`
+	Op        string  `db:"op"`
+	OldValue  *string `db:"old_value"`
+	NewValue  *string `db:"new_value"`
+	Actor     string  `db:"actor"`
+	CreatedAt string  `db:"created_at"`
+}
+
+type actorKey struct{}
+
+// SystemActor is recorded for mutations made outside any client request.
+const SystemActor = "bossman"
+
+// WithActor tags ctx with who is making changes; the audit log records it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	if a, ok := ctx.Value(actorKey{}).(string); ok && a != "" {
+		return a
+	}
+	return SystemActor
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise, so a compound change and its audit rows land together or
+// not at all. fn must do all its work through tx: with SQLite's single
+// connection, using db inside fn blocks forever.
+func WithTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if c := cacheFor(db); c != nil {
+		txCaches.Store(tx, c)
+		defer txCaches.Delete(tx)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func recordEvent(ctx context.Context, tx *sqlx.Tx, taskID, entity, op string, oldValue, newValue map[string]any) error {
+	oldJSON, err := encodeEventValue(oldValue)
+	if err != nil {
+		return err
+	}
+	newJSON, err := encodeEventValue(newValue)
+	if err != nil {
+		return err
+	}
+	args := []any{taskID, entity, op, oldJSON, newJSON, ActorFromContext(ctx), now(ctx)}
+	if stmt := txStmt(ctx, tx, recordEventQuery); stmt != nil {
+		_, err = stmt.ExecContext(ctx, args...)
+	} else {
+		_, err = tx.ExecContext(ctx, tx.Rebind(recordEventQuery), args...)
+	}
+	return err
+}
+
+func encodeEventValue(v map[string]any) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if err := sealEventValues(v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
+
+const recordEventQuery = `INSERT INTO task_events (task_id, entity, op, old_value, new_value, actor, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+const getTaskQuery = "SELECT * FROM tasks WHERE id = ?"
+
+func getTaskTx(ctx context.Context, tx *sqlx.Tx, id string) (*Task, error) {
+	var t Task
+	var err error
+	if stmt := txStmt(ctx, tx, getTaskQuery); stmt != nil {
+		err = stmt.GetContext(ctx, &t, id)
+	} else {
+		err = tx.GetContext(ctx, &t, tx.Rebind(getTaskQuery), id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// taskValues flattens a row into its column names for the audit log.
+func taskValues(t *Task) map[string]any {
+	return map[string]any{
+		"id":           t.ID,
+		"parent_id":    t.ParentID,
+		"description":  t.Description,
+		"context":      t.Context,
+		"priority":     t.Priority,
+		"status":       t.Status,
+		"result":       t.Result,
+		"created_at":   t.CreatedAt,
+		"started_at":   t.StartedAt,
+		"completed_at": t.CompletedAt,
+		"updated_at":   t.UpdatedAt,
+
+		"estimate_minutes": t.EstimateMinutes,
+		"assigned_to":      t.AssignedTo,
+		"due_at":           t.DueAt,
+		"project_id":       t.ProjectID,
+		"metadata":         t.Metadata,
+		"review_status":    t.ReviewStatus,
+		"reviewer":         t.Reviewer,
+		"revision":         t.Revision,
+		"priority_label":   t.PriorityLabel,
+		"priority_weight":  t.PriorityWeight,
+		"kind":             t.Kind,
+		"answer":           t.Answer,
+		"answered_by":      t.AnsweredBy,
+		"session_id":       t.SessionID,
+		"attempts":         t.Attempts,
+	}
+}
+
+// diffTasks returns the old and new values of every column that changed,
+// ignoring the updated_at bump every write makes.
+func diffTasks(before, after *Task) (map[string]any, map[string]any) {
+	oldValues, newValues := taskValues(before), taskValues(after)
+	for k := range oldValues {
+		if k == "updated_at" || jsonEqual(oldValues[k], newValues[k]) {
+			delete(oldValues, k)
+			delete(newValues, k)
+		}
+	}
+	return oldValues, newValues
+}
+
+func jsonEqual(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// GetTaskEvents returns a task's audit log, newest first.
+func GetTaskEvents(ctx context.Context, db *sqlx.DB, taskID string, limit int) ([]TaskEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var events []TaskEvent
+	err := db.SelectContext(ctx, &events,
+		`SELECT * FROM task_events WHERE task_id = ? ORDER BY id DESC LIMIT ?`,
+		taskID, limit)
+	for i := range events {
+		events[i].OldValue = openEventValue(events[i].OldValue)
+		events[i].NewValue = openEventValue(events[i].NewValue)
+	}
+	return events, err
+}
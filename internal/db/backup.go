@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// DefaultBackupKeep is how many backups the periodic job keeps when not
+// told otherwise.
+const DefaultBackupKeep = 7
+
+// backupPrefix and backupSuffix frame the timestamped files BackupToDir
+// writes; only files named like that are ever pruned.
+const (
+	backupPrefix = "bossman-"
+	backupSuffix = ".db"
+	backupStamp  = "20060102T150405.000Z"
+)
+
+// BackupInfo describes a finished backup.
+type BackupInfo struct {
+	Path      string   `json:"path"`
+	Bytes     int64    `json:"bytes"`
+	CreatedAt string   `json:"created_at"`
+	Pruned    []string `json:"pruned,omitempty"` // older backups removed to stay within keep
+}
+
+// Backup writes a consistent copy of the database to dest with VACUUM
+// INTO, while other connections keep reading and writing. The copy is
+// compacted and has no WAL of its own, so it opens as an ordinary
+// database. It goes to a temporary name first and is renamed into place,
+// so a file at dest is always complete. dest must not exist yet.
+func Backup(ctx context.Context, db *sqlx.DB, dest string) (BackupInfo, error) {
+	if isPostgres(db) {
+		return BackupInfo{}, errors.New("backup covers SQLite databases only; use pg_dump for Postgres")
+	}
+	dest = filepath.Clean(dest)
+	if _, err := os.Stat(dest); err == nil {
+		return BackupInfo{}, fmt.Errorf("%s already exists", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return BackupInfo{}, err
+	}
+	tmp := dest + ".partial"
+	os.Remove(tmp) // left by a backup that was interrupted
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+		os.Remove(tmp)
+		return BackupInfo{}, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return BackupInfo{}, err
+	}
+	st, err := os.Stat(dest)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	return BackupInfo{Path: dest, Bytes: st.Size(), CreatedAt: now(ctx)}, nil
+}
+
+// BackupToDir backs up into dir under a timestamped name and, with keep >
+// 0, removes all but the newest keep backups there.
+func BackupToDir(ctx context.Context, db *sqlx.DB, dir string, keep int) (BackupInfo, error) {
+	name := backupPrefix + clock.From(ctx).Now().UTC().Format(backupStamp) + backupSuffix
+	info, err := Backup(ctx, db, filepath.Join(dir, name))
+	if err != nil || keep <= 0 {
+		return info, err
+	}
+	backups, err := ListBackups(dir)
+	if err != nil {
+		return info, err
+	}
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return info, fmt.Errorf("prune %s: %w", backups[0], err)
+		}
+		info.Pruned = append(info.Pruned, backups[0])
+		backups = backups[1:]
+	}
+	return info, nil
+}
+
+// ListBackups returns the backups BackupToDir wrote in dir, oldest first.
+func ListBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, e := range entries {
+		stamp, ok := strings.CutPrefix(e.Name(), backupPrefix)
+		if stamp, ok = strings.CutSuffix(stamp, backupSuffix); !ok || e.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(backupStamp, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, e.Name()))
+	}
+	slices.Sort(backups) // the stamp sorts chronologically
+	return backups, nil
+}
+
+// DefaultBackupDir is the backups directory beside the database file. An
+// in-memory database has none.
+func DefaultBackupDir(ctx context.Context, db *sqlx.DB) (string, error) {
+	file, err := databaseFile(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if file == "" {
+		return "", errors.New("the database is in memory; give a backup path")
+	}
+	return filepath.Join(filepath.Dir(file), "backups"), nil
+}
+
+// databaseFile is the path of the main SQLite database file, or "" for an
+// in-memory database.
+func databaseFile(ctx context.Context, db *sqlx.DB) (string, error) {
+	var rows []struct {
+		Seq  int    `db:"seq"`
+		Name string `db:"name"`
+		File string `db:"file"`
+	}
+	if err := db.SelectContext(ctx, &rows, "PRAGMA database_list"); err != nil {
+		return "", err
+	}
+	for _, r := range rows {
+		if r.Name == "main" {
+			return r.File, nil
+		}
+	}
+	return "", nil
+}
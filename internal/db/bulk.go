@@ -0,0 +1,356 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// insertBatch is how many rows go in one multi-row INSERT, well inside
+// both SQLite's and Postgres's limits on bound parameters.
+const insertBatch = 500
+
+// insertRowsTx inserts rows into table with multi-row INSERT statements.
+func insertRowsTx(ctx context.Context, tx *sqlx.Tx, table string, columns []string, rows [][]any) error {
+	one := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	for chunk := range slices.Chunk(rows, insertBatch) {
+		values := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*len(columns))
+		for i, row := range chunk {
+			values[i] = one
+			args = append(args, row...)
+		}
+		query := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES " + strings.Join(values, ", ")
+		if _, err := tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskEvent is one row for recordEventsTx.
+type taskEvent struct {
+	TaskID, Entity, Op string
+	Old, New           map[string]any
+}
+
+// recordEventsTx is recordEvent for many rows at once.
+func recordEventsTx(ctx context.Context, tx *sqlx.Tx, events []taskEvent) error {
+	actor, at := ActorFromContext(ctx), now(ctx)
+	rows := make([][]any, len(events))
+	for i, e := range events {
+		oldJSON, err := encodeEventValue(e.Old)
+		if err != nil {
+			return err
+		}
+		newJSON, err := encodeEventValue(e.New)
+		if err != nil {
+			return err
+		}
+		rows[i] = []any{e.TaskID, e.Entity, e.Op, oldJSON, newJSON, actor, at}
+	}
+	return insertRowsTx(ctx, tx, "task_events",
+		[]string{"task_id", "entity", "op", "old_value", "new_value", "actor", "created_at"}, rows)
+}
+
+// InsertTasks inserts tasks in one transaction, with multi-row statements
+// for the rows and their audit events, for importers and bulk tools. Each
+// task is stamped and defaulted as InsertTask would.
+// A parent may be an existing task or one earlier in tasks; subtasks
+// without a project or session take their parent's.
+func InsertTasks(ctx context.Context, db *sqlx.DB, tasks []*Task) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return insertTasksTx(ctx, tx, tasks)
+	})
+}
+
+func insertTasksTx(ctx context.Context, tx *sqlx.Tx, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	at := nowTimestamp(ctx)
+	batch := make(map[string]*Task, len(tasks))
+	depth := make(map[string]int, len(tasks)) // levels from the top, 1 for a top-level task
+	existing := make(map[string]*Task)
+	scales := make(map[string][]PriorityLevel)
+
+	for _, t := range tasks {
+		if _, dup := batch[t.ID]; dup {
+			return fmt.Errorf("task %s is in the batch twice", t.ID)
+		}
+		depth[t.ID] = 1
+		if t.ParentID != nil {
+			parentID := *t.ParentID
+			parent, ok := batch[parentID]
+			if !ok {
+				if parent, ok = existing[parentID]; !ok {
+					var err error
+					if parent, err = parentForBatchTx(ctx, tx, parentID, depth); err != nil {
+						return fmt.Errorf("task %s: %w", t.ID, err)
+					}
+					existing[parentID] = parent
+				}
+			}
+			if depth[t.ID] = depth[parentID] + 1; depth[t.ID] > MaxTaskDepth {
+				return fmt.Errorf("task tree would be %d levels deep; the limit is %d", depth[t.ID], MaxTaskDepth)
+			}
+			if t.ProjectID == nil {
+				t.ProjectID = parent.ProjectID
+			}
+			if t.SessionID == nil {
+				t.SessionID = parent.SessionID
+			}
+		}
+		batch[t.ID] = t
+
+		t.CreatedAt, t.UpdatedAt = at, at
+		if t.Kind == "" {
+			t.Kind = KindTask
+		}
+		key := ""
+		if t.ProjectID != nil {
+			key = *t.ProjectID
+		}
+		scale, ok := scales[key]
+		if !ok {
+			var err error
+			if scale, err = GetPriorityScale(ctx, tx, t.ProjectID); err != nil {
+				return err
+			}
+			scales[key] = scale
+		}
+		if err := applyPriority(scale, t); err != nil {
+			return fmt.Errorf("task %s: %w", t.ID, err)
+		}
+	}
+
+	rows := make([][]any, len(tasks))
+	ids := make([]string, len(tasks))
+	for i, t := range tasks {
+		rows[i] = []any{t.ID, t.Description, t.ParentID, t.Priority, t.PriorityLabel, t.PriorityWeight, t.Context,
+			t.EstimateMinutes, t.DueAt, t.Metadata, t.ProjectID, t.Kind, t.SessionID, t.CreatedAt, t.UpdatedAt}
+		ids[i] = t.ID
+	}
+	if err := insertRowsTx(ctx, tx, "tasks", []string{"id", "description", "parent_id", "priority", "priority_label",
+		"priority_weight", "context", "estimate_minutes", "due_at", "metadata", "project_id", "kind", "session_id",
+		"created_at", "updated_at"}, rows); err != nil {
+		return err
+	}
+	created, err := tasksByID(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	events := make([]taskEvent, len(tasks))
+	for i, id := range ids {
+		events[i] = taskEvent{TaskID: id, Entity: "task", Op: "insert", New: taskValues(created[id])}
+	}
+	return recordEventsTx(ctx, tx, events)
+}
+
+// parentForBatchTx loads an existing parent for insertTasksTx and records
+// how deep it sits.
+func parentForBatchTx(ctx context.Context, tx *sqlx.Tx, id string, depth map[string]int) (*Task, error) {
+	parent, err := getTaskTx(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("parent not found: %s", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var levels int
+	if err := tx.GetContext(ctx, &levels, tx.Rebind(`
+		WITH RECURSIVE up(id, parent_id, depth) AS (
+			SELECT id, parent_id, 1 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, t.parent_id, up.depth + 1 FROM tasks t JOIN up ON t.id = up.parent_id
+			 WHERE up.depth <= ?
+		)
+		SELECT MAX(depth) FROM up`), id, MaxTaskDepth); err != nil {
+		return nil, err
+	}
+	depth[id] = levels
+	return parent, nil
+}
+
+// UpdateTasks applies opts to every task in ids in one transaction, with
+// one UPDATE for all of them, as UpdateTask would to each. It changes
+// nothing unless every task exists and can take the change; a missing one
+// fails with an error wrapping sql.ErrNoRows. It returns the updated
+// tasks by id, duplicates dropped.
+func UpdateTasks(ctx context.Context, db *sqlx.DB, ids []string, opts UpdateOpts) ([]Task, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	setClauses, args := updateClauses(ctx, db, opts)
+	var out []Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, err := tasksByID(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			t, ok := before[id]
+			if !ok {
+				return fmt.Errorf("task %s: %w", id, sql.ErrNoRows)
+			}
+			if err := checkUpdate(t, opts); err != nil {
+				return err
+			}
+			if opts.Resources != nil {
+				if err := setResourcesTx(ctx, tx, id, opts.Resources); err != nil {
+					return err
+				}
+			}
+			if opts.Files != nil {
+				if err := setFilesTx(ctx, tx, id, opts.Files); err != nil {
+					return err
+				}
+			}
+		}
+		out, err = updateTasksTx(ctx, tx, ids, before, opts, setClauses, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// updateTasksTx is the write UpdateTasks makes once every task in ids has
+// passed checkUpdate: one UPDATE with setClauses for all of them, then
+// priorities, locks and an audit event per task.
+func updateTasksTx(ctx context.Context, tx *sqlx.Tx, ids []string, before map[string]*Task, opts UpdateOpts, setClauses []string, args map[string]any) ([]Task, error) {
+	args["ids"] = ids
+	query, bound, err := sqlx.Named("UPDATE tasks SET "+strings.Join(setClauses, ", ")+" WHERE id IN (:ids) RETURNING *", args)
+	if err != nil {
+		return nil, err
+	}
+	if query, bound, err = sqlx.In(query, bound...); err != nil {
+		return nil, err
+	}
+	var updated []Task
+	if err := tx.SelectContext(ctx, &updated, tx.Rebind(query), bound...); err != nil {
+		return nil, err
+	}
+	after := make(map[string]*Task, len(updated))
+	for i := range updated {
+		after[updated[i].ID] = &updated[i]
+	}
+	if opts.Priority != nil {
+		for _, id := range ids {
+			t, err := setPriorityTx(ctx, tx, id, *opts.Priority)
+			if err != nil {
+				return nil, fmt.Errorf("task %s: %w", id, err)
+			}
+			after[id] = t
+		}
+	}
+	out := make([]Task, 0, len(ids))
+	events := make([]taskEvent, 0, len(ids))
+	for _, id := range ids {
+		if opts.Status != nil {
+			if err := updateLocksTx(ctx, tx, after[id]); err != nil {
+				return nil, err
+			}
+		}
+		oldValues, newValues := diffTasks(before[id], after[id])
+		events = append(events, taskEvent{TaskID: id, Entity: "task", Op: "update", Old: oldValues, New: newValues})
+		out = append(out, *after[id])
+	}
+	return out, recordEventsTx(ctx, tx, events)
+}
+
+// StatusOutcome is what UpdateTasksStatus did to one task: Task after the
+// change, or Error saying why it was left alone.
+type StatusOutcome struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+	Task  *Task  `json:"-"`
+}
+
+// UpdateTasksStatus moves every task in ids to status in one transaction,
+// checking the state machine for each. Unlike UpdateTasks, a task that is
+// missing or can't make the move is reported and left alone while the
+// others change. Outcomes follow ids, duplicates dropped.
+func UpdateTasksStatus(ctx context.Context, db *sqlx.DB, ids []string, status string, reopen bool) ([]StatusOutcome, error) {
+	seen := make(map[string]bool, len(ids))
+	outcomes := make([]StatusOutcome, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			outcomes = append(outcomes, StatusOutcome{ID: id})
+		}
+	}
+	if len(outcomes) == 0 {
+		return outcomes, nil
+	}
+	opts := UpdateOpts{Status: &status, Reopen: reopen}
+	setClauses, args := updateClauses(ctx, db, opts)
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, err := tasksByID(ctx, tx, slices.Collect(maps.Keys(seen)))
+		if err != nil {
+			return err
+		}
+		var movable []string
+		for i := range outcomes {
+			o := &outcomes[i]
+			t, ok := before[o.ID]
+			if !ok {
+				o.Error = "task not found: " + o.ID
+				continue
+			}
+			if err := checkUpdate(t, opts); err != nil {
+				o.Error = err.Error()
+				continue
+			}
+			movable = append(movable, o.ID)
+		}
+		if len(movable) == 0 {
+			return nil
+		}
+		updated, err := updateTasksTx(ctx, tx, movable, before, opts, setClauses, args)
+		if err != nil {
+			return err
+		}
+		after := make(map[string]*Task, len(updated))
+		for i := range updated {
+			after[updated[i].ID] = &updated[i]
+		}
+		for i := range outcomes {
+			if t := after[outcomes[i].ID]; t != nil {
+				outcomes[i].OK, outcomes[i].Task = true, t
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// tasksByID loads the tasks in ids that exist, by id.
+func tasksByID(ctx context.Context, db sqlx.ExtContext, ids []string) (map[string]*Task, error) {
+	out := make(map[string]*Task, len(ids))
+	for chunk := range slices.Chunk(ids, insertBatch) {
+		query, args, err := sqlx.In("SELECT * FROM tasks WHERE id IN (?)", chunk)
+		if err != nil {
+			return nil, err
+		}
+		var tasks []Task
+		if err := sqlx.SelectContext(ctx, db, &tasks, db.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for i := range tasks {
+			out[tasks[i].ID] = &tasks[i]
+		}
+	}
+	return out, nil
+}
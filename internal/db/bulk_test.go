@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TestInsertAndUpdateTasks inserts a plan whose subtasks hang off tasks
+// earlier in the same batch, then moves part of it along in one update.
+func TestInsertAndUpdateTasks(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	root := &Task{ID: NewTaskID(), Description: "ship the importer"}
+	if err := InsertTask(ctx, conn, root); err != nil {
+		t.Fatal(err)
+	}
+	plan := &Task{ID: NewTaskID(), ParentID: &root.ID, Description: "plan it", Priority: 2}
+	tasks := []*Task{plan}
+	for i := range 3 {
+		tasks = append(tasks, &Task{ID: NewTaskID(), ParentID: &plan.ID, Description: fmt.Sprintf("step %d", i+1)})
+	}
+	if err := InsertTasks(ctx, conn, tasks); err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range tasks {
+		got, err := GetTask(ctx, conn, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status != StatusPending || got.Kind != KindTask {
+			t.Errorf("%s: status %q kind %q", got.Description, got.Status, got.Kind)
+		}
+		events, err := GetTaskEvents(ctx, conn, task.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Op != "insert" {
+			t.Errorf("%s: events %+v", got.Description, events)
+		}
+	}
+
+	ids := []string{tasks[1].ID, tasks[2].ID, tasks[1].ID}
+	started := StatusInProgress
+	updated, err := UpdateTasks(ctx, conn, ids, UpdateOpts{Status: &started})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 2 {
+		t.Errorf("got %d tasks back, want 2", len(updated))
+	}
+	for _, task := range tasks[1:] {
+		got, err := GetTask(ctx, conn, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := StatusPending
+		if task != tasks[3] {
+			want = StatusInProgress
+		}
+		if got.Status != want {
+			t.Errorf("%s: status %q, want %q", got.Description, got.Status, want)
+		}
+	}
+
+	// one bad id leaves every task as it was
+	done := StatusCompleted
+	_, err = UpdateTasks(ctx, conn, []string{tasks[1].ID, "task_missing"}, UpdateOpts{Status: &done})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got %v, want sql.ErrNoRows", err)
+	}
+	if got, _ := GetTask(ctx, conn, tasks[1].ID); got.Status != StatusInProgress {
+		t.Errorf("status %q after a failed update", got.Status)
+	}
+
+	missing := "task_missing"
+	orphan := &Task{ID: NewTaskID(), ParentID: &missing, Description: "lost"}
+	if err := InsertTasks(ctx, conn, []*Task{orphan}); err == nil {
+		t.Error("inserted a task under a parent that doesn't exist")
+	}
+}
+
+// TestUpdateTasksStatus fails a subtree where one task already completed:
+// that one is reported and kept, the rest fail together.
+func TestUpdateTasksStatus(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	root := &Task{ID: NewTaskID(), Description: "migrate to the new queue"}
+	done := &Task{ID: NewTaskID(), ParentID: &root.ID, Description: "drain the old queue"}
+	open := &Task{ID: NewTaskID(), ParentID: &root.ID, Description: "switch the consumers"}
+	if err := InsertTasks(ctx, conn, []*Task{root, done, open}); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range []string{StatusInProgress, StatusCompleted} {
+		if _, err := UpdateTask(ctx, conn, done.ID, UpdateOpts{Status: &status}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := []string{root.ID, done.ID, open.ID, "task_missing", open.ID}
+	outcomes, err := UpdateTasksStatus(ctx, conn, ids, StatusFailed, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 4 {
+		t.Fatalf("got %d outcomes, want 4: %+v", len(outcomes), outcomes)
+	}
+	for i, want := range []bool{true, false, true, false} {
+		if o := outcomes[i]; o.ID != ids[i] || o.OK != want || (o.Error == "") != want {
+			t.Errorf("outcome %d: %+v", i, o)
+		}
+	}
+	for _, task := range []*Task{root, done, open} {
+		got, err := GetTask(ctx, conn, task.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := StatusFailed
+		if task == done {
+			want = StatusCompleted
+		}
+		if got.Status != want {
+			t.Errorf("%s: status %q, want %q", got.Description, got.Status, want)
+		}
+	}
+}
+
+func BenchmarkInsertTasks(b *testing.B) {
+	const n = 200
+	ctx := WithActor(context.Background(), "agent-1")
+	batch := func() []*Task {
+		tasks := make([]*Task, n)
+		for i := range tasks {
+			tasks[i] = &Task{ID: NewTaskID(), Description: "write the report"}
+		}
+		return tasks
+	}
+	b.Run("bulk", func(b *testing.B) {
+		conn := benchDB(b, true)
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := InsertTasks(ctx, conn, batch()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("one_by_one", func(b *testing.B) {
+		conn := benchDB(b, true)
+		b.ReportAllocs()
+		for b.Loop() {
+			err := WithTx(ctx, conn, func(tx *sqlx.Tx) error {
+				for _, t := range batch() {
+					if err := insertTaskTx(ctx, tx, t); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestGetTasksByIDs reads tasks back in the order asked for, with a repeat
+// dropped and the unknown ids reported.
+func TestGetTasksByIDs(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	var ids []string
+	for i := range 3 {
+		task := &Task{ID: NewTaskID(), Description: fmt.Sprintf("step %d", i+1)}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+	ask := []string{ids[2], "task_missing", ids[0], ids[2], ids[1]}
+	tasks, missing, err := GetTasksByIDs(ctx, conn, ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, task := range tasks {
+		got = append(got, task.ID)
+	}
+	if want := []string{ids[2], ids[0], ids[1]}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(missing) != 1 || missing[0] != "task_missing" {
+		t.Errorf("missing %v", missing)
+	}
+}
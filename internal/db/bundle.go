@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskBundle is a task with everything hanging off it, the unit exports
+// write and imports read.
+type TaskBundle struct {
+	Task
+	Project   string    `json:"project,omitempty"` // project name; importing creates it if missing
+	Tags      []string  `json:"tags,omitempty"`
+	Resources []string  `json:"resources,omitempty"`
+	Files     []string  `json:"files,omitempty"`
+	BlockedBy []string  `json:"blocked_by,omitempty"` // ids of tasks this one waits on
+	Comments  []Comment `json:"comments,omitempty"`
+}
+
+// LoadBundles returns every task except bossman's own system tasks and
+// sessions' scratch tasks, parents before children. A non-empty projectID keeps only that project's tasks;
+// parents and blockers outside it are dropped so the set imports on its own.
+func LoadBundles(ctx context.Context, db *sqlx.DB, projectID string) ([]TaskBundle, error) {
+	var tasks []Task
+	err := db.SelectContext(ctx, &tasks, `
+		WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE parent_id IS NULL AND id != ? AND session_id IS NULL
+			UNION ALL
+			SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id
+			WHERE t.session_id IS NULL
+		)
+		SELECT t.* FROM tasks t JOIN tree ON tree.id = t.id
+		ORDER BY tree.depth, t.created_at, t.id`, SystemTaskID)
+	if err != nil {
+		return nil, fmt.Errorf("load tasks: %w", err)
+	}
+	if projectID != "" {
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if t.ProjectID != nil && *t.ProjectID == projectID {
+				kept = append(kept, t)
+			}
+		}
+		tasks = kept
+	}
+	included := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		included[t.ID] = true
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	tags, err := GetTagsForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load tags: %w", err)
+	}
+	resources, err := GetResourcesForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load resources: %w", err)
+	}
+	files, err := GetFilesForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load files: %w", err)
+	}
+	edges, err := ListBlockerEdges(ctx, db)
+	if err != nil {
+		return nil, fmt.Errorf("load blockers: %w", err)
+	}
+	blockedBy := make(map[string][]string)
+	for _, e := range edges {
+		if included[e.BlockedByID] {
+			blockedBy[e.TaskID] = append(blockedBy[e.TaskID], e.BlockedByID)
+		}
+	}
+	var projects []Project
+	if err := db.SelectContext(ctx, &projects, "SELECT * FROM projects"); err != nil {
+		return nil, fmt.Errorf("load projects: %w", err)
+	}
+	projectNames := make(map[string]string, len(projects))
+	for _, p := range projects {
+		projectNames[p.ID] = p.Name
+	}
+	var comments []Comment
+	if err := db.SelectContext(ctx, &comments,
+		"SELECT * FROM task_comments ORDER BY created_at, id"); err != nil {
+		return nil, fmt.Errorf("load comments: %w", err)
+	}
+	commentsByTask := make(map[string][]Comment)
+	for _, c := range comments {
+		commentsByTask[c.TaskID] = append(commentsByTask[c.TaskID], c)
+	}
+
+	out := make([]TaskBundle, len(tasks))
+	for i, t := range tasks {
+		if t.ParentID != nil && !included[*t.ParentID] {
+			t.ParentID = nil
+		}
+		var project string
+		if t.ProjectID != nil {
+			project = projectNames[*t.ProjectID]
+		}
+		out[i] = TaskBundle{
+			Task:      t,
+			Project:   project,
+			Tags:      tags[t.ID],
+			Resources: resources[t.ID],
+			Files:     files[t.ID],
+			BlockedBy: blockedBy[t.ID],
+			Comments:  commentsByTask[t.ID],
+		}
+	}
+	return out, nil
+}
+
+// ImportBundles inserts bundles in one transaction, keeping their ids and
+// timestamps. Parents and blockers may refer to other bundles in the batch
+// or to tasks already in the database; an id that exists already is an
+// error, so an import never overwrites. Projects are matched by name.
+func ImportBundles(ctx context.Context, db *sqlx.DB, bundles []TaskBundle) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		// Rows go in without parents first so order within the batch
+		// doesn't matter to the foreign key.
+		rows := make([][]any, len(bundles))
+		ids := make([]string, len(bundles))
+		for i, b := range bundles {
+			t := b.Task
+			t.ParentID = nil
+			t.ProjectID = nil
+			if b.Project != "" {
+				id, err := ensureProjectTx(ctx, tx, b.Project)
+				if err != nil {
+					return fmt.Errorf("task %s: %w", t.ID, err)
+				}
+				t.ProjectID = &id
+			}
+			if t.CreatedAt.IsZero() {
+				t.CreatedAt = nowTimestamp(ctx)
+			}
+			if t.UpdatedAt.IsZero() {
+				t.UpdatedAt = t.CreatedAt
+			}
+			if t.Kind == "" {
+				t.Kind = KindTask
+			}
+			fillLegacyPriority(&t)
+			rows[i] = []any{t.ID, t.Description, t.Context, t.Priority, t.PriorityLabel, t.PriorityWeight, t.Status, t.Result,
+				t.CreatedAt, t.StartedAt, t.CompletedAt, t.UpdatedAt,
+				t.EstimateMinutes, t.AssignedTo, t.DueAt, t.ProjectID, t.Metadata,
+				t.ReviewStatus, t.Reviewer, t.Revision, t.Kind, t.Answer, t.AnsweredBy, t.SessionID, t.ArchivedAt, t.Attempts}
+			ids[i] = t.ID
+		}
+		if err := insertRowsTx(ctx, tx, "tasks", []string{"id", "description", "context", "priority", "priority_label",
+			"priority_weight", "status", "result", "created_at", "started_at", "completed_at", "updated_at",
+			"estimate_minutes", "assigned_to", "due_at", "project_id", "metadata",
+			"review_status", "reviewer", "revision", "kind", "answer", "answered_by", "session_id", "archived_at", "attempts"}, rows); err != nil {
+			return fmt.Errorf("insert tasks: %w", err)
+		}
+		for _, b := range bundles {
+			if b.Task.ParentID == nil {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE tasks SET parent_id = ? WHERE id = ?",
+				*b.Task.ParentID, b.Task.ID); err != nil {
+				return fmt.Errorf("set parent of %s: %w", b.Task.ID, err)
+			}
+		}
+
+		created, err := tasksByID(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		events := make([]taskEvent, len(ids))
+		for i, id := range ids {
+			events[i] = taskEvent{TaskID: id, Entity: "task", Op: "insert", New: taskValues(created[id])}
+		}
+		if err := recordEventsTx(ctx, tx, events); err != nil {
+			return err
+		}
+
+		for _, b := range bundles {
+			id := b.Task.ID
+			for _, tag := range b.Tags {
+				tag, err := NormalizeTag(tag)
+				if err != nil {
+					return fmt.Errorf("task %s: %w", id, err)
+				}
+				if _, err := tx.ExecContext(ctx,
+					"INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)", id, tag); err != nil {
+					return err
+				}
+			}
+			if err := setResourcesTx(ctx, tx, id, b.Resources); err != nil {
+				return fmt.Errorf("task %s: %w", id, err)
+			}
+			if err := setFilesTx(ctx, tx, id, b.Files); err != nil {
+				return fmt.Errorf("task %s: %w", id, err)
+			}
+			for _, dep := range b.BlockedBy {
+				if _, err := tx.ExecContext(ctx,
+					"INSERT OR IGNORE INTO task_blockers (task_id, blocked_by_id) VALUES (?, ?)", id, dep); err != nil {
+					return fmt.Errorf("task %s blocked by %s: %w", id, dep, err)
+				}
+			}
+			for _, c := range b.Comments {
+				if c.ID == "" {
+					c.ID = NewCommentID()
+				}
+				if c.CreatedAt == "" {
+					c.CreatedAt = now(ctx)
+				}
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO task_comments (id, task_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)`,
+					c.ID, id, c.Author, c.Body, c.CreatedAt); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// WorkDaysPerWeek turns a daily capacity into a weekly one.
+const WorkDaysPerWeek = 5
+
+// Capacity is how much an assignee can take on per working day, in
+// minutes of estimated work, in tasks, or both. Nil means no limit.
+type Capacity struct {
+	Assignee      string `db:"assignee" json:"assignee"`
+	MinutesPerDay *int   `db:"minutes_per_day" json:"minutes_per_day,omitempty"`
+	TasksPerDay   *int   `db:"tasks_per_day" json:"tasks_per_day,omitempty"`
+	UpdatedAt     string `db:"updated_at" json:"updated_at"`
+}
+
+// WeekMinutes is the weekly minute capacity, or 0 without one.
+func (c Capacity) WeekMinutes() int {
+	if c.MinutesPerDay == nil {
+		return 0
+	}
+	return *c.MinutesPerDay * WorkDaysPerWeek
+}
+
+// WeekTasks is the weekly task capacity, or 0 without one.
+func (c Capacity) WeekTasks() int {
+	if c.TasksPerDay == nil {
+		return 0
+	}
+	return *c.TasksPerDay * WorkDaysPerWeek
+}
+
+// SetCapacity declares an assignee's capacity; with neither limit set it
+// removes the declaration.
+func SetCapacity(ctx context.Context, db *sqlx.DB, c Capacity) error {
+	if c.Assignee == "" {
+		return fmt.Errorf("assignee is required")
+	}
+	if (c.MinutesPerDay != nil && *c.MinutesPerDay <= 0) || (c.TasksPerDay != nil && *c.TasksPerDay <= 0) {
+		return fmt.Errorf("capacity must be positive")
+	}
+	if c.MinutesPerDay == nil && c.TasksPerDay == nil {
+		_, err := db.ExecContext(ctx, "DELETE FROM capacities WHERE assignee = ?", c.Assignee)
+		return err
+	}
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO capacities (assignee, minutes_per_day, tasks_per_day, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(assignee) DO UPDATE SET minutes_per_day = excluded.minutes_per_day,
+		 tasks_per_day = excluded.tasks_per_day, updated_at = excluded.updated_at`,
+		c.Assignee, c.MinutesPerDay, c.TasksPerDay, now(ctx))
+	return err
+}
+
+// GetCapacity returns an assignee's capacity, or sql.ErrNoRows if they
+// haven't declared one.
+func GetCapacity(ctx context.Context, db sqlx.QueryerContext, assignee string) (Capacity, error) {
+	var c Capacity
+	err := sqlx.GetContext(ctx, db, &c, "SELECT * FROM capacities WHERE assignee = ?", assignee)
+	return c, err
+}
+
+// Workload is what an assignee holds: open tasks assigned to them and
+// their estimated minutes, tasks without an estimate counting as
+// DefaultPlanEstimate.
+type Workload struct {
+	Tasks   int `db:"tasks" json:"open_tasks"`
+	Minutes int `db:"minutes" json:"open_minutes"`
+}
+
+const workloadQuery = `
+	SELECT COUNT(*) AS tasks, COALESCE(SUM(COALESCE(estimate_minutes, ?)), 0) AS minutes
+	FROM tasks WHERE assigned_to = ? AND status IN ('pending', 'in_progress') AND archived_at IS NULL`
+
+// CapacityError is returned when an assignment would take an assignee past
+// a week's capacity.
+type CapacityError struct {
+	Assignee string
+	Detail   string
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("%s is at capacity: %s", e.Assignee, e.Detail)
+}
+
+// checkCapacityTx refuses to add t to assignee's workload when that would
+// exceed a week of their declared capacity. Assignees without one are
+// never refused.
+func checkCapacityTx(ctx context.Context, tx *sqlx.Tx, assignee string, t *Task) error {
+	c, err := GetCapacity(ctx, tx, assignee)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var load Workload
+	if err := tx.GetContext(ctx, &load, workloadQuery, DefaultPlanEstimate, assignee); err != nil {
+		return err
+	}
+	minutes := DefaultPlanEstimate
+	if t.EstimateMinutes != nil {
+		minutes = *t.EstimateMinutes
+	}
+	if n := c.WeekTasks(); n > 0 && load.Tasks+1 > n {
+		return &CapacityError{assignee, fmt.Sprintf("%d of %d open tasks a week", load.Tasks, n)}
+	}
+	if m := c.WeekMinutes(); m > 0 && load.Minutes+minutes > m {
+		return &CapacityError{assignee, fmt.Sprintf("%d open minutes plus %d would pass %d a week", load.Minutes, minutes, m)}
+	}
+	return nil
+}
+
+// Utilization is an assignee's workload against their weekly capacity.
+// Percent is the higher of the minute and task ratios, 0 without a
+// declared capacity.
+type Utilization struct {
+	Assignee string `json:"assignee"`
+	Workload
+	CapacityMinutes int `json:"capacity_minutes,omitempty"`
+	CapacityTasks   int `json:"capacity_tasks,omitempty"`
+	Percent         int `json:"percent"`
+}
+
+// GetUtilization reports everyone who has a declared capacity or open
+// assigned work, busiest first.
+func GetUtilization(ctx context.Context, db *sqlx.DB) ([]Utilization, error) {
+	var caps []Capacity
+	if err := db.SelectContext(ctx, &caps, "SELECT * FROM capacities"); err != nil {
+		return nil, err
+	}
+	var loads []struct {
+		Assignee string `db:"assigned_to"`
+		Workload
+	}
+	err := db.SelectContext(ctx, &loads, `
+		SELECT assigned_to, COUNT(*) AS tasks, COALESCE(SUM(COALESCE(estimate_minutes, ?)), 0) AS minutes
+		FROM tasks WHERE assigned_to IS NOT NULL AND status IN ('pending', 'in_progress') AND archived_at IS NULL
+		GROUP BY assigned_to`, DefaultPlanEstimate)
+	if err != nil {
+		return nil, err
+	}
+	byAssignee := make(map[string]*Utilization)
+	get := func(a string) *Utilization {
+		if u, ok := byAssignee[a]; ok {
+			return u
+		}
+		u := &Utilization{Assignee: a}
+		byAssignee[a] = u
+		return u
+	}
+	for _, c := range caps {
+		u := get(c.Assignee)
+		u.CapacityMinutes, u.CapacityTasks = c.WeekMinutes(), c.WeekTasks()
+	}
+	for _, l := range loads {
+		get(l.Assignee).Workload = l.Workload
+	}
+	out := make([]Utilization, 0, len(byAssignee))
+	for _, u := range byAssignee {
+		if u.CapacityMinutes > 0 {
+			u.Percent = u.Minutes * 100 / u.CapacityMinutes
+		}
+		if u.CapacityTasks > 0 {
+			u.Percent = max(u.Percent, u.Tasks*100/u.CapacityTasks)
+		}
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Percent != out[j].Percent {
+			return out[i].Percent > out[j].Percent
+		}
+		return out[i].Assignee < out[j].Assignee
+	})
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ChainTask is a row of GetDependencyChain: a blocker and the most hops
+// between it and the task the chain was asked for, direct blockers being 1.
+type ChainTask struct {
+	Task
+	Depth int
+}
+
+// GetDependencyChain returns every task id waits on, directly or through
+// other blockers, finished or not. The deepest come first, so the list is
+// an order the work can be done in. A missing task is sql.ErrNoRows.
+func GetDependencyChain(ctx context.Context, db *sqlx.DB, id string) ([]ChainTask, error) {
+	if ok, err := TaskExists(ctx, db, id); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, sql.ErrNoRows
+	}
+	// UNION drops repeats, which also ends any blocker cycle
+	var tasks []Task
+	err := db.SelectContext(ctx, &tasks, db.Rebind(`
+		WITH RECURSIVE upstream(id) AS (
+			SELECT blocked_by_id FROM task_blockers WHERE task_id = ?
+			UNION
+			SELECT tb.blocked_by_id FROM task_blockers tb JOIN upstream u ON tb.task_id = u.id
+		)
+		SELECT t.* FROM tasks t JOIN upstream u ON u.id = t.id WHERE t.id != ?`), id, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	edges, err := blockerEdgesAmong(ctx, db, append(ids, id))
+	if err != nil {
+		return nil, err
+	}
+	blocks := make(map[string][]string) // blocker -> tasks waiting on it
+	for _, e := range edges {
+		blocks[e.BlockedByID] = append(blocks[e.BlockedByID], e.TaskID)
+	}
+
+	// longest hop count to id, so a blocker always sorts before the tasks
+	// waiting on it
+	depth := map[string]int{id: 0}
+	visiting := make(map[string]bool)
+	var depthOf func(b string) int
+	depthOf = func(b string) int {
+		if d, ok := depth[b]; ok {
+			return d
+		}
+		visiting[b] = true
+		d := 0
+		for _, t := range blocks[b] {
+			if !visiting[t] { // a cycle slipped past add_blocker; cut it here
+				d = max(d, depthOf(t)+1)
+			}
+		}
+		delete(visiting, b)
+		depth[b] = d
+		return d
+	}
+
+	chain := make([]ChainTask, len(tasks))
+	for i, t := range tasks {
+		chain[i] = ChainTask{Task: t, Depth: depthOf(t.ID)}
+	}
+	sort.Slice(chain, func(i, j int) bool {
+		if chain[i].Depth != chain[j].Depth {
+			return chain[i].Depth > chain[j].Depth
+		}
+		return chain[i].ID < chain[j].ID
+	})
+	return chain, nil
+}
+
+// CriticalPath is the longest run of open tasks each blocking the next,
+// measured in estimated minutes (tasks without an estimate count as zero)
+// and then in tasks. Tasks are in the order they have to be done.
+type CriticalPath struct {
+	Tasks        []Task
+	TotalMinutes int
+}
+
+// GetCriticalPath finds the critical path among the open tasks of a
+// project, or of the whole database when projectID is nil ("" meaning
+// tasks outside any project). Dependencies on tasks outside that set are
+// ignored; nothing open gives an empty path.
+func GetCriticalPath(ctx context.Context, db *sqlx.DB, projectID *string) (CriticalPath, error) {
+	query := `SELECT * FROM tasks
+		WHERE status IN ('pending', 'in_progress') AND archived_at IS NULL
+		  AND id != ? AND COALESCE(parent_id, '') != ?`
+	args := []any{SystemTaskID, SystemTaskID}
+	if projectID != nil {
+		if *projectID == "" {
+			query += " AND project_id IS NULL"
+		} else {
+			query += " AND project_id = ?"
+			args = append(args, *projectID)
+		}
+	}
+	var tasks []Task
+	if err := db.SelectContext(ctx, &tasks, db.Rebind(query), args...); err != nil {
+		return CriticalPath{}, err
+	}
+	if len(tasks) == 0 {
+		return CriticalPath{Tasks: []Task{}}, nil
+	}
+	byID := make(map[string]*Task, len(tasks))
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+		ids[i] = tasks[i].ID
+	}
+	edges, err := blockerEdgesAmong(ctx, db, ids)
+	if err != nil {
+		return CriticalPath{}, err
+	}
+	waitsOn := make(map[string][]string)
+	for _, e := range edges {
+		waitsOn[e.TaskID] = append(waitsOn[e.TaskID], e.BlockedByID)
+	}
+
+	// best[id] is the longest chain ending at id: its length in minutes
+	// and tasks, and the blocker before it
+	type chain struct {
+		minutes, tasks int
+		prev           string
+	}
+	best := make(map[string]chain, len(tasks))
+	visiting := make(map[string]bool)
+	var longest func(id string) chain
+	longest = func(id string) chain {
+		if c, ok := best[id]; ok {
+			return c
+		}
+		visiting[id] = true
+		var c chain
+		for _, b := range waitsOn[id] {
+			if visiting[b] { // a cycle slipped past add_blocker; cut it here
+				continue
+			}
+			bc := longest(b)
+			if bc.minutes > c.minutes || (bc.minutes == c.minutes && bc.tasks > c.tasks) {
+				c = chain{minutes: bc.minutes, tasks: bc.tasks, prev: b}
+			}
+		}
+		if est := byID[id].EstimateMinutes; est != nil {
+			c.minutes += *est
+		}
+		c.tasks++
+		delete(visiting, id)
+		best[id] = c
+		return c
+	}
+
+	sort.Strings(ids) // ties go to the lowest ID, not map order
+	end := ""
+	for _, id := range ids {
+		c := longest(id)
+		if e := best[end]; end == "" || c.minutes > e.minutes || (c.minutes == e.minutes && c.tasks > e.tasks) {
+			end = id
+		}
+	}
+	path := CriticalPath{TotalMinutes: best[end].minutes}
+	for id := end; id != ""; id = best[id].prev {
+		path.Tasks = append(path.Tasks, *byID[id])
+	}
+	for i, j := 0, len(path.Tasks)-1; i < j; i, j = i+1, j-1 {
+		path.Tasks[i], path.Tasks[j] = path.Tasks[j], path.Tasks[i]
+	}
+	return path, nil
+}
+
+// blockerEdgesAmong returns the blocker edges with both ends in ids.
+func blockerEdgesAmong(ctx context.Context, db *sqlx.DB, ids []string) ([]BlockerEdge, error) {
+	query, args, err := sqlx.In(`SELECT task_id, blocked_by_id FROM task_blockers
+		WHERE task_id IN (?) AND blocked_by_id IN (?)`, ids, ids)
+	if err != nil {
+		return nil, err
+	}
+	var edges []BlockerEdge
+	err = db.SelectContext(ctx, &edges, db.Rebind(query), args...)
+	return edges, err
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Change is one row of the changes feed. Rows are written by triggers, so
+// every process sharing the database contributes to the same feed.
+type Change struct {
+	Seq       int64  `db:"seq" json:"seq"`
+	Entity    string `db:"entity" json:"entity"`
+	Op        string `db:"op" json:"op"`
+	TaskID    string `db:"task_id" json:"task_id"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+// ChangesSince returns changes with seq greater than since, oldest first.
+func ChangesSince(ctx context.Context, db *sqlx.DB, since int64, limit int) ([]Change, error) {
+	if limit <= 0 {
+		limit = 500
+	}
+	var changes []Change
+	err := db.SelectContext(ctx, &changes,
+		"SELECT * FROM changes WHERE seq > ? ORDER BY seq LIMIT ?", since, limit)
+	return changes, err
+}
+
+// LatestChangeSeq returns the newest seq, or 0 if the feed is empty.
+func LatestChangeSeq(ctx context.Context, db *sqlx.DB) (int64, error) {
+	var seq int64
+	err := db.GetContext(ctx, &seq, "SELECT COALESCE(MAX(seq), 0) FROM changes")
+	return seq, err
+}
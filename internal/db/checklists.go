@@ -0,0 +1,284 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Tags put on generated tasks so agents can find and filter them.
+const (
+	ChecklistTag   = "checklist"
+	CarriedOverTag = "carried-over"
+)
+
+// Checklist is a set of tasks regenerated every day at Hour (local time)
+// under a fresh parent task.
+type Checklist struct {
+	ID           string   `db:"id" json:"id"`
+	Name         string   `db:"name" json:"name"`
+	Items        []string `db:"-" json:"items"`
+	ItemsJSON    string   `db:"items" json:"-"`
+	Hour         int      `db:"hour" json:"hour"`
+	SkipWeekends bool     `db:"skip_weekends" json:"skip_weekends"`
+	// CarryOver moves the previous run's unfinished items onto the new run,
+	// tagged carried-over, instead of leaving them behind.
+	CarryOver bool   `db:"carry_over" json:"carry_over"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+// ExtChecklists is the extension holding checklists and their runs.
+const ExtChecklists = "checklists"
+
+func init() {
+	RegisterExtension(Extension{
+		Name: ExtChecklists,
+		Schema: `
+CREATE TABLE IF NOT EXISTS checklists (
+    id            TEXT PRIMARY KEY,
+    name          TEXT NOT NULL,
+    items         TEXT NOT NULL, -- JSON array of task descriptions
+    hour          INTEGER NOT NULL DEFAULT 6 CHECK (hour BETWEEN 0 AND 23),
+    skip_weekends INTEGER NOT NULL DEFAULT 0,
+    carry_over    INTEGER NOT NULL DEFAULT 0,
+    created_at    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS checklist_runs (
+    checklist_id TEXT NOT NULL REFERENCES checklists(id) ON DELETE CASCADE,
+    day          TEXT NOT NULL, -- YYYY-MM-DD, local time
+    task_id      TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    PRIMARY KEY (checklist_id, day)
+);
+`,
+		Tables: []string{"checklists", "checklist_runs"},
+	})
+}
+
+func NewChecklistID() string {
+	return "checklist_" + xid.New().String()
+}
+
+func CreateChecklist(ctx context.Context, db *sqlx.DB, c *Checklist) error {
+	if err := ensureExtension(ctx, db, ExtChecklists); err != nil {
+		return err
+	}
+	if c.ID == "" {
+		c.ID = NewChecklistID()
+	}
+	items, err := json.Marshal(c.Items)
+	if err != nil {
+		return err
+	}
+	c.ItemsJSON = string(items)
+	c.CreatedAt = now(ctx)
+	_, err = db.NamedExecContext(ctx,
+		`INSERT INTO checklists (id, name, items, hour, skip_weekends, carry_over, created_at)
+		 VALUES (:id, :name, :items, :hour, :skip_weekends, :carry_over, :created_at)`, c)
+	return err
+}
+
+func ListChecklists(ctx context.Context, db *sqlx.DB) ([]Checklist, error) {
+	if err := ensureExtension(ctx, db, ExtChecklists); err != nil {
+		return nil, err
+	}
+	var lists []Checklist
+	if err := db.SelectContext(ctx, &lists, "SELECT * FROM checklists ORDER BY name, id"); err != nil {
+		return nil, err
+	}
+	for i := range lists {
+		if err := json.Unmarshal([]byte(lists[i].ItemsJSON), &lists[i].Items); err != nil {
+			return nil, fmt.Errorf("checklist %s items: %w", lists[i].ID, err)
+		}
+	}
+	return lists, nil
+}
+
+func GetChecklist(ctx context.Context, db *sqlx.DB, id string) (*Checklist, error) {
+	if err := ensureExtension(ctx, db, ExtChecklists); err != nil {
+		return nil, err
+	}
+	var c Checklist
+	if err := db.GetContext(ctx, &c, "SELECT * FROM checklists WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(c.ItemsJSON), &c.Items); err != nil {
+		return nil, fmt.Errorf("checklist %s items: %w", id, err)
+	}
+	return &c, nil
+}
+
+// DeleteChecklist stops future runs; tasks already generated stay.
+func DeleteChecklist(ctx context.Context, db *sqlx.DB, id string) error {
+	if err := ensureExtension(ctx, db, ExtChecklists); err != nil {
+		return err
+	}
+	result, err := db.ExecContext(ctx, "DELETE FROM checklists WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ChecklistRun is one day's instance of a checklist.
+type ChecklistRun struct {
+	ChecklistID string `json:"checklist_id"`
+	Day         string `json:"day"`
+	TaskID      string `json:"task_id"`
+	Created     bool   `json:"created"` // false if the day had already been generated
+	CarriedOver int    `json:"carried_over"`
+}
+
+// GenerateChecklist creates the checklist's tasks for day (YYYY-MM-DD) unless
+// that day already has them.
+func GenerateChecklist(ctx context.Context, db *sqlx.DB, c *Checklist, day string) (*ChecklistRun, error) {
+	if err := ensureExtension(ctx, db, ExtChecklists); err != nil {
+		return nil, err
+	}
+	run := &ChecklistRun{ChecklistID: c.ID, Day: day}
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &run.TaskID,
+			"SELECT task_id FROM checklist_runs WHERE checklist_id = ? AND day = ?", c.ID, day)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		parent := &Task{
+			ID:          NewTaskID(),
+			Description: fmt.Sprintf("%s (%s)", c.Name, day),
+			Context:     Sensitive("generated from checklist " + c.ID),
+			Priority:    3,
+		}
+		if err := insertTaskTx(ctx, tx, parent); err != nil {
+			return err
+		}
+		if err := tagTx(ctx, tx, parent.ID, ChecklistTag); err != nil {
+			return err
+		}
+
+		// a carried-over item stands in for today's copy of it
+		carried := map[string]bool{}
+		if c.CarryOver {
+			descs, err := carryOver(ctx, tx, c.ID, day, parent.ID)
+			if err != nil {
+				return err
+			}
+			for _, d := range descs {
+				carried[d] = true
+			}
+			run.CarriedOver = len(descs)
+		}
+		var children []*Task
+		for _, item := range c.Items {
+			if !carried[item] {
+				children = append(children, &Task{ID: NewTaskID(), ParentID: &parent.ID, Description: item, Priority: 3})
+			}
+		}
+		if err := insertTasksTx(ctx, tx, children); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO checklist_runs (checklist_id, day, task_id) VALUES (?, ?, ?)",
+			c.ID, day, parent.ID); err != nil {
+			return err
+		}
+		run.TaskID, run.Created = parent.ID, true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// carryOver moves unfinished items of the latest earlier run under parentID
+// and returns their descriptions.
+func carryOver(ctx context.Context, tx *sqlx.Tx, checklistID, day, parentID string) ([]string, error) {
+	var items []struct {
+		ID          string `db:"id"`
+		Description string `db:"description"`
+	}
+	err := tx.SelectContext(ctx, &items, `
+		SELECT t.id, t.description FROM tasks t
+		WHERE t.status IN ('pending', 'in_progress') AND t.archived_at IS NULL
+		  AND t.parent_id = (SELECT task_id FROM checklist_runs
+		                      WHERE checklist_id = ? AND day < ? ORDER BY day DESC LIMIT 1)`,
+		checklistID, day)
+	if err != nil {
+		return nil, err
+	}
+	descs := make([]string, 0, len(items))
+	for _, item := range items {
+		before, err := getTaskTx(ctx, tx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET parent_id = ?, updated_at = ? WHERE id = ?",
+			parentID, now(ctx), item.ID); err != nil {
+			return nil, err
+		}
+		after, err := getTaskTx(ctx, tx, item.ID)
+		if err != nil {
+			return nil, err
+		}
+		oldValues, newValues := diffTasks(before, after)
+		if err := recordEvent(ctx, tx, item.ID, "task", "update", oldValues, newValues); err != nil {
+			return nil, err
+		}
+		if err := tagTx(ctx, tx, item.ID, CarriedOverTag); err != nil {
+			return nil, err
+		}
+		descs = append(descs, item.Description)
+	}
+	return descs, nil
+}
+
+// RolloverChecklists generates today's run of every checklist whose hour
+// has passed, skipping weekends where configured. Days are local time.
+func RolloverChecklists(ctx context.Context, db *sqlx.DB) (int, error) {
+	if ok, err := hasExtension(ctx, db, ExtChecklists); !ok {
+		return 0, err // no checklists were ever made
+	}
+	lists, err := ListChecklists(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	t := clock.From(ctx).Now().In(time.Local)
+	day := t.Format("2006-01-02")
+	weekend := t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+
+	generated := 0
+	for i := range lists {
+		c := &lists[i]
+		if t.Hour() < c.Hour || (weekend && c.SkipWeekends) {
+			continue
+		}
+		run, err := GenerateChecklist(ctx, db, c, day)
+		if err != nil {
+			return generated, fmt.Errorf("checklist %s: %w", c.ID, err)
+		}
+		if run.Created {
+			generated++
+		}
+	}
+	return generated, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestClaimNextTaskConcurrent has more agents than ready tasks claim at
+// once over several connections and wants every task claimed exactly once,
+// by the agent it ends up assigned to.
+func TestClaimNextTaskConcurrent(t *testing.T) {
+	conn, ctx := newTestDB(t)
+	conn.SetMaxOpenConns(8)
+
+	const tasks, agents = 5, 8
+	blocker := &Task{ID: NewTaskID(), Description: "design the schema"}
+	if err := InsertTask(ctx, conn, blocker); err != nil {
+		t.Fatal(err)
+	}
+	for i := range tasks {
+		task := &Task{ID: NewTaskID(), Description: fmt.Sprintf("migrate table %d", i)}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	blocked := &Task{ID: NewTaskID(), Description: "write the queries"}
+	if err := InsertTask(ctx, conn, blocked); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddBlocker(ctx, conn, blocked.ID, blocker.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	claimedBy := make(map[string]string)
+	var wg sync.WaitGroup
+	for i := range agents {
+		agent := fmt.Sprintf("agent-%d", i+1)
+		wg.Go(func() {
+			task, err := ClaimNextTask(WithActor(ctx, agent), conn, agent, ParallelOpts{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if task == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if prev, ok := claimedBy[task.ID]; ok {
+				t.Errorf("%s claimed by %s and %s", task.ID, prev, agent)
+			}
+			claimedBy[task.ID] = agent
+			if task.Status != StatusInProgress || task.StartedAt == nil || task.AssignedTo == nil || *task.AssignedTo != agent {
+				t.Errorf("%s claimed as %+v", agent, task)
+			}
+		})
+	}
+	wg.Wait()
+	// the blocker is ready too; the blocked task isn't
+	if len(claimedBy) != tasks+1 {
+		t.Errorf("claimed %d tasks, want %d", len(claimedBy), tasks+1)
+	}
+	if _, ok := claimedBy[blocked.ID]; ok {
+		t.Error("claimed a blocked task")
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+// Comment is an append-style progress note on a task, kept apart from the
+// task's single context field so notes never overwrite each other.
+type Comment struct {
+	ID        string `db:"id" json:"id"`
+	TaskID    string `db:"task_id" json:"task_id"`
+	Author    string `db:"author" json:"author"`
+	Body      string `db:"body" json:"body"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+}
+
+func NewCommentID() string {
+	return "comment_" + xid.New().String()
+}
+
+// AddComment assigns an ID if missing and stamps CreatedAt.
+func AddComment(ctx context.Context, db *sqlx.DB, c *Comment) error {
+	return insertComment(ctx, db, c)
+}
+
+// insertComment is AddComment for either a connection or a transaction.
+func insertComment(ctx context.Context, e sqlx.ExecerContext, c *Comment) error {
+	if c.ID == "" {
+		c.ID = NewCommentID()
+	}
+	c.CreatedAt = now(ctx)
+	_, err := e.ExecContext(ctx,
+		`INSERT INTO task_comments (id, task_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)`,
+		c.ID, c.TaskID, c.Author, c.Body, c.CreatedAt)
+	return err
+}
+
+func GetComment(ctx context.Context, db *sqlx.DB, id string) (*Comment, error) {
+	var c Comment
+	err := db.GetContext(ctx, &c, "SELECT * FROM task_comments WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListComments returns a task's comments oldest first. limit <= 0 means all.
+func ListComments(ctx context.Context, db *sqlx.DB, taskID string, limit int) ([]Comment, error) {
+	query := "SELECT * FROM task_comments WHERE task_id = ? ORDER BY created_at ASC, id ASC"
+	args := []any{taskID}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var comments []Comment
+	err := db.SelectContext(ctx, &comments, query, args...)
+	return comments, err
+}
+
+func UpdateComment(ctx context.Context, db *sqlx.DB, id, body string) error {
+	result, err := db.ExecContext(ctx, "UPDATE task_comments SET body = ? WHERE id = ?", body, id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func DeleteComment(ctx context.Context, db *sqlx.DB, id string) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM task_comments WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Counters are named integers kept in the counters table for sequences,
+// quotas and rate accounting. Every change is one upsert that returns the
+// new value, never a read followed by a write, so connections and
+// processes bumping the same counter at once each see a distinct result
+// and none of the increments is lost.
+
+// Counter is one row of the counters table.
+type Counter struct {
+	Name      string `db:"name" json:"name"`
+	Value     int64  `db:"value" json:"value"`
+	UpdatedAt string `db:"updated_at" json:"updated_at"`
+}
+
+// windowSep joins a windowed counter's name to the start of its window.
+const windowSep = "@"
+
+const incrementCounterQuery = `
+	INSERT INTO counters (name, value, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT (name) DO UPDATE SET value = counters.value + excluded.value,
+	updated_at = excluded.updated_at
+	RETURNING value`
+
+func checkCounterName(name string) error {
+	if name == "" {
+		return errors.New("counter name must not be empty")
+	}
+	if strings.Contains(name, windowSep) {
+		return fmt.Errorf("counter name %q must not contain %q", name, windowSep)
+	}
+	return nil
+}
+
+// IncrementCounter adds delta to the named counter, creating it at zero
+// first, and returns the new value. A negative delta counts down.
+func IncrementCounter(ctx context.Context, db *sqlx.DB, name string, delta int64) (int64, error) {
+	if err := checkCounterName(name); err != nil {
+		return 0, err
+	}
+	var value int64
+	err := db.GetContext(ctx, &value, db.Rebind(incrementCounterQuery), name, delta, now(ctx))
+	return value, err
+}
+
+// incrementCounterTx is IncrementCounter inside tx, so the count commits
+// or rolls back with the rest of the transaction.
+func incrementCounterTx(ctx context.Context, tx *sqlx.Tx, name string, delta int64) (int64, error) {
+	var value int64
+	err := tx.GetContext(ctx, &value, tx.Rebind(incrementCounterQuery), name, delta, now(ctx))
+	return value, err
+}
+
+// NextSequence returns the next number of the named sequence: 1, 2, 3...
+// No two callers get the same number, whichever connection they use.
+func NextSequence(ctx context.Context, db *sqlx.DB, name string) (int64, error) {
+	return IncrementCounter(ctx, db, name, 1)
+}
+
+// GetCounter returns the named counter's value, 0 if it was never set.
+func GetCounter(ctx context.Context, db *sqlx.DB, name string) (int64, error) {
+	var value int64
+	err := db.GetContext(ctx, &value, db.Rebind("SELECT value FROM counters WHERE name = ?"), name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return value, err
+}
+
+// ListCounters returns the counters whose names start with prefix, by
+// name; windowed counters show up once per window.
+func ListCounters(ctx context.Context, db *sqlx.DB, prefix string) ([]Counter, error) {
+	var counters []Counter
+	err := db.SelectContext(ctx, &counters, db.Rebind(
+		"SELECT * FROM counters WHERE substr(name, 1, ?) = ? ORDER BY name"), utf8.RuneCountInString(prefix), prefix)
+	return counters, err
+}
+
+// CountInWindow adds delta to the named counter's count for the current
+// fixed window of the given length, and returns that count: how often
+// something happened this minute or this hour, shared by every process.
+// Counts of earlier windows are dropped as it goes.
+func CountInWindow(ctx context.Context, db *sqlx.DB, name string, window time.Duration, delta int64) (int64, error) {
+	if err := checkCounterName(name); err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return 0, errors.New("window must be positive")
+	}
+	start := clock.From(ctx).Now().UTC().Truncate(window)
+	key := name + windowSep + FormatTime(start)
+	var value int64
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		// FormatTime sorts chronologically, so earlier windows sort first
+		if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM counters WHERE name >= ? AND name < ?"),
+			name+windowSep, key); err != nil {
+			return err
+		}
+		var err error
+		value, err = incrementCounterTx(ctx, tx, key, delta)
+		return err
+	})
+	return value, err
+}
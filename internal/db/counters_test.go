@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// TestNextSequenceConcurrent takes numbers from many connections at once,
+// as a pool without SetMaxOpenConns(1) would, and wants each exactly once.
+func TestNextSequenceConcurrent(t *testing.T) {
+	conn, ctx := newTestDB(t)
+	conn.SetMaxOpenConns(8)
+
+	const workers, each = 8, 25
+	var mu sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for range each {
+				n, err := NextSequence(ctx, conn, "task_number")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if seen[n] {
+					t.Errorf("number %d handed out twice", n)
+				}
+				seen[n] = true
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if got, err := GetCounter(ctx, conn, "task_number"); err != nil || got != workers*each {
+		t.Errorf("GetCounter = %d, %v; want %d", got, err, workers*each)
+	}
+}
+
+func TestCountInWindow(t *testing.T) {
+	conn, err := InitDB(MemoryPath, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fake := clock.NewFake(time.Date(2026, 3, 2, 9, 0, 10, 0, time.UTC))
+	ctx := clock.With(context.Background(), fake)
+
+	count := func(delta int64) int64 {
+		t.Helper()
+		n, err := CountInWindow(ctx, conn, "creates:agent-1", time.Minute, delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if got := count(1); got != 1 {
+		t.Errorf("first count = %d, want 1", got)
+	}
+	if got := count(2); got != 3 {
+		t.Errorf("same window = %d, want 3", got)
+	}
+	fake.Advance(time.Minute)
+	if got := count(1); got != 1 {
+		t.Errorf("next window = %d, want 1", got)
+	}
+	counters, err := ListCounters(ctx, conn, "creates:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counters) != 1 || counters[0].Name != "creates:agent-1@2026-03-02T09:01:00.000Z" {
+		t.Errorf("counters = %+v, want only the current window", counters)
+	}
+	if _, err := IncrementCounter(ctx, conn, "a@b", 1); err == nil {
+		t.Error("a name with @ was accepted")
+	}
+}
@@ -0,0 +1,313 @@
+package db
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Tasks' context and result can hold credentials or proprietary detail,
+// so with a key set they are stored encrypted with AES-256-GCM. Sensitive
+// does it in Scan and Value: rows read through the Task struct come back
+// in plain text and every write of the type is sealed, so GetTask,
+// QueryTasks, InsertTask and UpdateTask need no changes. Sealed text
+// carries sealedPrefix; anything else reads back as it is, so a database
+// can be switched on with SealTasks and plain rows never break.
+//
+// Sealed columns can't be searched: full-text search still matches
+// descriptions, but not the context or result of a sealed task.
+
+// sealedPrefix marks an encrypted value; v1 is AES-256-GCM with a random
+// 12-byte nonce before the ciphertext, base64 encoded.
+const sealedPrefix = "enc:v1:"
+
+// EncryptionKeySize is the key length SetEncryptionKey takes.
+const EncryptionKeySize = 32
+
+// ErrNoEncryptionKey is reading a sealed value without the key.
+var ErrNoEncryptionKey = errors.New("task data is encrypted but no encryption key is set (BOSSMAN_ENCRYPTION_KEY or BOSSMAN_ENCRYPTION_KEYFILE)")
+
+var sealer atomic.Pointer[cipher.AEAD]
+
+// SetEncryptionKey turns encryption on for everything written from now
+// on; nil turns it off for writes, leaving sealed rows unreadable.
+func SetEncryptionKey(key []byte) error {
+	if key == nil {
+		sealer.Store(nil)
+		return nil
+	}
+	if len(key) != EncryptionKeySize {
+		return fmt.Errorf("encryption key is %d bytes, want %d", len(key), EncryptionKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	sealer.Store(&aead)
+	return nil
+}
+
+// ParseEncryptionKey decodes a key as given in BOSSMAN_ENCRYPTION_KEY or
+// a key file: base64 of EncryptionKeySize random bytes, e.g. from
+// `openssl rand -base64 32`.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: want base64: %w", err)
+	}
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key is %d bytes, want %d", len(key), EncryptionKeySize)
+	}
+	return key, nil
+}
+
+// Encrypted reports whether writes are being sealed.
+func Encrypted() bool {
+	return sealer.Load() != nil
+}
+
+// Sensitive is text stored encrypted when a key is set. The empty string
+// is stored as is, so "no context" stays visible to SQL.
+type Sensitive string
+
+// Value seals s with the current key, if any.
+func (s Sensitive) Value() (driver.Value, error) {
+	return seal(string(s))
+}
+
+// Scan opens sealed text and takes anything else as plain.
+func (s *Sensitive) Scan(src any) error {
+	var text string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("scan sensitive: unsupported type %T", src)
+	}
+	plain, err := open(text)
+	if err != nil {
+		return err
+	}
+	*s = Sensitive(plain)
+	return nil
+}
+
+func seal(plain string) (string, error) {
+	aead := sealer.Load()
+	if aead == nil || plain == "" {
+		return plain, nil
+	}
+	nonce := make([]byte, (*aead).NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := (*aead).Seal(nonce, nonce, []byte(plain), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func open(text string) (string, error) {
+	rest, ok := strings.CutPrefix(text, sealedPrefix)
+	if !ok {
+		return text, nil
+	}
+	aead := sealer.Load()
+	if aead == nil {
+		return "", ErrNoEncryptionKey
+	}
+	data, err := base64.StdEncoding.DecodeString(rest)
+	n := (*aead).NonceSize()
+	if err != nil || len(data) < n {
+		return "", errors.New("open sealed task data: malformed value")
+	}
+	plain, err := (*aead).Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", fmt.Errorf("open sealed task data: wrong key or corrupted value: %w", err)
+	}
+	return string(plain), nil
+}
+
+// sealedFields are the audit value keys holding Sensitive columns.
+var sealedFields = []string{"context", "result"}
+
+// sealEventValues seals the sensitive fields of an audit value in place,
+// so the history doesn't keep what the row no longer shows in the clear.
+func sealEventValues(v map[string]any) error {
+	for _, k := range sealedFields {
+		var plain string
+		switch x := v[k].(type) {
+		case Sensitive:
+			plain = string(x)
+		case *Sensitive:
+			if x == nil {
+				continue
+			}
+			plain = string(*x)
+		default:
+			continue
+		}
+		sealed, err := seal(plain)
+		if err != nil {
+			return err
+		}
+		v[k] = sealed
+	}
+	return nil
+}
+
+// openEventValue is sealEventValues undone on a stored audit value, for
+// reading the history back. Values it can't open are left sealed.
+func openEventValue(s *string) *string {
+	if s == nil || !strings.Contains(*s, sealedPrefix) {
+		return s
+	}
+	var v map[string]any
+	if err := json.Unmarshal([]byte(*s), &v); err != nil {
+		return s
+	}
+	for _, k := range sealedFields {
+		if text, ok := v[k].(string); ok {
+			if plain, err := open(text); err == nil {
+				v[k] = plain
+			}
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return s
+	}
+	out := string(b)
+	return &out
+}
+
+// SealTasks encrypts the context and result of tasks written before the
+// key was set, and the same fields in their history, returning how many
+// tasks it rewrote. It leaves updated_at and the events themselves alone:
+// the data hasn't changed, only how it's kept. On SQLite the plain text
+// would otherwise linger in the full-text index's old segments and in
+// free pages, so when anything was sealed it merges the index, vacuums
+// and truncates the write-ahead log.
+func SealTasks(ctx context.Context, db *sqlx.DB) (int, error) {
+	if !Encrypted() {
+		return 0, nil
+	}
+	var n, events int
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var rows []struct {
+			ID      string     `db:"id"`
+			Context Sensitive  `db:"context"`
+			Result  *Sensitive `db:"result"`
+		}
+		if err := tx.SelectContext(ctx, &rows, tx.Rebind(`SELECT id, context, result FROM tasks
+			WHERE (context != '' AND context NOT LIKE ?) OR (result != '' AND result NOT LIKE ?)`),
+			sealedPrefix+"%", sealedPrefix+"%"); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET context = ?, result = ? WHERE id = ?"),
+				r.Context, r.Result, r.ID); err != nil {
+				return err
+			}
+		}
+		n = len(rows)
+		var err error
+		events, err = sealEvents(ctx, tx)
+		return err
+	})
+	if err != nil || isPostgres(db) || n+events == 0 {
+		return n, err
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO tasks_fts(tasks_fts) VALUES('optimize')"); err != nil {
+		return n, fmt.Errorf("merge search index: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+		return n, fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := Checkpoint(ctx, db); err != nil {
+		return n, fmt.Errorf("checkpoint: %w", err)
+	}
+	return n, nil
+}
+
+// sealEvents seals the context and result in task events recorded before
+// the key was set, returning how many events it rewrote.
+func sealEvents(ctx context.Context, tx *sqlx.Tx) (int, error) {
+	var rows []struct {
+		ID       int64   `db:"id"`
+		OldValue *string `db:"old_value"`
+		NewValue *string `db:"new_value"`
+	}
+	if err := tx.SelectContext(ctx, &rows, `SELECT id, old_value, new_value FROM task_events
+		WHERE entity = 'task' AND (old_value IS NOT NULL OR new_value IS NOT NULL)`); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range rows {
+		oldValue, oldChanged, err := sealEventValue(r.OldValue)
+		if err != nil {
+			return n, err
+		}
+		newValue, newChanged, err := sealEventValue(r.NewValue)
+		if err != nil {
+			return n, err
+		}
+		if !oldChanged && !newChanged {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE task_events SET old_value = ?, new_value = ? WHERE id = ?"),
+			oldValue, newValue, r.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// sealEventValue seals the plain sensitive fields of a stored audit value
+// with sealEventValues, reporting whether there were any.
+func sealEventValue(s *string) (*string, bool, error) {
+	if s == nil {
+		return s, false, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(*s))
+	dec.UseNumber() // leave other fields as they were written
+	var v map[string]any
+	if err := dec.Decode(&v); err != nil {
+		return s, false, nil // not an object, so nothing of ours
+	}
+	plain := false
+	for _, k := range sealedFields {
+		if text, ok := v[k].(string); ok && text != "" && !strings.HasPrefix(text, sealedPrefix) {
+			v[k] = Sensitive(text)
+			plain = true
+		}
+	}
+	if !plain {
+		return s, false, nil
+	}
+	if err := sealEventValues(v); err != nil {
+		return s, false, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return s, false, err
+	}
+	out := string(b)
+	return &out, true, nil
+}
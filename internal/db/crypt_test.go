@@ -0,0 +1,192 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEncryptedColumns writes a task with a key set, checks what reached
+// the disk, reads it back through the usual functions, and seals a row
+// written before the key.
+func TestEncryptedColumns(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	plain := &Task{ID: NewTaskID(), Description: "from before the key", Context: "db password is hunter2"}
+	if err := InsertTask(ctx, conn, plain); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParseEncryptionKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEncryptionKey(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetEncryptionKey(nil) })
+
+	task := &Task{ID: NewTaskID(), Description: "rotate the keys", Context: "the API token is tok_secret"}
+	if err := InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	result := "rotated tok_secret"
+	if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Result: &result}); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := func(id string) (context, result string) {
+		t.Helper()
+		var row struct {
+			Context string  `db:"context"`
+			Result  *string `db:"result"`
+		}
+		if err := conn.GetContext(ctx, &row, "SELECT context, result FROM tasks WHERE id = ?", id); err != nil {
+			t.Fatal(err)
+		}
+		if row.Result != nil {
+			result = *row.Result
+		}
+		return row.Context, result
+	}
+	c, r := stored(task.ID)
+	if !strings.HasPrefix(c, sealedPrefix) || !strings.HasPrefix(r, sealedPrefix) || strings.Contains(c+r, "tok_secret") {
+		t.Errorf("stored context %q, result %q", c, r)
+	}
+	got, err := GetTask(ctx, conn, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Context != task.Context || got.Result == nil || string(*got.Result) != result {
+		t.Errorf("read back context %q, result %v", got.Context, got.Result)
+	}
+
+	var raw []string
+	if err := conn.SelectContext(ctx, &raw, "SELECT COALESCE(new_value, '') FROM task_events WHERE task_id = ?", task.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range raw {
+		if strings.Contains(v, "tok_secret") {
+			t.Errorf("audit log holds %s", v)
+		}
+	}
+	events, err := GetTaskEvents(ctx, conn, task.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].NewValue == nil || !strings.Contains(*events[0].NewValue, result) {
+		t.Errorf("history doesn't read back: %+v", events)
+	}
+
+	if n, err := SealTasks(ctx, conn); err != nil || n != 1 {
+		t.Errorf("SealTasks = %d, %v; want the 1 plain row", n, err)
+	}
+	if c, _ := stored(plain.ID); !strings.HasPrefix(c, sealedPrefix) {
+		t.Errorf("plain row still stored as %q", c)
+	}
+
+	SetEncryptionKey(nil)
+	if _, err := GetTask(ctx, conn, task.ID); !errors.Is(err, ErrNoEncryptionKey) {
+		t.Errorf("read without the key: %v", err)
+	}
+}
+
+// TestSealTasksLeavesNoPlainText seals a task and its history written
+// before the key, then reads the raw database files: the old text mustn't
+// survive in the history, the search index or free pages.
+func TestSealTasksLeavesNoPlainText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bossman.db")
+	conn, err := InitDB(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := WithActor(context.Background(), "agent-1")
+
+	const secret = "hunter2zebra"
+	task := &Task{ID: NewTaskID(), Description: "rotate the keys", Context: "the password is " + secret}
+	if err := InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	result := "rotated " + secret
+	if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Result: &result}); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParseEncryptionKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEncryptionKey(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetEncryptionKey(nil) })
+	if n, err := SealTasks(ctx, conn); err != nil || n != 1 {
+		t.Fatalf("SealTasks = %d, %v; want the 1 plain row", n, err)
+	}
+
+	for _, name := range []string{path, path + "-wal"} {
+		raw, err := os.ReadFile(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Errorf("%s still holds %q in plain text", filepath.Base(name), secret)
+		}
+	}
+
+	events, err := GetTaskEvents(ctx, conn, task.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].NewValue == nil || !strings.Contains(*events[0].NewValue, result) {
+		t.Errorf("history doesn't read back: %+v", events)
+	}
+}
+
+// TestMaintenanceRunResultSealed records a maintenance run with a key set
+// and wants the detail it mirrors into the job's result sealed too.
+func TestMaintenanceRunResultSealed(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	job := &Task{ID: NewTaskID(), Description: "rotate the logs"}
+	if err := InsertTask(ctx, conn, job); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseEncryptionKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEncryptionKey(key); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetEncryptionKey(nil) })
+
+	stamp := FormatTime(time.Now())
+	run := &MaintenanceRun{TaskID: job.ID, StartedAt: stamp, FinishedAt: stamp, OK: true, Detail: "rotated tok_secret"}
+	if err := RecordMaintenanceRun(ctx, conn, run); err != nil {
+		t.Fatal(err)
+	}
+	var stored string
+	if err := conn.GetContext(ctx, &stored, "SELECT result FROM tasks WHERE id = ?", job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored, sealedPrefix) {
+		t.Errorf("stored result %q", stored)
+	}
+	got, err := GetTask(ctx, conn, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Result == nil || string(*got.Result) != run.Detail {
+		t.Errorf("read back result %v", got.Result)
+	}
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a ListOpts.Cursor that CursorAfter
+// didn't produce for the same ordering.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Orderings QueryTasks accepts in ListOpts.OrderBy.
+const (
+	OrderPriority  = "priority" // weight, then newest first among equals
+	OrderCreatedAt = "created_at"
+	OrderUpdatedAt = "updated_at"
+	OrderDueAt     = "due_at" // tasks without a due date come last either way
+)
+
+// sortKey is one ORDER BY term and how to read its value off a task for a
+// cursor.
+type sortKey struct {
+	expr  func(desc bool) string
+	value func(t *Task, desc bool) any
+}
+
+func column(name string, value func(t *Task) any) sortKey {
+	return sortKey{
+		expr:  func(bool) string { return name },
+		value: func(t *Task, _ bool) any { return value(t) },
+	}
+}
+
+// dueKey sorts missing due dates after every real one in both directions:
+// "~" sorts above any timestamp and the empty string below.
+var dueKey = sortKey{
+	expr: func(desc bool) string {
+		if desc {
+			return "COALESCE(due_at, '')"
+		}
+		return "COALESCE(due_at, '~')"
+	},
+	value: func(t *Task, desc bool) any {
+		switch {
+		case t.DueAt != nil:
+			return *t.DueAt
+		case desc:
+			return ""
+		}
+		return "~"
+	},
+}
+
+// taskOrders are the ORDER BY keys of each ordering, before the id that
+// breaks ties so a cursor position is exact.
+var taskOrders = map[string][]sortKey{
+	OrderPriority: {
+		column("priority_weight", func(t *Task) any { return t.PriorityWeight }),
+		column("created_at", func(t *Task) any { return t.CreatedAt }),
+	},
+	OrderCreatedAt: {column("created_at", func(t *Task) any { return t.CreatedAt })},
+	OrderUpdatedAt: {column("updated_at", func(t *Task) any { return t.UpdatedAt })},
+	OrderDueAt:     {dueKey},
+}
+
+// ValidateOrder checks ListOpts.OrderBy and SortDir, either of which may be
+// empty for the default: priority, highest first; other orderings default
+// to ascending.
+func ValidateOrder(orderBy, dir string) error {
+	if _, ok := taskOrders[orderBy]; !ok && orderBy != "" {
+		return fmt.Errorf("invalid sort %q: want priority, created_at, updated_at or due_at", orderBy)
+	}
+	if dir != "" && dir != "asc" && dir != "desc" {
+		return fmt.Errorf("invalid sort direction %q: want asc or desc", dir)
+	}
+	return nil
+}
+
+// order resolves the defaults of ValidateOrder.
+func (o ListOpts) order() (string, bool) {
+	orderBy := o.OrderBy
+	if orderBy == "" {
+		orderBy = OrderPriority
+	}
+	if o.SortDir == "" {
+		return orderBy, orderBy == OrderPriority
+	}
+	return orderBy, o.SortDir == "desc"
+}
+
+// orderClause returns the ORDER BY for o and, with a cursor, the condition
+// selecting the rows after it, adding its binds to args.
+func (o ListOpts) orderClause(args map[string]any) (order, after string, err error) {
+	if err := ValidateOrder(o.OrderBy, o.SortDir); err != nil {
+		return "", "", err
+	}
+	orderBy, desc := o.order()
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	keys := taskOrders[orderBy]
+	exprs := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		exprs = append(exprs, k.expr(desc))
+	}
+	exprs = append(exprs, "id")
+	terms := make([]string, len(exprs))
+	for i, e := range exprs {
+		terms[i] = e + " " + dir
+	}
+	order = " ORDER BY " + strings.Join(terms, ", ")
+	if o.Cursor == "" {
+		return order, "", nil
+	}
+
+	values, err := decodeCursor(o.Cursor, orderBy, desc, len(exprs))
+	if err != nil {
+		return "", "", err
+	}
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... for each key in turn
+	var alts []string
+	for i, e := range exprs {
+		var conds []string
+		for j := range i {
+			conds = append(conds, fmt.Sprintf("%s = :cursor_%d", exprs[j], j))
+		}
+		conds = append(conds, fmt.Sprintf("%s %s :cursor_%d", e, cmp, i))
+		alts = append(alts, "("+strings.Join(conds, " AND ")+")")
+		args[fmt.Sprintf("cursor_%d", i)] = values[i]
+	}
+	return order, " AND (" + strings.Join(alts, " OR ") + ")", nil
+}
+
+// taskCursor is a position in one ordering: its name and direction, and
+// the sort key values of the last task seen, id last.
+type taskCursor struct {
+	Order string `json:"o"`
+	Keys  []any  `json:"k"`
+}
+
+// CursorAfter returns the cursor that continues a listing made with o
+// after t. Cursors are opaque to clients.
+func (o ListOpts) CursorAfter(t *Task) string {
+	orderBy, desc := o.order()
+	c := taskCursor{Order: cursorOrder(orderBy, desc)}
+	for _, k := range taskOrders[orderBy] {
+		c.Keys = append(c.Keys, k.value(t, desc))
+	}
+	c.Keys = append(c.Keys, t.ID)
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func cursorOrder(orderBy string, desc bool) string {
+	if desc {
+		return orderBy + " desc"
+	}
+	return orderBy + " asc"
+}
+
+// decodeCursor reads a cursor made for the same ordering, with n keys.
+func decodeCursor(s, orderBy string, desc bool, n int) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var c taskCursor
+	if dec.Decode(&c) != nil || c.Order != cursorOrder(orderBy, desc) || len(c.Keys) != n {
+		return nil, ErrInvalidCursor
+	}
+	for i, k := range c.Keys {
+		switch v := k.(type) {
+		case json.Number:
+			if c.Keys[i], err = v.Int64(); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return c.Keys, nil
+}
@@ -3,8 +3,13 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/rs/xid"
@@ -19,13 +24,42 @@ CREATE TABLE IF NOT EXISTS tasks (
     context     TEXT NOT NULL DEFAULT '',
     priority    INTEGER NOT NULL DEFAULT 3
         CHECK (priority BETWEEN 1 AND 5),
+    priority_label TEXT NOT NULL DEFAULT 'normal',
+    priority_weight INTEGER NOT NULL DEFAULT 50
+        CHECK (priority_weight BETWEEN 0 AND 100),
     status      TEXT NOT NULL DEFAULT 'pending'
         CHECK (status IN ('pending', 'in_progress', 'completed', 'failed')),
     result      TEXT,
     created_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
     started_at  TEXT,
     completed_at TEXT,
-    updated_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+    updated_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+    estimate_minutes INTEGER CHECK (estimate_minutes >= 0),
+    assigned_to TEXT,
+    due_at      TEXT,
+    project_id  TEXT REFERENCES projects(id),
+    metadata    TEXT CHECK (metadata IS NULL OR json_valid(metadata)),
+    review_status TEXT CHECK (review_status IN ('requested', 'approved', 'changes_requested')),
+    reviewer    TEXT,
+    revision    INTEGER NOT NULL DEFAULT 0,
+    kind        TEXT NOT NULL DEFAULT 'task' CHECK (kind IN ('task', 'question')),
+    answer      TEXT,
+    answered_by TEXT,
+    session_id  TEXT,
+    archived_at TEXT,
+    attempts    INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS projects (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS priority_scales (
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    label      TEXT NOT NULL,
+    weight     INTEGER NOT NULL CHECK (weight BETWEEN 0 AND 100),
+    PRIMARY KEY (project_id, label)
 );
 CREATE TABLE IF NOT EXISTS task_blockers (
     task_id       TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
@@ -33,54 +67,452 @@ CREATE TABLE IF NOT EXISTS task_blockers (
     PRIMARY KEY (task_id, blocked_by_id),
     CHECK (task_id != blocked_by_id)
 );
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    tag     TEXT NOT NULL,
+    PRIMARY KEY (task_id, tag)
+);
+CREATE TABLE IF NOT EXISTS task_resources (
+    task_id  TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    resource TEXT NOT NULL,
+    PRIMARY KEY (task_id, resource)
+);
+CREATE TABLE IF NOT EXISTS task_files (
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    path    TEXT NOT NULL,
+    PRIMARY KEY (task_id, path)
+);
+CREATE TABLE IF NOT EXISTS resource_locks (
+    resource    TEXT PRIMARY KEY,
+    task_id     TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    holder      TEXT NOT NULL,
+    acquired_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS work_windows (
+    id         TEXT PRIMARY KEY,
+    kind       TEXT NOT NULL CHECK (kind IN ('tag', 'resource')),
+    name       TEXT NOT NULL,
+    days       INTEGER NOT NULL, -- bitmask, bit 0 = Sunday
+    start_time TEXT NOT NULL,    -- HH:MM local
+    end_time   TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS task_comments (
+    id         TEXT PRIMARY KEY,
+    task_id    TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    author     TEXT NOT NULL,
+    body       TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE TABLE IF NOT EXISTS time_entries (
+    id         TEXT PRIMARY KEY,
+    task_id    TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    actor      TEXT NOT NULL,
+    started_at TEXT NOT NULL,
+    ended_at   TEXT,
+    seconds    INTEGER -- set when the entry is stopped
+);
+CREATE TABLE IF NOT EXISTS task_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id    TEXT NOT NULL, -- no FK: history outlives the task
+    entity     TEXT NOT NULL CHECK (entity IN ('task', 'blocker', 'tag')),
+    op         TEXT NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+    old_value  TEXT,
+    new_value  TEXT,
+    actor      TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE TABLE IF NOT EXISTS maintenance_runs (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id     TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    started_at  TEXT NOT NULL,
+    finished_at TEXT NOT NULL,
+    ok          INTEGER NOT NULL,
+    detail      TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS dead_letters (
+    id          TEXT PRIMARY KEY,
+    kind        TEXT NOT NULL,
+    source      TEXT NOT NULL,
+    payload     TEXT NOT NULL DEFAULT '',
+    error       TEXT NOT NULL,
+    attempts    INTEGER NOT NULL,
+    failed_at   TEXT NOT NULL,
+    resolved_at TEXT
+);
+CREATE TABLE IF NOT EXISTS capacities (
+    assignee        TEXT PRIMARY KEY,
+    minutes_per_day INTEGER CHECK (minutes_per_day > 0),
+    tasks_per_day   INTEGER CHECK (tasks_per_day > 0),
+    updated_at      TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS settings (
+    key        TEXT PRIMARY KEY,
+    value      TEXT NOT NULL,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE TABLE IF NOT EXISTS schema_extensions (
+    name       TEXT PRIMARY KEY,
+    version    INTEGER NOT NULL, -- last migration applied
+    enabled_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS counters (
+    name       TEXT PRIMARY KEY,
+    value      INTEGER NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS changes (
+    seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+    entity     TEXT NOT NULL CHECK (entity IN ('task', 'blocker')),
+    op         TEXT NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+    task_id    TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE TRIGGER IF NOT EXISTS trg_tasks_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO changes (entity, op, task_id) VALUES ('task', 'insert', NEW.id);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_tasks_update AFTER UPDATE ON tasks BEGIN
+    INSERT INTO changes (entity, op, task_id) VALUES ('task', 'update', NEW.id);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_tasks_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO changes (entity, op, task_id) VALUES ('task', 'delete', OLD.id);
+END;
+CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(
+    description, context, result,
+    content='tasks', content_rowid='rowid'
+);
+CREATE TRIGGER IF NOT EXISTS trg_tasks_fts_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO tasks_fts (rowid, description, context, result)
+    VALUES (NEW.rowid, NEW.description, NEW.context, NEW.result);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_tasks_fts_update AFTER UPDATE OF description, context, result ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, description, context, result)
+    VALUES ('delete', OLD.rowid, OLD.description, OLD.context, OLD.result);
+    INSERT INTO tasks_fts (rowid, description, context, result)
+    VALUES (NEW.rowid, NEW.description, NEW.context, NEW.result);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_tasks_fts_delete AFTER DELETE ON tasks BEGIN
+    INSERT INTO tasks_fts (tasks_fts, rowid, description, context, result)
+    VALUES ('delete', OLD.rowid, OLD.description, OLD.context, OLD.result);
+END;
+CREATE VIRTUAL TABLE IF NOT EXISTS comments_fts USING fts5(
+    body,
+    content='task_comments', content_rowid='rowid'
+);
+CREATE TRIGGER IF NOT EXISTS trg_comments_fts_insert AFTER INSERT ON task_comments BEGIN
+    INSERT INTO comments_fts (rowid, body) VALUES (NEW.rowid, NEW.body);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_comments_fts_update AFTER UPDATE OF body ON task_comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, body) VALUES ('delete', OLD.rowid, OLD.body);
+    INSERT INTO comments_fts (rowid, body) VALUES (NEW.rowid, NEW.body);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_comments_fts_delete AFTER DELETE ON task_comments BEGIN
+    INSERT INTO comments_fts (comments_fts, rowid, body) VALUES ('delete', OLD.rowid, OLD.body);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_blockers_insert AFTER INSERT ON task_blockers BEGIN
+    INSERT INTO changes (entity, op, task_id) VALUES ('blocker', 'insert', NEW.task_id);
+END;
+CREATE TRIGGER IF NOT EXISTS trg_blockers_delete AFTER DELETE ON task_blockers BEGIN
+    INSERT INTO changes (entity, op, task_id) VALUES ('blocker', 'delete', OLD.task_id);
+END;
 CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
 CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_id);
 CREATE INDEX IF NOT EXISTS idx_tasks_priority ON tasks(priority);
 CREATE INDEX IF NOT EXISTS idx_task_blockers_task ON task_blockers(task_id);
 CREATE INDEX IF NOT EXISTS idx_task_blockers_blocked_by ON task_blockers(blocked_by_id);
-CREATE INDEX IF NOT EXISTS idx_tasks_status_priority ON tasks(status, priority);
+CREATE INDEX IF NOT EXISTS idx_tasks_status_weight ON tasks(status, priority_weight);
+CREATE INDEX IF NOT EXISTS idx_tasks_assigned_to ON tasks(assigned_to);
+CREATE INDEX IF NOT EXISTS idx_tasks_project ON tasks(project_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_review ON tasks(review_status) WHERE review_status IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_tasks_questions ON tasks(status, created_at) WHERE kind = 'question';
+CREATE INDEX IF NOT EXISTS idx_tasks_session ON tasks(session_id) WHERE session_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag);
+CREATE INDEX IF NOT EXISTS idx_resource_locks_task ON resource_locks(task_id);
+CREATE INDEX IF NOT EXISTS idx_task_files_path ON task_files(path);
+CREATE INDEX IF NOT EXISTS idx_task_events_task ON task_events(task_id, id);
+CREATE INDEX IF NOT EXISTS idx_task_comments_task ON task_comments(task_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries(task_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_open ON time_entries(task_id, actor) WHERE ended_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_maintenance_runs_task ON maintenance_runs(task_id, id);
+CREATE INDEX IF NOT EXISTS idx_dead_letters_open ON dead_letters(resolved_at, failed_at);
 `
 
+// addedColumns are columns added to existing tables after release. CREATE
+// TABLE IF NOT EXISTS leaves an older table alone, so InitDB adds whichever
+// are missing before applying the schema.
+// backfill, if set, runs once right after its column is added.
+var addedColumns = []struct{ table, column, def, backfill string }{
+	{"tasks", "estimate_minutes", "INTEGER CHECK (estimate_minutes >= 0)", ""},
+	{"tasks", "assigned_to", "TEXT", ""},
+	{"tasks", "due_at", "TEXT", ""},
+	{"tasks", "project_id", "TEXT REFERENCES projects(id)", ""},
+	{"tasks", "metadata", "TEXT CHECK (metadata IS NULL OR json_valid(metadata))", ""},
+	{"tasks", "review_status", "TEXT CHECK (review_status IN ('requested', 'approved', 'changes_requested'))", ""},
+	{"tasks", "reviewer", "TEXT", ""},
+	{"tasks", "revision", "INTEGER NOT NULL DEFAULT 0", ""},
+	{"tasks", "priority_label", "TEXT NOT NULL DEFAULT 'normal'", ""},
+	{"tasks", "priority_weight", "INTEGER NOT NULL DEFAULT 50 CHECK (priority_weight BETWEEN 0 AND 100)", priorityBackfill},
+	{"tasks", "kind", "TEXT NOT NULL DEFAULT 'task' CHECK (kind IN ('task', 'question'))", ""},
+	{"tasks", "answer", "TEXT", ""},
+	{"tasks", "answered_by", "TEXT", ""},
+	{"tasks", "session_id", "TEXT", ""},
+	{"tasks", "archived_at", "TEXT", ""},
+	{"tasks", "attempts", "INTEGER NOT NULL DEFAULT 0", ""},
+}
+
+func addColumns(ctx context.Context, conn *sqlx.DB) error {
+	for _, c := range addedColumns {
+		cols, err := tableColumns(ctx, conn, c.table)
+		if err != nil {
+			return err
+		}
+		if _, ok := cols[c.column]; ok || len(cols) == 0 {
+			continue // already there, or a fresh table the schema will create
+		}
+		if _, err := conn.ExecContext(ctx,
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.def)); err != nil {
+			return fmt.Errorf("add %s.%s: %w", c.table, c.column, err)
+		}
+		if c.backfill != "" {
+			if _, err := conn.ExecContext(ctx, c.backfill); err != nil {
+				return fmt.Errorf("backfill %s.%s: %w", c.table, c.column, err)
+			}
+		}
+	}
+	return nil
+}
+
 type Task struct {
-	ID          string  `db:"id"`
-	ParentID    *string `db:"parent_id"`
-	Description string  `db:"description"`
-	Context     string  `db:"context"`
-	Priority    int     `db:"priority"`
-	Status      string  `db:"status"`
-	Result      *string `db:"result"`
-	CreatedAt   string  `db:"created_at"`
-	StartedAt   *string `db:"started_at"`
-	CompletedAt *string `db:"completed_at"`
-	UpdatedAt   string  `db:"updated_at"`
+	ID          string     `db:"id" json:"id"`
+	ParentID    *string    `db:"parent_id" json:"parent_id,omitempty"`
+	Description string     `db:"description" json:"description"`
+	Context     Sensitive  `db:"context" json:"context,omitempty"`
+	Priority    int        `db:"priority" json:"priority"` // 1-5, derived from PriorityWeight
+	Status      string     `db:"status" json:"status"`
+	Result      *Sensitive `db:"result" json:"result,omitempty"`
+	CreatedAt   Timestamp  `db:"created_at" json:"created_at"`
+	StartedAt   *Timestamp `db:"started_at" json:"started_at,omitempty"`
+	CompletedAt *Timestamp `db:"completed_at" json:"completed_at,omitempty"`
+	UpdatedAt   Timestamp  `db:"updated_at" json:"updated_at"`
+
+	EstimateMinutes *int       `db:"estimate_minutes" json:"estimate_minutes,omitempty"`
+	AssignedTo      *string    `db:"assigned_to" json:"assigned_to,omitempty"`
+	DueAt           *Timestamp `db:"due_at" json:"due_at,omitempty"`
+	ProjectID       *string    `db:"project_id" json:"project_id,omitempty"`
+	Metadata        *string    `db:"metadata" json:"metadata,omitempty"` // JSON object
+
+	ReviewStatus *string `db:"review_status" json:"review_status,omitempty"`
+	Reviewer     *string `db:"reviewer" json:"reviewer,omitempty"`
+	Revision     int     `db:"revision" json:"revision,omitempty"` // times sent back by a reviewer
+
+	PriorityLabel  string `db:"priority_label" json:"priority_label,omitempty"`
+	PriorityWeight int    `db:"priority_weight" json:"priority_weight"`
+
+	// Kind is KindTask, or KindQuestion for a decision put to a person;
+	// see AskQuestion.
+	Kind       string  `db:"kind" json:"kind"`
+	Answer     *string `db:"answer" json:"answer,omitempty"`
+	AnsweredBy *string `db:"answered_by" json:"answered_by,omitempty"`
+
+	// SessionID marks a scratch task, private to the MCP session that
+	// made it until promoted; see DropSessionTasks.
+	SessionID *string `db:"session_id" json:"session_id,omitempty"`
+	// ArchivedAt is set once ArchiveCompleted has moved the task out of
+	// listings.
+	ArchivedAt *Timestamp `db:"archived_at" json:"archived_at,omitempty"`
+	// Attempts counts the times FailTask has failed the task.
+	Attempts int `db:"attempts" json:"attempts,omitempty"`
 }
 
 type ListOpts struct {
 	Status   *string
 	ParentID *string
-	Limit    int
+	Tags     []string // task must carry every tag
+	// Files matches tasks touching any of the paths; see filesFilter.
+	Files []string
+	// Open leaves out completed and failed tasks.
+	Open bool
+	// AssignedTo filters by assignee; "" matches unassigned tasks.
+	AssignedTo *string
+	// ProjectID filters by project; "" matches tasks outside any project.
+	ProjectID *string
+	// Metadata matches tasks whose metadata has each key equal to the value;
+	// a nil value matches tasks without the key. See metadataPath for keys.
+	Metadata     map[string]any
+	ReviewStatus *string
+	Kind         *string // KindTask or KindQuestion
+	// CreatedAfter, CreatedBefore and UpdatedAfter bound the timestamps,
+	// exclusive; the zero time leaves a bound off.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	// Text matches tasks whose description contains it, ignoring case.
+	Text  string
+	Limit int
+	// Session is the caller's MCP session. Scratch tasks of any other
+	// session are left out; with Scratch set, only the caller's are listed.
+	Session string
+	Scratch bool
+	// Archived lists only archived tasks, which are otherwise left out.
+	Archived bool
+	// OrderBy is one of the Order constants, OrderPriority by default;
+	// SortDir is "asc" or "desc". See ValidateOrder.
+	OrderBy string
+	SortDir string
+	// Cursor continues a listing after the task CursorAfter was given, so
+	// pages stay stable while tasks are added.
+	Cursor string
 }
 
 type UpdateOpts struct {
 	Description *string
-	Priority    *int
-	Status      *string
-	Context     *string
-	Result      *string
+	Priority    *PriorityChange
+	// Status must be a move CheckTransition allows; started_at and
+	// completed_at follow it.
+	Status *string
+	// Reopen lets Status take a completed or failed task back to pending
+	// or in_progress.
+	Reopen bool
+
+	Context *string
+	Result  *string
+
+	EstimateMinutes *int
+	DueAt           *string // "" clears
+	ProjectID       *string // "" clears
+	// Metadata is a JSON object merged into the stored one (RFC 7396):
+	// keys set to null are removed.
+	Metadata *string
+	// Resources replaces the declared resources; nil leaves them alone.
+	Resources []string
+	// Files replaces the paths the task touches; nil leaves them alone.
+	Files []string
+}
+
+// MemoryPath makes InitDB open a private in-memory database that is gone
+// once the connection closes, for tests and throwaway sessions.
+const MemoryPath = ":memory:"
+
+// Options tune how InitDB opens a SQLite file. The zero value is what
+// bossman uses by default.
+type Options struct {
+	// BusyTimeout is how long a statement waits for another process's
+	// lock before failing with SQLITE_BUSY; zero means 5s.
+	BusyTimeout time.Duration
+	// JournalMode is SQLite's journal_mode, e.g. wal or delete; empty
+	// means wal. Network filesystems that can't share memory need delete.
+	JournalMode string
+	// ReadOnly opens the file without write access and leaves the schema
+	// as it is, so the database must already have been opened read-write
+	// by this version. Every write fails.
+	ReadOnly bool
+}
+
+// journalModes are the journal_mode values SQLite accepts.
+var journalModes = []string{"delete", "truncate", "persist", "memory", "wal", "off"}
+
+// dsn is the modernc connection string opening path with o applied.
+func (o Options) dsn(path string) (string, error) {
+	mode := strings.ToLower(o.JournalMode)
+	if mode == "" {
+		mode = "wal"
+	}
+	if !slices.Contains(journalModes, mode) {
+		return "", fmt.Errorf("unknown journal mode %q, want one of %s", o.JournalMode, strings.Join(journalModes, ", "))
+	}
+	timeout := o.BusyTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	// _txlock=immediate starts transactions with BEGIN IMMEDIATE. Audited
+	// writes read the task before changing it; a deferred transaction would
+	// only ask for the write lock at its first write, and fail with
+	// SQLITE_BUSY instead of waiting if another process wrote meanwhile.
+	pragmas := fmt.Sprintf("_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)&_txlock=immediate", timeout.Milliseconds())
+	if o.ReadOnly {
+		// a read-only connection can't change the journal mode, and keeps
+		// whatever the file was left in
+		return "file:" + filepath.ToSlash(path) + "?mode=ro&" + pragmas, nil
+	}
+	return path + "?_pragma=journal_mode(" + mode + ")&" + pragmas, nil
 }
 
-func InitDB(path string) (*sqlx.DB, error) {
-	conn, err := sqlx.Connect("sqlite",
-		path+"?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=ON")
+func InitDB(path string, opts Options) (*sqlx.DB, error) {
+	var dsn string
+	if path == MemoryPath {
+		if opts.ReadOnly {
+			return nil, errors.New("an in-memory database can't be opened read-only")
+		}
+		// A named shared-cache database, so every pooled connection sees
+		// the same data; the unique name keeps separate InitDB calls apart.
+		dsn = "file:bossman-" + xid.New().String() + "?mode=memory&cache=shared&_txlock=immediate"
+	} else {
+		// native separators, so C:\Users\... and ./bossman.db both work
+		path = filepath.Clean(path)
+		if opts.ReadOnly {
+			// SQLite reports a missing file as "out of memory"
+			if _, err := os.Stat(path); err != nil {
+				return nil, fmt.Errorf("open database: %w", err)
+			}
+		} else if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("create database directory: %w", err)
+		}
+		var err error
+		if dsn, err = opts.dsn(path); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := connect("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
 	conn.SetMaxOpenConns(1)
-	if _, err = conn.ExecContext(context.Background(), schema); err != nil {
+	ctx := context.Background()
+	if opts.ReadOnly {
+		if err := enableStmtCache(ctx, conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("open database read-only: %w", err)
+		}
+		return conn, nil
+	}
+	var hadIndex []string
+	if err := conn.SelectContext(ctx, &hadIndex,
+		"SELECT name FROM sqlite_master WHERE name IN ('tasks_fts', 'comments_fts')"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("inspect schema: %w", err)
+	}
+	if err := migrateLegacy(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := addColumns(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("upgrade schema: %w", err)
+	}
+	if _, err = conn.ExecContext(ctx, schema); err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("init schema: %w", err)
 	}
+	if err := upgradeExtensions(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("upgrade extensions: %w", err)
+	}
+	if err := enableStmtCache(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("prepare statements: %w", err)
+	}
+	// first run with a search index: index the rows that predate its triggers
+	for _, index := range []string{"tasks_fts", "comments_fts"} {
+		if slices.Contains(hadIndex, index) {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx,
+			"INSERT INTO "+index+"("+index+") VALUES ('rebuild')"); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("build search index: %w", err)
+		}
+	}
 	return conn, nil
 }
 
@@ -89,12 +521,91 @@ func NewTaskID() string {
 }
 
 func InsertTask(ctx context.Context, db *sqlx.DB, t *Task) error {
-	_, err := db.NamedExecContext(ctx,
-		`INSERT INTO tasks (id, description, parent_id, priority, context)
-         VALUES (:id, :description, :parent_id, :priority, :context)`,
-		t,
-	)
-	return err
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return insertTaskTx(ctx, tx, t)
+	})
+}
+
+// CreateOpts is what a new task starts with besides its own row.
+type CreateOpts struct {
+	Resources []string
+	Files     []string // repo-relative paths the task touches
+	BlockedBy []string // existing task ids
+	Subtasks  []*Task  // inserted under the new task
+}
+
+// CreateTask inserts t together with its resources, blockers and subtasks
+// in one transaction, so a failure part way leaves nothing behind. A
+// blocker that doesn't exist fails the whole create with sql.ErrNoRows.
+func CreateTask(ctx context.Context, db *sqlx.DB, t *Task, opts CreateOpts) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if err := insertTaskTx(ctx, tx, t); err != nil {
+			return err
+		}
+		if len(opts.Resources) > 0 {
+			if err := setResourcesTx(ctx, tx, t.ID, opts.Resources); err != nil {
+				return fmt.Errorf("set resources: %w", err)
+			}
+		}
+		if len(opts.Files) > 0 {
+			if err := setFilesTx(ctx, tx, t.ID, opts.Files); err != nil {
+				return fmt.Errorf("set files: %w", err)
+			}
+		}
+		for _, id := range opts.BlockedBy {
+			if _, err := getTaskTx(ctx, tx, id); err != nil {
+				return fmt.Errorf("blocker %s: %w", id, err)
+			}
+			if err := addBlockerTx(ctx, tx, t.ID, id); err != nil {
+				return fmt.Errorf("add blocker %s: %w", id, err)
+			}
+		}
+		for _, sub := range opts.Subtasks {
+			sub.ParentID = &t.ID
+		}
+		if err := insertTasksTx(ctx, tx, opts.Subtasks); err != nil {
+			return fmt.Errorf("insert subtasks: %w", err)
+		}
+		return nil
+	})
+}
+
+const insertTaskQuery = `INSERT INTO tasks (id, description, parent_id, priority, priority_label, priority_weight, context, estimate_minutes, due_at, metadata, project_id, kind, session_id, created_at, updated_at)
+         VALUES (:id, :description, :parent_id, :priority, :priority_label, :priority_weight, :context, :estimate_minutes, :due_at, :metadata,
+                 COALESCE(:project_id, (SELECT project_id FROM tasks WHERE id = :parent_id)), :kind,
+                 COALESCE(:session_id, (SELECT session_id FROM tasks WHERE id = :parent_id)), :created_at, :updated_at)`
+
+// insertTaskTx stamps CreatedAt/UpdatedAt, inserts t and records the audit
+// event. The parent must exist and leave the tree within MaxTaskDepth; a
+// subtask without a project joins its parent's.
+func insertTaskTx(ctx context.Context, tx *sqlx.Tx, t *Task) error {
+	if t.ParentID != nil {
+		if err := validateParentTx(ctx, tx, t.ID, *t.ParentID); err != nil {
+			return err
+		}
+	}
+	t.CreatedAt = nowTimestamp(ctx)
+	t.UpdatedAt = t.CreatedAt
+	if t.Kind == "" {
+		t.Kind = KindTask
+	}
+	if err := applyPriorityTx(ctx, tx, t); err != nil {
+		return err
+	}
+	var err error
+	if stmt := txNamedStmt(ctx, tx, insertTaskQuery); stmt != nil {
+		_, err = stmt.ExecContext(ctx, t)
+	} else {
+		_, err = tx.NamedExecContext(ctx, insertTaskQuery, t)
+	}
+	if err != nil {
+		return err
+	}
+	created, err := getTaskTx(ctx, tx, t.ID)
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, tx, t.ID, "task", "insert", nil, taskValues(created))
 }
 
 func QueryTasks(ctx context.Context, db *sqlx.DB, opts ListOpts) ([]Task, error) {
@@ -106,20 +617,135 @@ func QueryTasks(ctx context.Context, db *sqlx.DB, opts ListOpts) ([]Task, error)
 		args["status"] = *opts.Status
 	}
 
+	if opts.Open {
+		query += " AND status IN ('pending', 'in_progress')"
+	}
+
 	if opts.ParentID != nil {
 		query += " AND parent_id = :parent_id"
 		args["parent_id"] = *opts.ParentID
 	}
 
-	query += " ORDER BY priority ASC, created_at DESC"
+	if opts.ReviewStatus != nil {
+		query += " AND review_status = :review_status"
+		args["review_status"] = *opts.ReviewStatus
+	}
+
+	if opts.Kind != nil {
+		query += " AND kind = :kind"
+		args["kind"] = *opts.Kind
+	}
+
+	if !opts.CreatedAfter.IsZero() {
+		query += " AND created_at > :created_after"
+		args["created_after"] = FormatTime(opts.CreatedAfter)
+	}
+	if !opts.CreatedBefore.IsZero() {
+		query += " AND created_at < :created_before"
+		args["created_before"] = FormatTime(opts.CreatedBefore)
+	}
+	if !opts.UpdatedAfter.IsZero() {
+		query += " AND updated_at > :updated_after"
+		args["updated_after"] = FormatTime(opts.UpdatedAfter)
+	}
+
+	if opts.Text != "" {
+		query += ` AND LOWER(description) LIKE :text ESCAPE '\'`
+		args["text"] = "%" + likeEscaper.Replace(strings.ToLower(opts.Text)) + "%"
+	}
+
+	if opts.Scratch {
+		query += " AND session_id = :session"
+	} else {
+		query += " AND (session_id IS NULL OR session_id = :session)"
+	}
+	args["session"] = opts.Session
+
+	if opts.Archived {
+		query += " AND archived_at IS NOT NULL"
+	} else {
+		query += " AND archived_at IS NULL"
+	}
+
+	if opts.AssignedTo != nil {
+		if *opts.AssignedTo == "" {
+			query += " AND assigned_to IS NULL"
+		} else {
+			query += " AND assigned_to = :assigned_to"
+			args["assigned_to"] = *opts.AssignedTo
+		}
+	}
+
+	if opts.ProjectID != nil {
+		if *opts.ProjectID == "" {
+			query += " AND project_id IS NULL"
+		} else {
+			query += " AND project_id = :project_id"
+			args["project_id"] = *opts.ProjectID
+		}
+	}
+
+	for i, key := range sortedKeys(opts.Metadata) {
+		path, err := metadataPath(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := metadataValue(key, opts.Metadata[key])
+		if err != nil {
+			return nil, err
+		}
+		pathArg, valueArg := fmt.Sprintf("meta_path_%d", i), fmt.Sprintf("meta_value_%d", i)
+		if isPostgres(db) {
+			query += postgresMetadataFilter(pathArg, valueArg, opts.Metadata[key], args)
+		} else if value == nil {
+			query += fmt.Sprintf(" AND json_extract(metadata, :%s) IS NULL", pathArg)
+		} else {
+			query += fmt.Sprintf(" AND json_extract(metadata, :%s) = :%s", pathArg, valueArg)
+			args[valueArg] = value
+		}
+		args[pathArg] = path
+	}
+
+	if len(opts.Tags) > 0 {
+		// a task carries each tag once, so a repeat would never match
+		tags := slices.Compact(slices.Sorted(slices.Values(opts.Tags)))
+		query += ` AND id IN (SELECT task_id FROM task_tags WHERE tag IN (:tags)
+		           GROUP BY task_id HAVING COUNT(*) = :tag_count)`
+		args["tags"] = tags
+		args["tag_count"] = len(tags)
+	}
+
+	if len(opts.Files) > 0 {
+		filter, err := filesFilter(opts.Files, args)
+		if err != nil {
+			return nil, err
+		}
+		query += filter
+	}
+
+	order, after, err := opts.orderClause(args)
+	if err != nil {
+		return nil, err
+	}
+	query += after + order
 
 	if opts.Limit > 0 {
 		query += " LIMIT :limit"
 		args["limit"] = opts.Limit
 	}
 
+	// Named binds first, then In to expand slice args like :tags.
+	query, bound, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, err
+	}
+	query, bound, err = sqlx.In(query, bound...)
+	if err != nil {
+		return nil, err
+	}
+
 	var tasks []Task
-	rows, err := db.NamedQueryContext(ctx, query, args)
+	rows, err := db.QueryxContext(ctx, db.Rebind(query), bound...)
 	if err != nil {
 		return nil, err
 	}
@@ -136,76 +762,387 @@ func QueryTasks(ctx context.Context, db *sqlx.DB, opts ListOpts) ([]Task, error)
 }
 
 func GetTask(ctx context.Context, db *sqlx.DB, id string) (*Task, error) {
+	stmt, err := prepared(ctx, db, getTaskQuery)
+	if err != nil {
+		return nil, err
+	}
 	var t Task
-	err := db.GetContext(ctx, &t, "SELECT * FROM tasks WHERE id = ?", id)
+	if stmt != nil {
+		err = stmt.GetContext(ctx, &t, id)
+	} else {
+		err = db.GetContext(ctx, &t, db.Rebind(getTaskQuery), id)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &t, nil
 }
 
-func UpdateTask(ctx context.Context, db *sqlx.DB, id string, opts UpdateOpts) error {
-	setClauses := []string{"updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', 'now')"}
-	args := map[string]any{"id": id}
+// GetTasksByIDs reads the tasks in ids with one query per 500, in the
+// order of ids with repeats dropped. Missing lists the ids that don't
+// exist, in the same order.
+func GetTasksByIDs(ctx context.Context, db *sqlx.DB, ids []string) ([]Task, []string, error) {
+	found, err := tasksByID(ctx, db, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tasks []Task
+	var missing []string
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if t, ok := found[id]; ok {
+			tasks = append(tasks, *t)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return tasks, missing, nil
+}
 
-	if opts.Description != nil {
-		setClauses = append(setClauses, "description = :description")
-		args["description"] = *opts.Description
+func UpdateTask(ctx context.Context, db *sqlx.DB, id string, opts UpdateOpts) (*Task, error) {
+	query, args, err := updateTaskQuery(ctx, db, id, opts)
+	if err != nil {
+		return nil, err
 	}
+	var after *Task
+	err = WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var err error
+		_, after, err = updateTaskTx(ctx, tx, id, opts, query, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
 
+// UpdateTaskSteps applies each of steps to task id in turn, as UpdateTask
+// would, in one transaction: a step that fails undoes the ones before it.
+// It returns the task after the last step.
+func UpdateTaskSteps(ctx context.Context, db *sqlx.DB, id string, steps ...UpdateOpts) (*Task, error) {
+	queries := make([]string, len(steps))
+	args := make([]map[string]any, len(steps))
+	for i, opts := range steps {
+		var err error
+		if queries[i], args[i], err = updateTaskQuery(ctx, db, id, opts); err != nil {
+			return nil, err
+		}
+	}
+	var after *Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		for i, opts := range steps {
+			var err error
+			if _, after, err = updateTaskTx(ctx, tx, id, opts, queries[i], args[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return after, nil
+}
+
+// updateTaskQuery builds UpdateTask's statement for opts and prepares it
+// before any transaction starts.
+func updateTaskQuery(ctx context.Context, db *sqlx.DB, id string, opts UpdateOpts) (string, map[string]any, error) {
+	setClauses, args := updateClauses(ctx, db, opts)
+	args["id"] = id
+	// RETURNING hands back the row as written, inside the transaction, so
+	// the caller sees exactly this update without reading it again
+	query := "UPDATE tasks SET " + strings.Join(setClauses, ", ") + " WHERE id = :id RETURNING *"
+	if _, err := preparedNamed(ctx, db, query); err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// updateTaskTx is UpdateTask inside tx, with query and args already built
+// from opts. It returns the task before and after the update.
+func updateTaskTx(ctx context.Context, tx *sqlx.Tx, id string, opts UpdateOpts, query string, args map[string]any) (*Task, *Task, error) {
+	before, err := getTaskTx(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkUpdate(before, opts); err != nil {
+		return nil, nil, err
+	}
+	if opts.Resources != nil {
+		if err := setResourcesTx(ctx, tx, id, opts.Resources); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.Files != nil {
+		if err := setFilesTx(ctx, tx, id, opts.Files); err != nil {
+			return nil, nil, err
+		}
+	}
+	var after Task
+	if stmt := txNamedStmt(ctx, tx, query); stmt != nil {
+		err = stmt.GetContext(ctx, &after, args)
+	} else {
+		q, a, bindErr := tx.BindNamed(query, args)
+		if bindErr != nil {
+			return nil, nil, bindErr
+		}
+		err = tx.GetContext(ctx, &after, q, a...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
 	if opts.Priority != nil {
-		setClauses = append(setClauses, "priority = :priority")
-		args["priority"] = *opts.Priority
+		t, err := setPriorityTx(ctx, tx, id, *opts.Priority)
+		if err != nil {
+			return nil, nil, err
+		}
+		after = *t
+	}
+	if opts.Status != nil {
+		if err := updateLocksTx(ctx, tx, &after); err != nil {
+			return nil, nil, err
+		}
+	}
+	oldValues, newValues := diffTasks(before, &after)
+	return before, &after, recordEvent(ctx, tx, id, "task", "update", oldValues, newValues)
+}
+
+// updateClauses is the SET list and named args for opts, short of the
+// WHERE clause and of the priority, which setPriorityTx resolves per task.
+func updateClauses(ctx context.Context, db *sqlx.DB, opts UpdateOpts) ([]string, map[string]any) {
+	setClauses := []string{"updated_at = :now"}
+	args := map[string]any{"now": now(ctx)}
+
+	if opts.Description != nil {
+		setClauses = append(setClauses, "description = :description")
+		args["description"] = *opts.Description
 	}
 
 	if opts.Status != nil {
-		setClauses = append(setClauses, "status = :status")
+		setClauses = append(setClauses, "status = :status",
+			// first start and latest completion, for cycle times
+			"started_at = CASE WHEN :status = 'in_progress' THEN COALESCE(started_at, :now) ELSE started_at END",
+			`completed_at = CASE WHEN :status != 'completed' THEN NULL
+			                     WHEN status = 'completed' THEN COALESCE(completed_at, :now) ELSE :now END`,
+			// reopening an archived task brings it back into listings
+			"archived_at = CASE WHEN :status = 'completed' THEN archived_at END")
 		args["status"] = *opts.Status
+		// finishing a sent-back task puts it in front of the reviewer again
+		if *opts.Status == "completed" {
+			setClauses = append(setClauses,
+				"review_status = CASE review_status WHEN 'changes_requested' THEN 'requested' ELSE review_status END")
+		}
 	}
 
 	if opts.Context != nil {
 		setClauses = append(setClauses, "context = :context")
-		args["context"] = *opts.Context
+		args["context"] = Sensitive(*opts.Context)
 	}
 
 	if opts.Result != nil {
 		setClauses = append(setClauses, "result = :result")
-		args["result"] = *opts.Result
+		args["result"] = Sensitive(*opts.Result)
 	}
 
-	query := "UPDATE tasks SET " + strings.Join(setClauses, ", ") + " WHERE id = :id"
+	if opts.EstimateMinutes != nil {
+		setClauses = append(setClauses, "estimate_minutes = :estimate_minutes")
+		args["estimate_minutes"] = *opts.EstimateMinutes
+	}
 
-	result, err := db.NamedExecContext(ctx, query, args)
-	if err != nil {
+	if opts.DueAt != nil {
+		setClauses = append(setClauses, "due_at = :due_at")
+		args["due_at"] = nullIfEmpty(*opts.DueAt)
+	}
+
+	if opts.ProjectID != nil {
+		setClauses = append(setClauses, "project_id = :project_id")
+		args["project_id"] = nullIfEmpty(*opts.ProjectID)
+	}
+
+	if opts.Metadata != nil {
+		merge := "NULLIF(json_patch(COALESCE(metadata, '{}'), :metadata), '{}')"
+		if isPostgres(db) {
+			merge = postgresMetadataMerge
+		}
+		setClauses = append(setClauses, "metadata = "+merge)
+		args["metadata"] = *opts.Metadata
+	}
+
+	return setClauses, args
+}
+
+// checkUpdate refuses the parts of opts before can't take.
+func checkUpdate(before *Task, opts UpdateOpts) error {
+	if opts.Status == nil {
+		return nil
+	}
+	if err := CheckTransition(before.ID, before.Status, *opts.Status, opts.Reopen); err != nil {
 		return err
 	}
+	if *opts.Status == "completed" && before.Kind == KindQuestion && before.Answer == nil {
+		return fmt.Errorf("%s is a question; it completes when answered", before.ID)
+	}
+	return nil
+}
 
-	rows, err := result.RowsAffected()
+// setPriorityTx applies change to task id on its project's scale.
+func setPriorityTx(ctx context.Context, tx *sqlx.Tx, id string, change PriorityChange) (*Task, error) {
+	level, err := priorityChangeTx(ctx, tx, id, change)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
+	var t Task
+	err = tx.GetContext(ctx, &t, tx.Rebind(
+		"UPDATE tasks SET priority = ?, priority_label = ?, priority_weight = ? WHERE id = ? RETURNING *"),
+		legacyPriorityLevel(level.Weight), level.Label, level.Weight, id)
+	if err != nil {
+		return nil, err
 	}
+	return &t, nil
+}
 
-	return nil
+// AssignTask sets a task's assignee; "" unassigns. Taking a task someone
+// else holds fails unless force is set, so two workers can't both claim it.
+// So does one that would put the assignee over their declared capacity
+// (see SetCapacity), with a *CapacityError.
+func AssignTask(ctx context.Context, db *sqlx.DB, id, assignee string, force bool) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return assignTaskTx(ctx, tx, id, assignee, force)
+	})
 }
 
-func DeleteTask(ctx context.Context, db *sqlx.DB, id string) error {
-	result, err := db.ExecContext(ctx, "DELETE FROM tasks WHERE id = ?", id)
+func assignTaskTx(ctx context.Context, tx *sqlx.Tx, id, assignee string, force bool) error {
+	before, err := getTaskTx(ctx, tx, id)
 	if err != nil {
 		return err
 	}
-
-	rows, err := result.RowsAffected()
+	if before.AssignedTo != nil && assignee != "" && *before.AssignedTo != assignee && !force {
+		return fmt.Errorf("%s is already assigned to %s", id, *before.AssignedTo)
+	}
+	if assignee != "" && !force && (before.AssignedTo == nil || *before.AssignedTo != assignee) {
+		if err := checkCapacityTx(ctx, tx, assignee, before); err != nil {
+			return err
+		}
+	}
+	var value *string
+	if assignee != "" {
+		value = &assignee
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE tasks SET assigned_to = ?, updated_at = ? WHERE id = ?", value, now(ctx), id); err != nil {
+		return err
+	}
+	// whoever takes the task takes its resources; letting go frees them
+	if assignee != "" {
+		err = acquireLocksTx(ctx, tx, id, assignee)
+	} else {
+		err = releaseLocksTx(ctx, tx, id)
+	}
 	if err != nil {
 		return err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
+	after, err := getTaskTx(ctx, tx, id)
+	if err != nil {
+		return err
 	}
-	return nil
+	oldValues, newValues := diffTasks(before, after)
+	return recordEvent(ctx, tx, id, "task", "update", oldValues, newValues)
+}
+
+// DeletePolicy says what happens to a deleted task's subtasks.
+type DeletePolicy string
+
+const (
+	// DeleteForbid refuses to delete a task that has subtasks.
+	DeleteForbid DeletePolicy = "forbid-if-children"
+	// DeleteCascade deletes every descendant along with the task.
+	DeleteCascade DeletePolicy = "cascade"
+	// DeleteOrphan keeps the children, which become top-level tasks.
+	DeleteOrphan DeletePolicy = "orphan"
+)
+
+// DeleteResult counts what a delete did to the task's descendants.
+type DeleteResult struct {
+	Task               *Task `json:"task"` // as it was just before deletion
+	DescendantsDeleted int   `json:"descendants_deleted"`
+	ChildrenOrphaned   int   `json:"children_orphaned"`
+}
+
+// DeleteTask deletes a task and handles its subtasks per policy ("" is
+// DeleteForbid), all in one transaction with an audit event for every
+// task deleted or orphaned.
+func DeleteTask(ctx context.Context, db *sqlx.DB, id string, policy DeletePolicy) (DeleteResult, error) {
+	var res DeleteResult
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if _, err := getTaskTx(ctx, tx, id); err != nil {
+			return err
+		}
+		var children []string
+		if err := tx.SelectContext(ctx, &children,
+			tx.Rebind("SELECT id FROM tasks WHERE parent_id = ? ORDER BY id"), id); err != nil {
+			return err
+		}
+
+		// deepest first, so no row outlives its parent
+		doomed := []string{id}
+		switch policy {
+		case DeleteForbid, "":
+			if len(children) > 0 {
+				return fmt.Errorf("task %s has %d subtasks; delete them first or use policy cascade or orphan", id, len(children))
+			}
+		case DeleteCascade:
+			var descendants []string
+			if err := tx.SelectContext(ctx, &descendants, tx.Rebind(`
+				WITH RECURSIVE tree(id, depth) AS (
+					SELECT id, 0 FROM tasks WHERE id = ?
+					UNION ALL
+					SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id
+				)
+				SELECT id FROM tree WHERE depth > 0 ORDER BY depth DESC, id`), id); err != nil {
+				return err
+			}
+			doomed = append(descendants, id)
+			res.DescendantsDeleted = len(descendants)
+		case DeleteOrphan:
+			for _, child := range children {
+				before, err := getTaskTx(ctx, tx, child)
+				if err != nil {
+					return err
+				}
+				var after Task
+				if err := tx.GetContext(ctx, &after, tx.Rebind(
+					"UPDATE tasks SET parent_id = NULL, updated_at = ? WHERE id = ? RETURNING *"), now(ctx), child); err != nil {
+					return err
+				}
+				oldValues, newValues := diffTasks(before, &after)
+				if err := recordEvent(ctx, tx, child, "task", "update", oldValues, newValues); err != nil {
+					return err
+				}
+			}
+			res.ChildrenOrphaned = len(children)
+		default:
+			return fmt.Errorf("unknown delete policy %q: want %s, %s or %s", policy, DeleteForbid, DeleteCascade, DeleteOrphan)
+		}
 
+		for _, taskID := range doomed {
+			var before Task
+			if err := tx.GetContext(ctx, &before, tx.Rebind("DELETE FROM tasks WHERE id = ? RETURNING *"), taskID); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, taskID, "task", "delete", taskValues(&before), nil); err != nil {
+				return err
+			}
+			if taskID == id {
+				res.Task = &before
+			}
+		}
+		return nil
+	})
+	return res, err
 }
 
 func TaskExists(ctx context.Context, db *sqlx.DB, id string) (bool, error) {
@@ -215,33 +1152,180 @@ func TaskExists(ctx context.Context, db *sqlx.DB, id string) (bool, error) {
 }
 
 func AddBlocker(ctx context.Context, db *sqlx.DB, taskID, blockedByID string) error {
-	_, err := db.ExecContext(ctx, "INSERT INTO task_blockers (task_id, blocked_by_id) VALUES (?, ?)",
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return addBlockerTx(ctx, tx, taskID, blockedByID)
+	})
+}
+
+func addBlockerTx(ctx context.Context, tx *sqlx.Tx, taskID, blockedByID string) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind("INSERT INTO task_blockers (task_id, blocked_by_id) VALUES (?, ?)"),
 		taskID, blockedByID)
-	return err
+	if err != nil {
+		return err
+	}
+	return recordEvent(ctx, tx, taskID, "blocker", "insert", nil,
+		map[string]any{"blocked_by_id": blockedByID})
 }
 
 func RemoveBlocker(ctx context.Context, db *sqlx.DB, taskID, blockedByID string) error {
-	result, err := db.ExecContext(ctx, "DELETE FROM task_blockers WHERE task_id = ? AND blocked_by_id = ?", taskID, blockedByID)
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM task_blockers WHERE task_id = ? AND blocked_by_id = ?"), taskID, blockedByID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		return recordEvent(ctx, tx, taskID, "blocker", "delete",
+			map[string]any{"blocked_by_id": blockedByID}, nil)
+	})
+}
+func GetBlockers(ctx context.Context, db *sqlx.DB, taskID string) ([]Task, error) {
+	var tasks []Task
+	err := db.SelectContext(ctx, &tasks, db.Rebind(
+		`SELECT t.* from tasks t 
+		 INNER JOIN task_blockers tb ON t.id = tb.blocked_by_id
+		 WHERE tb.task_id = ?`), taskID)
+	return tasks, err
+}
+
+// GetBlocking is GetBlockers the other way round: the tasks waiting on
+// taskID, most urgent first. With transitive it also follows their
+// dependents, so the result is everything a delay would hold up.
+func GetBlocking(ctx context.Context, db *sqlx.DB, taskID string, transitive bool) ([]Task, error) {
+	query := `SELECT t.* FROM tasks t
+		 INNER JOIN task_blockers tb ON t.id = tb.task_id
+		 WHERE tb.blocked_by_id = ?
+		 ORDER BY t.priority_weight DESC, t.id`
+	args := []any{taskID}
+	if transitive {
+		// UNION drops repeats, which also ends any blocker cycle
+		query = `WITH RECURSIVE downstream(id) AS (
+			SELECT task_id FROM task_blockers WHERE blocked_by_id = ?
+			UNION
+			SELECT tb.task_id FROM task_blockers tb JOIN downstream d ON tb.blocked_by_id = d.id
+		)
+		SELECT t.* FROM tasks t JOIN downstream d ON d.id = t.id
+		WHERE t.id != ?
+		ORDER BY t.priority_weight DESC, t.id`
+		args = append(args, taskID)
+	}
+	var tasks []Task
+	err := db.SelectContext(ctx, &tasks, db.Rebind(query), args...)
+	return tasks, err
+}
+
+// CountOpenTasks counts pending and in_progress tasks.
+func CountOpenTasks(ctx context.Context, db *sqlx.DB) (int, error) {
+	return CountTasks(ctx, db, "pending", "in_progress")
+}
 
+// CountTasks counts tasks in any of the given statuses.
+func CountTasks(ctx context.Context, db *sqlx.DB, statuses ...string) (int, error) {
+	query, args, err := sqlx.In("SELECT COUNT(*) FROM tasks WHERE status IN (?) AND "+notMerged, statuses)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	var n int
+	err = db.GetContext(ctx, &n, query, args...)
+	return n, err
+}
 
-	rows, err := result.RowsAffected()
+// CountTasksByStatus counts tasks per status, leaving out bossman's own
+// system tasks.
+func CountTasksByStatus(ctx context.Context, db *sqlx.DB) (map[string]int, error) {
+	var rows []struct {
+		Status string `db:"status"`
+		N      int    `db:"n"`
+	}
+	err := db.SelectContext(ctx, &rows, db.Rebind(`
+		SELECT status, COUNT(*) AS n FROM tasks
+		 WHERE id != ? AND (parent_id IS NULL OR parent_id != ?) AND `+notMerged+`
+		 GROUP BY status`), SystemTaskID, SystemTaskID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rows == 0 {
-		return sql.ErrNoRows
+	counts := make(map[string]int, len(rows))
+	for _, r := range rows {
+		counts[r.Status] = r.N
 	}
+	return counts, nil
+}
 
-	return nil
+// CountBlockedTasks counts pending tasks waiting on a blocker that hasn't
+// completed.
+func CountBlockedTasks(ctx context.Context, db *sqlx.DB) (int, error) {
+	var n int
+	err := db.GetContext(ctx, &n, `
+		SELECT COUNT(*) FROM tasks t
+		 WHERE t.status = 'pending'
+		   AND EXISTS (SELECT 1 FROM task_blockers tb
+		                 JOIN tasks b ON b.id = tb.blocked_by_id
+		                WHERE tb.task_id = t.id AND b.status != 'completed')`)
+	return n, err
 }
-func GetBlockers(ctx context.Context, db *sqlx.DB, taskID string) ([]Task, error) {
+
+// ListDueTasks returns open tasks due at or before the given time,
+// earliest first.
+func ListDueTasks(ctx context.Context, db *sqlx.DB, before time.Time) ([]Task, error) {
 	var tasks []Task
-	err := db.SelectContext(ctx, &tasks,
-		`SELECT t.* from tasks t 
-		 INNER JOIN task_blockers tb ON t.id = tb.blocked_by_id
-		 WHERE tb.task_id = ?`, taskID)
+	err := db.SelectContext(ctx, &tasks, db.Rebind(`
+		SELECT * FROM tasks
+		 WHERE status IN ('pending', 'in_progress') AND archived_at IS NULL AND due_at IS NOT NULL AND due_at <= ?
+		 ORDER BY due_at, id`), FormatTime(before))
 	return tasks, err
 }
+
+type BlockerEdge struct {
+	TaskID      string `db:"task_id" json:"task_id"`
+	BlockedByID string `db:"blocked_by_id" json:"blocked_by_id"`
+}
+
+// ListBlockerEdges returns every dependency in the database.
+func ListBlockerEdges(ctx context.Context, db *sqlx.DB) ([]BlockerEdge, error) {
+	var edges []BlockerEdge
+	err := db.SelectContext(ctx, &edges,
+		"SELECT task_id, blocked_by_id FROM task_blockers ORDER BY task_id, blocked_by_id")
+	return edges, err
+}
+
+// TaskRelations are counts used to derive API fields.
+type TaskRelations struct {
+	OpenBlockers int `db:"open_blockers"`
+	Children     int `db:"children"`
+}
+
+// GetTaskRelations returns relation counts for each id. Missing ids are
+// simply absent from the map.
+func GetTaskRelations(ctx context.Context, db *sqlx.DB, ids []string) (map[string]TaskRelations, error) {
+	out := make(map[string]TaskRelations, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`
+		SELECT t.id,
+		  (SELECT COUNT(*) FROM task_blockers tb
+		     JOIN tasks b ON b.id = tb.blocked_by_id
+		    WHERE tb.task_id = t.id AND b.status != 'completed') AS open_blockers,
+		  (SELECT COUNT(*) FROM tasks c WHERE c.parent_id = t.id) AS children
+		FROM tasks t WHERE t.id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		ID string `db:"id"`
+		TaskRelations
+	}
+	if err := db.SelectContext(ctx, &rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.ID] = r.TaskRelations
+	}
+	return out, nil
+}
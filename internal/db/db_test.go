@@ -0,0 +1,21 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestDB opens a fresh database file for t, closed when t ends, and a
+// context acting as agent-1.
+func newTestDB(t *testing.T) (*sqlx.DB, context.Context) {
+	t.Helper()
+	conn, err := InitDB(filepath.Join(t.TempDir(), "bossman.db"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, WithActor(context.Background(), "agent-1")
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+// DeadLetterMaintenance is the kind for maintenance job runs; Source is the
+// job's task ID.
+const DeadLetterMaintenance = "maintenance"
+
+// DeadLetter is an automated run that failed every retry. It stays open
+// until a replay succeeds, so failures are never just logged and dropped.
+// Kind says what failed and Source which one; Payload is whatever the
+// replay needs beyond that.
+type DeadLetter struct {
+	ID         string  `db:"id" json:"id"`
+	Kind       string  `db:"kind" json:"kind"`
+	Source     string  `db:"source" json:"source"`
+	Payload    string  `db:"payload" json:"payload,omitempty"`
+	Error      string  `db:"error" json:"error"`
+	Attempts   int     `db:"attempts" json:"attempts"`
+	FailedAt   string  `db:"failed_at" json:"failed_at"`
+	ResolvedAt *string `db:"resolved_at" json:"resolved_at,omitempty"`
+}
+
+func NewDeadLetterID() string {
+	return "dead_" + xid.New().String()
+}
+
+// RecordDeadLetter stores d, filling in its ID and FailedAt.
+func RecordDeadLetter(ctx context.Context, db *sqlx.DB, d *DeadLetter) error {
+	d.ID, d.FailedAt = NewDeadLetterID(), now(ctx)
+	_, err := db.NamedExecContext(ctx,
+		`INSERT INTO dead_letters (id, kind, source, payload, error, attempts, failed_at)
+		 VALUES (:id, :kind, :source, :payload, :error, :attempts, :failed_at)`, d)
+	return err
+}
+
+// ListDeadLetters returns open dead letters, or all of them with resolved,
+// newest failure first.
+func ListDeadLetters(ctx context.Context, db *sqlx.DB, resolved bool) ([]DeadLetter, error) {
+	query := "SELECT * FROM dead_letters"
+	if !resolved {
+		query += " WHERE resolved_at IS NULL"
+	}
+	letters := []DeadLetter{}
+	err := db.SelectContext(ctx, &letters, query+" ORDER BY failed_at DESC, id DESC")
+	return letters, err
+}
+
+// GetDeadLetter returns one dead letter, or sql.ErrNoRows.
+func GetDeadLetter(ctx context.Context, db *sqlx.DB, id string) (*DeadLetter, error) {
+	var d DeadLetter
+	if err := db.GetContext(ctx, &d, "SELECT * FROM dead_letters WHERE id = ?", id); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ResolveDeadLetter records the outcome of replaying d: success closes it,
+// failure counts the attempt and keeps the latest error. The count is
+// bumped in place, so replays racing from two processes both count.
+func ResolveDeadLetter(ctx context.Context, db *sqlx.DB, d *DeadLetter, replayErr error) error {
+	if replayErr != nil {
+		d.Error = replayErr.Error()
+	} else {
+		at := now(ctx)
+		d.ResolvedAt = &at
+	}
+	return db.GetContext(ctx, &d.Attempts,
+		"UPDATE dead_letters SET error = ?, attempts = attempts + 1, resolved_at = ? WHERE id = ? RETURNING attempts",
+		d.Error, d.ResolvedAt, d.ID)
+}
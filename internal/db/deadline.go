@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// DeadlineRisk is a task's place in the deadlines of the work it blocks.
+// ImpliedDeadline is the earlier of its own due_at and the latest start of
+// every open task it blocks, transitively; LatestStart takes its estimate
+// off that. DueTask is the task whose due_at the deadline comes from.
+//
+// A pending task is late once LatestStart has passed, an in-progress one
+// once ImpliedDeadline has. AtRisk marks a late task and every task on the
+// chain from it down to DueTask, since the due date depends on all of them.
+type DeadlineRisk struct {
+	ImpliedDeadline string
+	LatestStart     string
+	DueTask         string
+	AtRisk          bool
+}
+
+// GetDeadlineRisks returns risks for each id that has an implied deadline;
+// others are absent. It reads the open dependency graph once, so risk
+// coming from tasks outside ids is still seen.
+func GetDeadlineRisks(ctx context.Context, db *sqlx.DB, ids []string) (map[string]DeadlineRisk, error) {
+	out := make(map[string]DeadlineRisk, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	risks, err := deadlineRisks(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if r, ok := risks[id]; ok {
+			out[id] = r
+		}
+	}
+	return out, nil
+}
+
+// ListAtRiskTasks returns every open task at risk, soonest latest start
+// first, with its risk.
+func ListAtRiskTasks(ctx context.Context, db *sqlx.DB) ([]Task, map[string]DeadlineRisk, error) {
+	risks, err := deadlineRisks(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ids []string
+	for id, r := range risks {
+		if r.AtRisk {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, risks, nil
+	}
+	query, args, err := sqlx.In("SELECT * FROM tasks WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tasks []Task
+	if err := db.SelectContext(ctx, &tasks, db.Rebind(query), args...); err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if a, b := risks[tasks[i].ID].LatestStart, risks[tasks[j].ID].LatestStart; a != b {
+			return a < b
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, risks, nil
+}
+
+// deadlineRisks propagates due dates up the open dependency graph.
+func deadlineRisks(ctx context.Context, db *sqlx.DB) (map[string]DeadlineRisk, error) {
+	var nodes []struct {
+		ID              string  `db:"id"`
+		Status          string  `db:"status"`
+		DueAt           *string `db:"due_at"`
+		EstimateMinutes *int    `db:"estimate_minutes"`
+	}
+	err := db.SelectContext(ctx, &nodes, `
+		SELECT id, status, due_at, estimate_minutes FROM tasks
+		WHERE status IN ('pending', 'in_progress') AND archived_at IS NULL
+		  AND (due_at IS NOT NULL
+		       OR id IN (SELECT task_id FROM task_blockers)
+		       OR id IN (SELECT blocked_by_id FROM task_blockers))`)
+	if err != nil {
+		return nil, err
+	}
+	var edges []struct {
+		TaskID    string `db:"task_id"`
+		BlockedBy string `db:"blocked_by_id"`
+	}
+	err = db.SelectContext(ctx, &edges, `
+		SELECT tb.task_id, tb.blocked_by_id FROM task_blockers tb
+		JOIN tasks t ON t.id = tb.task_id
+		JOIN tasks b ON b.id = tb.blocked_by_id
+		WHERE t.status IN ('pending', 'in_progress') AND b.status IN ('pending', 'in_progress')`)
+	if err != nil {
+		return nil, err
+	}
+
+	type node struct {
+		status   string
+		due      time.Time // zero without due_at
+		estimate time.Duration
+	}
+	graph := make(map[string]node, len(nodes))
+	for _, n := range nodes {
+		v := node{status: n.Status}
+		if n.DueAt != nil {
+			if due, err := time.Parse(time.RFC3339Nano, *n.DueAt); err == nil {
+				v.due = due
+			}
+		}
+		if n.EstimateMinutes != nil {
+			v.estimate = time.Duration(*n.EstimateMinutes) * time.Minute
+		}
+		graph[n.ID] = v
+	}
+	blocks := make(map[string][]string) // blocker -> tasks waiting on it
+	for _, e := range edges {
+		blocks[e.BlockedBy] = append(blocks[e.BlockedBy], e.TaskID)
+	}
+
+	type deadline struct {
+		at      time.Time // zero when nothing downstream is due
+		dueTask string
+		via     string // the dependent the deadline came through; "" for its own due_at
+	}
+	deadlines := make(map[string]deadline, len(graph))
+	visiting := make(map[string]bool)
+	var resolve func(id string) deadline
+	resolve = func(id string) deadline {
+		if d, ok := deadlines[id]; ok {
+			return d
+		}
+		if visiting[id] { // a cycle slipped past add_blocker; don't loop
+			return deadline{}
+		}
+		visiting[id] = true
+		var d deadline
+		if due := graph[id].due; !due.IsZero() {
+			d = deadline{at: due, dueTask: id}
+		}
+		for _, dep := range blocks[id] {
+			dd := resolve(dep)
+			if dd.at.IsZero() {
+				continue
+			}
+			start := dd.at.Add(-graph[dep].estimate)
+			if d.at.IsZero() || start.Before(d.at) {
+				d = deadline{at: start, dueTask: dd.dueTask, via: dep}
+			}
+		}
+		delete(visiting, id)
+		deadlines[id] = d
+		return d
+	}
+
+	at := clock.From(ctx).Now()
+	risks := make(map[string]DeadlineRisk)
+	for id, n := range graph {
+		d := resolve(id)
+		if d.at.IsZero() {
+			continue
+		}
+		risks[id] = DeadlineRisk{
+			ImpliedDeadline: FormatTime(d.at),
+			LatestStart:     FormatTime(d.at.Add(-n.estimate)),
+			DueTask:         d.dueTask,
+		}
+	}
+	for id, n := range graph {
+		d := deadlines[id]
+		if d.at.IsZero() {
+			continue
+		}
+		late := at.After(d.at.Add(-n.estimate))
+		if n.status == "in_progress" {
+			late = at.After(d.at)
+		}
+		for cur := id; late && cur != ""; cur = deadlines[cur].via {
+			r := risks[cur]
+			if r.AtRisk {
+				break
+			}
+			r.AtRisk = true
+			risks[cur] = r
+		}
+	}
+	return risks, nil
+}
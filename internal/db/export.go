@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ExportVersion is the version of the JSON export document. Bump it when a
+// change would make older bossman builds misread a document.
+const ExportVersion = 1
+
+// ExportDocument is the whole task graph as one JSON document: every task
+// with its project, tags, blockers and comments, parents before children.
+type ExportDocument struct {
+	Format     string       `json:"format"`
+	Version    int          `json:"version"`
+	ExportedAt string       `json:"exported_at"`
+	Tasks      []TaskBundle `json:"tasks"`
+}
+
+// ImportResult reports what ImportAll did. Remapped maps the document's
+// ids that were already taken to the ids they were stored under.
+type ImportResult struct {
+	Tasks    int               `json:"imported"`
+	Remapped map[string]string `json:"remapped,omitempty"`
+}
+
+// ExportAll returns the task graph, limited to one project if projectID is
+// not empty.
+func ExportAll(ctx context.Context, db *sqlx.DB, projectID string) (*ExportDocument, error) {
+	bundles, err := LoadBundles(ctx, db, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if bundles == nil {
+		bundles = []TaskBundle{}
+	}
+	return &ExportDocument{
+		Format:     "bossman",
+		Version:    ExportVersion,
+		ExportedAt: now(ctx),
+		Tasks:      bundles,
+	}, nil
+}
+
+// ImportAll adds the document's tasks to the database. Ids already in use,
+// say when a document is imported back into the database it came from,
+// get fresh ones, and parents and blockers follow them; comments always get
+// fresh ids. Nothing already stored is changed.
+func ImportAll(ctx context.Context, db *sqlx.DB, doc *ExportDocument) (*ImportResult, error) {
+	if doc.Format != "" && doc.Format != "bossman" {
+		return nil, fmt.Errorf("unknown document format: %s", doc.Format)
+	}
+	if doc.Version < 1 || doc.Version > ExportVersion {
+		return nil, fmt.Errorf("unsupported export version %d (this build reads up to %d)", doc.Version, ExportVersion)
+	}
+
+	seen := make(map[string]bool, len(doc.Tasks))
+	ids := make([]string, 0, len(doc.Tasks))
+	for _, b := range doc.Tasks {
+		if b.ID == "" {
+			return nil, fmt.Errorf("task without id: %q", b.Description)
+		}
+		if seen[b.ID] {
+			return nil, fmt.Errorf("duplicate task id in document: %s", b.ID)
+		}
+		seen[b.ID] = true
+		ids = append(ids, b.ID)
+	}
+	var taken []string
+	if len(ids) > 0 {
+		query, args, err := sqlx.In("SELECT id FROM tasks WHERE id IN (?)", ids)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.SelectContext(ctx, &taken, db.Rebind(query), args...); err != nil {
+			return nil, fmt.Errorf("check ids: %w", err)
+		}
+	}
+	remap := make(map[string]string, len(taken))
+	for _, id := range taken {
+		remap[id] = NewTaskID()
+	}
+	mapped := func(id string) string {
+		if to, ok := remap[id]; ok {
+			return to
+		}
+		return id
+	}
+
+	bundles := make([]TaskBundle, len(doc.Tasks))
+	for i, b := range doc.Tasks {
+		b.ID = mapped(b.ID)
+		if b.ParentID != nil {
+			parent := mapped(*b.ParentID)
+			b.ParentID = &parent
+		}
+		blockedBy := make([]string, len(b.BlockedBy))
+		for j, dep := range b.BlockedBy {
+			blockedBy[j] = mapped(dep)
+		}
+		b.BlockedBy = blockedBy
+		comments := make([]Comment, len(b.Comments))
+		for j, c := range b.Comments {
+			c.ID = ""
+			c.TaskID = b.ID
+			comments[j] = c
+		}
+		b.Comments = comments
+		bundles[i] = b
+	}
+	if err := ImportBundles(ctx, db, bundles); err != nil {
+		return nil, err
+	}
+	res := &ImportResult{Tasks: len(bundles)}
+	if len(remap) > 0 {
+		res.Remapped = remap
+	}
+	return res, nil
+}
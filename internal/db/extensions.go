@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"weak"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Optional modules keep their tables out of the core schema and register
+// them as extensions instead. An extension's tables are provisioned the
+// first time the module touches them, or at startup when its feature is
+// enabled, so a database that never uses a module never grows its tables.
+// Once provisioned, an extension is recorded in schema_extensions and its
+// later migrations run when InitDB next opens the database.
+
+// Migration is one step of an extension's schema after its first release.
+type Migration struct {
+	Version int // 1, 2, 3... in order
+	SQL     string
+}
+
+// Extension is an optional module's share of the schema.
+type Extension struct {
+	Name string
+	// Schema creates the extension's tables and indexes as first released;
+	// it runs every time the extension is provisioned, so it must be
+	// idempotent. Later changes go in Migrations, not here.
+	Schema string
+	// PostgresSchema is Schema for Postgres; empty means the module is
+	// SQLite-only.
+	PostgresSchema string
+	// Migrations change tables an older Schema created. Each runs once.
+	Migrations []Migration
+	// Tables are the tables Schema creates. A database that has them from
+	// before the module became an extension adopts it on open.
+	Tables []string
+}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = map[string]*Extension{}
+
+	// provisioned remembers, per connection, the extensions known to be
+	// in place, so modules pay for the check once.
+	provisioned sync.Map // weak.Pointer[sqlx.DB] -> *sync.Map of names
+)
+
+// RegisterExtension adds ext to the registry. Modules call it from init;
+// registering a name twice or migrations out of order panics.
+func RegisterExtension(ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	if _, ok := extensions[ext.Name]; ok {
+		panic("db: extension " + ext.Name + " registered twice")
+	}
+	for i, m := range ext.Migrations {
+		if m.Version != i+1 {
+			panic(fmt.Sprintf("db: extension %s migration %d should be version %d", ext.Name, m.Version, i+1))
+		}
+	}
+	extensions[ext.Name] = &ext
+}
+
+// Extensions lists the registered extension names, sorted.
+func Extensions() []string {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	return slices.Sorted(func(yield func(string) bool) {
+		for name := range extensions {
+			if !yield(name) {
+				return
+			}
+		}
+	})
+}
+
+// ExtensionStatus is what ListExtensions reports for one extension.
+type ExtensionStatus struct {
+	Name string `json:"name"`
+	// Version is the last migration applied, 0 when only Schema has run.
+	Version     int     `json:"version"`
+	Provisioned bool    `json:"provisioned"`
+	EnabledAt   *string `json:"enabled_at,omitempty"`
+}
+
+// ListExtensions reports every registered extension and whether db has
+// its tables yet.
+func ListExtensions(ctx context.Context, db *sqlx.DB) ([]ExtensionStatus, error) {
+	var rows []struct {
+		Name      string `db:"name"`
+		Version   int    `db:"version"`
+		EnabledAt string `db:"enabled_at"`
+	}
+	if err := db.SelectContext(ctx, &rows, "SELECT name, version, enabled_at FROM schema_extensions"); err != nil {
+		return nil, err
+	}
+	var out []ExtensionStatus
+	for _, name := range Extensions() {
+		s := ExtensionStatus{Name: name}
+		for _, r := range rows {
+			if r.Name == name {
+				s.Version, s.Provisioned, s.EnabledAt = r.Version, true, &r.EnabledAt
+			}
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// EnableExtension provisions the named extension's tables and runs any
+// migrations db hasn't had yet. It is safe to call again, and from several
+// processes at once.
+func EnableExtension(ctx context.Context, db *sqlx.DB, name string) error {
+	extensionsMu.RLock()
+	ext, ok := extensions[name]
+	extensionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown extension %q; registered: %v", name, Extensions())
+	}
+	schema := ext.Schema
+	if isPostgres(db) {
+		if ext.PostgresSchema == "" {
+			return fmt.Errorf("%s isn't available on Postgres", name)
+		}
+		schema = ext.PostgresSchema
+	}
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var version int
+		err := tx.GetContext(ctx, &version, tx.Rebind("SELECT version FROM schema_extensions WHERE name = ?"), name)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, schema); err != nil {
+			return fmt.Errorf("create tables: %w", err)
+		}
+		for _, m := range ext.Migrations[min(version, len(ext.Migrations)):] {
+			if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+				return fmt.Errorf("migration %d: %w", m.Version, err)
+			}
+		}
+		_, err = tx.ExecContext(ctx, tx.Rebind(
+			`INSERT INTO schema_extensions (name, version, enabled_at) VALUES (?, ?, ?)
+			 ON CONFLICT (name) DO UPDATE SET version = excluded.version`), name, len(ext.Migrations), now(ctx))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("enable extension %s: %w", name, err)
+	}
+	key := weak.Make(db)
+	names, loaded := provisioned.LoadOrStore(key, &sync.Map{})
+	if !loaded {
+		runtime.AddCleanup(db, func(key weak.Pointer[sqlx.DB]) { provisioned.Delete(key) }, key)
+	}
+	names.(*sync.Map).Store(name, true)
+	return nil
+}
+
+// hasExtension reports whether db has the named extension's tables, for
+// code such as background jobs and search that should leave a module
+// alone when it was never used.
+func hasExtension(ctx context.Context, db *sqlx.DB, name string) (bool, error) {
+	if names, ok := provisioned.Load(weak.Make(db)); ok {
+		if _, ok := names.(*sync.Map).Load(name); ok {
+			return true, nil
+		}
+	}
+	var n int
+	err := db.GetContext(ctx, &n, db.Rebind("SELECT COUNT(*) FROM schema_extensions WHERE name = ?"), name)
+	return n > 0, err
+}
+
+// ensureExtension is EnableExtension for module code, cheap once the
+// extension is in place. Call it before a transaction, not inside one.
+func ensureExtension(ctx context.Context, db *sqlx.DB, name string) error {
+	if names, ok := provisioned.Load(weak.Make(db)); ok {
+		if _, ok := names.(*sync.Map).Load(name); ok {
+			return nil
+		}
+	}
+	return EnableExtension(ctx, db, name)
+}
+
+// upgradeExtensions brings every extension db already has up to date, so
+// a new release's migrations run at startup rather than mid-request, and
+// adopts extensions whose tables an older release created with the core
+// schema.
+func upgradeExtensions(ctx context.Context, db *sqlx.DB) error {
+	var recorded []string
+	if err := db.SelectContext(ctx, &recorded, "SELECT name FROM schema_extensions"); err != nil {
+		return err
+	}
+	for _, name := range Extensions() {
+		if !slices.Contains(recorded, name) {
+			extensionsMu.RLock()
+			tables := extensions[name].Tables
+			extensionsMu.RUnlock()
+			if isPostgres(db) || len(tables) == 0 {
+				continue
+			}
+			cols, err := tableColumns(ctx, db, tables[0])
+			if err != nil {
+				return err
+			}
+			if len(cols) == 0 {
+				continue // never used; provisioned on first use
+			}
+		}
+		if err := EnableExtension(ctx, db, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
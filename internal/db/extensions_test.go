@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtensionProvisionedOnFirstUse opens a fresh database, which has no
+// attachment tables until the first attachment, and reopens it to check
+// the extension stays recorded.
+func TestExtensionProvisionedOnFirstUse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bossman.db")
+	conn, err := InitDB(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithActor(context.Background(), "agent-1")
+
+	has := func(name string) bool {
+		t.Helper()
+		ok, err := hasExtension(ctx, conn, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if has(ExtAttachments) || has(ExtChecklists) {
+		t.Fatal("fresh database already has optional tables")
+	}
+	if hits, err := SearchAll(ctx, conn, "notes", GlobalSearchOpts{}); err != nil || len(hits) != 0 {
+		t.Fatalf("search before any attachment: %v %v", hits, err)
+	}
+
+	task := &Task{ID: NewTaskID(), Description: "write the report"}
+	if err := InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	a := &Attachment{TaskID: task.ID, Name: "notes.txt", MimeType: "text/plain", Data: []byte("hi"), Author: "agent-1"}
+	if err := AddAttachment(ctx, conn, a); err != nil {
+		t.Fatal(err)
+	}
+	if !has(ExtAttachments) || has(ExtChecklists) {
+		t.Fatal("only attachments should be provisioned")
+	}
+	conn.Close()
+
+	if conn, err = InitDB(path, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	status, err := ListExtensions(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range status {
+		if s.Provisioned != (s.Name == ExtAttachments) {
+			t.Errorf("%s: provisioned %v after reopening", s.Name, s.Provisioned)
+		}
+	}
+	if _, err := GetAttachment(ctx, conn, a.ID); err != nil {
+		t.Fatal(err)
+	}
+}
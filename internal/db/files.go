@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// A task's files are the repo-relative paths it touches, files or whole
+// directories, so an agent about to edit internal/db/db.go can find the
+// work already planned there.
+
+const maxFilePathLen = 1024
+
+// NormalizeFilePath cleans a repo-relative path to slash-separated form
+// without a leading ./ or trailing slash. Absolute paths and paths that
+// climb out of the repo are rejected.
+func NormalizeFilePath(p string) (string, error) {
+	p = strings.ReplaceAll(strings.TrimSpace(p), `\`, "/")
+	if p == "" {
+		return "", fmt.Errorf("file path must not be empty")
+	}
+	if strings.HasPrefix(p, "/") || (len(p) > 1 && p[1] == ':') {
+		return "", fmt.Errorf("file path %q must be relative to the repo", p)
+	}
+	p = path.Clean(p)
+	if p == "." {
+		return "", fmt.Errorf("file path must name a file or directory, not the whole repo")
+	}
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("file path %q is outside the repo", p)
+	}
+	if len(p) > maxFilePathLen {
+		return "", fmt.Errorf("file path longer than %d characters", maxFilePathLen)
+	}
+	return p, nil
+}
+
+func setFilesTx(ctx context.Context, tx *sqlx.Tx, taskID string, files []string) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM task_files WHERE task_id = ?"), taskID); err != nil {
+		return err
+	}
+	for _, f := range files {
+		f, err := NormalizeFilePath(f)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(
+			"INSERT INTO task_files (task_id, path) VALUES (?, ?) ON CONFLICT DO NOTHING"), taskID, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetFilesForTasks returns the paths each task touches, sorted.
+func GetFilesForTasks(ctx context.Context, db *sqlx.DB, ids []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT task_id, path FROM task_files WHERE task_id IN (?) ORDER BY path", ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskID string `db:"task_id"`
+		Path   string `db:"path"`
+	}
+	if err := db.SelectContext(ctx, &rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TaskID] = append(out[r.TaskID], r.Path)
+	}
+	return out, nil
+}
+
+// filesFilter is the QueryTasks condition for ListOpts.Files: the task
+// lists one of the paths, something under one of them, or a directory
+// holding one of them.
+func filesFilter(files []string, args map[string]any) (string, error) {
+	var exact []string
+	var under []string
+	for i, f := range files {
+		f, err := NormalizeFilePath(f)
+		if err != nil {
+			return "", err
+		}
+		exact = append(exact, f)
+		for dir := path.Dir(f); dir != "."; dir = path.Dir(dir) {
+			exact = append(exact, dir)
+		}
+		dirArg, lenArg := fmt.Sprintf("file_dir_%d", i), fmt.Sprintf("file_len_%d", i)
+		under = append(under, fmt.Sprintf("substr(path, 1, :%s) = :%s", lenArg, dirArg))
+		args[dirArg] = f + "/"
+		args[lenArg] = utf8.RuneCountInString(f) + 1
+	}
+	args["files"] = exact
+	return ` AND id IN (SELECT task_id FROM task_files WHERE path IN (:files) OR ` +
+		strings.Join(under, " OR ") + `)`, nil
+}
@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// HealthOpts picks how thorough HealthCheck is.
+type HealthOpts struct {
+	// Full runs PRAGMA integrity_check, which also checks that every index
+	// matches its table, instead of quick_check. Both read the whole file;
+	// integrity_check takes noticeably longer on a large database.
+	Full bool
+}
+
+// DanglingParent is a task whose parent_id names no task.
+type DanglingParent struct {
+	TaskID   string `db:"id" json:"task_id"`
+	ParentID string `db:"parent_id" json:"parent_id"`
+}
+
+// HealthReport is what HealthCheck found. Integrity holds the pragma's
+// complaints, empty when the file is sound or on Postgres, which has no
+// equivalent check.
+type HealthReport struct {
+	OK              bool             `json:"ok"`
+	Check           string           `json:"check,omitempty"` // the pragma run
+	Integrity       []string         `json:"integrity"`
+	OrphanBlockers  []BlockerEdge    `json:"orphan_blockers"`
+	DanglingParents []DanglingParent `json:"dangling_parents"`
+	Took            string           `json:"took"`
+}
+
+// Problems lists what the report found, one line each.
+func (h HealthReport) Problems() []string {
+	problems := append([]string{}, h.Integrity...)
+	for _, b := range h.OrphanBlockers {
+		problems = append(problems, fmt.Sprintf("blocker %s -> %s references a missing task", b.TaskID, b.BlockedByID))
+	}
+	for _, p := range h.DanglingParents {
+		problems = append(problems, fmt.Sprintf("task %s has missing parent %s", p.TaskID, p.ParentID))
+	}
+	return problems
+}
+
+// healthLimit caps each list in a HealthReport; a database that far gone
+// needs a restore, not a longer report.
+const healthLimit = 100
+
+// HealthCheck checks the database file with PRAGMA quick_check, or
+// integrity_check with opts.Full, and looks for rows foreign keys should
+// have ruled out: blockers naming a missing task and subtasks of a missing
+// parent. Those turn up after writes made with foreign keys off, such as
+// an import by hand or a restore of part of a backup.
+func HealthCheck(ctx context.Context, db *sqlx.DB, opts HealthOpts) (HealthReport, error) {
+	start := time.Now()
+	report := HealthReport{Integrity: []string{}}
+	if !isPostgres(db) {
+		report.Check = "quick_check"
+		if opts.Full {
+			report.Check = "integrity_check"
+		}
+		var lines []string
+		if err := db.SelectContext(ctx, &lines, fmt.Sprintf("PRAGMA %s(%d)", report.Check, healthLimit)); err != nil {
+			return HealthReport{}, fmt.Errorf("%s: %w", report.Check, err)
+		}
+		// a sound file reports the single line "ok"
+		if len(lines) != 1 || lines[0] != "ok" {
+			report.Integrity = lines
+		}
+	}
+	if err := db.SelectContext(ctx, &report.OrphanBlockers, db.Rebind(`SELECT task_id, blocked_by_id FROM task_blockers b
+		WHERE NOT EXISTS (SELECT 1 FROM tasks t WHERE t.id = b.task_id)
+		   OR NOT EXISTS (SELECT 1 FROM tasks t WHERE t.id = b.blocked_by_id)
+		ORDER BY task_id, blocked_by_id LIMIT ?`), healthLimit); err != nil {
+		return HealthReport{}, fmt.Errorf("find orphan blockers: %w", err)
+	}
+	if err := db.SelectContext(ctx, &report.DanglingParents, db.Rebind(`SELECT id, parent_id FROM tasks c
+		WHERE parent_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM tasks p WHERE p.id = c.parent_id)
+		ORDER BY id LIMIT ?`), healthLimit); err != nil {
+		return HealthReport{}, fmt.Errorf("find dangling parents: %w", err)
+	}
+	if report.OrphanBlockers == nil {
+		report.OrphanBlockers = []BlockerEdge{}
+	}
+	if report.DanglingParents == nil {
+		report.DanglingParents = []DanglingParent{}
+	}
+	report.OK = len(report.Problems()) == 0
+	report.Took = time.Since(start).Round(time.Millisecond).String()
+	return report, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"testing"
+)
+
+// TestHealthCheck passes a fresh database, then finds a blocker and a
+// subtask left behind by a delete made with foreign keys off.
+func TestHealthCheck(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	parent := &Task{ID: NewTaskID(), Description: "ship the release"}
+	child := &Task{ID: NewTaskID(), Description: "write the notes", ParentID: &parent.ID}
+	for _, task := range []*Task{parent, child} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddBlocker(ctx, conn, child.ID, parent.ID); err != nil {
+		t.Fatal(err)
+	}
+	report, err := HealthCheck(ctx, conn, HealthOpts{Full: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK || report.Check != "integrity_check" {
+		t.Fatalf("fresh database: %+v", report)
+	}
+
+	// one connection, so the pragma holds for the delete
+	for _, q := range []string{"PRAGMA foreign_keys = OFF", "DELETE FROM tasks WHERE id = '" + parent.ID + "'", "PRAGMA foreign_keys = ON"} {
+		if _, err := conn.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err = HealthCheck(ctx, conn, HealthOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || len(report.OrphanBlockers) != 1 || len(report.DanglingParents) != 1 {
+		t.Fatalf("after the delete: %+v", report)
+	}
+	if p := report.DanglingParents[0]; p.TaskID != child.ID || p.ParentID != parent.ID {
+		t.Errorf("dangling parent %+v", p)
+	}
+	if n := len(report.Problems()); n != 2 {
+		t.Errorf("%d problems, want 2: %q", n, report.Problems())
+	}
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// MaxTaskDepth is how many levels a task tree may have, root included.
+// Deeper trees are almost always an agent decomposing in a loop.
+const MaxTaskDepth = 32
+
+// validateParentTx checks that id may sit under parentID: the parent
+// exists, isn't id or one of its descendants, and the tree stays within
+// MaxTaskDepth once id's own subtree hangs below it.
+func validateParentTx(ctx context.Context, tx *sqlx.Tx, id, parentID string) error {
+	if parentID == id {
+		return fmt.Errorf("task %s cannot be its own parent", id)
+	}
+	var ancestors []string
+	err := tx.SelectContext(ctx, &ancestors, tx.Rebind(`
+		WITH RECURSIVE up(id, parent_id, depth) AS (
+			SELECT id, parent_id, 1 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, t.parent_id, up.depth + 1 FROM tasks t JOIN up ON t.id = up.parent_id
+			 WHERE up.depth <= ?
+		)
+		SELECT id FROM up ORDER BY depth`), parentID, MaxTaskDepth)
+	if err != nil {
+		return err
+	}
+	if len(ancestors) == 0 {
+		return fmt.Errorf("parent not found: %s", parentID)
+	}
+	if slices.Contains(ancestors, id) {
+		return fmt.Errorf("task %s cannot move under %s, which is one of its subtasks", id, parentID)
+	}
+
+	var height int
+	err = tx.GetContext(ctx, &height, tx.Rebind(`
+		WITH RECURSIVE down(id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, down.depth + 1 FROM tasks t JOIN down ON t.parent_id = down.id
+			 WHERE down.depth < ?
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM down`), id, MaxTaskDepth)
+	if err != nil {
+		return err
+	}
+	if depth := len(ancestors) + 1 + height; depth > MaxTaskDepth {
+		return fmt.Errorf("task tree would be %d levels deep; the limit is %d", depth, MaxTaskDepth)
+	}
+	return nil
+}
+
+// ReparentTask moves a task, with its subtasks, under parentID, or to the
+// top level when parentID is nil. The task keeps its project.
+func ReparentTask(ctx context.Context, db *sqlx.DB, id string, parentID *string) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if parentID != nil {
+			if err := validateParentTx(ctx, tx, id, *parentID); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET parent_id = ?, updated_at = ? WHERE id = ?"),
+			parentID, now(ctx), id); err != nil {
+			return err
+		}
+		after, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		oldValues, newValues := diffTasks(before, after)
+		return recordEvent(ctx, tx, id, "task", "update", oldValues, newValues)
+	})
+}
+
+// SubtreeTask is a row of GetSubtree: a task and how far below the root
+// it sits, the root being depth 0.
+type SubtreeTask struct {
+	Task
+	Depth int `db:"depth"`
+}
+
+// GetSubtree returns the task id and all its descendants, parents before
+// children and siblings in creation order. maxDepth > 0 stops that many
+// levels below the root. A missing root is sql.ErrNoRows.
+func GetSubtree(ctx context.Context, db *sqlx.DB, id string, maxDepth int) ([]SubtreeTask, error) {
+	if maxDepth <= 0 {
+		maxDepth = MaxTaskDepth
+	}
+	var tasks []SubtreeTask
+	err := db.SelectContext(ctx, &tasks, db.Rebind(`
+		WITH RECURSIVE tree(id, depth) AS (
+			SELECT id, 0 FROM tasks WHERE id = ?
+			UNION ALL
+			SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id
+			 WHERE tree.depth < ?
+		)
+		SELECT t.*, tree.depth FROM tasks t JOIN tree ON tree.id = t.id
+		ORDER BY tree.depth, t.created_at, t.id`), id, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return tasks, nil
+}
+
+// DecomposeOpts say how DecomposeTask wires the subtasks it creates.
+type DecomposeOpts struct {
+	// Sequential blocks each subtask on the one before it, so they are
+	// handed out in order; otherwise they are all ready at once.
+	Sequential bool
+	// After, when After[i] is non-nil, lists the earlier subtasks, by
+	// index, that subtask i waits on, in place of the Sequential default.
+	After [][]int
+	// Start moves a pending parent to in_progress.
+	Start bool
+}
+
+// DecomposeTask creates subtasks under parentID, wires the blockers opts
+// asks for between them and, with opts.Start, starts the parent, all in
+// one transaction. It returns the parent as it ends up and fills subtasks
+// in as stored.
+func DecomposeTask(ctx context.Context, db *sqlx.DB, parentID string, subtasks []*Task, opts DecomposeOpts) (*Task, error) {
+	if len(opts.After) > len(subtasks) {
+		return nil, fmt.Errorf("dependencies given for %d subtasks, but there are %d", len(opts.After), len(subtasks))
+	}
+	for i, after := range opts.After {
+		for _, j := range after {
+			if j < 0 || j >= i {
+				return nil, fmt.Errorf("subtask %d can only wait on an earlier subtask, not %d", i, j)
+			}
+		}
+	}
+	inProgress := StatusInProgress
+	start := UpdateOpts{Status: &inProgress}
+	setClauses, args := updateClauses(ctx, db, start)
+
+	var parent *Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var err error
+		if parent, err = getTaskTx(ctx, tx, parentID); err != nil {
+			return err
+		}
+		for _, sub := range subtasks {
+			sub.ParentID = &parent.ID
+		}
+		if err := insertTasksTx(ctx, tx, subtasks); err != nil {
+			return err
+		}
+		for i, sub := range subtasks {
+			var after []int
+			switch {
+			case i < len(opts.After) && opts.After[i] != nil:
+				after = opts.After[i]
+			case opts.Sequential && i > 0:
+				after = []int{i - 1}
+			}
+			for _, j := range slices.Compact(slices.Sorted(slices.Values(after))) {
+				if err := addBlockerTx(ctx, tx, sub.ID, subtasks[j].ID); err != nil {
+					return fmt.Errorf("add blocker %s: %w", subtasks[j].ID, err)
+				}
+			}
+		}
+		ids := make([]string, len(subtasks))
+		for i, sub := range subtasks {
+			ids[i] = sub.ID
+		}
+		created, err := tasksByID(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subtasks {
+			*sub = *created[sub.ID]
+		}
+		if !opts.Start || parent.Status == StatusInProgress {
+			return nil
+		}
+		if err := checkUpdate(parent, start); err != nil {
+			return err
+		}
+		updated, err := updateTasksTx(ctx, tx, []string{parent.ID}, map[string]*Task{parent.ID: parent}, start, setClauses, args)
+		if err != nil {
+			return err
+		}
+		parent = &updated[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parent, nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+)
+
+// TestDecomposeTask breaks a task into a sequential chain with one step
+// pulled out to run alongside, and wants a bad dependency to leave nothing
+// behind.
+func TestDecomposeTask(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	root := &Task{ID: NewTaskID(), Description: "ship the importer"}
+	if err := InsertTask(ctx, conn, root); err != nil {
+		t.Fatal(err)
+	}
+	var subtasks []*Task
+	for _, desc := range []string{"parse the CSV", "map the columns", "write the docs", "load the rows"} {
+		subtasks = append(subtasks, &Task{ID: NewTaskID(), Description: desc})
+	}
+	// the docs wait on nothing; the load waits on the mapping only
+	after := [][]int{nil, nil, {}, {1}}
+	parent, err := DecomposeTask(ctx, conn, root.ID, subtasks, DecomposeOpts{Sequential: true, After: after, Start: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.Status != StatusInProgress || parent.StartedAt == nil {
+		t.Errorf("parent %s, want it started", parent.Status)
+	}
+	want := map[int][]string{1: {subtasks[0].ID}, 3: {subtasks[1].ID}}
+	for i, sub := range subtasks {
+		if sub.ParentID == nil || *sub.ParentID != root.ID || sub.Status != StatusPending {
+			t.Errorf("%s: %+v", sub.Description, sub)
+		}
+		blockers, err := GetBlockers(ctx, conn, sub.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(blockers) != len(want[i]) || (len(blockers) == 1 && blockers[0].ID != want[i][0]) {
+			t.Errorf("%s blocked by %v, want %v", sub.Description, blockers, want[i])
+		}
+	}
+
+	late := []*Task{{ID: NewTaskID(), Description: "announce it"}, {ID: NewTaskID(), Description: "tidy up"}}
+	if _, err := DecomposeTask(ctx, conn, root.ID, late, DecomposeOpts{After: [][]int{{1}}}); err == nil {
+		t.Fatal("a subtask waited on a later one")
+	}
+	children, err := GetSubtree(ctx, conn, root.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != len(subtasks)+1 {
+		t.Errorf("%d tasks in the tree, want %d", len(children), len(subtasks)+1)
+	}
+}
@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// legacyTable is where a pre-extraction tasks table is kept after import,
+// so nothing is lost if the mapping guessed wrong.
+const legacyTable = "legacy_tasks"
+
+// SettingLegacyImport records when and how many rows were imported.
+const SettingLegacyImport = "legacy_import"
+
+// requiredColumns are the tasks columns present since the schema was
+// extracted; a tasks table missing any of them predates it. Columns added
+// later are handled by addColumns instead.
+var requiredColumns = []string{"id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at"}
+
+// Column names the old schema may have used, in order of preference.
+var legacyAliases = map[string][]string{
+	"description":  {"description", "title", "name", "text", "task"},
+	"context":      {"context", "notes", "details", "body"},
+	"status":       {"status", "state", "done", "completed"},
+	"priority":     {"priority", "prio"},
+	"parent_id":    {"parent_id", "parent"},
+	"result":       {"result", "outcome"},
+	"created_at":   {"created_at", "created", "inserted_at"},
+	"started_at":   {"started_at"},
+	"completed_at": {"completed_at", "done_at", "finished_at"},
+	"updated_at":   {"updated_at", "modified_at"},
+}
+
+// migrateLegacy detects a tasks table in the old shape, moves it aside and
+// copies its rows into the current schema. It runs before the schema is
+// applied, since the current indexes can't be built on the old table.
+func migrateLegacy(ctx context.Context, conn *sqlx.DB) error {
+	cols, err := tableColumns(ctx, conn, "tasks")
+	if err != nil || len(cols) == 0 {
+		return err
+	}
+	legacy := false
+	for _, c := range requiredColumns {
+		if _, ok := cols[c]; !ok {
+			legacy = true
+			break
+		}
+	}
+	if !legacy {
+		return nil
+	}
+	if existing, err := tableColumns(ctx, conn, legacyTable); err != nil {
+		return err
+	} else if len(existing) > 0 {
+		return fmt.Errorf("found an old-style tasks table but %s already exists; move one aside by hand", legacyTable)
+	}
+
+	slog.Warn("legacy database detected, importing", "table", "tasks", "kept_as", legacyTable)
+	n, err := importLegacy(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("import legacy tasks: %w", err)
+	}
+	slog.Info("legacy import done", "tasks", n)
+	return nil
+}
+
+func importLegacy(ctx context.Context, conn *sqlx.DB) (int, error) {
+	// foreign keys can't be toggled inside a transaction, and the old rows
+	// may reference each other in any order
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return 0, err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	blockerCols, err := tableColumns(ctx, conn, "task_blockers")
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	err = WithTx(ctx, conn, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE tasks RENAME TO "+legacyTable); err != nil {
+			return err
+		}
+		if len(blockerCols) > 0 {
+			if _, err := tx.ExecContext(ctx, "ALTER TABLE task_blockers RENAME TO legacy_task_blockers"); err != nil {
+				return err
+			}
+		}
+		// old indexes follow the renamed table but keep their names
+		if err := dropIndexes(ctx, tx, legacyTable, "legacy_task_blockers"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, schema); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryxContext(ctx, "SELECT * FROM "+legacyTable+" ORDER BY rowid")
+		if err != nil {
+			return err
+		}
+		var old []map[string]any
+		for rows.Next() {
+			row := make(map[string]any)
+			if err := rows.MapScan(row); err != nil {
+				rows.Close()
+				return err
+			}
+			old = append(old, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// integer ids become regular task ids; text ids are kept
+		ids := make(map[string]string, len(old))
+		for _, row := range old {
+			oldID := legacyString(row["id"])
+			if _, isInt := row["id"].(int64); isInt || oldID == "" {
+				ids[oldID] = NewTaskID()
+			} else {
+				ids[oldID] = oldID
+			}
+		}
+
+		stamp := nowTimestamp(ctx)
+		for _, row := range old {
+			t := legacyTask(row, ids, stamp)
+			fillLegacyPriority(&t)
+			_, err := tx.NamedExecContext(ctx,
+				`INSERT INTO tasks (id, parent_id, description, context, priority, priority_label, priority_weight, status, result,
+				                    created_at, started_at, completed_at, updated_at)
+				 VALUES (:id, :parent_id, :description, :context, :priority, :priority_label, :priority_weight, :status, :result,
+				         :created_at, :started_at, :completed_at, :updated_at)`, t)
+			if err != nil {
+				return fmt.Errorf("row %s: %w", legacyString(row["id"]), err)
+			}
+			imported++
+		}
+
+		if len(blockerCols) > 0 {
+			if err := importLegacyBlockers(ctx, tx, ids); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+			 ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+			SettingLegacyImport, fmt.Sprintf("imported %d tasks at %s", imported, stamp), stamp.String())
+		return err
+	})
+	return imported, err
+}
+
+func importLegacyBlockers(ctx context.Context, tx *sqlx.Tx, ids map[string]string) error {
+	var edges []struct {
+		TaskID      any `db:"task_id"`
+		BlockedByID any `db:"blocked_by_id"`
+	}
+	if err := tx.SelectContext(ctx, &edges, "SELECT task_id, blocked_by_id FROM legacy_task_blockers"); err != nil {
+		return err
+	}
+	for _, e := range edges {
+		from, ok1 := ids[legacyString(e.TaskID)]
+		to, ok2 := ids[legacyString(e.BlockedByID)]
+		if !ok1 || !ok2 || from == to {
+			continue // dangling edge in the old data
+		}
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO task_blockers (task_id, blocked_by_id) VALUES (?, ?)", from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyTask maps one old row onto the current columns, filling gaps with
+// the schema defaults.
+func legacyTask(row map[string]any, ids map[string]string, stamp Timestamp) Task {
+	pick := func(col string) any {
+		for _, name := range legacyAliases[col] {
+			if v, ok := row[name]; ok && v != nil {
+				return v
+			}
+		}
+		return nil
+	}
+	optional := func(col string) *Sensitive {
+		if s := Sensitive(legacyString(pick(col))); s != "" {
+			return &s
+		}
+		return nil
+	}
+	optionalTime := func(col string) *Timestamp {
+		if v := pick(col); v != nil {
+			if t := legacyTime(v, Timestamp{}); !t.IsZero() {
+				return &t
+			}
+		}
+		return nil
+	}
+
+	t := Task{
+		ID:          ids[legacyString(row["id"])],
+		Description: legacyString(pick("description")),
+		Context:     Sensitive(legacyString(pick("context"))),
+		Priority:    legacyPriority(pick("priority")),
+		Status:      legacyStatus(pick("status")),
+		Result:      optional("result"),
+		CreatedAt:   legacyTime(pick("created_at"), stamp),
+		StartedAt:   optionalTime("started_at"),
+		CompletedAt: optionalTime("completed_at"),
+	}
+	if t.Description == "" {
+		t.Description = "(imported task without description)"
+	}
+	if parent := legacyString(pick("parent_id")); parent != "" {
+		if id, ok := ids[parent]; ok {
+			t.ParentID = &id
+		}
+	}
+	t.UpdatedAt = legacyTime(pick("updated_at"), t.CreatedAt)
+	return t
+}
+
+func legacyString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return FormatTime(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func legacyStatus(v any) string {
+	switch strings.ToLower(strings.TrimSpace(legacyString(v))) {
+	case "done", "complete", "completed", "closed", "finished", "1", "true":
+		return "completed"
+	case "doing", "started", "in_progress", "in-progress", "active", "wip":
+		return "in_progress"
+	case "failed", "error", "cancelled", "canceled":
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func legacyPriority(v any) int {
+	p, err := strconv.Atoi(legacyString(v))
+	if err != nil {
+		return 3
+	}
+	return min(max(p, 1), 5)
+}
+
+// legacyTime accepts unix seconds or any string SQLite's datetime() parses.
+func legacyTime(v any, fallback Timestamp) Timestamp {
+	switch v := v.(type) {
+	case int64:
+		return TimestampOf(time.Unix(v, 0))
+	case time.Time:
+		return TimestampOf(v)
+	}
+	s := legacyString(v)
+	if s == "" {
+		return fallback
+	}
+	for _, layout := range []string{TimeLayout, time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TimestampOf(t)
+		}
+	}
+	return fallback
+}
+
+// tableColumns returns column name -> declared type, empty if no such table.
+func tableColumns(ctx context.Context, conn *sqlx.DB, table string) (map[string]string, error) {
+	var cols []struct {
+		Name string `db:"name"`
+		Type string `db:"type"`
+	}
+	if err := conn.SelectContext(ctx, &cols, "SELECT name, type FROM pragma_table_info(?)", table); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(cols))
+	for _, c := range cols {
+		out[c.Name] = c.Type
+	}
+	return out, nil
+}
+
+func dropIndexes(ctx context.Context, tx *sqlx.Tx, tables ...string) error {
+	query, args, err := sqlx.In(
+		"SELECT name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND tbl_name IN (?)", tables)
+	if err != nil {
+		return err
+	}
+	var names []string
+	if err := tx.SelectContext(ctx, &names, query, args...); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, `DROP INDEX "`+strings.ReplaceAll(name, `"`, `""`)+`"`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CompleteTask marks id completed, with result when it's non-nil. Beside
+// the task it returns what the completion unblocked: the tasks id blocked
+// that ReadyTasks now hands out, in its order. A task that was already
+// completed unblocks nothing.
+func CompleteTask(ctx context.Context, db *sqlx.DB, id string, result *string) (*Task, []Task, error) {
+	completed := StatusCompleted
+	opts := UpdateOpts{Status: &completed, Result: result}
+	query, args, err := updateTaskQuery(ctx, db, id, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var task *Task
+	var unblocked []Task
+	err = WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, after, err := updateTaskTx(ctx, tx, id, opts, query, args)
+		if err != nil {
+			return err
+		}
+		task = after
+		if before.Status == StatusCompleted {
+			return nil
+		}
+		unblocked, err = readyDependentsTx(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return task, unblocked, nil
+}
+
+// FailTask records a failed attempt at id: reason becomes its result and
+// its attempts count goes up by one. A retryable failure puts the task back
+// to pending and unassigned, for the next ready-task query to hand out
+// again; otherwise it is failed.
+func FailTask(ctx context.Context, db *sqlx.DB, id, reason string, retryable bool) (*Task, error) {
+	status := StatusFailed
+	if retryable {
+		status = StatusPending
+	}
+	opts := UpdateOpts{Status: &status, Result: &reason}
+	setClauses, args := updateClauses(ctx, db, opts)
+	setClauses = append(setClauses, "attempts = attempts + 1")
+	if retryable {
+		setClauses = append(setClauses, "assigned_to = NULL")
+	}
+	args["id"] = id
+	query := "UPDATE tasks SET " + strings.Join(setClauses, ", ") + " WHERE id = :id RETURNING *"
+	if _, err := preparedNamed(ctx, db, query); err != nil {
+		return nil, err
+	}
+	var task *Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var err error
+		_, task, err = updateTaskTx(ctx, tx, id, opts, query, args)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// ReopenTask takes a completed or failed task back to pending, clearing
+// completed_at and result; the update's audit event keeps the old result.
+// With reblock, the tasks id blocks that were already in progress go back
+// to pending too, since they are waiting on it again; they are returned.
+func ReopenTask(ctx context.Context, db *sqlx.DB, id string, reblock bool) (*Task, []Task, error) {
+	pending := StatusPending
+	opts := UpdateOpts{Status: &pending, Reopen: true}
+	setClauses, args := updateClauses(ctx, db, opts)
+	args["id"] = id
+	query := "UPDATE tasks SET " + strings.Join(append(setClauses, "result = NULL"), ", ") + " WHERE id = :id RETURNING *"
+	if _, err := preparedNamed(ctx, db, query); err != nil {
+		return nil, nil, err
+	}
+	back := UpdateOpts{Status: &pending}
+	backClauses, backArgs := updateClauses(ctx, db, back)
+	var task *Task
+	var reblocked []Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, after, err := updateTaskTx(ctx, tx, id, opts, query, args)
+		if err != nil {
+			return err
+		}
+		if before.Status != StatusCompleted && before.Status != StatusFailed {
+			return fmt.Errorf("task %s is %s; only completed or failed tasks reopen", id, before.Status)
+		}
+		task = after
+		if !reblock {
+			return nil
+		}
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, tx.Rebind(`
+			SELECT t.id FROM tasks t JOIN task_blockers tb ON tb.task_id = t.id
+			 WHERE tb.blocked_by_id = ? AND t.status = 'in_progress'`), id); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		slices.Sort(ids)
+		running, err := tasksByID(ctx, tx, ids)
+		if err != nil {
+			return err
+		}
+		reblocked, err = updateTasksTx(ctx, tx, ids, running, back, backClauses, backArgs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return task, reblocked, nil
+}
+
+// readyDependentsTx returns the tasks id directly blocks that are ready.
+func readyDependentsTx(ctx context.Context, tx *sqlx.Tx, id string) ([]Task, error) {
+	var dependents []string
+	if err := tx.SelectContext(ctx, &dependents, tx.Rebind("SELECT task_id FROM task_blockers WHERE blocked_by_id = ?"), id); err != nil {
+		return nil, err
+	}
+	if len(dependents) == 0 {
+		return nil, nil
+	}
+	waiting := make(map[string]bool, len(dependents))
+	for _, d := range dependents {
+		waiting[d] = true
+	}
+	ready, err := ReadyTasks(ctx, tx, ParallelOpts{IncludeAssigned: true})
+	if err != nil {
+		return nil, err
+	}
+	var out []Task
+	for _, t := range ready {
+		if waiting[t.ID] {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCompleteTask completes the blockers of a task one at a time and
+// wants it reported as unblocked only by the last of them.
+func TestCompleteTask(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	schema := &Task{ID: NewTaskID(), Description: "design the schema"}
+	fixtures := &Task{ID: NewTaskID(), Description: "write the fixtures"}
+	queries := &Task{ID: NewTaskID(), Description: "write the queries"}
+	for _, task := range []*Task{schema, fixtures, queries} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, blocker := range []*Task{schema, fixtures} {
+		if err := AddBlocker(ctx, conn, queries.ID, blocker.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started := StatusInProgress
+	if _, err := UpdateTasks(ctx, conn, []string{schema.ID, fixtures.ID}, UpdateOpts{Status: &started}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := "three tables"
+	task, unblocked, err := CompleteTask(ctx, conn, schema.ID, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != StatusCompleted || task.CompletedAt == nil || task.Result == nil || string(*task.Result) != result {
+		t.Errorf("completed as %+v", task)
+	}
+	if len(unblocked) != 0 {
+		t.Errorf("unblocked %v while the fixtures are open", unblocked)
+	}
+	_, unblocked, err = CompleteTask(ctx, conn, fixtures.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unblocked) != 1 || unblocked[0].ID != queries.ID {
+		t.Errorf("unblocked %v, want the queries", unblocked)
+	}
+	// completing again changes nothing and unblocks nothing new
+	if _, unblocked, err = CompleteTask(ctx, conn, fixtures.ID, nil); err != nil || len(unblocked) != 0 {
+		t.Errorf("completing twice: %v, %v", unblocked, err)
+	}
+	if _, _, err := CompleteTask(ctx, conn, queries.ID, nil); err == nil {
+		t.Error("completed a task that never started")
+	}
+}
+
+// TestFailTask retries a flaky task once, then gives up on it, and wants
+// both attempts counted with the latest reason kept.
+func TestFailTask(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	task := &Task{ID: NewTaskID(), Description: "run the integration suite"}
+	if err := InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ClaimNextTask(ctx, conn, "agent-1", ParallelOpts{}); err != nil {
+		t.Fatal(err)
+	}
+	retried, err := FailTask(ctx, conn, task.ID, "flaky: timed out", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried.Status != StatusPending || retried.Attempts != 1 || retried.AssignedTo != nil {
+		t.Errorf("after a retryable failure: %+v", retried)
+	}
+	if next, err := ClaimNextTask(ctx, conn, "agent-2", ParallelOpts{}); err != nil || next == nil || next.ID != task.ID {
+		t.Fatalf("retry not handed out again: %v, %v", next, err)
+	}
+	failed, err := FailTask(ctx, conn, task.ID, "the schema is wrong", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != StatusFailed || failed.Attempts != 2 || string(*failed.Result) != "the schema is wrong" {
+		t.Errorf("after giving up: %+v", failed)
+	}
+	if _, err := FailTask(ctx, conn, task.ID, "again", true); err == nil {
+		t.Error("retried a failed task without reopening it")
+	}
+}
+
+// TestReopenTask reopens a task whose dependent already started and wants
+// the dependent sent back, the result cleared and kept in the history.
+func TestReopenTask(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	migration := &Task{ID: NewTaskID(), Description: "migrate the users table"}
+	backfill := &Task{ID: NewTaskID(), Description: "backfill the emails"}
+	for _, task := range []*Task{migration, backfill} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddBlocker(ctx, conn, backfill.ID, migration.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReopenTask(ctx, conn, migration.ID, true); err == nil {
+		t.Fatal("reopened a pending task")
+	}
+	result := "added the column"
+	for _, id := range []string{migration.ID, backfill.ID} {
+		if task, err := ClaimNextTask(ctx, conn, "agent-1", ParallelOpts{}); err != nil || task == nil || task.ID != id {
+			t.Fatalf("claimed %v, %v; want %s", task, err, id)
+		}
+		if id == migration.ID {
+			if _, _, err := CompleteTask(ctx, conn, id, &result); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	task, reblocked, err := ReopenTask(ctx, conn, migration.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != StatusPending || task.CompletedAt != nil || task.Result != nil {
+		t.Errorf("reopened as %+v", task)
+	}
+	if len(reblocked) != 1 || reblocked[0].ID != backfill.ID || reblocked[0].Status != StatusPending {
+		t.Errorf("reblocked %+v, want the backfill back to pending", reblocked)
+	}
+	events, err := GetTaskEvents(ctx, conn, migration.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].OldValue == nil || !strings.Contains(*events[0].OldValue, result) {
+		t.Errorf("history lost the old result: %+v", events)
+	}
+}
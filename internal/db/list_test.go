@@ -0,0 +1,69 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// TestQueryTasksTimeAndText lists tasks by creation and update time and by
+// text in their description.
+func TestQueryTasksTimeAndText(t *testing.T) {
+	conn, ctx := newTestDB(t)
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	ctx = clock.With(ctx, fake)
+
+	add := func(description string) *Task {
+		task := &Task{ID: NewTaskID(), Description: description}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+		fake.Advance(time.Hour)
+		return task
+	}
+	add("Design the schema")
+	add("write 100% of the tests")
+	migrate := add("migrate the schema")
+	checked := fake.Now()
+	fake.Advance(time.Hour)
+	inProgress := StatusInProgress
+	if _, err := UpdateTask(ctx, conn, migrate.ID, UpdateOpts{Status: &inProgress}); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(opts ListOpts) []string {
+		t.Helper()
+		opts.OrderBy = "created_at"
+		opts.SortDir = "asc"
+		tasks, err := QueryTasks(ctx, conn, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, task := range tasks {
+			got = append(got, task.Description)
+		}
+		return got
+	}
+	for _, tc := range []struct {
+		name string
+		opts ListOpts
+		want []string
+	}{
+		{"created after", ListOpts{CreatedAfter: start}, []string{"write 100% of the tests", "migrate the schema"}},
+		{"created before", ListOpts{CreatedBefore: start.Add(2 * time.Hour)}, []string{"Design the schema", "write 100% of the tests"}},
+		{"created between", ListOpts{CreatedAfter: start, CreatedBefore: start.Add(2 * time.Hour)}, []string{"write 100% of the tests"}},
+		{"updated after", ListOpts{UpdatedAfter: checked}, []string{"migrate the schema"}},
+		{"text ignores case", ListOpts{Text: "SCHEMA"}, []string{"Design the schema", "migrate the schema"}},
+		{"text is literal", ListOpts{Text: "100%"}, []string{"write 100% of the tests"}},
+		{"text and time", ListOpts{Text: "schema", CreatedAfter: start}, []string{"migrate the schema"}},
+		{"no match", ListOpts{Text: "the_schema"}, nil},
+	} {
+		if got := list(tc.opts); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
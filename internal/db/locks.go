@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ResourceLock records that a task holds a named shared resource, such as
+// "staging-env", until it completes or fails.
+type ResourceLock struct {
+	Resource   string `db:"resource" json:"resource"`
+	TaskID     string `db:"task_id" json:"task_id"`
+	Holder     string `db:"holder" json:"holder"`
+	AcquiredAt string `db:"acquired_at" json:"acquired_at"`
+}
+
+// NormalizeResource lowercases and trims a resource name, like tags.
+func NormalizeResource(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("resource name must not be empty")
+	}
+	if len(name) > maxTagLen {
+		return "", fmt.Errorf("resource name longer than %d characters", maxTagLen)
+	}
+	return name, nil
+}
+
+// SetTaskResources replaces the resources a task needs while it is worked.
+// Locks the task already holds are left alone.
+func SetTaskResources(ctx context.Context, db *sqlx.DB, taskID string, resources []string) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if _, err := getTaskTx(ctx, tx, taskID); err != nil {
+			return err
+		}
+		return setResourcesTx(ctx, tx, taskID, resources)
+	})
+}
+
+func setResourcesTx(ctx context.Context, tx *sqlx.Tx, taskID string, resources []string) error {
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM task_resources WHERE task_id = ?"), taskID); err != nil {
+		return err
+	}
+	for _, res := range resources {
+		res, err := NormalizeResource(res)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, tx.Rebind(
+			"INSERT INTO task_resources (task_id, resource) VALUES (?, ?) ON CONFLICT DO NOTHING"), taskID, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetResourcesForTasks returns the declared resources of each task.
+func GetResourcesForTasks(ctx context.Context, db sqlx.ExtContext, ids []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT task_id, resource FROM task_resources WHERE task_id IN (?) ORDER BY resource", ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskID   string `db:"task_id"`
+		Resource string `db:"resource"`
+	}
+	if err := sqlx.SelectContext(ctx, db, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TaskID] = append(out[r.TaskID], r.Resource)
+	}
+	return out, nil
+}
+
+// acquireLocksTx takes every resource the task declares, all or none. A
+// resource held by a different task is an error naming the holder.
+func acquireLocksTx(ctx context.Context, tx *sqlx.Tx, taskID, holder string) error {
+	var held []ResourceLock
+	if err := tx.SelectContext(ctx, &held, tx.Rebind(`
+		SELECT l.* FROM resource_locks l
+		JOIN task_resources tr ON tr.resource = l.resource
+		WHERE tr.task_id = ? AND l.task_id != ?
+		ORDER BY l.resource`), taskID, taskID); err != nil {
+		return err
+	}
+	if len(held) > 0 {
+		l := held[0]
+		return fmt.Errorf("resource %s is held by %s (%s)", l.Resource, l.TaskID, l.Holder)
+	}
+	_, err := tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO resource_locks (resource, task_id, holder, acquired_at)
+		SELECT resource, task_id, ?, ? FROM task_resources WHERE task_id = ?
+		ON CONFLICT (resource) DO UPDATE SET holder = excluded.holder`),
+		holder, now(ctx), taskID)
+	return err
+}
+
+// updateLocksTx follows a status change: starting a task takes its
+// resources for the assignee (or the acting client), finishing it frees them.
+func updateLocksTx(ctx context.Context, tx *sqlx.Tx, t *Task) error {
+	switch t.Status {
+	case "in_progress":
+		holder := ActorFromContext(ctx)
+		if t.AssignedTo != nil {
+			holder = *t.AssignedTo
+		}
+		return acquireLocksTx(ctx, tx, t.ID, holder)
+	case "completed", "failed":
+		return releaseLocksTx(ctx, tx, t.ID)
+	}
+	return nil
+}
+
+func releaseLocksTx(ctx context.Context, tx *sqlx.Tx, taskID string) error {
+	_, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM resource_locks WHERE task_id = ?"), taskID)
+	return err
+}
+
+// ListLocks returns the locks currently held, by resource.
+func ListLocks(ctx context.Context, db *sqlx.DB) ([]ResourceLock, error) {
+	var locks []ResourceLock
+	err := db.SelectContext(ctx, &locks, "SELECT * FROM resource_locks ORDER BY resource")
+	return locks, err
+}
+
+// ReleaseLock frees a resource whatever task holds it, for locks left
+// behind by a worker that went away. sql.ErrNoRows if it isn't held.
+func ReleaseLock(ctx context.Context, db *sqlx.DB, resource string) (*ResourceLock, error) {
+	resource, err := NormalizeResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	var l ResourceLock
+	err = WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if err := tx.GetContext(ctx, &l, "SELECT * FROM resource_locks WHERE resource = ?", resource); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM resource_locks WHERE resource = ?", resource)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
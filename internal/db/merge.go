@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// notMerged leaves out duplicates MergeTasks archived while still open:
+// archiving otherwise only takes completed tasks, so an open archived task
+// is one that was merged away.
+const notMerged = "(archived_at IS NULL OR status NOT IN ('pending', 'in_progress'))"
+
+// MergeResult is what MergeTasks did.
+type MergeResult struct {
+	Survivor      *Task
+	Duplicate     *Task
+	ChildrenMoved int
+	BlockersMoved int
+	// CyclesDropped are the moved edges MergeTasks left out because they
+	// would have closed a blocker cycle, as they would have read.
+	CyclesDropped []BlockerEdge
+}
+
+// MergeTasks folds duplicateID into survivorID in one transaction. The
+// duplicate's subtasks move under the survivor; a survivor that is itself
+// one of them takes the duplicate's place under its parent instead. Its
+// blocker edges in both directions are taken over by the survivor,
+// dropping any that would block the survivor on itself, repeat an edge it
+// has or close a cycle, and its context is appended to the survivor's.
+// The duplicate is soft-deleted: archived, with merged_into naming the
+// survivor in its metadata, and its status left as it was. Each task's
+// audit log records the merge.
+func MergeTasks(ctx context.Context, db *sqlx.DB, survivorID, duplicateID string) (MergeResult, error) {
+	var res MergeResult
+	if survivorID == duplicateID {
+		return res, fmt.Errorf("task %s can't be merged into itself", survivorID)
+	}
+	marker, err := json.Marshal(map[string]string{"merged_into": survivorID})
+	if err != nil {
+		return res, err
+	}
+	metadata := string(marker)
+	archiveClauses, archiveArgs := updateClauses(ctx, db, UpdateOpts{Metadata: &metadata})
+	archiveClauses = append(archiveClauses, "archived_at = :now")
+	archiveArgs["id"] = duplicateID
+	archive, archiveBound, err := sqlx.Named("UPDATE tasks SET "+strings.Join(archiveClauses, ", ")+" WHERE id = :id RETURNING *", archiveArgs)
+	if err != nil {
+		return res, err
+	}
+
+	err = WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		survivor, err := getTaskTx(ctx, tx, survivorID)
+		if err != nil {
+			return err
+		}
+		dup, err := getTaskTx(ctx, tx, duplicateID)
+		if err != nil {
+			return err
+		}
+		at := now(ctx)
+
+		inside, err := isDescendantTx(ctx, tx, survivor, dup.ID)
+		if err != nil {
+			return err
+		}
+		if inside {
+			if dup.ParentID != nil {
+				if err := validateParentTx(ctx, tx, survivor.ID, *dup.ParentID); err != nil {
+					return fmt.Errorf("move survivor: %w", err)
+				}
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET parent_id = ?, updated_at = ? WHERE id = ?"),
+				dup.ParentID, at, survivor.ID); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, survivor.ID, "task", "update",
+				map[string]any{"parent_id": survivor.ParentID}, map[string]any{"parent_id": dup.ParentID}); err != nil {
+				return err
+			}
+			survivor.ParentID = dup.ParentID
+		}
+
+		var children []string
+		if err := tx.SelectContext(ctx, &children,
+			tx.Rebind("SELECT id FROM tasks WHERE parent_id = ? AND id != ? ORDER BY id"), dup.ID, survivor.ID); err != nil {
+			return err
+		}
+		for _, id := range children {
+			if err := validateParentTx(ctx, tx, id, survivor.ID); err != nil {
+				return fmt.Errorf("move subtask %s: %w", id, err)
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET parent_id = ?, updated_at = ? WHERE id = ?"),
+				survivor.ID, at, id); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, id, "task", "update",
+				map[string]any{"parent_id": dup.ID}, map[string]any{"parent_id": survivor.ID}); err != nil {
+				return err
+			}
+		}
+		res.ChildrenMoved = len(children)
+
+		var edges []BlockerEdge
+		if err := tx.SelectContext(ctx, &edges, tx.Rebind(
+			"SELECT task_id, blocked_by_id FROM task_blockers WHERE task_id = ? OR blocked_by_id = ? ORDER BY task_id, blocked_by_id"),
+			dup.ID, dup.ID); err != nil {
+			return err
+		}
+		for _, e := range edges {
+			if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM task_blockers WHERE task_id = ? AND blocked_by_id = ?"),
+				e.TaskID, e.BlockedByID); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, e.TaskID, "blocker", "delete",
+				map[string]any{"blocked_by_id": e.BlockedByID}, nil); err != nil {
+				return err
+			}
+		}
+		for _, e := range edges {
+			moved := e
+			if moved.TaskID == dup.ID {
+				moved.TaskID = survivor.ID
+			} else {
+				moved.BlockedByID = survivor.ID
+			}
+			if moved.TaskID == moved.BlockedByID {
+				continue
+			}
+			var exists bool
+			if err := tx.GetContext(ctx, &exists, tx.Rebind(
+				"SELECT EXISTS (SELECT 1 FROM task_blockers WHERE task_id = ? AND blocked_by_id = ?)"),
+				moved.TaskID, moved.BlockedByID); err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			// the blocker already waiting on the task would deadlock both
+			cycle, err := waitsOnTx(ctx, tx, moved.BlockedByID, moved.TaskID)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				res.CyclesDropped = append(res.CyclesDropped, moved)
+				continue
+			}
+			if err := addBlockerTx(ctx, tx, moved.TaskID, moved.BlockedByID); err != nil {
+				return err
+			}
+			res.BlockersMoved++
+		}
+
+		merged := survivor.Context
+		if dup.Context != "" {
+			if merged != "" {
+				merged += "\n\n"
+			}
+			merged += dup.Context
+		}
+		var after Task
+		if err := tx.GetContext(ctx, &after, tx.Rebind("UPDATE tasks SET context = ?, updated_at = ? WHERE id = ? RETURNING *"),
+			merged, at, survivor.ID); err != nil {
+			return err
+		}
+		oldValues, newValues := diffTasks(survivor, &after)
+		oldValues["merged_from"], newValues["merged_from"] = nil, dup.ID
+		if err := recordEvent(ctx, tx, survivor.ID, "task", "update", oldValues, newValues); err != nil {
+			return err
+		}
+		res.Survivor = &after
+
+		var archived Task
+		if err := tx.GetContext(ctx, &archived, tx.Rebind(archive), archiveBound...); err != nil {
+			return err
+		}
+		// an archived task is never worked on, so it holds nothing
+		if err := releaseLocksTx(ctx, tx, dup.ID); err != nil {
+			return err
+		}
+		oldValues, newValues = diffTasks(dup, &archived)
+		oldValues["archived_at"], newValues["archived_at"] = dup.ArchivedAt, archived.ArchivedAt
+		oldValues["merged_into"], newValues["merged_into"] = nil, survivor.ID
+		if err := recordEvent(ctx, tx, dup.ID, "task", "update", oldValues, newValues); err != nil {
+			return err
+		}
+		res.Duplicate = &archived
+		return nil
+	})
+	if err != nil {
+		return MergeResult{}, err
+	}
+	return res, nil
+}
+
+// isDescendantTx reports whether ancestorID is above t in its tree.
+func isDescendantTx(ctx context.Context, tx *sqlx.Tx, t *Task, ancestorID string) (bool, error) {
+	for parent := t.ParentID; parent != nil; {
+		if *parent == ancestorID {
+			return true, nil
+		}
+		p, err := getTaskTx(ctx, tx, *parent)
+		if err != nil {
+			return false, err
+		}
+		parent = p.ParentID
+	}
+	return false, nil
+}
+
+// waitsOnTx reports whether taskID is blocked by blockerID, directly or
+// through other blockers.
+func waitsOnTx(ctx context.Context, tx *sqlx.Tx, taskID, blockerID string) (bool, error) {
+	var found bool
+	// UNION drops repeats, which also ends any blocker cycle
+	err := tx.GetContext(ctx, &found, tx.Rebind(`
+		WITH RECURSIVE upstream(id) AS (
+			SELECT blocked_by_id FROM task_blockers WHERE task_id = ?
+			UNION
+			SELECT tb.blocked_by_id FROM task_blockers tb JOIN upstream u ON tb.task_id = u.id
+		)
+		SELECT EXISTS (SELECT 1 FROM upstream WHERE id = ?)`), taskID, blockerID)
+	return found, err
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestMergeTasks merges two agents' copies of the same task and wants the
+// survivor to end up with both sets of subtasks and blockers, the edge
+// between the two copies dropped, and the duplicate archived, not completed,
+// and out of the way.
+func TestMergeTasks(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	survivor := &Task{ID: NewTaskID(), Description: "add rate limiting", Context: "per API key"}
+	dup := &Task{ID: NewTaskID(), Description: "rate-limit the API", Context: "429 with Retry-After"}
+	design := &Task{ID: NewTaskID(), Description: "pick the algorithm"}
+	docs := &Task{ID: NewTaskID(), Description: "document the limits"}
+	child := &Task{ID: NewTaskID(), Description: "token bucket", ParentID: &dup.ID}
+	for _, task := range []*Task{survivor, dup, design, docs, child} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, edge := range [][2]string{{dup.ID, design.ID}, {docs.ID, dup.ID}, {docs.ID, survivor.ID}, {dup.ID, survivor.ID}} {
+		if err := AddBlocker(ctx, conn, edge[0], edge[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := MergeTasks(ctx, conn, survivor.ID, dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// dup->design moves; docs->dup repeats docs->survivor; dup->survivor would be a self-block
+	if res.ChildrenMoved != 1 || res.BlockersMoved != 1 {
+		t.Errorf("moved %d children and %d blockers, want 1 and 1", res.ChildrenMoved, res.BlockersMoved)
+	}
+	if got := string(res.Survivor.Context); got != "per API key\n\n429 with Retry-After" {
+		t.Errorf("survivor context %q", got)
+	}
+	if d := res.Duplicate; d.Status != StatusPending || d.ArchivedAt == nil || d.Metadata == nil || !strings.Contains(string(*d.Metadata), survivor.ID) {
+		t.Errorf("duplicate left as %+v", d)
+	}
+	if ready, err := GetReadyTasks(ctx, conn, ParallelOpts{}, 0); err != nil {
+		t.Fatal(err)
+	} else if slices.ContainsFunc(ready, func(r Task) bool { return r.ID == dup.ID }) {
+		t.Error("merged duplicate is still handed out")
+	}
+	moved, err := GetTask(ctx, conn, child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ParentID == nil || *moved.ParentID != survivor.ID {
+		t.Errorf("subtask parent %v, want the survivor", moved.ParentID)
+	}
+	edges, err := ListBlockerEdges(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[BlockerEdge]bool{{survivor.ID, design.ID}: true, {docs.ID, survivor.ID}: true}
+	if len(edges) != len(want) {
+		t.Errorf("edges %v, want %v", edges, want)
+	}
+	for _, e := range edges {
+		if !want[e] {
+			t.Errorf("unexpected edge %v", e)
+		}
+	}
+	if _, err := MergeTasks(ctx, conn, survivor.ID, survivor.ID); err == nil {
+		t.Error("merged a task into itself")
+	}
+}
+
+// TestMergeTasksCycle merges B into A where x waits on A and B waits on
+// x: taking over B's edge would have A wait on x, so it is dropped and
+// reported rather than deadlocking both.
+func TestMergeTasksCycle(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	a := &Task{ID: NewTaskID(), Description: "set up CI"}
+	b := &Task{ID: NewTaskID(), Description: "configure CI"}
+	x := &Task{ID: NewTaskID(), Description: "run the test suite"}
+	for _, task := range []*Task{a, b, x} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, edge := range [][2]string{{x.ID, a.ID}, {b.ID, x.ID}} {
+		if err := AddBlocker(ctx, conn, edge[0], edge[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := MergeTasks(ctx, conn, a.ID, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []BlockerEdge{{a.ID, x.ID}}; !slices.Equal(res.CyclesDropped, want) || res.BlockersMoved != 0 {
+		t.Errorf("dropped %v and moved %d, want %v and 0", res.CyclesDropped, res.BlockersMoved, want)
+	}
+	edges, err := ListBlockerEdges(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []BlockerEdge{{x.ID, a.ID}}; !slices.Equal(edges, want) {
+		t.Errorf("edges %v, want %v", edges, want)
+	}
+}
+
+// TestMergeTasksIntoSubtask keeps one of the duplicate's own subtasks:
+// it takes the duplicate's place under its parent and gets its siblings.
+func TestMergeTasksIntoSubtask(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	root := &Task{ID: NewTaskID(), Description: "ship the release"}
+	dup := &Task{ID: NewTaskID(), Description: "write release notes", ParentID: &root.ID}
+	keep := &Task{ID: NewTaskID(), Description: "draft the release notes", ParentID: &dup.ID}
+	sibling := &Task{ID: NewTaskID(), Description: "list the breaking changes", ParentID: &dup.ID}
+	for _, task := range []*Task{root, dup, keep, sibling} {
+		if err := InsertTask(ctx, conn, task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := MergeTasks(ctx, conn, keep.ID, dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res.Survivor.ParentID; p == nil || *p != root.ID {
+		t.Errorf("survivor parent %v, want %s", p, root.ID)
+	}
+	moved, err := GetTask(ctx, conn, sibling.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := moved.ParentID; p == nil || *p != keep.ID || res.ChildrenMoved != 1 {
+		t.Errorf("sibling parent %v after moving %d children, want %s and 1", p, res.ChildrenMoved, keep.ID)
+	}
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ValidateMetadata checks that raw is a JSON object, the only shape the
+// metadata column holds, and returns it compacted.
+func ValidateMetadata(raw json.RawMessage) (string, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
+		return "", fmt.Errorf("metadata must be a JSON object")
+	}
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// metadataPath turns a filter key into a json_extract path. Dots separate
+// nested keys ("ci.status" is $."ci"."status"); each segment is quoted so
+// keys can't inject path syntax.
+func metadataPath(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("metadata filter key must not be empty")
+	}
+	var b strings.Builder
+	b.WriteString("$")
+	for _, seg := range strings.Split(key, ".") {
+		if seg == "" || strings.ContainsAny(seg, `"\`) {
+			return "", fmt.Errorf("invalid metadata key: %q", key)
+		}
+		b.WriteString(`."` + seg + `"`)
+	}
+	return b.String(), nil
+}
+
+// metadataValue converts a filter value to what json_extract returns for
+// it: scalars as-is, booleans as 1/0. nil means "key absent".
+func metadataValue(key string, v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, float64, int, int64:
+		return v, nil
+	case json.Number:
+		return v.Float64()
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return nil, fmt.Errorf("metadata filter %s: value must be a string, number, boolean or null", key)
+	}
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// metricsTable is one dataset of ExportMetrics. The query's first column
+// is a timestamp the rows are ordered by and, with partition, split by day
+// on.
+type metricsTable struct {
+	name      string
+	query     string
+	partition bool
+}
+
+var metricsTables = []metricsTable{
+	{"events", `SELECT created_at, id, task_id, entity, op, actor, old_value, new_value
+		FROM task_events WHERE created_at >= ? ORDER BY created_at, id`, true},
+	{"time_entries", `SELECT started_at, id, task_id, actor, ended_at, seconds
+		FROM time_entries WHERE started_at >= ? ORDER BY started_at, id`, true},
+	{"maintenance_runs", `SELECT started_at, id, task_id, finished_at, ok, detail
+		FROM maintenance_runs WHERE started_at >= ? ORDER BY started_at, id`, true},
+	{"tasks", `SELECT created_at, id, parent_id, project_id, status, priority_label, priority_weight,
+		estimate_minutes, assigned_to, due_at, started_at, completed_at, updated_at, review_status, revision
+		FROM tasks WHERE created_at >= ? ORDER BY created_at, id`, false},
+}
+
+// ExportMetrics writes the event history, time entries, maintenance runs and
+// a task snapshot under dir as CSV with a header row, for notebooks and
+// DuckDB. Histories are split Hive-style into
+// <dataset>/day=YYYY-MM-DD/part-0.csv so readers can prune by date; the
+// snapshot is tasks/tasks.csv. Only rows from since (a stored timestamp,
+// "" for all) on are written. It returns the rows written per dataset.
+func ExportMetrics(ctx context.Context, db *sqlx.DB, dir, since string) (map[string]int, error) {
+	counts := make(map[string]int, len(metricsTables))
+	for _, t := range metricsTables {
+		n, err := exportMetricsTable(ctx, db, filepath.Join(dir, t.name), t, since)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", t.name, err)
+		}
+		counts[t.name] = n
+	}
+	return counts, nil
+}
+
+func exportMetricsTable(ctx context.Context, db *sqlx.DB, dir string, t metricsTable, since string) (int, error) {
+	rows, err := db.QueryxContext(ctx, db.Rebind(t.query), since)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		f    *os.File
+		w    *csv.Writer
+		day  string
+		n    int
+		cell = make([]string, len(columns))
+	)
+	closeFile := func() error {
+		if f == nil {
+			return nil
+		}
+		w.Flush()
+		err := w.Error()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		f = nil
+		return err
+	}
+	defer closeFile()
+	open := func(path string) error {
+		if err := closeFile(); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		var err error
+		if f, err = os.Create(path); err != nil {
+			return err
+		}
+		w = csv.NewWriter(f)
+		return w.Write(columns)
+	}
+	if !t.partition {
+		if err := open(filepath.Join(dir, filepath.Base(dir)+".csv")); err != nil {
+			return 0, err
+		}
+	}
+
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			cell[i] = metricsCell(v)
+		}
+		if d := cell[0][:min(len(cell[0]), 10)]; t.partition && (f == nil || d != day) {
+			day = d
+			if err := open(filepath.Join(dir, "day="+day, "part-0.csv")); err != nil {
+				return n, err
+			}
+		}
+		if err := w.Write(cell); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, closeFile()
+}
+
+func metricsCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
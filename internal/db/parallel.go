@@ -0,0 +1,255 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+type ParallelOpts struct {
+	Workers         int
+	ProjectID       *string
+	IncludeAssigned bool // also hand out tasks someone already holds
+}
+
+// ParallelGroup is the work suggested for one worker.
+type ParallelGroup struct {
+	Worker int
+	Tasks  []Task
+}
+
+// ReadyTasks returns pending leaf tasks whose blockers are all completed,
+// whose parent hasn't failed, whose resources no other task holds and
+// whose work windows are open now, leaving out bossman's system tasks,
+// questions, which wait for a person rather than an agent, and scratch
+// tasks, which belong to the session that made them.
+func ReadyTasks(ctx context.Context, db sqlx.ExtContext, opts ParallelOpts) ([]Task, error) {
+	query := `
+		SELECT t.* FROM tasks t
+		WHERE t.status = 'pending' AND t.kind = 'task' AND t.session_id IS NULL AND t.archived_at IS NULL
+		  AND t.id != ? AND COALESCE(t.parent_id, '') != ?
+		  AND NOT EXISTS (SELECT 1 FROM task_blockers tb JOIN tasks b ON b.id = tb.blocked_by_id
+		                  WHERE tb.task_id = t.id AND b.status != 'completed')
+		  AND NOT EXISTS (SELECT 1 FROM tasks c
+		                  WHERE c.parent_id = t.id AND c.status IN ('pending', 'in_progress') AND c.archived_at IS NULL)
+		  AND NOT EXISTS (SELECT 1 FROM tasks p WHERE p.id = t.parent_id AND p.status = 'failed')`
+	args := []any{SystemTaskID, SystemTaskID}
+	if opts.ProjectID != nil {
+		query += " AND t.project_id = ?"
+		args = append(args, *opts.ProjectID)
+	}
+	if !opts.IncludeAssigned {
+		query += " AND t.assigned_to IS NULL"
+	}
+	query += " ORDER BY t.priority_weight DESC, t.created_at, t.id"
+	var tasks []Task
+	if err := sqlx.SelectContext(ctx, db, &tasks, query, args...); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	at := clock.From(ctx).Now()
+	opens, err := NextWindowOpen(ctx, db, ids, at)
+	if err != nil {
+		return nil, err
+	}
+	ready := tasks[:0]
+	for _, t := range tasks {
+		if next, ok := opens[t.ID]; ok && (next.IsZero() || next.After(at)) {
+			continue // deferred until its window opens
+		}
+		ready = append(ready, t)
+	}
+	return ready, nil
+}
+
+// GetReadyTasks is ReadyTasks for an agent picking its next task: the
+// same order, most urgent first, cut to limit when limit > 0.
+func GetReadyTasks(ctx context.Context, db *sqlx.DB, opts ParallelOpts, limit int) ([]Task, error) {
+	tasks, err := ReadyTasks(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(tasks) > limit {
+		tasks = tasks[:limit]
+	}
+	return tasks, nil
+}
+
+// ClaimNextTask starts the most urgent ready task for assignee: in one
+// transaction it picks a task ReadyTasks offers that is unassigned or
+// already assignee's, assigns it, marks it in_progress and takes its
+// resources, so agents polling at once never get the same task. Tasks
+// whose resources another task holds, or that would put assignee over
+// capacity, are passed over for the next. It returns nil when nothing can
+// be claimed, with the *CapacityError if capacity is why.
+func ClaimNextTask(ctx context.Context, db *sqlx.DB, assignee string, opts ParallelOpts) (*Task, error) {
+	opts.IncludeAssigned = true
+	var claimed *Task
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		ready, err := ReadyTasks(ctx, tx, opts)
+		if err != nil {
+			return err
+		}
+		var full error
+		for i := range ready {
+			before := &ready[i]
+			if before.AssignedTo != nil && *before.AssignedTo != assignee {
+				continue
+			}
+			var held bool
+			if err := tx.GetContext(ctx, &held, tx.Rebind(`SELECT EXISTS (
+				SELECT 1 FROM resource_locks l JOIN task_resources tr ON tr.resource = l.resource
+				WHERE tr.task_id = ? AND l.task_id != ?)`), before.ID, before.ID); err != nil {
+				return err
+			}
+			if held {
+				continue
+			}
+			if before.AssignedTo == nil {
+				err := checkCapacityTx(ctx, tx, assignee, before)
+				var capErr *CapacityError
+				if errors.As(err, &capErr) {
+					full = cmp.Or(full, err)
+					continue
+				}
+				if err != nil {
+					return err
+				}
+			}
+			at := now(ctx)
+			var after Task
+			if err := tx.GetContext(ctx, &after, tx.Rebind(`UPDATE tasks
+				SET status = 'in_progress', assigned_to = ?, started_at = COALESCE(started_at, ?), updated_at = ?
+				WHERE id = ? RETURNING *`), assignee, at, at, before.ID); err != nil {
+				return err
+			}
+			if err := updateLocksTx(ctx, tx, &after); err != nil {
+				return err
+			}
+			oldValues, newValues := diffTasks(before, &after)
+			if err := recordEvent(ctx, tx, after.ID, "task", "update", oldValues, newValues); err != nil {
+				return err
+			}
+			claimed = &after
+			return nil
+		}
+		return full
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// SuggestParallel splits the ready tasks into at most opts.Workers groups
+// that can be worked at the same time. Ready tasks linked through the open
+// part of the dependency graph (say both block the same pending task) feed
+// the same downstream work, and ready tasks needing the same resource would
+// wait on each other's lock, so either way they land in the same group; groups
+// are then balanced by task count. Only leaves are ready, so no group holds
+// a task together with one of its ancestors.
+func SuggestParallel(ctx context.Context, db *sqlx.DB, opts ParallelOpts) ([]ParallelGroup, error) {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	ready, err := ReadyTasks(ctx, db, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(ready) == 0 {
+		return nil, nil
+	}
+
+	var edges []BlockerEdge
+	if err := db.SelectContext(ctx, &edges, `
+		SELECT tb.task_id, tb.blocked_by_id FROM task_blockers tb
+		JOIN tasks a ON a.id = tb.task_id
+		JOIN tasks b ON b.id = tb.blocked_by_id
+		WHERE a.status IN ('pending', 'in_progress') AND b.status IN ('pending', 'in_progress')`); err != nil {
+		return nil, err
+	}
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(id string) string {
+		p, ok := parent[id]
+		if !ok || p == id {
+			return id
+		}
+		root := find(p)
+		parent[id] = root
+		return root
+	}
+	union := func(x, y string) {
+		if a, b := find(x), find(y); a != b {
+			parent[a] = b
+		}
+	}
+	for _, e := range edges {
+		union(e.TaskID, e.BlockedByID)
+	}
+	// tasks needing the same resource can't run side by side either
+	ids := make([]string, len(ready))
+	for i := range ready {
+		ids[i] = ready[i].ID
+	}
+	resources, err := GetResourcesForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	first := make(map[string]string)
+	for _, id := range ids {
+		for _, res := range resources[id] {
+			if other, ok := first[res]; ok {
+				union(id, other)
+			} else {
+				first[res] = id
+			}
+		}
+	}
+
+	// components keep the ready list's priority order
+	var order []string
+	components := make(map[string][]Task)
+	for _, t := range ready {
+		root := find(t.ID)
+		if _, ok := components[root]; !ok {
+			order = append(order, root)
+		}
+		components[root] = append(components[root], t)
+	}
+	// largest first so the greedy fill stays balanced; stable keeps
+	// higher-priority components ahead among equals
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(components[order[i]]) > len(components[order[j]])
+	})
+
+	n := min(opts.Workers, len(order))
+	groups := make([]ParallelGroup, n)
+	for i := range groups {
+		groups[i].Worker = i + 1
+	}
+	for _, root := range order {
+		lightest := 0
+		for i := range groups {
+			if len(groups[i].Tasks) < len(groups[lightest].Tasks) {
+				lightest = i
+			}
+		}
+		groups[lightest].Tasks = append(groups[lightest].Tasks, components[root]...)
+	}
+	for i := range groups {
+		sort.SliceStable(groups[i].Tasks, func(a, b int) bool {
+			return groups[i].Tasks[a].PriorityWeight > groups[i].Tasks[b].PriorityWeight
+		})
+	}
+	return groups, nil
+}
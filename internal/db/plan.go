@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// SettingWeekPlan holds the current week plan as JSON.
+const SettingWeekPlan = "week_plan"
+
+// DefaultWeekCapacity is one person's week: five days of eight hours.
+const DefaultWeekCapacity = 5 * 8 * 60
+
+// DefaultPlanEstimate stands in for tasks without estimate_minutes so they
+// still use up capacity.
+const DefaultPlanEstimate = 60
+
+// WeekPlan is a draft of the tasks to take on in one week, kept until it
+// is replaced. Committing assigns them.
+type WeekPlan struct {
+	WeekStart       string     `json:"week_start"`
+	WeekEnd         string     `json:"week_end"`
+	Assignee        string     `json:"assignee,omitempty"`
+	ProjectID       *string    `json:"project_id,omitempty"`
+	CapacityMinutes int        `json:"capacity_minutes"`
+	CapacityTasks   int        `json:"capacity_tasks,omitempty"`
+	PlannedMinutes  int        `json:"planned_minutes"`
+	Items           []PlanItem `json:"items"`
+	GeneratedAt     string     `json:"generated_at"`
+	CommittedAt     string     `json:"committed_at,omitempty"`
+}
+
+// PlanItem is one planned task and why it was picked.
+type PlanItem struct {
+	TaskID          string   `json:"task_id"`
+	Description     string   `json:"description"`
+	EstimateMinutes int      `json:"estimate_minutes"`
+	Estimated       bool     `json:"estimated"` // false when DefaultPlanEstimate stood in
+	Reasons         []string `json:"reasons"`
+}
+
+// PlanOpts configures PlanWeek. WeekOf is any day of the week to plan;
+// zero means the coming week (this one on a Monday). Assignee keeps
+// tasks already assigned to them as candidates and is who CommitWeekPlan
+// assigns to. CapacityMinutes defaults to a week of the assignee's
+// declared capacity, or DefaultWeekCapacity without one; a declared task
+// limit caps the number of tasks too.
+type PlanOpts struct {
+	WeekOf          time.Time
+	Assignee        string
+	ProjectID       *string
+	CapacityMinutes int
+}
+
+// PlanWeek picks the tasks for a week; SaveWeekPlan makes the result the
+// current draft. Candidates are pending tasks without open subtasks,
+// unassigned or already the assignee's. They are ranked by priority
+// weight, plus a bonus for being due by the end of the week (more if
+// overdue) and for being at risk of missing a due date downstream, then
+// fitted into capacity in that order. A blocked task only goes in after all its open blockers, so
+// one waiting on work in progress elsewhere is left for a later week.
+func PlanWeek(ctx context.Context, db *sqlx.DB, opts PlanOpts) (WeekPlan, error) {
+	at := clock.From(ctx).Now()
+	if opts.WeekOf.IsZero() {
+		opts.WeekOf = at
+		if at.Weekday() != time.Monday {
+			opts.WeekOf = at.AddDate(0, 0, 7)
+		}
+	}
+	var capTasks int
+	if opts.Assignee != "" {
+		c, err := GetCapacity(ctx, db, opts.Assignee)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return WeekPlan{}, err
+		}
+		if opts.CapacityMinutes <= 0 {
+			opts.CapacityMinutes = c.WeekMinutes()
+		}
+		capTasks = c.WeekTasks()
+	}
+	if opts.CapacityMinutes <= 0 {
+		opts.CapacityMinutes = DefaultWeekCapacity
+	}
+	day := opts.WeekOf.UTC().Truncate(24 * time.Hour)
+	start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	end := start.AddDate(0, 0, 7)
+
+	query := `
+		SELECT t.* FROM tasks t
+		WHERE t.status = 'pending' AND t.kind = 'task' AND t.session_id IS NULL AND t.archived_at IS NULL
+		  AND t.id != ? AND COALESCE(t.parent_id, '') != ?
+		  AND NOT EXISTS (SELECT 1 FROM tasks c
+		                  WHERE c.parent_id = t.id AND c.status IN ('pending', 'in_progress') AND c.archived_at IS NULL)
+		  AND (t.assigned_to IS NULL OR t.assigned_to = ?)`
+	args := []any{SystemTaskID, SystemTaskID, opts.Assignee}
+	if opts.ProjectID != nil {
+		query += " AND t.project_id IS ?"
+		args = append(args, nullIfEmpty(*opts.ProjectID))
+	}
+	var tasks []Task
+	if err := db.SelectContext(ctx, &tasks, query, args...); err != nil {
+		return WeekPlan{}, err
+	}
+	risks, err := deadlineRisks(ctx, db)
+	if err != nil {
+		return WeekPlan{}, err
+	}
+	var edges []BlockerEdge
+	err = db.SelectContext(ctx, &edges, `
+		SELECT tb.task_id, tb.blocked_by_id FROM task_blockers tb
+		JOIN tasks b ON b.id = tb.blocked_by_id
+		WHERE b.status IN ('pending', 'in_progress')`)
+	if err != nil {
+		return WeekPlan{}, err
+	}
+	waitsOn := make(map[string][]string)
+	for _, e := range edges {
+		waitsOn[e.TaskID] = append(waitsOn[e.TaskID], e.BlockedByID)
+	}
+
+	type candidate struct {
+		task    Task
+		score   int
+		reasons []string
+	}
+	cands := make([]candidate, len(tasks))
+	for i, t := range tasks {
+		c := candidate{task: t, score: t.PriorityWeight, reasons: []string{"priority " + t.PriorityLabel}}
+		if t.DueAt != nil {
+			if due := t.DueAt.Time; due.Before(end) {
+				c.score += 50
+				if due.Before(at) {
+					c.score += 30
+					c.reasons = append(c.reasons, "overdue since "+due.Format(time.DateOnly))
+				} else {
+					c.reasons = append(c.reasons, "due "+due.Format(time.DateOnly))
+				}
+			}
+		}
+		if risks[t.ID].AtRisk {
+			c.score += 60
+			c.reasons = append(c.reasons, "at risk: start by "+risks[t.ID].LatestStart)
+		}
+		cands[i] = c
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].score != cands[j].score {
+			return cands[i].score > cands[j].score
+		}
+		return cands[i].task.CreatedAt.Before(cands[j].task.CreatedAt.Time)
+	})
+
+	plan := WeekPlan{
+		WeekStart:       FormatTime(start),
+		WeekEnd:         FormatTime(end),
+		Assignee:        opts.Assignee,
+		ProjectID:       opts.ProjectID,
+		CapacityMinutes: opts.CapacityMinutes,
+		CapacityTasks:   capTasks,
+		Items:           []PlanItem{},
+		GeneratedAt:     FormatTime(at),
+	}
+	planned := make(map[string]bool)
+	taken := make([]bool, len(cands))
+	// a pass can unblock tasks ranked above the blocker, so repeat until
+	// nothing more fits
+	for changed := true; changed; {
+		changed = false
+		for i, c := range cands {
+			if taken[i] || !blockersPlanned(waitsOn[c.task.ID], planned) {
+				continue
+			}
+			item := planItem(&c.task, c.reasons)
+			if plan.PlannedMinutes+item.EstimateMinutes > plan.CapacityMinutes ||
+				(capTasks > 0 && len(plan.Items) >= capTasks) {
+				continue
+			}
+			taken[i], planned[c.task.ID], changed = true, true, true
+			plan.Items = append(plan.Items, item)
+			plan.PlannedMinutes += item.EstimateMinutes
+		}
+	}
+	return plan, nil
+}
+
+func blockersPlanned(blockers []string, planned map[string]bool) bool {
+	for _, b := range blockers {
+		if !planned[b] {
+			return false
+		}
+	}
+	return true
+}
+
+func planItem(t *Task, reasons []string) PlanItem {
+	item := PlanItem{TaskID: t.ID, Description: t.Description, EstimateMinutes: DefaultPlanEstimate, Reasons: reasons}
+	if t.EstimateMinutes != nil {
+		item.EstimateMinutes, item.Estimated = *t.EstimateMinutes, true
+	}
+	return item
+}
+
+// GetWeekPlan returns the current plan, or sql.ErrNoRows if none was made.
+func GetWeekPlan(ctx context.Context, db *sqlx.DB) (WeekPlan, error) {
+	value, ok, err := GetSetting(ctx, db, SettingWeekPlan)
+	if err != nil {
+		return WeekPlan{}, err
+	}
+	if !ok {
+		return WeekPlan{}, sql.ErrNoRows
+	}
+	var plan WeekPlan
+	if err := json.Unmarshal([]byte(value), &plan); err != nil {
+		return WeekPlan{}, fmt.Errorf("decode week plan: %w", err)
+	}
+	return plan, nil
+}
+
+// SaveWeekPlan replaces the current plan.
+func SaveWeekPlan(ctx context.Context, db *sqlx.DB, plan *WeekPlan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	return SetSetting(ctx, db, SettingWeekPlan, string(data))
+}
+
+// EditWeekPlan drops remove from the current plan and appends add, which
+// must be open tasks. Capacity is not enforced on hand edits; compare
+// PlannedMinutes with CapacityMinutes. A committed plan can't be edited.
+func EditWeekPlan(ctx context.Context, db *sqlx.DB, add, remove []string) (WeekPlan, error) {
+	plan, err := GetWeekPlan(ctx, db)
+	if err != nil {
+		return WeekPlan{}, err
+	}
+	if plan.CommittedAt != "" {
+		return WeekPlan{}, fmt.Errorf("the week plan was committed at %s; make a new one", plan.CommittedAt)
+	}
+	drop := make(map[string]bool, len(remove))
+	for _, id := range remove {
+		drop[id] = true
+	}
+	kept := plan.Items[:0]
+	present := make(map[string]bool)
+	for _, item := range plan.Items {
+		if !drop[item.TaskID] {
+			kept = append(kept, item)
+			present[item.TaskID] = true
+		}
+	}
+	plan.Items = kept
+	for _, id := range add {
+		if present[id] {
+			continue
+		}
+		t, err := GetTask(ctx, db, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return WeekPlan{}, fmt.Errorf("task not found: %s", id)
+		}
+		if err != nil {
+			return WeekPlan{}, err
+		}
+		if t.Status != "pending" && t.Status != "in_progress" {
+			return WeekPlan{}, fmt.Errorf("task %s is %s", id, t.Status)
+		}
+		plan.Items = append(plan.Items, planItem(t, []string{"added by hand"}))
+		present[id] = true
+	}
+	plan.PlannedMinutes = 0
+	for _, item := range plan.Items {
+		plan.PlannedMinutes += item.EstimateMinutes
+	}
+	if err := SaveWeekPlan(ctx, db, &plan); err != nil {
+		return WeekPlan{}, err
+	}
+	return plan, nil
+}
+
+// CommitResult lists what CommitWeekPlan assigned and what it passed over,
+// with why.
+type CommitResult struct {
+	Assigned []string          `json:"assigned"`
+	Skipped  map[string]string `json:"skipped"`
+}
+
+// CommitWeekPlan assigns every task in the current plan to assignee (the
+// plan's own when empty) in one transaction and marks the plan committed.
+// Tasks finished, deleted or taken by someone else since planning, and
+// those that would put the assignee over capacity, are skipped rather than
+// failing the rest.
+func CommitWeekPlan(ctx context.Context, db *sqlx.DB, assignee string) (CommitResult, error) {
+	plan, err := GetWeekPlan(ctx, db)
+	if err != nil {
+		return CommitResult{}, err
+	}
+	if assignee == "" {
+		assignee = plan.Assignee
+	}
+	if assignee == "" {
+		return CommitResult{}, fmt.Errorf("no assignee: give one or plan with one")
+	}
+	res := CommitResult{Assigned: []string{}, Skipped: map[string]string{}}
+	err = WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		for _, item := range plan.Items {
+			t, err := getTaskTx(ctx, tx, item.TaskID)
+			if errors.Is(err, sql.ErrNoRows) {
+				res.Skipped[item.TaskID] = "deleted"
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case t.Status == "completed" || t.Status == "failed":
+				res.Skipped[item.TaskID] = t.Status
+				continue
+			case t.AssignedTo != nil && *t.AssignedTo != assignee:
+				res.Skipped[item.TaskID] = "assigned to " + *t.AssignedTo
+				continue
+			}
+			err = assignTaskTx(ctx, tx, item.TaskID, assignee, false)
+			var capErr *CapacityError
+			if errors.As(err, &capErr) {
+				res.Skipped[item.TaskID] = "over capacity: " + capErr.Detail
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("assign %s: %w", item.TaskID, err)
+			}
+			res.Assigned = append(res.Assigned, item.TaskID)
+		}
+		plan.Assignee, plan.CommittedAt = assignee, now(ctx)
+		data, err := json.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+			 ON CONFLICT(key) DO UPDATE SET value = excluded.value,
+			 updated_at = excluded.updated_at`,
+			SettingWeekPlan, string(data), plan.CommittedAt)
+		return err
+	})
+	return res, err
+}
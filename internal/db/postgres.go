@@ -0,0 +1,176 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresSchema covers what a Store needs: tasks, projects (for the
+// foreign key) and their priority scales, blockers, tags, resources, files
+// and locks, counters, and the audit log.
+// Timestamps stay TEXT in TimeLayout so rows scan into the same Task as
+// SQLite's; metadata is TEXT holding a JSON object, cast to jsonb to query.
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS projects (
+    id          TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_name ON projects (lower(name));
+CREATE TABLE IF NOT EXISTS tasks (
+    id          TEXT PRIMARY KEY,
+    parent_id   TEXT REFERENCES tasks(id),
+    description TEXT NOT NULL,
+    context     TEXT NOT NULL DEFAULT '',
+    priority    INTEGER NOT NULL DEFAULT 3
+        CHECK (priority BETWEEN 1 AND 5),
+    priority_label TEXT NOT NULL DEFAULT 'normal',
+    priority_weight INTEGER NOT NULL DEFAULT 50
+        CHECK (priority_weight BETWEEN 0 AND 100),
+    status      TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'in_progress', 'completed', 'failed')),
+    result      TEXT,
+    created_at  TEXT NOT NULL,
+    started_at  TEXT,
+    completed_at TEXT,
+    updated_at  TEXT NOT NULL,
+    estimate_minutes INTEGER CHECK (estimate_minutes >= 0),
+    assigned_to TEXT,
+    due_at      TEXT,
+    project_id  TEXT REFERENCES projects(id),
+    metadata    TEXT CHECK (metadata IS NULL OR jsonb_typeof(CAST(metadata AS jsonb)) = 'object'),
+    review_status TEXT CHECK (review_status IN ('requested', 'approved', 'changes_requested')),
+    reviewer    TEXT,
+    revision    INTEGER NOT NULL DEFAULT 0,
+    kind        TEXT NOT NULL DEFAULT 'task' CHECK (kind IN ('task', 'question')),
+    answer      TEXT,
+    answered_by TEXT,
+    session_id  TEXT,
+    archived_at TEXT,
+    attempts    INTEGER NOT NULL DEFAULT 0
+);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'task' CHECK (kind IN ('task', 'question'));
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS answer TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS answered_by TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS session_id TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS archived_at TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+-- databases created before priority weights: add, backfill, then tighten
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority_label TEXT;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority_weight INTEGER CHECK (priority_weight BETWEEN 0 AND 100);
+` + priorityBackfill + ` WHERE priority_label IS NULL;
+ALTER TABLE tasks ALTER COLUMN priority_label SET DEFAULT 'normal', ALTER COLUMN priority_label SET NOT NULL,
+    ALTER COLUMN priority_weight SET DEFAULT 50, ALTER COLUMN priority_weight SET NOT NULL;
+CREATE TABLE IF NOT EXISTS priority_scales (
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    label      TEXT NOT NULL,
+    weight     INTEGER NOT NULL CHECK (weight BETWEEN 0 AND 100),
+    PRIMARY KEY (project_id, label)
+);
+CREATE TABLE IF NOT EXISTS task_blockers (
+    task_id       TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    blocked_by_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocked_by_id),
+    CHECK (task_id != blocked_by_id)
+);
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    tag     TEXT NOT NULL,
+    PRIMARY KEY (task_id, tag)
+);
+CREATE TABLE IF NOT EXISTS task_resources (
+    task_id  TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    resource TEXT NOT NULL,
+    PRIMARY KEY (task_id, resource)
+);
+CREATE TABLE IF NOT EXISTS task_files (
+    task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    path    TEXT NOT NULL,
+    PRIMARY KEY (task_id, path)
+);
+CREATE TABLE IF NOT EXISTS resource_locks (
+    resource    TEXT PRIMARY KEY,
+    task_id     TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    holder      TEXT NOT NULL,
+    acquired_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS capacities (
+    assignee        TEXT PRIMARY KEY,
+    minutes_per_day INTEGER CHECK (minutes_per_day > 0),
+    tasks_per_day   INTEGER CHECK (tasks_per_day > 0),
+    updated_at      TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS schema_extensions (
+    name       TEXT PRIMARY KEY,
+    version    INTEGER NOT NULL,
+    enabled_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS counters (
+    name       TEXT PRIMARY KEY,
+    value      BIGINT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS task_events (
+    id         BIGSERIAL PRIMARY KEY,
+    task_id    TEXT NOT NULL, -- no FK: history outlives the task
+    entity     TEXT NOT NULL CHECK (entity IN ('task', 'blocker', 'tag')),
+    op         TEXT NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+    old_value  TEXT,
+    new_value  TEXT,
+    actor      TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_status_weight ON tasks(status, priority_weight);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent ON tasks(parent_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_assigned_to ON tasks(assigned_to);
+CREATE INDEX IF NOT EXISTS idx_tasks_project ON tasks(project_id);
+CREATE INDEX IF NOT EXISTS idx_task_blockers_blocked_by ON task_blockers(blocked_by_id);
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag ON task_tags(tag);
+CREATE INDEX IF NOT EXISTS idx_resource_locks_task ON resource_locks(task_id);
+CREATE INDEX IF NOT EXISTS idx_task_files_path ON task_files(path);
+CREATE INDEX IF NOT EXISTS idx_task_events_task ON task_events(task_id, id);
+`
+
+// OpenPostgres connects to a Postgres database and creates the schema if
+// needed. dsn is a lib/pq connection string or postgres:// URL.
+func OpenPostgres(dsn string) (*SQLStore, error) {
+	conn, err := connect("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), postgresSchema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+	if err := upgradeExtensions(context.Background(), conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("upgrade extensions: %w", err)
+	}
+	return &SQLStore{DB: conn}, nil
+}
+
+func isPostgres(db interface{ DriverName() string }) bool {
+	return db.DriverName() == "postgres"
+}
+
+// postgresMetadataFilter is QueryTasks' metadata condition for Postgres.
+// Like json_extract, a missing key and a JSON null both count as null.
+func postgresMetadataFilter(pathArg, valueArg string, value any, args map[string]any) string {
+	expr := fmt.Sprintf("jsonb_path_query_first(CAST(metadata AS jsonb), CAST(:%s AS jsonpath))", pathArg)
+	if value == nil {
+		return fmt.Sprintf(" AND COALESCE(%s, 'null') = 'null'", expr)
+	}
+	b, _ := json.Marshal(value) // metadataValue already vetted the type
+	args[valueArg] = string(b)
+	return fmt.Sprintf(" AND %s = CAST(:%s AS jsonb)", expr, valueArg)
+}
+
+// postgresMetadataMerge is UpdateTask's json_patch for Postgres: top-level
+// keys are merged and null values removed. Unlike RFC 7396, nested objects
+// are replaced rather than merged.
+const postgresMetadataMerge = `NULLIF(CAST(jsonb_strip_nulls(
+	COALESCE(CAST(metadata AS jsonb), '{}') || CAST(:metadata AS jsonb)) AS text), '{}')`
@@ -0,0 +1,234 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PriorityLevel is a named priority. Weights run from 0 to 100 and higher
+// weights sort first; the label is what agents and people say.
+type PriorityLevel struct {
+	Label  string `db:"label" json:"label"`
+	Weight int    `db:"weight" json:"weight"`
+}
+
+// DefaultPriorityScale applies to tasks outside a project and to projects
+// without a scale of their own. Its weights are the old 1-5 levels.
+var DefaultPriorityScale = []PriorityLevel{
+	{"critical", 100},
+	{"high", 75},
+	{"normal", 50},
+	{"low", 25},
+	{"someday", 0},
+}
+
+// DefaultPriorityWeight is the weight of a task created without a priority.
+const DefaultPriorityWeight = 50
+
+// The tasks.priority column still holds a 1-5 level derived from the
+// weight, for CalDAV, Taskwarrior and readers of older exports. These
+// convert between the two.
+func legacyPriorityWeight(level int) int { return (5 - min(max(level, 1), 5)) * 25 }
+func legacyPriorityLevel(weight int) int { return 5 - (weight+12)/25 }
+
+// priorityBackfill sets label and weight from the old 1-5 column, for
+// databases that predate weights.
+const priorityBackfill = `UPDATE tasks SET
+    priority_weight = CASE priority WHEN 1 THEN 100 WHEN 2 THEN 75 WHEN 3 THEN 50 WHEN 4 THEN 25 ELSE 0 END,
+    priority_label = CASE priority WHEN 1 THEN 'critical' WHEN 2 THEN 'high' WHEN 3 THEN 'normal' WHEN 4 THEN 'low' ELSE 'someday' END`
+
+// NormalizePriorityLabel lowercases and trims a label.
+func NormalizePriorityLabel(label string) (string, error) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" {
+		return "", fmt.Errorf("priority label is empty")
+	}
+	return label, nil
+}
+
+// GetPriorityScale returns the project's scale, highest weight first, or
+// DefaultPriorityScale when it has none or projectID is nil.
+func GetPriorityScale(ctx context.Context, db sqlx.ExtContext, projectID *string) ([]PriorityLevel, error) {
+	if projectID == nil {
+		return DefaultPriorityScale, nil
+	}
+	var levels []PriorityLevel
+	err := sqlx.SelectContext(ctx, db, &levels, db.Rebind(
+		"SELECT label, weight FROM priority_scales WHERE project_id = ? ORDER BY weight DESC, label"), *projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(levels) == 0 {
+		return DefaultPriorityScale, nil
+	}
+	return levels, nil
+}
+
+// SetPriorityScale replaces a project's scale; no levels restores the
+// default. Tasks in the project whose label is on the new scale take its
+// weight, so relabelling a level re-sorts them in one step.
+func SetPriorityScale(ctx context.Context, db *sqlx.DB, projectID string, levels []PriorityLevel) error {
+	seen := make(map[string]bool, len(levels))
+	for i := range levels {
+		label, err := NormalizePriorityLabel(levels[i].Label)
+		if err != nil {
+			return err
+		}
+		if seen[label] {
+			return fmt.Errorf("priority %q is listed twice", label)
+		}
+		seen[label] = true
+		if levels[i].Weight < 0 || levels[i].Weight > 100 {
+			return fmt.Errorf("priority %q: weight %d is outside 0-100", label, levels[i].Weight)
+		}
+		levels[i].Label = label
+	}
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM priority_scales WHERE project_id = ?"), projectID); err != nil {
+			return err
+		}
+		for _, l := range levels {
+			if _, err := tx.ExecContext(ctx, tx.Rebind(
+				"INSERT INTO priority_scales (project_id, label, weight) VALUES (?, ?, ?)"),
+				projectID, l.Label, l.Weight); err != nil {
+				return err
+			}
+		}
+		scale := levels
+		if len(scale) == 0 {
+			scale = DefaultPriorityScale
+		}
+		for _, l := range scale {
+			if _, err := tx.ExecContext(ctx, tx.Rebind(
+				`UPDATE tasks SET priority_weight = ?, priority = ?
+				  WHERE project_id = ? AND priority_label = ? AND priority_weight != ?`),
+				l.Weight, legacyPriorityLevel(l.Weight), projectID, l.Label, l.Weight); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// resolvePriority finds label on scale.
+func resolvePriority(scale []PriorityLevel, label string) (PriorityLevel, error) {
+	label, err := NormalizePriorityLabel(label)
+	if err != nil {
+		return PriorityLevel{}, err
+	}
+	for _, l := range scale {
+		if l.Label == label {
+			return l, nil
+		}
+	}
+	labels := make([]string, len(scale))
+	for i, l := range scale {
+		labels[i] = l.Label
+	}
+	return PriorityLevel{}, fmt.Errorf("unknown priority %q: want one of %s", label, strings.Join(labels, ", "))
+}
+
+// nearestPriority labels an arbitrary weight with the closest level on
+// scale, the higher one on a tie.
+func nearestPriority(scale []PriorityLevel, weight int) PriorityLevel {
+	best := slices.MinFunc(scale, func(a, b PriorityLevel) int {
+		da, db := abs(a.Weight-weight), abs(b.Weight-weight)
+		if da != db {
+			return da - db
+		}
+		return b.Weight - a.Weight
+	})
+	return PriorityLevel{Label: best.Label, Weight: weight}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// PriorityChange sets a task's priority by label, resolved on its
+// project's scale, or by exact weight, labelled with the nearest level.
+// Give one of the two.
+type PriorityChange struct {
+	Label  string
+	Weight *int
+}
+
+// applyPriorityTx fills t's label, weight and legacy level for a new row.
+// A label is looked up on the project's scale; without one, t.Priority (a
+// 1-5 level from an older caller) or else DefaultPriorityWeight gives the
+// weight and the scale the nearest label.
+func applyPriorityTx(ctx context.Context, tx *sqlx.Tx, t *Task) error {
+	projectID := t.ProjectID
+	if projectID == nil && t.ParentID != nil {
+		var parentProject *string
+		if err := tx.GetContext(ctx, &parentProject,
+			tx.Rebind("SELECT project_id FROM tasks WHERE id = ?"), *t.ParentID); err != nil {
+			return err
+		}
+		projectID = parentProject
+	}
+	scale, err := GetPriorityScale(ctx, tx, projectID)
+	if err != nil {
+		return err
+	}
+	return applyPriority(scale, t)
+}
+
+// applyPriority is applyPriorityTx once the scale is known.
+func applyPriority(scale []PriorityLevel, t *Task) error {
+	var level PriorityLevel
+	switch {
+	case t.PriorityLabel != "":
+		var err error
+		if level, err = resolvePriority(scale, t.PriorityLabel); err != nil {
+			return err
+		}
+	case t.Priority != 0:
+		level = nearestPriority(scale, legacyPriorityWeight(t.Priority))
+	default:
+		level = nearestPriority(scale, DefaultPriorityWeight)
+	}
+	t.PriorityLabel, t.PriorityWeight, t.Priority = level.Label, level.Weight, legacyPriorityLevel(level.Weight)
+	return nil
+}
+
+// fillLegacyPriority gives an imported row without a label the label and
+// weight of its 1-5 level on the default scale.
+func fillLegacyPriority(t *Task) {
+	if t.PriorityLabel != "" {
+		t.Priority = legacyPriorityLevel(t.PriorityWeight)
+		return
+	}
+	if t.Priority == 0 {
+		t.Priority = legacyPriorityLevel(DefaultPriorityWeight)
+	}
+	level := nearestPriority(DefaultPriorityScale, legacyPriorityWeight(t.Priority))
+	t.PriorityLabel, t.PriorityWeight = level.Label, level.Weight
+}
+
+// priorityChangeTx resolves change for the task after the rest of an
+// update, so a project moved in the same call supplies the scale.
+func priorityChangeTx(ctx context.Context, tx *sqlx.Tx, id string, change PriorityChange) (PriorityLevel, error) {
+	var projectID *string
+	if err := tx.GetContext(ctx, &projectID, tx.Rebind("SELECT project_id FROM tasks WHERE id = ?"), id); err != nil {
+		return PriorityLevel{}, err
+	}
+	scale, err := GetPriorityScale(ctx, tx, projectID)
+	if err != nil {
+		return PriorityLevel{}, err
+	}
+	if change.Weight != nil {
+		if *change.Weight < 0 || *change.Weight > 100 {
+			return PriorityLevel{}, fmt.Errorf("priority weight %d is outside 0-100", *change.Weight)
+		}
+		return nearestPriority(scale, *change.Weight), nil
+	}
+	return resolvePriority(scale, change.Label)
+}
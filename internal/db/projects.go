@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+// Project groups tasks belonging to one initiative. Tasks without a project
+// stay in the shared default space.
+type Project struct {
+	ID          string `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description,omitempty"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+}
+
+// ProjectSummary is a project with counts of its tasks.
+type ProjectSummary struct {
+	Project
+	OpenTasks  int `db:"open_tasks" json:"open_tasks"`
+	TotalTasks int `db:"total_tasks" json:"total_tasks"`
+}
+
+func NewProjectID() string {
+	return "project_" + xid.New().String()
+}
+
+// CreateProject assigns an ID if missing and stamps CreatedAt. Names are
+// unique, case-insensitively.
+func CreateProject(ctx context.Context, db *sqlx.DB, p *Project) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return createProjectTx(ctx, tx, p)
+	})
+}
+
+func createProjectTx(ctx context.Context, tx *sqlx.Tx, p *Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("project name must not be empty")
+	}
+	var taken bool
+	if err := tx.GetContext(ctx, &taken,
+		"SELECT EXISTS(SELECT 1 FROM projects WHERE name = ? COLLATE NOCASE)", p.Name); err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("project %q already exists", p.Name)
+	}
+	if p.ID == "" {
+		p.ID = NewProjectID()
+	}
+	p.CreatedAt = now(ctx)
+	_, err := tx.NamedExecContext(ctx,
+		`INSERT INTO projects (id, name, description, created_at) VALUES (:id, :name, :description, :created_at)`, p)
+	return err
+}
+
+// ListProjects returns every project by name with its task counts.
+func ListProjects(ctx context.Context, db *sqlx.DB) ([]ProjectSummary, error) {
+	var projects []ProjectSummary
+	err := db.SelectContext(ctx, &projects, `
+		SELECT p.*,
+		  COUNT(t.id) AS total_tasks,
+		  COUNT(CASE WHEN t.status IN ('pending', 'in_progress') AND t.archived_at IS NULL THEN 1 END) AS open_tasks
+		FROM projects p LEFT JOIN tasks t ON t.project_id = p.id
+		GROUP BY p.id
+		ORDER BY p.name COLLATE NOCASE`)
+	return projects, err
+}
+
+// GetProject looks a project up by ID, or failing that by name.
+func GetProject(ctx context.Context, db *sqlx.DB, idOrName string) (*Project, error) {
+	var p Project
+	err := db.GetContext(ctx, &p,
+		"SELECT * FROM projects WHERE id = ? OR name = ? COLLATE NOCASE ORDER BY id = ? DESC LIMIT 1",
+		idOrName, idOrName, idOrName)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ensureProjectTx returns the ID of the project called name, creating it if
+// there is none.
+func ensureProjectTx(ctx context.Context, tx *sqlx.Tx, name string) (string, error) {
+	var id string
+	err := tx.GetContext(ctx, &id, "SELECT id FROM projects WHERE name = ? COLLATE NOCASE", name)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	p := &Project{Name: name}
+	if err := createProjectTx(ctx, tx, p); err != nil {
+		return "", err
+	}
+	return p.ID, nil
+}
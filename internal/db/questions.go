@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Task kinds. A question is a decision only a person can make: agents
+// never get it as ready work, the tasks waiting on it stay blocked, and
+// it completes only when answered.
+const (
+	KindTask     = "task"
+	KindQuestion = "question"
+)
+
+// AskQuestion inserts q as a question and makes each of blocks wait for
+// its answer. A task in blocks that doesn't exist fails the whole ask with
+// sql.ErrNoRows.
+func AskQuestion(ctx context.Context, db *sqlx.DB, q *Task, blocks []string) error {
+	q.Kind = KindQuestion
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		if err := insertTaskTx(ctx, tx, q); err != nil {
+			return err
+		}
+		for _, id := range blocks {
+			if _, err := getTaskTx(ctx, tx, id); err != nil {
+				return fmt.Errorf("blocked task %s: %w", id, err)
+			}
+			if err := addBlockerTx(ctx, tx, id, q.ID); err != nil {
+				return fmt.Errorf("block %s: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// AnswerQuestion records the answer to an open question and completes it,
+// which releases the tasks waiting on it. They read the answer off their
+// blocker.
+func AnswerQuestion(ctx context.Context, db *sqlx.DB, id, answer, answeredBy string) error {
+	if answer == "" {
+		return fmt.Errorf("answer is empty")
+	}
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before.Kind != KindQuestion {
+			return fmt.Errorf("%s is not a question", id)
+		}
+		if before.Status == "completed" || before.Status == "failed" {
+			return fmt.Errorf("%s is already %s", id, before.Status)
+		}
+		at := now(ctx)
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE tasks SET answer = ?, answered_by = ?, status = 'completed',
+			  completed_at = ?, updated_at = ?
+			WHERE id = ?`,
+			answer, nullIfEmpty(answeredBy), at, at, id); err != nil {
+			return err
+		}
+		after, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		oldValues, newValues := diffTasks(before, after)
+		return recordEvent(ctx, tx, id, "task", "update", oldValues, newValues)
+	})
+}
+
+// ListQuestions returns open questions, most urgent first, or with
+// answered the answered ones, latest first.
+func ListQuestions(ctx context.Context, db *sqlx.DB, answered bool) ([]Task, error) {
+	query := `SELECT * FROM tasks WHERE kind = 'question' AND status IN ('pending', 'in_progress') AND archived_at IS NULL
+	          ORDER BY priority_weight DESC, created_at, id`
+	if answered {
+		query = `SELECT * FROM tasks WHERE kind = 'question' AND status = 'completed'
+		         ORDER BY completed_at DESC, id`
+	}
+	tasks := []Task{}
+	err := db.SelectContext(ctx, &tasks, query)
+	return tasks, err
+}
+
+// CountOpenQuestions counts the questions waiting for an answer.
+func CountOpenQuestions(ctx context.Context, db *sqlx.DB) (int, error) {
+	var n int
+	err := db.GetContext(ctx, &n,
+		"SELECT COUNT(*) FROM tasks WHERE kind = 'question' AND status IN ('pending', 'in_progress') AND archived_at IS NULL")
+	return n, err
+}
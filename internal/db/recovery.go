@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteCode returns the primary result code of a SQLite error in err's
+// chain, or 0.
+func sqliteCode(err error) int {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return 0
+	}
+	return e.Code() & 0xff // drop the extended code
+}
+
+// IsBusy reports whether err is SQLite giving up on a lock another
+// connection or process holds.
+func IsBusy(err error) bool {
+	code := sqliteCode(err)
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// IsCorrupt reports whether err says the database file is damaged or not
+// a database at all.
+func IsCorrupt(err error) bool {
+	code := sqliteCode(err)
+	return code == sqlite3.SQLITE_CORRUPT || code == sqlite3.SQLITE_NOTADB
+}
+
+// Recover tries to bring a struggling SQLite database back: it checkpoints
+// the write-ahead log, drops pooled connections so the file is reopened,
+// runs PRAGMA quick_check and finally takes the write lock to prove writes
+// work again. It returns the first step that failed.
+func Recover(ctx context.Context, db *sqlx.DB) error {
+	// Busy is expected here; the checkpoint is a best effort.
+	db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+
+	var files []struct {
+		Seq  int    `db:"seq"`
+		Name string `db:"name"`
+		File string `db:"file"`
+	}
+	if err := db.SelectContext(ctx, &files, "PRAGMA database_list"); err != nil {
+		return fmt.Errorf("list databases: %w", err)
+	}
+	// an in-memory database dies with its last connection
+	if len(files) > 0 && files[0].File != "" {
+		db.SetMaxIdleConns(0)
+		db.SetMaxIdleConns(2) // database/sql's default
+	}
+
+	var problems []string
+	if err := db.SelectContext(ctx, &problems, "PRAGMA quick_check"); err != nil {
+		return fmt.Errorf("quick check: %w", err)
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		return fmt.Errorf("quick check: %s", strings.Join(problems, "; "))
+	}
+
+	if err := WithTx(ctx, db, func(*sqlx.Tx) error { return nil }); err != nil {
+		return fmt.Errorf("take write lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,401 @@
+package db
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// taskColumns maps each stored column to its index in Task; columnOrder
+// keeps struct order for the default CSV header.
+var taskColumns, columnOrder = func() (map[string]int, []string) {
+	index := make(map[string]int)
+	var order []string
+	t := reflect.TypeOf(Task{})
+	for i := range t.NumField() {
+		if name := t.Field(i).Tag.Get("db"); name != "" && name != "-" {
+			index[name] = i
+			order = append(order, name)
+		}
+	}
+	return index, order
+}()
+
+// ReportColumns returns the columns WriteTasksCSV accepts, in default order:
+// the stored task columns plus "tags".
+func ReportColumns() []string {
+	return append(append([]string{}, columnOrder...), "tags")
+}
+
+// ValidateReportColumns rejects names WriteTasksCSV doesn't know.
+func ValidateReportColumns(columns []string) error {
+	for _, c := range columns {
+		if _, ok := taskColumns[c]; !ok && c != "tags" {
+			return fmt.Errorf("unknown column: %s", c)
+		}
+	}
+	return nil
+}
+
+// WriteTasksCSV runs a task query and writes the rows as CSV with a header
+// line, returning how many tasks were written. No columns means all of
+// ReportColumns. Empty values are blank cells, metadata is its JSON text
+// and tags are joined with ";".
+func WriteTasksCSV(ctx context.Context, s TaskStore, w io.Writer, opts ListOpts, columns []string) (int, error) {
+	if len(columns) == 0 {
+		columns = ReportColumns()
+	}
+	if err := ValidateReportColumns(columns); err != nil {
+		return 0, err
+	}
+	tasks, err := s.QueryTasks(ctx, opts)
+	if err != nil {
+		return 0, err
+	}
+	var tags map[string][]string
+	for _, c := range columns {
+		if c == "tags" {
+			ids := make([]string, len(tasks))
+			for i := range tasks {
+				ids[i] = tasks[i].ID
+			}
+			if tags, err = s.TagsForTasks(ctx, ids); err != nil {
+				return 0, err
+			}
+			break
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return 0, err
+	}
+	record := make([]string, len(columns))
+	for _, t := range tasks {
+		v := reflect.ValueOf(t)
+		for i, c := range columns {
+			if c == "tags" {
+				record[i] = strings.Join(tags[t.ID], ";")
+				continue
+			}
+			record[i] = csvCell(v.Field(taskColumns[c]))
+		}
+		if err := cw.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	cw.Flush()
+	return len(tasks), cw.Error()
+}
+
+func csvCell(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// ReportOpts configures GenerateReport. Since is a stored-format time;
+// GroupBy is "project" (default) or "tag".
+type ReportOpts struct {
+	Since     string
+	ProjectID *string
+	GroupBy   string
+}
+
+// reportSection is one heading's worth of tasks in a status report.
+type reportSection struct {
+	Completed, InProgress, Blocked []Task
+}
+
+// GenerateReport writes a Markdown digest for standups: open tasks at
+// risk of missing a due date, then tasks completed since opts.Since, work
+// in progress with its age, and open tasks waiting on blockers (named),
+// under one heading per project or tag. A task with several tags shows
+// under each. System tasks are left out.
+func GenerateReport(ctx context.Context, db *sqlx.DB, w io.Writer, opts ReportOpts) error {
+	if opts.GroupBy == "" {
+		opts.GroupBy = "project"
+	}
+	if opts.GroupBy != "project" && opts.GroupBy != "tag" {
+		return fmt.Errorf("invalid group_by: %s (want project or tag)", opts.GroupBy)
+	}
+
+	// completion time falls back to the last update for rows that predate
+	// completed_at being kept
+	query := `
+		SELECT t.* FROM tasks t
+		WHERE t.id != ? AND COALESCE(t.parent_id, '') != ?
+		  AND ((t.status = 'completed' AND COALESCE(t.completed_at, t.updated_at) >= ?)
+		       OR (t.status = 'in_progress' AND t.archived_at IS NULL)
+		       OR (t.status = 'pending' AND EXISTS (
+		             SELECT 1 FROM task_blockers tb JOIN tasks b ON b.id = tb.blocked_by_id
+		             WHERE tb.task_id = t.id AND b.status IN ('pending', 'in_progress'))))`
+	args := []any{SystemTaskID, SystemTaskID, opts.Since}
+	if opts.ProjectID != nil {
+		query += " AND t.project_id IS ?"
+		args = append(args, nullIfEmpty(*opts.ProjectID))
+	}
+	query += " ORDER BY t.priority_weight DESC, t.updated_at DESC, t.id"
+	var tasks []Task
+	if err := db.SelectContext(ctx, &tasks, query, args...); err != nil {
+		return fmt.Errorf("load tasks: %w", err)
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	var blockers []struct {
+		TaskID      string `db:"task_id"`
+		ID          string `db:"id"`
+		Description string `db:"description"`
+	}
+	if len(ids) > 0 {
+		q, a, err := sqlx.In(`
+			SELECT tb.task_id, b.id, b.description FROM task_blockers tb
+			JOIN tasks b ON b.id = tb.blocked_by_id
+			WHERE tb.task_id IN (?) AND b.status IN ('pending', 'in_progress')
+			ORDER BY b.priority_weight DESC, b.id`, ids)
+		if err != nil {
+			return err
+		}
+		if err := db.SelectContext(ctx, &blockers, q, a...); err != nil {
+			return fmt.Errorf("load blockers: %w", err)
+		}
+	}
+	waitingOn := make(map[string][]string)
+	for _, b := range blockers {
+		waitingOn[b.TaskID] = append(waitingOn[b.TaskID], fmt.Sprintf("%s (`%s`)", b.Description, b.ID))
+	}
+
+	// the heading(s) each task goes under; "" sorts last as the catch-all
+	groupsOf := func(t Task) []string { return []string{""} }
+	switch opts.GroupBy {
+	case "project":
+		projects, err := ListProjects(ctx, db)
+		if err != nil {
+			return fmt.Errorf("load projects: %w", err)
+		}
+		names := make(map[string]string, len(projects))
+		for _, p := range projects {
+			names[p.ID] = p.Name
+		}
+		groupsOf = func(t Task) []string {
+			if t.ProjectID == nil {
+				return []string{""}
+			}
+			return []string{names[*t.ProjectID]}
+		}
+	case "tag":
+		tags, err := GetTagsForTasks(ctx, db, ids)
+		if err != nil {
+			return fmt.Errorf("load tags: %w", err)
+		}
+		groupsOf = func(t Task) []string {
+			if len(tags[t.ID]) == 0 {
+				return []string{""}
+			}
+			return tags[t.ID]
+		}
+	}
+	sections := make(map[string]*reportSection)
+	for _, t := range tasks {
+		for _, g := range groupsOf(t) {
+			s := sections[g]
+			if s == nil {
+				s = &reportSection{}
+				sections[g] = s
+			}
+			switch t.Status {
+			case "completed":
+				s.Completed = append(s.Completed, t)
+			case "in_progress":
+				s.InProgress = append(s.InProgress, t)
+			}
+			if len(waitingOn[t.ID]) > 0 {
+				s.Blocked = append(s.Blocked, t)
+			}
+		}
+	}
+
+	at := clock.From(ctx).Now()
+	var b strings.Builder
+	b.WriteString("# Status report\n\n")
+	fmt.Fprintf(&b, "_Generated %s, covering work completed since %s._\n",
+		at.UTC().Format(reportLayout), reportTime(opts.Since))
+	if len(sections) == 0 {
+		b.WriteString("\nNothing completed, in progress or blocked.\n")
+	}
+	if err := writeAtRisk(ctx, db, &b, opts.ProjectID); err != nil {
+		return err
+	}
+	if err := writeUtilization(ctx, db, &b); err != nil {
+		return err
+	}
+	groups := sortedKeys(sections)
+	if len(groups) > 0 && groups[0] == "" {
+		groups = append(groups[1:], "") // catch-all last
+	}
+	for _, g := range groups {
+		s := sections[g]
+		heading := g
+		switch {
+		case g == "" && opts.GroupBy == "tag":
+			heading = "Untagged"
+		case g == "":
+			heading = "No project"
+		}
+		fmt.Fprintf(&b, "\n## %s\n", heading)
+		if len(s.Completed) > 0 {
+			fmt.Fprintf(&b, "\n### Completed (%d)\n\n", len(s.Completed))
+			for _, t := range s.Completed {
+				fmt.Fprintf(&b, "- %s (`%s`)", t.Description, t.ID)
+				if t.Result != nil && *t.Result != "" {
+					fmt.Fprintf(&b, ": %s", firstLine(string(*t.Result)))
+				}
+				b.WriteString("\n")
+			}
+		}
+		if len(s.InProgress) > 0 {
+			fmt.Fprintf(&b, "\n### In progress (%d)\n\n", len(s.InProgress))
+			for _, t := range s.InProgress {
+				fmt.Fprintf(&b, "- %s (`%s`), %s", t.Description, t.ID, reportAge(t.TimeInStatus(at)))
+				if t.AssignedTo != nil {
+					fmt.Fprintf(&b, ", %s", *t.AssignedTo)
+				}
+				b.WriteString("\n")
+			}
+		}
+		if len(s.Blocked) > 0 {
+			fmt.Fprintf(&b, "\n### Blocked (%d)\n\n", len(s.Blocked))
+			for _, t := range s.Blocked {
+				fmt.Fprintf(&b, "- %s (`%s`), waiting on %s\n", t.Description, t.ID, strings.Join(waitingOn[t.ID], ", "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeAtRisk lists open tasks that can no longer make a due date
+// downstream of them, each with when it should have started.
+func writeAtRisk(ctx context.Context, db *sqlx.DB, b *strings.Builder, projectID *string) error {
+	tasks, risks, err := ListAtRiskTasks(ctx, db)
+	if err != nil {
+		return fmt.Errorf("load deadlines: %w", err)
+	}
+	if projectID != nil {
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if (t.ProjectID == nil && *projectID == "") || (t.ProjectID != nil && *t.ProjectID == *projectID) {
+				kept = append(kept, t)
+			}
+		}
+		tasks = kept
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	fmt.Fprintf(b, "\n## At risk (%d)\n\n", len(tasks))
+	for _, t := range tasks {
+		r := risks[t.ID]
+		fmt.Fprintf(b, "- %s (`%s`): start by %s, finish by %s", t.Description, t.ID,
+			reportTime(r.LatestStart), reportTime(r.ImpliedDeadline))
+		if r.DueTask != t.ID {
+			fmt.Fprintf(b, " for `%s`", r.DueTask)
+		}
+		b.WriteString("\n")
+	}
+	return nil
+}
+
+// writeUtilization shows each assignee's open work against a week of their
+// declared capacity. Capacity is per person, so this ignores the project
+// filter.
+func writeUtilization(ctx context.Context, db *sqlx.DB, b *strings.Builder) error {
+	usage, err := GetUtilization(ctx, db)
+	if err != nil {
+		return fmt.Errorf("load utilization: %w", err)
+	}
+	if len(usage) == 0 {
+		return nil
+	}
+	b.WriteString("\n## Utilization\n\n")
+	for _, u := range usage {
+		fmt.Fprintf(b, "- %s: %d open tasks, %s", u.Assignee, u.Tasks, reportMinutes(u.Minutes))
+		switch {
+		case u.CapacityMinutes == 0 && u.CapacityTasks == 0:
+			b.WriteString(", no capacity declared\n")
+			continue
+		case u.CapacityMinutes > 0 && u.CapacityTasks > 0:
+			fmt.Fprintf(b, " of %s and %d tasks a week", reportMinutes(u.CapacityMinutes), u.CapacityTasks)
+		case u.CapacityMinutes > 0:
+			fmt.Fprintf(b, " of %s a week", reportMinutes(u.CapacityMinutes))
+		default:
+			fmt.Fprintf(b, " of %d tasks a week", u.CapacityTasks)
+		}
+		fmt.Fprintf(b, " (%d%%)", u.Percent)
+		if u.Percent > 100 {
+			b.WriteString(", over capacity")
+		}
+		b.WriteString("\n")
+	}
+	return nil
+}
+
+func reportMinutes(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+const reportLayout = "2006-01-02 15:04 UTC"
+
+func reportTime(stored string) string {
+	t, err := time.Parse(time.RFC3339Nano, stored)
+	if err != nil {
+		return stored
+	}
+	return t.UTC().Format(reportLayout)
+}
+
+// reportAge renders d, to the minute.
+func reportAge(d time.Duration) string {
+	d = d.Round(time.Minute)
+	switch {
+	case d < time.Minute:
+		return "just started"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh %dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd %dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
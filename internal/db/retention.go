@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// RetentionPolicy says how long finished tasks are kept before Purge
+// deletes them. A zero duration keeps that status forever.
+type RetentionPolicy struct {
+	Completed time.Duration // after completion
+	Failed    time.Duration // after the task last changed
+}
+
+// DefaultRetention is what `bossman gc` applies when not told otherwise.
+var DefaultRetention = RetentionPolicy{Completed: 90 * 24 * time.Hour}
+
+// Enabled reports whether the policy purges anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.Completed > 0 || p.Failed > 0
+}
+
+// PurgeResult is what Purge deleted, or with DryRun would have.
+type PurgeResult struct {
+	DryRun   bool     `json:"dry_run"`
+	Tasks    []string `json:"tasks"` // leaves first
+	Comments int64    `json:"comments"`
+	Events   int64    `json:"events"`
+	Changes  int64    `json:"changes"`
+}
+
+// errDryRun rolls a dry run's transaction back once it has counted.
+var errDryRun = errors.New("dry run")
+
+// Purge deletes the tasks policy no longer keeps, with their comments,
+// time entries and audit events, the events of tasks deleted before the
+// Completed cutoff, and changes feed rows older than the earlier cutoff. As with ArchiveCompleted a task goes only with its
+// whole subtree. A task still blocking open work stays, so that work
+// doesn't start early, and system and scratch tasks are left alone. With
+// dryRun it deletes the same rows inside a transaction it then rolls back,
+// so the result is exactly what a real run would delete.
+func Purge(ctx context.Context, db *sqlx.DB, policy RetentionPolicy, dryRun bool) (PurgeResult, error) {
+	res := PurgeResult{DryRun: dryRun, Tasks: []string{}}
+	if !policy.Enabled() {
+		return res, nil
+	}
+	at := clock.From(ctx).Now()
+	var cond string
+	args := map[string]any{"system": SystemTaskID}
+	if policy.Completed > 0 {
+		cond = "(status = 'completed' AND completed_at < :completed)"
+		args["completed"] = FormatTime(at.Add(-policy.Completed))
+	}
+	if policy.Failed > 0 {
+		if cond != "" {
+			cond += " OR "
+		}
+		cond += "(status = 'failed' AND updated_at < :failed)"
+		args["failed"] = FormatTime(at.Add(-policy.Failed))
+	}
+	leaves := `SELECT id FROM tasks WHERE (` + cond + `)
+		  AND session_id IS NULL AND id != :system AND (parent_id IS NULL OR parent_id != :system)
+		  AND NOT EXISTS (SELECT 1 FROM tasks c WHERE c.parent_id = tasks.id)
+		  AND NOT EXISTS (SELECT 1 FROM task_blockers b JOIN tasks w ON w.id = b.task_id
+		                  WHERE b.blocked_by_id = tasks.id AND w.status IN ('pending', 'in_progress'))
+		ORDER BY id`
+
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		query, bound, err := tx.BindNamed(leaves, args)
+		if err != nil {
+			return err
+		}
+		// each pass deletes the purgeable tasks without subtasks, so a
+		// subtree goes leaves first, one level per pass
+		for {
+			var ids []string
+			if err := tx.SelectContext(ctx, &ids, query, bound...); err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				break
+			}
+			for chunk := range slices.Chunk(ids, insertBatch) {
+				if err := purgeTasksTx(ctx, tx, chunk, &res); err != nil {
+					return err
+				}
+			}
+			res.Tasks = append(res.Tasks, ids...)
+		}
+
+		if policy.Completed > 0 {
+			r, err := tx.ExecContext(ctx, tx.Rebind(`DELETE FROM task_events
+				WHERE created_at < ? AND NOT EXISTS (SELECT 1 FROM tasks t WHERE t.id = task_events.task_id)`),
+				args["completed"])
+			if err != nil {
+				return err
+			}
+			n, _ := r.RowsAffected()
+			res.Events += n
+		}
+		// last, since the delete triggers above feed it too
+		if !isPostgres(db) {
+			cutoff := policy.Completed
+			if policy.Failed > cutoff {
+				cutoff = policy.Failed
+			}
+			r, err := tx.ExecContext(ctx, "DELETE FROM changes WHERE created_at < ?", FormatTime(at.Add(-cutoff)))
+			if err != nil {
+				return err
+			}
+			res.Changes, _ = r.RowsAffected()
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return PurgeResult{}, err
+	}
+	return res, nil
+}
+
+// purgeTasksTx deletes tasks none of which has subtasks left, counting
+// what goes with them into res. Comments and time entries cascade.
+func purgeTasksTx(ctx context.Context, tx *sqlx.Tx, ids []string, res *PurgeResult) error {
+	query, args, err := sqlx.In("SELECT COUNT(*) FROM task_comments WHERE task_id IN (?)", ids)
+	if err != nil {
+		return err
+	}
+	var comments int64
+	if err := tx.GetContext(ctx, &comments, tx.Rebind(query), args...); err != nil {
+		return err
+	}
+	res.Comments += comments
+
+	query, args, err = sqlx.In("DELETE FROM task_events WHERE task_id IN (?)", ids)
+	if err != nil {
+		return err
+	}
+	r, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	events, _ := r.RowsAffected()
+	res.Events += events
+
+	query, args, err = sqlx.In("DELETE FROM tasks WHERE id IN (?)", ids)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
+	return err
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Review states, kept apart from status so a task stays completed while it
+// waits for a reviewer.
+const (
+	ReviewRequested        = "requested"
+	ReviewApproved         = "approved"
+	ReviewChangesRequested = "changes_requested"
+)
+
+// RequestReview queues a completed task for review. reviewer may be empty
+// to leave it to whoever picks up the queue.
+func RequestReview(ctx context.Context, db *sqlx.DB, id, reviewer string) error {
+	return reviewTx(ctx, db, id, "", nil, func(tx *sqlx.Tx, before *Task) error {
+		if before.Status != "completed" {
+			return fmt.Errorf("%s is %s; only completed tasks can be reviewed", id, before.Status)
+		}
+		_, err := tx.ExecContext(ctx,
+			"UPDATE tasks SET review_status = ?, reviewer = ?, updated_at = ? WHERE id = ?",
+			ReviewRequested, nullIfEmpty(reviewer), now(ctx), id)
+		return err
+	})
+}
+
+// ApproveTask signs off a task waiting for review. comment is optional.
+func ApproveTask(ctx context.Context, db *sqlx.DB, id, reviewer, comment string) error {
+	var note *Comment
+	if comment != "" {
+		note = &Comment{TaskID: id, Author: reviewer, Body: comment}
+	}
+	return reviewTx(ctx, db, id, ReviewRequested, note, func(tx *sqlx.Tx, before *Task) error {
+		_, err := tx.ExecContext(ctx,
+			"UPDATE tasks SET review_status = ?, reviewer = ?, updated_at = ? WHERE id = ?",
+			ReviewApproved, reviewer, now(ctx), id)
+		return err
+	})
+}
+
+// SendBack rejects a task waiting for review: it returns to pending with
+// the reviewer's comment, and its revision goes up by one. Completing it
+// again re-queues it for review.
+func SendBack(ctx context.Context, db *sqlx.DB, id, reviewer, comment string) error {
+	if comment == "" {
+		return fmt.Errorf("sending a task back needs a comment saying what to change")
+	}
+	note := &Comment{TaskID: id, Author: reviewer, Body: comment}
+	return reviewTx(ctx, db, id, ReviewRequested, note, func(tx *sqlx.Tx, before *Task) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE tasks SET status = 'pending', completed_at = NULL,
+			  review_status = ?, reviewer = ?, revision = revision + 1, updated_at = ?
+			WHERE id = ?`,
+			ReviewChangesRequested, reviewer, now(ctx), id)
+		return err
+	})
+}
+
+// reviewTx runs one review transition: it checks the task is in the want
+// review state (any, if empty), applies update, adds the comment if any and
+// records the change in the audit log.
+func reviewTx(ctx context.Context, db *sqlx.DB, id, want string, note *Comment,
+	update func(tx *sqlx.Tx, before *Task) error) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		before, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if want != "" && (before.ReviewStatus == nil || *before.ReviewStatus != want) {
+			return fmt.Errorf("%s is not waiting for review", id)
+		}
+		if err := update(tx, before); err != nil {
+			return err
+		}
+		if note != nil {
+			if err := insertComment(ctx, tx, note); err != nil {
+				return err
+			}
+		}
+		after, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		oldValues, newValues := diffTasks(before, after)
+		return recordEvent(ctx, tx, id, "task", "update", oldValues, newValues)
+	})
+}
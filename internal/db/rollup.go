@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// subtreeCTE pairs each task in the IN (?) list (root) with itself and
+// every descendant (id), for rollups over whole subtrees.
+const subtreeCTE = `
+	WITH RECURSIVE subtree(root, id) AS (
+		SELECT id, id FROM tasks WHERE id IN (?)
+		UNION ALL
+		SELECT s.root, t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+	)`
+
+// EstimateRollup sums estimate_minutes over a task and its descendants.
+// Remaining leaves out completed and failed tasks.
+type EstimateRollup struct {
+	Total     int `db:"total"`
+	Remaining int `db:"remaining"`
+}
+
+// GetEstimateRollups returns rollups for each task that has an estimate
+// anywhere in its subtree; others are absent.
+func GetEstimateRollups(ctx context.Context, db *sqlx.DB, ids []string) (map[string]EstimateRollup, error) {
+	out := make(map[string]EstimateRollup, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(subtreeCTE+`
+		SELECT s.root AS task_id,
+		       SUM(t.estimate_minutes) AS total,
+		       COALESCE(SUM(CASE WHEN t.status IN ('completed', 'failed') THEN 0 ELSE t.estimate_minutes END), 0) AS remaining
+		FROM subtree s JOIN tasks t ON t.id = s.id
+		WHERE t.estimate_minutes IS NOT NULL
+		GROUP BY s.root`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskID string `db:"task_id"`
+		EstimateRollup
+	}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TaskID] = r.EstimateRollup
+	}
+	return out, nil
+}
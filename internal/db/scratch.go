@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Scratch tasks are an agent's own micro-steps. They carry the ID of the
+// MCP session that made them (Task.SessionID), and subtasks of a scratch
+// task are scratch too. Other sessions' listings and ready work leave them
+// out, exports skip them, and they're dropped when their session ends
+// unless PromoteTask made them part of the shared backlog first.
+
+// ScratchIdle is how long a session's scratch tasks may go untouched before
+// SweepScratch takes them for the leftovers of a process that died
+// without ending its sessions.
+const ScratchIdle = 24 * time.Hour
+
+// PromoteTask turns a scratch task, with its scratch subtasks, into
+// ordinary shared tasks. A scratch task under a scratch parent can't be
+// promoted alone.
+func PromoteTask(ctx context.Context, db *sqlx.DB, id string) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		task, err := getTaskTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if task.SessionID == nil {
+			return fmt.Errorf("%s is not a scratch task", id)
+		}
+		if task.ParentID != nil {
+			parent, err := getTaskTx(ctx, tx, *task.ParentID)
+			if err != nil {
+				return err
+			}
+			if parent.SessionID != nil {
+				return fmt.Errorf("the parent of %s, %s, is a scratch task; promote it instead", id, parent.ID)
+			}
+		}
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, tx.Rebind(`
+			WITH RECURSIVE tree(id) AS (
+				SELECT id FROM tasks WHERE id = ?
+				UNION ALL
+				SELECT t.id FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.session_id = ?
+			)
+			SELECT id FROM tree ORDER BY id`), id, *task.SessionID); err != nil {
+			return err
+		}
+		at := now(ctx)
+		for _, taskID := range ids {
+			before, err := getTaskTx(ctx, tx, taskID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET session_id = NULL, updated_at = ? WHERE id = ?"),
+				at, taskID); err != nil {
+				return err
+			}
+			after, err := getTaskTx(ctx, tx, taskID)
+			if err != nil {
+				return err
+			}
+			oldValues, newValues := diffTasks(before, after)
+			if err := recordEvent(ctx, tx, taskID, "task", "update", oldValues, newValues); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DropSessionTasks deletes the scratch tasks of session, deepest first,
+// and returns how many there were. A shared task that was moved under one
+// of them is kept and loses its parent instead. The audit log keeps what
+// they were.
+func DropSessionTasks(ctx context.Context, db *sqlx.DB, session string) (int, error) {
+	var dropped int
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var ids []string
+		if err := tx.SelectContext(ctx, &ids, tx.Rebind(`
+			WITH RECURSIVE tree(id, depth) AS (
+				SELECT id, 0 FROM tasks
+				WHERE session_id = ?
+				  AND (parent_id IS NULL OR parent_id NOT IN (SELECT id FROM tasks WHERE session_id = ?))
+				UNION ALL
+				SELECT t.id, tree.depth + 1 FROM tasks t JOIN tree ON t.parent_id = tree.id WHERE t.session_id = ?
+			)
+			SELECT id FROM tree ORDER BY depth DESC, id`), session, session, session); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		query, args, err := sqlx.In(`SELECT id FROM tasks WHERE parent_id IN (?) AND (session_id IS NULL OR session_id != ?)`, ids, session)
+		if err != nil {
+			return err
+		}
+		var kept []string
+		if err := tx.SelectContext(ctx, &kept, tx.Rebind(query), args...); err != nil {
+			return err
+		}
+		for _, child := range kept {
+			before, err := getTaskTx(ctx, tx, child)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("UPDATE tasks SET parent_id = NULL, updated_at = ? WHERE id = ?"),
+				now(ctx), child); err != nil {
+				return err
+			}
+			after, err := getTaskTx(ctx, tx, child)
+			if err != nil {
+				return err
+			}
+			oldValues, newValues := diffTasks(before, after)
+			if err := recordEvent(ctx, tx, child, "task", "update", oldValues, newValues); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range ids {
+			before, err := getTaskTx(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM tasks WHERE id = ?"), id); err != nil {
+				return err
+			}
+			if err := recordEvent(ctx, tx, id, "task", "delete", taskValues(before), nil); err != nil {
+				return err
+			}
+		}
+		dropped = len(ids)
+		return nil
+	})
+	return dropped, err
+}
+
+// SweepScratch drops the scratch tasks of every session whose tasks have
+// all gone untouched for idle, and returns how many it dropped.
+func SweepScratch(ctx context.Context, db *sqlx.DB, idle time.Duration) (int, error) {
+	cutoff := FormatTime(clock.From(ctx).Now().Add(-idle))
+	var sessions []string
+	if err := db.SelectContext(ctx, &sessions, db.Rebind(`
+		SELECT session_id FROM tasks WHERE session_id IS NOT NULL
+		GROUP BY session_id HAVING MAX(updated_at) < ?`), cutoff); err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, s := range sessions {
+		n, err := DropSessionTasks(ctx, db, s)
+		if err != nil {
+			return total, fmt.Errorf("drop scratch tasks of %s: %w", s, err)
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type SearchOpts struct {
+	Status *string
+	Limit  int
+}
+
+// SearchHit is a task matching a full-text query. Lower Rank is better
+// (bm25); Snippet is the best-matching fragment with hits in [brackets].
+type SearchHit struct {
+	Task
+	Rank    float64 `db:"rank"`
+	Snippet string  `db:"snippet"`
+}
+
+// SearchTasks matches description, context and result through the FTS5
+// index. Each whitespace-separated word must appear, as a word or prefix.
+func SearchTasks(ctx context.Context, db *sqlx.DB, query string, opts SearchOpts) ([]SearchHit, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	q := `SELECT t.*, bm25(tasks_fts) AS rank,
+	             snippet(tasks_fts, -1, '[', ']', '…', 12) AS snippet
+	      FROM tasks_fts JOIN tasks t ON t.rowid = tasks_fts.rowid
+	      WHERE tasks_fts MATCH ?`
+	args := []any{match}
+	if opts.Status != nil {
+		q += " AND t.status = ?"
+		args = append(args, *opts.Status)
+	}
+	q += " ORDER BY rank"
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	q += " LIMIT ?"
+	args = append(args, opts.Limit)
+
+	var hits []SearchHit
+	err := db.SelectContext(ctx, &hits, q, args...)
+	return hits, err
+}
+
+// ftsQuery turns free text into an FTS5 query: every word quoted (so
+// punctuation can't be read as syntax) and prefix-matched.
+func ftsQuery(text string) string {
+	var terms []string
+	for _, w := range strings.Fields(text) {
+		w = strings.ReplaceAll(w, `"`, "")
+		if w != "" {
+			terms = append(terms, `"`+w+`"*`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// Kinds of entity SearchAll returns.
+const (
+	HitTask       = "task"
+	HitComment    = "comment"
+	HitAttachment = "attachment"
+	HitProject    = "project"
+)
+
+var hitKinds = []string{HitTask, HitComment, HitAttachment, HitProject}
+
+type GlobalSearchOpts struct {
+	Kinds []string // default: all
+	Limit int
+}
+
+// GlobalHit is one match from SearchAll. TaskID is the task a comment or
+// attachment belongs to; Title names the entity (task description, project
+// or file name, comment author). Lower Rank is better.
+type GlobalHit struct {
+	Kind    string  `db:"kind" json:"kind"`
+	ID      string  `db:"id" json:"id"`
+	TaskID  string  `db:"task_id" json:"task_id,omitempty"`
+	Title   string  `db:"title" json:"title"`
+	Snippet string  `db:"snippet" json:"snippet,omitempty"`
+	Rank    float64 `db:"rank" json:"rank"`
+}
+
+// SearchAll looks for every word of query in tasks and comments (full
+// text, ranked by bm25) and in attachment and project names (substring,
+// ranked by how many words hit plus a bonus for an exact name). The kinds
+// are merged by rank; the two scales only roughly agree, so a caller
+// wanting one kind should ask for it.
+func SearchAll(ctx context.Context, db *sqlx.DB, query string, opts GlobalSearchOpts) ([]GlobalHit, error) {
+	if len(opts.Kinds) == 0 {
+		opts.Kinds = hitKinds
+	}
+	for _, k := range opts.Kinds {
+		if !slices.Contains(hitKinds, k) {
+			return nil, fmt.Errorf("unknown search kind: %s", k)
+		}
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	match := ftsQuery(query)
+	words := strings.Fields(strings.ReplaceAll(query, `"`, ""))
+	if match == "" {
+		return nil, nil
+	}
+
+	var hits []GlobalHit
+	for _, kind := range opts.Kinds {
+		var q string
+		var args []any
+		switch kind {
+		case HitTask:
+			q = `SELECT 'task' AS kind, t.id, '' AS task_id, t.description AS title,
+			            snippet(tasks_fts, -1, '[', ']', '…', 12) AS snippet, bm25(tasks_fts) AS rank
+			     FROM tasks_fts JOIN tasks t ON t.rowid = tasks_fts.rowid
+			     WHERE tasks_fts MATCH ? ORDER BY rank LIMIT ?`
+			args = []any{match, opts.Limit}
+		case HitComment:
+			q = `SELECT 'comment' AS kind, c.id, c.task_id, c.author AS title,
+			            snippet(comments_fts, -1, '[', ']', '…', 12) AS snippet, bm25(comments_fts) AS rank
+			     FROM comments_fts JOIN task_comments c ON c.rowid = comments_fts.rowid
+			     WHERE comments_fts MATCH ? ORDER BY rank LIMIT ?`
+			args = []any{match, opts.Limit}
+		case HitAttachment:
+			if ok, err := hasExtension(ctx, db, ExtAttachments); !ok {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			q, args = nameSearch(`SELECT 'attachment' AS kind, id, task_id, name AS title, '' AS snippet, %s AS rank
+			                      FROM task_attachments`, "name", words, opts.Limit)
+		case HitProject:
+			q, args = nameSearch(`SELECT 'project' AS kind, id, '' AS task_id, name AS title, description AS snippet, %s AS rank
+			                      FROM projects`, "name", words, opts.Limit)
+		}
+		var found []GlobalHit
+		if err := db.SelectContext(ctx, &found, q, args...); err != nil {
+			return nil, fmt.Errorf("search %ss: %w", kind, err)
+		}
+		hits = append(hits, found...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank < hits[j].Rank })
+	if len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits, nil
+}
+
+// nameSearch completes a SELECT (with a %s placeholder for the rank) that
+// keeps rows whose column contains every word, case-insensitively.
+func nameSearch(selectFmt, column string, words []string, limit int) (string, []any) {
+	var where []string
+	var args []any
+	for _, w := range words {
+		where = append(where, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+likeEscaper.Replace(w)+"%")
+	}
+	// one point per word, two more for the exact name, negated to sort like bm25
+	rank := fmt.Sprintf("-(%d + 2 * (%s = ? COLLATE NOCASE))", len(words), column)
+	q := fmt.Sprintf(selectFmt, rank) + " WHERE " + strings.Join(where, " AND ") + " ORDER BY rank LIMIT ?"
+	return q, append([]any{strings.Join(words, " ")}, append(args, limit)...)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
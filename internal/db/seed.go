@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SeedTask is one task of a fixture. Key names it within the fixture so
+// Parent and BlockedBy can point at other fixture tasks without knowing
+// their ids; anything else there is taken as an existing task id.
+type SeedTask struct {
+	Key         string         `json:"key"`
+	Description string         `json:"description"`
+	Parent      string         `json:"parent,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Priority    int            `json:"priority,omitempty"`
+	Status      string         `json:"status,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	BlockedBy   []string       `json:"blocked_by,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+}
+
+// Seed inserts a fixture in one transaction and returns the new task ids
+// by key, for tests and throwaway sessions. Priority defaults to 3 and
+// status to pending; projects are created by name as needed, and subtasks
+// without one take their parent's.
+func Seed(ctx context.Context, db *sqlx.DB, tasks []SeedTask) (map[string]string, error) {
+	tasks = slices.Clone(tasks)
+	ids := make(map[string]string, len(tasks))
+	for i := range tasks {
+		if tasks[i].Key == "" {
+			tasks[i].Key = fmt.Sprintf("#%d", i) // unkeyed tasks can't be referred to
+		}
+		if _, dup := ids[tasks[i].Key]; dup {
+			return nil, fmt.Errorf("duplicate fixture key: %s", tasks[i].Key)
+		}
+		ids[tasks[i].Key] = NewTaskID()
+	}
+	// a subtask without a project joins its parent's, as in InsertTask
+	byKey := make(map[string]*SeedTask, len(tasks))
+	for i := range tasks {
+		byKey[tasks[i].Key] = &tasks[i]
+	}
+	for i := range tasks {
+		for p := byKey[tasks[i].Parent]; tasks[i].Project == "" && p != nil && p != &tasks[i]; p = byKey[p.Parent] {
+			tasks[i].Project = p.Project
+		}
+	}
+	resolve := func(ref string) string {
+		if id, ok := ids[ref]; ok {
+			return id
+		}
+		return ref
+	}
+
+	bundles := make([]TaskBundle, len(tasks))
+	for i, st := range tasks {
+		key := st.Key
+		if st.Description == "" {
+			return nil, fmt.Errorf("fixture task %s: description is required", key)
+		}
+		t := Task{ID: ids[key], Description: st.Description, Priority: st.Priority, Status: st.Status}
+		if t.Priority == 0 {
+			t.Priority = 3
+		}
+		if t.Status == "" {
+			t.Status = "pending"
+		}
+		if st.Parent != "" {
+			parent := resolve(st.Parent)
+			t.ParentID = &parent
+		}
+		if st.Metadata != nil {
+			raw, err := json.Marshal(st.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("fixture task %s: %w", key, err)
+			}
+			meta := string(raw)
+			t.Metadata = &meta
+		}
+		b := TaskBundle{Task: t, Project: st.Project, Tags: st.Tags}
+		for _, ref := range st.BlockedBy {
+			b.BlockedBy = append(b.BlockedBy, resolve(ref))
+		}
+		bundles[i] = b
+	}
+	if err := ImportBundles(ctx, db, bundles); err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	return ids, nil
+}
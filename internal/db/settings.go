@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SettingReadOnly holds the reason writes are suspended; absent means writable.
+// It lives in the database so every process sharing the file honours it.
+const SettingReadOnly = "read_only"
+
+// GetSetting returns the value for key and whether it was set.
+func GetSetting(ctx context.Context, db *sqlx.DB, key string) (string, bool, error) {
+	var value string
+	err := db.GetContext(ctx, &value, "SELECT value FROM settings WHERE key = ?", key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func SetSetting(ctx context.Context, db *sqlx.DB, key, value string) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT(key) DO UPDATE SET value = excluded.value,
+		 updated_at = excluded.updated_at`,
+		key, value, now(ctx))
+	return err
+}
+
+func DeleteSetting(ctx context.Context, db *sqlx.DB, key string) error {
+	_, err := db.ExecContext(ctx, "DELETE FROM settings WHERE key = ?", key)
+	return err
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Stats is a summary of the task list for dashboards. System tasks are
+// left out throughout.
+type Stats struct {
+	Total      int            `json:"total"`
+	ByStatus   map[string]int `json:"by_status"`
+	ByPriority map[string]int `json:"by_priority"` // open tasks, by label
+	// Blocked pending tasks wait on a blocker that hasn't completed; the
+	// rest of the pending tasks are Ready.
+	Blocked int `json:"blocked"`
+	Ready   int `json:"ready"`
+	// AvgCompletionSeconds is the mean time from start (creation if never
+	// started) to completion over completed tasks; nil without any.
+	AvgCompletionSeconds *int        `json:"avg_completion_seconds,omitempty"`
+	OldestPending        *OldestTask `json:"oldest_pending,omitempty"`
+}
+
+// OldestTask identifies the longest-waiting pending task.
+type OldestTask struct {
+	ID          string `db:"id" json:"id"`
+	Description string `db:"description" json:"description"`
+	CreatedAt   string `db:"created_at" json:"created_at"`
+	AgeSeconds  int64  `db:"-" json:"age_seconds"`
+}
+
+// GetStats computes Stats in a handful of queries.
+func GetStats(ctx context.Context, db *sqlx.DB) (Stats, error) {
+	s := Stats{ByStatus: map[string]int{}, ByPriority: map[string]int{}}
+	byStatus, err := CountTasksByStatus(ctx, db)
+	if err != nil {
+		return Stats{}, err
+	}
+	for status, n := range byStatus {
+		s.ByStatus[status] = n
+		s.Total += n
+	}
+
+	var rows []struct {
+		Label string `db:"priority_label"`
+		N     int    `db:"n"`
+	}
+	err = db.SelectContext(ctx, &rows, db.Rebind(`
+		SELECT priority_label, COUNT(*) AS n FROM tasks
+		 WHERE status IN ('pending', 'in_progress') AND archived_at IS NULL
+		   AND id != ? AND (parent_id IS NULL OR parent_id != ?)
+		 GROUP BY priority_label`), SystemTaskID, SystemTaskID)
+	if err != nil {
+		return Stats{}, err
+	}
+	for _, r := range rows {
+		s.ByPriority[r.Label] = r.N
+	}
+
+	if s.Blocked, err = CountBlockedTasks(ctx, db); err != nil {
+		return Stats{}, err
+	}
+	s.Ready = s.ByStatus["pending"] - s.Blocked
+
+	var spans []struct {
+		Start       string `db:"start"`
+		CompletedAt string `db:"completed_at"`
+	}
+	err = db.SelectContext(ctx, &spans, db.Rebind(`
+		SELECT COALESCE(started_at, created_at) AS start,
+		       COALESCE(completed_at, updated_at) AS completed_at
+		  FROM tasks WHERE status = 'completed'
+		   AND id != ? AND (parent_id IS NULL OR parent_id != ?)`), SystemTaskID, SystemTaskID)
+	if err != nil {
+		return Stats{}, err
+	}
+	var total time.Duration
+	var n int
+	for _, sp := range spans {
+		start, err1 := time.Parse(time.RFC3339Nano, sp.Start)
+		end, err2 := time.Parse(time.RFC3339Nano, sp.CompletedAt)
+		if err1 != nil || err2 != nil || end.Before(start) {
+			continue
+		}
+		total += end.Sub(start)
+		n++
+	}
+	if n > 0 {
+		avg := int((total / time.Duration(n)).Seconds())
+		s.AvgCompletionSeconds = &avg
+	}
+
+	var oldest OldestTask
+	err = db.GetContext(ctx, &oldest, db.Rebind(`
+		SELECT id, description, created_at FROM tasks
+		 WHERE status = 'pending' AND archived_at IS NULL AND id != ? AND (parent_id IS NULL OR parent_id != ?)
+		 ORDER BY created_at, id LIMIT 1`), SystemTaskID, SystemTaskID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return Stats{}, err
+	default:
+		if created, err := time.Parse(time.RFC3339Nano, oldest.CreatedAt); err == nil {
+			oldest.AgeSeconds = int64(clock.From(ctx).Now().Sub(created).Seconds())
+		}
+		s.OldestPending = &oldest
+	}
+	return s, nil
+}
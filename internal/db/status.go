@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Task statuses.
+const (
+	StatusPending    = "pending"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// transitions lists where each status may go. A task is started before it
+// completes, and may be given up before or after starting. Completed and
+// failed tasks only move again when reopened on purpose; see
+// UpdateOpts.Reopen.
+var transitions = map[string][]string{
+	StatusPending:    {StatusInProgress, StatusFailed},
+	StatusInProgress: {StatusPending, StatusCompleted, StatusFailed},
+	StatusCompleted:  nil,
+	StatusFailed:     nil,
+}
+
+// reopenTo are the statuses a reopened task may go back to.
+var reopenTo = []string{StatusPending, StatusInProgress}
+
+// TransitionError is a status change the state machine doesn't allow.
+type TransitionError struct {
+	ID, From, To string
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("task %s can't go from %s to %s", e.ID, e.From, e.To)
+	if next := transitions[e.From]; len(next) > 0 {
+		msg += "; from " + e.From + " it can go to " + strings.Join(next, ", ")
+	}
+	if e.From == StatusCompleted || e.From == StatusFailed {
+		msg += "; reopen it to move it back to " + strings.Join(reopenTo, " or ")
+	}
+	return msg
+}
+
+// ErrorCode is the code tool error envelopes carry.
+func (e *TransitionError) ErrorCode() string { return "INVALID_TRANSITION" }
+
+// CheckTransition reports whether task id may go from status from to
+// status to. Staying put is always allowed; reopen lets a completed or
+// failed task go back to pending or in_progress.
+func CheckTransition(id, from, to string, reopen bool) error {
+	if _, ok := transitions[to]; !ok {
+		return fmt.Errorf("unknown status %q", to)
+	}
+	if from == to || slices.Contains(transitions[from], to) {
+		return nil
+	}
+	if reopen && (from == StatusCompleted || from == StatusFailed) && slices.Contains(reopenTo, to) {
+		return nil
+	}
+	return &TransitionError{ID: id, From: from, To: to}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"weak"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The hot paths (GetTask, and the reads and writes inside InsertTask and
+// UpdateTask) run the same few statements over and over. InitDB gives its
+// connection a cache of them, prepared once with Preparex and keyed by
+// query text, so a call binds and steps a statement instead of parsing and
+// planning it again. Transactions borrow a cached statement with
+// Tx.Stmtx, which reuses it on the connection it was prepared on.
+//
+// A transaction holds SQLite's only connection, so nothing is prepared
+// from inside one: txStmt only finds statements prepared beforehand,
+// either when InitDB warms the cache or by the caller before WithTx.
+
+// maxCachedStmts bounds a cache. UpdateTask caches one statement per set
+// of changed fields; past the bound, queries just run unprepared.
+const maxCachedStmts = 256
+
+type stmtCache struct {
+	mu    sync.Mutex
+	stmts map[string]*sqlx.Stmt
+	named map[string]*sqlx.NamedStmt
+}
+
+var (
+	// stmtCaches holds each connection's cache without keeping the
+	// connection alive; the cache is closed once it's collected.
+	stmtCaches sync.Map // weak.Pointer[sqlx.DB] -> *stmtCache
+	// txCaches maps a transaction from WithTx to its connection's cache
+	// while it runs.
+	txCaches sync.Map // *sqlx.Tx -> *stmtCache
+)
+
+// Statements InitDB prepares up front: the ones every write makes inside
+// its transaction.
+var warmStmts = []string{getTaskQuery, recordEventQuery}
+var warmNamedStmts = []string{insertTaskQuery}
+
+// enableStmtCache gives conn a statement cache and warms it.
+func enableStmtCache(ctx context.Context, conn *sqlx.DB) error {
+	c := &stmtCache{stmts: make(map[string]*sqlx.Stmt), named: make(map[string]*sqlx.NamedStmt)}
+	key := weak.Make(conn)
+	stmtCaches.Store(key, c)
+	runtime.AddCleanup(conn, func(key weak.Pointer[sqlx.DB]) {
+		if c, ok := stmtCaches.LoadAndDelete(key); ok {
+			c.(*stmtCache).close()
+		}
+	}, key)
+	for _, q := range warmStmts {
+		if _, err := prepared(ctx, conn, q); err != nil {
+			return err
+		}
+	}
+	for _, q := range warmNamedStmts {
+		if _, err := preparedNamed(ctx, conn, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cacheFor(db *sqlx.DB) *stmtCache {
+	if c, ok := stmtCaches.Load(weak.Make(db)); ok {
+		return c.(*stmtCache)
+	}
+	return nil
+}
+
+// prepared returns query prepared on db, preparing it on first use. It is
+// nil, with no error, when db has no cache or the cache is full. Never
+// call it inside a transaction on db.
+func prepared(ctx context.Context, db *sqlx.DB, query string) (*sqlx.Stmt, error) {
+	c := cacheFor(db)
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	s, ok := c.stmts[query]
+	full := len(c.stmts)+len(c.named) >= maxCachedStmts
+	c.mu.Unlock()
+	if ok || full {
+		return s, nil
+	}
+	// unlocked while preparing: a transaction looking up a statement may
+	// hold the connection this waits for
+	s, err := db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prev, ok := c.stmts[query]; ok {
+		s.Close()
+		return prev, nil
+	}
+	c.stmts[query] = s
+	return s, nil
+}
+
+// preparedNamed is prepared for a query with :name binds.
+func preparedNamed(ctx context.Context, db *sqlx.DB, query string) (*sqlx.NamedStmt, error) {
+	c := cacheFor(db)
+	if c == nil {
+		return nil, nil
+	}
+	c.mu.Lock()
+	s, ok := c.named[query]
+	full := len(c.stmts)+len(c.named) >= maxCachedStmts
+	c.mu.Unlock()
+	if ok || full {
+		return s, nil
+	}
+	s, err := db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if prev, ok := c.named[query]; ok {
+		s.Close()
+		return prev, nil
+	}
+	c.named[query] = s
+	return s, nil
+}
+
+// txStmt returns the cached statement for query bound to tx, or nil if it
+// hasn't been prepared.
+func txStmt(ctx context.Context, tx *sqlx.Tx, query string) *sqlx.Stmt {
+	c, ok := txCaches.Load(tx)
+	if !ok {
+		return nil
+	}
+	cache := c.(*stmtCache)
+	cache.mu.Lock()
+	s := cache.stmts[query]
+	cache.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+	return tx.StmtxContext(ctx, s)
+}
+
+// txNamedStmt is txStmt for a query with :name binds.
+func txNamedStmt(ctx context.Context, tx *sqlx.Tx, query string) *sqlx.NamedStmt {
+	c, ok := txCaches.Load(tx)
+	if !ok {
+		return nil
+	}
+	cache := c.(*stmtCache)
+	cache.mu.Lock()
+	s := cache.named[query]
+	cache.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+	return tx.NamedStmtContext(ctx, s)
+}
+
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.stmts {
+		s.Close()
+	}
+	for _, s := range c.named {
+		s.Close()
+	}
+	clear(c.stmts)
+	clear(c.named)
+}
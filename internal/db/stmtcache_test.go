@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"weak"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// benchDB opens a file database like the one agents use, with the
+// statement cache InitDB sets up, or without it when cached is false.
+func benchDB(b *testing.B, cached bool) *sqlx.DB {
+	b.Helper()
+	conn, err := InitDB(filepath.Join(b.TempDir(), "bossman.db"), Options{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	if !cached {
+		if c, ok := stmtCaches.LoadAndDelete(weak.Make(conn)); ok {
+			c.(*stmtCache).close()
+		}
+	}
+	return conn
+}
+
+func benchCached(b *testing.B, run func(b *testing.B, conn *sqlx.DB)) {
+	for _, cached := range []bool{true, false} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			run(b, benchDB(b, cached))
+		})
+	}
+}
+
+func BenchmarkGetTask(b *testing.B) {
+	benchCached(b, func(b *testing.B, conn *sqlx.DB) {
+		ctx := WithActor(context.Background(), "agent-1")
+		task := &Task{ID: NewTaskID(), Description: "write the report"}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := GetTask(ctx, conn, task.ID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkInsertTask(b *testing.B) {
+	benchCached(b, func(b *testing.B, conn *sqlx.DB) {
+		ctx := WithActor(context.Background(), "agent-1")
+		b.ReportAllocs()
+		for b.Loop() {
+			if err := InsertTask(ctx, conn, &Task{ID: NewTaskID(), Description: "write the report"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkUpdateTask(b *testing.B) {
+	benchCached(b, func(b *testing.B, conn *sqlx.DB) {
+		ctx := WithActor(context.Background(), "agent-1")
+		task := &Task{ID: NewTaskID(), Description: "write the report"}
+		if err := InsertTask(ctx, conn, task); err != nil {
+			b.Fatal(err)
+		}
+		statuses := []string{"in_progress", "pending"}
+		b.ReportAllocs()
+		n := 0
+		for b.Loop() {
+			status := statuses[n%2]
+			n++
+			if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Status: &status}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkAgentLoop is one agent's cycle on a task: read it, start it,
+// read it again and finish it.
+func BenchmarkAgentLoop(b *testing.B) {
+	benchCached(b, func(b *testing.B, conn *sqlx.DB) {
+		ctx := WithActor(context.Background(), "agent-1")
+		inProgress, completed := "in_progress", "completed"
+		b.ReportAllocs()
+		for b.Loop() {
+			task := &Task{ID: NewTaskID(), Description: "write the report"}
+			if err := InsertTask(ctx, conn, task); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := GetTask(ctx, conn, task.ID); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Status: &inProgress}); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := GetTask(ctx, conn, task.ID); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Status: &completed}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TaskStore is the task half of a storage backend.
+type TaskStore interface {
+	InsertTask(ctx context.Context, t *Task) error
+	GetTask(ctx context.Context, id string) (*Task, error)
+	QueryTasks(ctx context.Context, opts ListOpts) ([]Task, error)
+	UpdateTask(ctx context.Context, id string, opts UpdateOpts) (*Task, error)
+	DeleteTask(ctx context.Context, id string, policy DeletePolicy) (DeleteResult, error)
+	TaskRelations(ctx context.Context, ids []string) (map[string]TaskRelations, error)
+	TagsForTasks(ctx context.Context, ids []string) (map[string][]string, error)
+}
+
+// BlockerStore is the dependency half of a storage backend.
+type BlockerStore interface {
+	AddBlocker(ctx context.Context, taskID, blockedByID string) error
+	RemoveBlocker(ctx context.Context, taskID, blockedByID string) error
+	GetBlockers(ctx context.Context, taskID string) ([]Task, error)
+	GetBlocking(ctx context.Context, taskID string, transitive bool) ([]Task, error)
+	ListBlockerEdges(ctx context.Context) ([]BlockerEdge, error)
+}
+
+// Store is a storage backend. Missing tasks are sql.ErrNoRows, as with the
+// package functions.
+type Store interface {
+	TaskStore
+	BlockerStore
+	Close() error
+}
+
+// SQLStore is a Store over a SQLite or Postgres connection. The package
+// functions it calls rebind their placeholders and branch on the driver
+// where the dialects differ. Everything else in the package (search,
+// changes, review and the rest) is still SQLite only.
+type SQLStore struct {
+	DB *sqlx.DB
+}
+
+// Open picks a backend from dsn: a postgres:// or postgresql:// URL opens
+// Postgres, anything else is a SQLite file path opened with opts.
+func Open(dsn string, opts Options) (*SQLStore, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return OpenPostgres(dsn)
+	}
+	conn, err := InitDB(dsn, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLStore{DB: conn}, nil
+}
+
+// SQLite returns the connection if the store is SQLite, nil otherwise, for
+// callers that need the SQLite-only parts of the package.
+func SQLite(s Store) *sqlx.DB {
+	if s, ok := s.(*SQLStore); ok && !isPostgres(s.DB) {
+		return s.DB
+	}
+	return nil
+}
+
+func (s *SQLStore) InsertTask(ctx context.Context, t *Task) error {
+	return InsertTask(ctx, s.DB, t)
+}
+
+func (s *SQLStore) GetTask(ctx context.Context, id string) (*Task, error) {
+	return GetTask(ctx, s.DB, id)
+}
+
+func (s *SQLStore) QueryTasks(ctx context.Context, opts ListOpts) ([]Task, error) {
+	return QueryTasks(ctx, s.DB, opts)
+}
+
+func (s *SQLStore) UpdateTask(ctx context.Context, id string, opts UpdateOpts) (*Task, error) {
+	return UpdateTask(ctx, s.DB, id, opts)
+}
+
+func (s *SQLStore) DeleteTask(ctx context.Context, id string, policy DeletePolicy) (DeleteResult, error) {
+	return DeleteTask(ctx, s.DB, id, policy)
+}
+
+func (s *SQLStore) TaskRelations(ctx context.Context, ids []string) (map[string]TaskRelations, error) {
+	return GetTaskRelations(ctx, s.DB, ids)
+}
+
+func (s *SQLStore) TagsForTasks(ctx context.Context, ids []string) (map[string][]string, error) {
+	return GetTagsForTasks(ctx, s.DB, ids)
+}
+
+func (s *SQLStore) AddBlocker(ctx context.Context, taskID, blockedByID string) error {
+	return AddBlocker(ctx, s.DB, taskID, blockedByID)
+}
+
+func (s *SQLStore) RemoveBlocker(ctx context.Context, taskID, blockedByID string) error {
+	return RemoveBlocker(ctx, s.DB, taskID, blockedByID)
+}
+
+func (s *SQLStore) GetBlockers(ctx context.Context, taskID string) ([]Task, error) {
+	return GetBlockers(ctx, s.DB, taskID)
+}
+
+func (s *SQLStore) GetBlocking(ctx context.Context, taskID string, transitive bool) ([]Task, error) {
+	return GetBlocking(ctx, s.DB, taskID, transitive)
+}
+
+func (s *SQLStore) ListBlockerEdges(ctx context.Context) ([]BlockerEdge, error) {
+	return ListBlockerEdges(ctx, s.DB)
+}
+
+func (s *SQLStore) Close() error {
+	return s.DB.Close()
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SystemTaskID is the reserved root under which bossman's own jobs live.
+// Agents see maintenance status through the same task tools they already use.
+const SystemTaskID = "task_system"
+
+type MaintenanceRun struct {
+	ID         int64  `db:"id"`
+	TaskID     string `db:"task_id"`
+	StartedAt  string `db:"started_at"`
+	FinishedAt string `db:"finished_at"`
+	OK         bool   `db:"ok"`
+	Detail     string `db:"detail"`
+}
+
+// EnsureSystemTask creates the system root and the given job task if missing.
+// Existing rows are left untouched so run history survives restarts.
+func EnsureSystemTask(ctx context.Context, db *sqlx.DB, id, description string) error {
+	ts := now(ctx)
+	_, err := db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO tasks (id, description, priority, priority_label, priority_weight, context, created_at, updated_at)
+		 VALUES (?, 'bossman system maintenance', 5, 'someday', 0, 'reserved: managed by bossman', ?, ?)`,
+		SystemTaskID, ts, ts)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO tasks (id, parent_id, description, priority, priority_label, priority_weight, context, created_at, updated_at)
+		 VALUES (?, ?, ?, 5, 'someday', 0, 'reserved: managed by bossman', ?, ?)`,
+		id, SystemTaskID, description, ts, ts)
+	return err
+}
+
+// IsSystemTask reports whether id is the system root or one of its jobs.
+func IsSystemTask(ctx context.Context, db *sqlx.DB, id string) (bool, error) {
+	if id == SystemTaskID {
+		return true, nil
+	}
+	var parent sql.NullString
+	err := db.GetContext(ctx, &parent, "SELECT parent_id FROM tasks WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return parent.String == SystemTaskID, err
+}
+
+// RecordMaintenanceRun stores a run and mirrors its outcome onto the job task.
+func RecordMaintenanceRun(ctx context.Context, db *sqlx.DB, run *MaintenanceRun) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		res, err := tx.NamedExecContext(ctx,
+			`INSERT INTO maintenance_runs (task_id, started_at, finished_at, ok, detail)
+			 VALUES (:task_id, :started_at, :finished_at, :ok, :detail)`, run)
+		if err != nil {
+			return err
+		}
+		if run.ID, err = res.LastInsertId(); err != nil {
+			return err
+		}
+
+		before, err := getTaskTx(ctx, tx, run.TaskID)
+		if err != nil {
+			return err
+		}
+		status := "completed"
+		if !run.OK {
+			status = "failed"
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE tasks SET status = ?, result = ?, started_at = ?, completed_at = ?,
+			 updated_at = ?
+			 WHERE id = ?`,
+			status, Sensitive(run.Detail), run.StartedAt, run.FinishedAt, now(ctx), run.TaskID)
+		if err != nil {
+			return err
+		}
+		after, err := getTaskTx(ctx, tx, run.TaskID)
+		if err != nil {
+			return err
+		}
+		oldValues, newValues := diffTasks(before, after)
+		return recordEvent(ctx, tx, run.TaskID, "task", "update", oldValues, newValues)
+	})
+}
+
+// GetMaintenanceRuns returns the most recent runs for a job, newest first.
+func GetMaintenanceRuns(ctx context.Context, db *sqlx.DB, taskID string, limit int) ([]MaintenanceRun, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	var runs []MaintenanceRun
+	err := db.SelectContext(ctx, &runs,
+		`SELECT * FROM maintenance_runs WHERE task_id = ? ORDER BY id DESC LIMIT ?`,
+		taskID, limit)
+	return runs, err
+}
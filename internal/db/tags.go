@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const maxTagLen = 64
+
+// NormalizeTag lowercases and trims a tag, rejecting empty or oversized ones.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag must not be empty")
+	}
+	if len(tag) > maxTagLen {
+		return "", fmt.Errorf("tag longer than %d characters", maxTagLen)
+	}
+	return tag, nil
+}
+
+// AddTag is idempotent: tagging twice is not an error.
+func AddTag(ctx context.Context, db *sqlx.DB, taskID, tag string) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		return tagTx(ctx, tx, taskID, tag)
+	})
+}
+
+func tagTx(ctx context.Context, tx *sqlx.Tx, taskID, tag string) error {
+	result, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)", taskID, tag)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return err
+	}
+	return recordEvent(ctx, tx, taskID, "tag", "insert", nil, map[string]any{"tag": tag})
+}
+
+func RemoveTag(ctx context.Context, db *sqlx.DB, taskID, tag string) error {
+	return WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM task_tags WHERE task_id = ? AND tag = ?", taskID, tag)
+		if err != nil {
+			return err
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		return recordEvent(ctx, tx, taskID, "tag", "delete", map[string]any{"tag": tag}, nil)
+	})
+}
+
+func ListByTag(ctx context.Context, db *sqlx.DB, tag string) ([]Task, error) {
+	return QueryTasks(ctx, db, ListOpts{Tags: []string{tag}})
+}
+
+// GetTagsForTasks returns each task's tags in alphabetical order.
+func GetTagsForTasks(ctx context.Context, db sqlx.ExtContext, ids []string) (map[string][]string, error) {
+	out := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT task_id, tag FROM task_tags WHERE task_id IN (?) ORDER BY tag", ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskID string `db:"task_id"`
+		Tag    string `db:"tag"`
+	}
+	if err := sqlx.SelectContext(ctx, db, &rows, db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TaskID] = append(out[r.TaskID], r.Tag)
+	}
+	return out, nil
+}
+
+type TagCount struct {
+	Tag   string `db:"tag" json:"tag"`
+	Count int    `db:"count" json:"count"`
+}
+
+// ListTags returns every tag in use with how many tasks carry it.
+func ListTags(ctx context.Context, db *sqlx.DB) ([]TagCount, error) {
+	var tags []TagCount
+	err := db.SelectContext(ctx, &tags,
+		"SELECT tag, COUNT(*) AS count FROM task_tags GROUP BY tag ORDER BY tag")
+	return tags, err
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Timestamp is a time kept in a TimeLayout column. It scans from the
+// stored text, writes back as the same text so columns still sort and
+// compare as strings in SQL, and marshals to JSON in TimeLayout, which is
+// RFC 3339. Embedding time.Time gives Go code Before, Sub and the rest,
+// so nothing outside SQL compares dates as strings.
+type Timestamp struct {
+	time.Time
+}
+
+// TimestampOf is t as stored: UTC, to the millisecond.
+func TimestampOf(t time.Time) Timestamp {
+	return Timestamp{t.UTC().Truncate(time.Millisecond)}
+}
+
+// ParseTimestamp is ParseTime returning a Timestamp.
+func ParseTimestamp(s string) (Timestamp, error) {
+	text, err := ParseTime(s)
+	if err != nil {
+		return Timestamp{}, err
+	}
+	t, err := time.Parse(TimeLayout, text)
+	return Timestamp{t}, err
+}
+
+// nowTimestamp is now as a Timestamp.
+func nowTimestamp(ctx context.Context) Timestamp {
+	return TimestampOf(clock.From(ctx).Now())
+}
+
+// String is the stored form.
+func (t Timestamp) String() string {
+	return FormatTime(t.Time)
+}
+
+// Scan reads the stored text; Postgres drivers may hand over a time.Time.
+func (t *Timestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Timestamp{}
+		return nil
+	case time.Time:
+		*t = TimestampOf(v)
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+	return fmt.Errorf("scan timestamp: unsupported type %T", src)
+}
+
+func (t *Timestamp) parse(s string) error {
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return fmt.Errorf("scan timestamp: %w", err)
+	}
+	*t = TimestampOf(parsed)
+	return nil
+}
+
+// Value writes the stored text.
+func (t Timestamp) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON takes what ParseTime takes: RFC 3339 or a bare date.
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseTimestamp(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Age is how long ago the task was created.
+func (t *Task) Age(now time.Time) time.Duration {
+	return now.Sub(t.CreatedAt.Time)
+}
+
+// TimeInStatus is how long the task has had its current status: since it
+// was started while in progress, since it completed once completed, and
+// since it was created while pending. Status changes aren't timestamped
+// otherwise, so anything else counts from the task's last update.
+func (t *Task) TimeInStatus(now time.Time) time.Duration {
+	since := t.UpdatedAt
+	switch {
+	case t.Status == StatusInProgress && t.StartedAt != nil:
+		since = *t.StartedAt
+	case t.Status == StatusCompleted && t.CompletedAt != nil:
+		since = *t.CompletedAt
+	case t.Status == StatusPending && t.StartedAt == nil:
+		since = t.CreatedAt
+	}
+	return now.Sub(since.Time)
+}
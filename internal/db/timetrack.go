@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// TimeEntry is one stretch of work on a task. An entry with no EndedAt is
+// still running; each actor has at most one running entry per task.
+type TimeEntry struct {
+	ID        string  `db:"id" json:"id"`
+	TaskID    string  `db:"task_id" json:"task_id"`
+	Actor     string  `db:"actor" json:"actor"`
+	StartedAt string  `db:"started_at" json:"started_at"`
+	EndedAt   *string `db:"ended_at" json:"ended_at,omitempty"`
+	Seconds   *int64  `db:"seconds" json:"seconds,omitempty"`
+}
+
+func NewTimeEntryID() string {
+	return "time_" + xid.New().String()
+}
+
+// elapsedExpr is an entry's length in seconds; running entries count up to
+// the ? argument (now).
+const elapsedExpr = "COALESCE(e.seconds, CAST(strftime('%s', ?) AS INTEGER) - CAST(strftime('%s', e.started_at) AS INTEGER))"
+
+// StartWork opens an entry for the actor in ctx.
+func StartWork(ctx context.Context, db *sqlx.DB, taskID string) (*TimeEntry, error) {
+	e := TimeEntry{ID: NewTimeEntryID(), TaskID: taskID, Actor: ActorFromContext(ctx), StartedAt: now(ctx)}
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		var running int
+		if err := tx.GetContext(ctx, &running,
+			"SELECT COUNT(*) FROM time_entries WHERE task_id = ? AND actor = ? AND ended_at IS NULL",
+			taskID, e.Actor); err != nil {
+			return err
+		}
+		if running > 0 {
+			return fmt.Errorf("work already started on %s by %s", taskID, e.Actor)
+		}
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO time_entries (id, task_id, actor, started_at) VALUES (?, ?, ?, ?)",
+			e.ID, e.TaskID, e.Actor, e.StartedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// StopWork closes the actor's running entry on a task. It returns
+// sql.ErrNoRows when there is none.
+func StopWork(ctx context.Context, db *sqlx.DB, taskID string) (*TimeEntry, error) {
+	var e TimeEntry
+	err := WithTx(ctx, db, func(tx *sqlx.Tx) error {
+		err := tx.GetContext(ctx, &e,
+			"SELECT * FROM time_entries WHERE task_id = ? AND actor = ? AND ended_at IS NULL",
+			taskID, ActorFromContext(ctx))
+		if err != nil {
+			return err
+		}
+		started, err := time.Parse(TimeLayout, e.StartedAt)
+		if err != nil {
+			return fmt.Errorf("parse started_at: %w", err)
+		}
+		end := clock.From(ctx).Now()
+		ended, seconds := FormatTime(end), max(int64(end.Sub(started).Seconds()), 0)
+		e.EndedAt, e.Seconds = &ended, &seconds
+		_, err = tx.ExecContext(ctx,
+			"UPDATE time_entries SET ended_at = ?, seconds = ? WHERE id = ?", ended, seconds, e.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListTimeEntries returns a task's entries, oldest first.
+func ListTimeEntries(ctx context.Context, db *sqlx.DB, taskID string) ([]TimeEntry, error) {
+	var entries []TimeEntry
+	err := db.SelectContext(ctx, &entries,
+		"SELECT * FROM time_entries WHERE task_id = ? ORDER BY started_at, id", taskID)
+	return entries, err
+}
+
+// GetTimeSpent returns seconds worked on each task and its subtasks,
+// counting running entries up to now. Tasks with no time are absent.
+func GetTimeSpent(ctx context.Context, db *sqlx.DB, ids []string) (map[string]int64, error) {
+	out := make(map[string]int64, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(subtreeCTE+`
+		SELECT s.root AS task_id, SUM(`+elapsedExpr+`) AS seconds
+		FROM subtree s JOIN time_entries e ON e.task_id = s.id
+		GROUP BY s.root`, ids, now(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		TaskID  string `db:"task_id"`
+		Seconds int64  `db:"seconds"`
+	}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		out[r.TaskID] = r.Seconds
+	}
+	return out, nil
+}
+
+// timeGroupExprs whitelists time report dimensions, over time_entries (e).
+// An entry counts toward the day it started on.
+var timeGroupExprs = map[string]string{
+	"day":   "substr(e.started_at, 1, 10)",
+	"task":  "e.task_id",
+	"actor": "e.actor",
+	"tag":   "tg.tag",
+}
+
+var timeGroupJoins = map[string]string{
+	"tag": "LEFT JOIN task_tags tg ON tg.task_id = e.task_id",
+}
+
+type TimeReportOpts struct {
+	GroupBy []string
+	Since   string // started_at >= Since, if set
+	Until   string // started_at < Until, if set
+}
+
+func TimeGroupByNames() []string { return sortedKeys(timeGroupExprs) }
+
+// TimeReport sums seconds worked per group. Rows reuse AggregateRow, with
+// Value in seconds.
+func TimeReport(ctx context.Context, db *sqlx.DB, opts TimeReportOpts) ([]AggregateRow, error) {
+	cols := make([]string, 0, len(opts.GroupBy)+1)
+	for i, g := range opts.GroupBy {
+		expr, ok := timeGroupExprs[g]
+		if !ok {
+			return nil, fmt.Errorf("unknown group_by: %s (want one of %s)", g, strings.Join(TimeGroupByNames(), ", "))
+		}
+		cols = append(cols, fmt.Sprintf("%s AS g%d", expr, i))
+	}
+	cols = append(cols, "SUM("+elapsedExpr+") AS value")
+	args := []any{now(ctx)}
+
+	query := "SELECT " + strings.Join(cols, ", ") + " FROM time_entries e"
+	for _, g := range opts.GroupBy {
+		if join, ok := timeGroupJoins[g]; ok {
+			query += " " + join
+		}
+	}
+	query += " WHERE 1=1"
+	if opts.Since != "" {
+		query += " AND e.started_at >= ?"
+		args = append(args, opts.Since)
+	}
+	if opts.Until != "" {
+		query += " AND e.started_at < ?"
+		args = append(args, opts.Until)
+	}
+	if len(opts.GroupBy) > 0 {
+		groups := make([]string, len(opts.GroupBy))
+		for i := range opts.GroupBy {
+			groups[i] = fmt.Sprintf("g%d", i)
+		}
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []AggregateRow
+	for rows.Next() {
+		vals, err := rows.SliceScan()
+		if err != nil {
+			return nil, err
+		}
+		row := AggregateRow{Group: make(map[string]any, len(opts.GroupBy))}
+		for i, g := range opts.GroupBy {
+			row.Group[g] = vals[i]
+		}
+		switch v := vals[len(vals)-1].(type) {
+		case int64:
+			row.Value = float64(v)
+		case float64:
+			row.Value = v
+		case nil:
+			continue // no entries at all
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
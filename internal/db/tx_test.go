@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TestConcurrentWritersAcrossConnections updates one task from two
+// connections at once, as the CLI, MCP and HTTP processes do. Audited
+// writes read the task before changing it, so a transaction that only took
+// the write lock at its first write would fail with SQLITE_BUSY whenever
+// the other connection committed in between; every update must succeed.
+func TestConcurrentWritersAcrossConnections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bossman.db")
+	var conns []*sqlx.DB
+	for range 2 {
+		conn, err := InitDB(path, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	ctx := WithActor(context.Background(), "agent-1")
+
+	task := &Task{ID: NewTaskID(), Description: "design the schema", Priority: 3}
+	if err := InsertTask(ctx, conns[0], task); err != nil {
+		t.Fatal(err)
+	}
+	const updates = 50
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Go(func() {
+			for j := range updates {
+				description := fmt.Sprintf("design the schema, pass %d.%d", i, j)
+				if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Description: &description}); err != nil {
+					t.Errorf("update %d.%d: %v", i, j, err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUpdateTaskStampsUpdatedAt updates a task and wants updated_at
+// rewritten in the stored layout. The statement's strftime format has
+// colons, which sqlx reads as bind parameters unless they are doubled.
+func TestUpdateTaskStampsUpdatedAt(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	task := &Task{ID: NewTaskID(), Description: "design the schema", Priority: 3}
+	if err := InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	const stale = "2000-01-01T00:00:00.000Z"
+	if _, err := conn.ExecContext(ctx, "UPDATE tasks SET updated_at = ? WHERE id = ?", stale, task.ID); err != nil {
+		t.Fatal(err)
+	}
+	description := "design the task schema"
+	if _, err := UpdateTask(ctx, conn, task.ID, UpdateOpts{Description: &description}); err != nil {
+		t.Fatal(err)
+	}
+	var updatedAt string
+	if err := conn.GetContext(ctx, &updatedAt, "SELECT updated_at FROM tasks WHERE id = ?", task.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse("2006-01-02T15:04:05.000Z", updatedAt); err != nil || updatedAt == stale {
+		t.Errorf("updated_at = %q, want the update time", updatedAt)
+	}
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// TimeLayout is how every timestamp column is stored: UTC, millisecond
+// precision, sortable as text. It matches the schema's strftime defaults.
+const TimeLayout = "2006-01-02T15:04:05.000Z"
+
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// now is the write timestamp for ctx, from its clock rather than SQLite's,
+// so tests and simulations control every stored time.
+func now(ctx context.Context) string {
+	return FormatTime(clock.From(ctx).Now())
+}
+
+// ParseTime accepts an RFC 3339 time or a bare date (midnight UTC) and
+// returns it in TimeLayout.
+func ParseTime(s string) (string, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return FormatTime(t), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time %q: want RFC 3339 (2025-01-31T17:00:00Z) or a date (2025-01-31)", s)
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// With one connection and a steady trickle of writes, SQLite's automatic
+// checkpoints copy the write-ahead log back into the database but never
+// shrink the -wal file, which keeps the size of the busiest stretch since
+// startup. Checkpoint truncates it; Optimize also compacts the database.
+
+// CheckpointInfo is what a checkpoint did. LogFrames and Checkpointed are
+// -1 when the database isn't in WAL mode.
+type CheckpointInfo struct {
+	Busy         bool `json:"busy,omitempty"` // a reader kept part of the log in use; the next checkpoint catches up
+	LogFrames    int  `json:"log_frames"`
+	Checkpointed int  `json:"checkpointed"`
+}
+
+// Checkpoint copies the write-ahead log into the database and truncates
+// the -wal file to zero bytes.
+func Checkpoint(ctx context.Context, db *sqlx.DB) (CheckpointInfo, error) {
+	var busy int
+	var info CheckpointInfo
+	row := db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	if err := row.Scan(&busy, &info.LogFrames, &info.Checkpointed); err != nil {
+		return CheckpointInfo{}, err
+	}
+	info.Busy = busy != 0
+	return info, nil
+}
+
+// OptimizeOpts picks the heavier steps of Optimize.
+type OptimizeOpts struct {
+	// Vacuum rebuilds the database file without its free pages. It holds
+	// the write lock throughout and needs as much free disk as the file.
+	Vacuum bool
+	// Analyze refreshes the query planner's statistics for every index,
+	// rather than only the stale ones PRAGMA optimize picks.
+	Analyze bool
+}
+
+// OptimizeInfo describes a finished Optimize. The sizes count the database
+// file and its -wal file, and are zero for an in-memory database.
+type OptimizeInfo struct {
+	Vacuumed    bool           `json:"vacuumed"`
+	Analyzed    bool           `json:"analyzed"`
+	Checkpoint  CheckpointInfo `json:"checkpoint"`
+	BytesBefore int64          `json:"bytes_before"`
+	BytesAfter  int64          `json:"bytes_after"`
+	Took        string         `json:"took"`
+}
+
+// Optimize runs ANALYZE and VACUUM as opts asks, then PRAGMA optimize and
+// a truncating checkpoint.
+func Optimize(ctx context.Context, db *sqlx.DB, opts OptimizeOpts) (OptimizeInfo, error) {
+	if isPostgres(db) {
+		return OptimizeInfo{}, errors.New("optimize covers SQLite databases only; Postgres has autovacuum")
+	}
+	start := time.Now()
+	file, err := databaseFile(ctx, db)
+	if err != nil {
+		return OptimizeInfo{}, fmt.Errorf("find database file: %w", err)
+	}
+	info := OptimizeInfo{BytesBefore: fileBytes(file)}
+	if opts.Analyze {
+		if _, err := db.ExecContext(ctx, "ANALYZE"); err != nil {
+			return info, fmt.Errorf("analyze: %w", err)
+		}
+		info.Analyzed = true
+	}
+	if opts.Vacuum {
+		if _, err := db.ExecContext(ctx, "VACUUM"); err != nil {
+			return info, fmt.Errorf("vacuum: %w", err)
+		}
+		info.Vacuumed = true
+	}
+	if _, err := db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		return info, fmt.Errorf("optimize: %w", err)
+	}
+	if info.Checkpoint, err = Checkpoint(ctx, db); err != nil {
+		return info, fmt.Errorf("checkpoint: %w", err)
+	}
+	info.BytesAfter = fileBytes(file)
+	info.Took = time.Since(start).Round(time.Millisecond).String()
+	return info, nil
+}
+
+// fileBytes is the size of a database file with its -wal file, or 0 for
+// an in-memory database.
+func fileBytes(file string) int64 {
+	if file == "" {
+		return 0
+	}
+	var n int64
+	for _, f := range []string{file, file + "-wal"} {
+		if st, err := os.Stat(f); err == nil {
+			n += st.Size()
+		}
+	}
+	return n
+}
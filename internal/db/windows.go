@@ -0,0 +1,267 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+)
+
+// Kinds of thing a work window is attached to.
+const (
+	WindowTag      = "tag"
+	WindowResource = "resource"
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WorkWindow limits when tasks carrying a tag or needing a resource may be
+// started: on Days, from Start until End local time ("09:00"-"17:00"). An
+// End at or before Start runs past midnight into the next day.
+type WorkWindow struct {
+	ID        string   `db:"id" json:"id"`
+	Kind      string   `db:"kind" json:"kind"`
+	Name      string   `db:"name" json:"name"`
+	Days      []string `db:"-" json:"days"`
+	DayMask   int      `db:"days" json:"-"` // bit i is time.Weekday(i)
+	Start     string   `db:"start_time" json:"start"`
+	End       string   `db:"end_time" json:"end"`
+	CreatedAt string   `db:"created_at" json:"created_at"`
+}
+
+func NewWorkWindowID() string {
+	return "window_" + xid.New().String()
+}
+
+// parseClock reads "HH:MM" as minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// normalize validates w and fills DayMask from Days, or Days from DayMask.
+func (w *WorkWindow) normalize() error {
+	if len(w.Days) > 0 {
+		w.DayMask = 0
+		for _, d := range w.Days {
+			name := strings.ToLower(strings.TrimSpace(d))
+			if len(name) > 3 {
+				name = name[:3] // "monday" works too
+			}
+			i := indexOf(weekdays, name)
+			if i < 0 {
+				return fmt.Errorf("invalid day %q, want mon..sun", d)
+			}
+			w.DayMask |= 1 << i
+		}
+	}
+	if w.DayMask == 0 {
+		return fmt.Errorf("work window needs at least one day")
+	}
+	w.Days = nil
+	for i, d := range weekdays {
+		if w.DayMask&(1<<i) != 0 {
+			w.Days = append(w.Days, d)
+		}
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("work window start and end must differ")
+	}
+	return nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *WorkWindow) onDay(d time.Weekday) bool {
+	return w.DayMask&(1<<d) != 0
+}
+
+// Contains reports whether t falls inside the window, in local time.
+func (w *WorkWindow) Contains(t time.Time) bool {
+	t = t.In(time.Local)
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+	m := t.Hour()*60 + t.Minute()
+	if start < end {
+		return w.onDay(t.Weekday()) && m >= start && m < end
+	}
+	// overnight: the evening part belongs to today, the morning to yesterday
+	return (w.onDay(t.Weekday()) && m >= start) || (w.onDay(t.AddDate(0, 0, -1).Weekday()) && m < end)
+}
+
+// NextOpen returns t if the window is open then, otherwise when it next opens.
+func (w *WorkWindow) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	local := t.In(time.Local)
+	start, _ := parseClock(w.Start)
+	for d := 0; d <= 7; d++ {
+		day := local.AddDate(0, 0, d)
+		open := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, time.Local)
+		if w.onDay(open.Weekday()) && open.After(t) {
+			return open
+		}
+	}
+	return time.Time{} // unreachable for a validated window
+}
+
+func CreateWorkWindow(ctx context.Context, db *sqlx.DB, w *WorkWindow) error {
+	switch w.Kind {
+	case WindowTag:
+		name, err := NormalizeTag(w.Name)
+		if err != nil {
+			return err
+		}
+		w.Name = name
+	case WindowResource:
+		name, err := NormalizeResource(w.Name)
+		if err != nil {
+			return err
+		}
+		w.Name = name
+	default:
+		return fmt.Errorf("invalid work window kind: %s", w.Kind)
+	}
+	if err := w.normalize(); err != nil {
+		return err
+	}
+	if w.ID == "" {
+		w.ID = NewWorkWindowID()
+	}
+	w.CreatedAt = now(ctx)
+	_, err := db.NamedExecContext(ctx,
+		`INSERT INTO work_windows (id, kind, name, days, start_time, end_time, created_at)
+		 VALUES (:id, :kind, :name, :days, :start_time, :end_time, :created_at)`, w)
+	return err
+}
+
+func ListWorkWindows(ctx context.Context, db sqlx.QueryerContext) ([]WorkWindow, error) {
+	var windows []WorkWindow
+	if err := sqlx.SelectContext(ctx, db, &windows,
+		"SELECT * FROM work_windows ORDER BY kind, name, start_time, id"); err != nil {
+		return nil, err
+	}
+	for i := range windows {
+		windows[i].Days = nil
+		if err := windows[i].normalize(); err != nil {
+			return nil, fmt.Errorf("work window %s: %w", windows[i].ID, err)
+		}
+	}
+	return windows, nil
+}
+
+// DeleteWorkWindow returns sql.ErrNoRows if there is no such window.
+func DeleteWorkWindow(ctx context.Context, db *sqlx.DB, id string) error {
+	result, err := db.ExecContext(ctx, "DELETE FROM work_windows WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// NextWindowOpen returns, for each task that has work windows, the
+// earliest time at or after t when all of them are open together: every
+// tag and resource with windows must be inside one of its windows. Tasks
+// without windows are left out, and the zero time means the windows never
+// line up. Planning tools use it to shift start times; a task whose value
+// is after t is outside its windows now.
+func NextWindowOpen(ctx context.Context, db sqlx.ExtContext, ids []string, t time.Time) (map[string]time.Time, error) {
+	out := make(map[string]time.Time)
+	windows, err := ListWorkWindows(ctx, db)
+	if err != nil || len(windows) == 0 {
+		return out, err
+	}
+	byName := make(map[string][]*WorkWindow)
+	for i := range windows {
+		key := windows[i].Kind + ":" + windows[i].Name
+		byName[key] = append(byName[key], &windows[i])
+	}
+	tags, err := GetTagsForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := GetResourcesForTasks(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		var sets [][]*WorkWindow
+		for _, tag := range tags[id] {
+			if ws := byName[WindowTag+":"+tag]; ws != nil {
+				sets = append(sets, ws)
+			}
+		}
+		for _, res := range resources[id] {
+			if ws := byName[WindowResource+":"+res]; ws != nil {
+				sets = append(sets, ws)
+			}
+		}
+		if len(sets) == 0 {
+			continue
+		}
+		if at, ok := nextOpenAll(sets, t); ok {
+			out[id] = at
+		} else {
+			// the windows never overlap; report the task as never open
+			out[id] = time.Time{}
+		}
+	}
+	return out, nil
+}
+
+// nextOpenAll moves forward until every set has an open window. Each step
+// jumps to the next opening of a closed set, so it settles quickly unless
+// the sets never overlap, which gives up after a few weeks of trying.
+func nextOpenAll(sets [][]*WorkWindow, t time.Time) (time.Time, bool) {
+	limit := t.AddDate(0, 0, 28)
+	for !t.After(limit) {
+		moved := false
+		for _, ws := range sets {
+			next := time.Time{}
+			for _, w := range ws {
+				if at := w.NextOpen(t); next.IsZero() || at.Before(next) {
+					next = at
+				}
+			}
+			if next.After(t) {
+				t, moved = next, true
+			}
+		}
+		if !moved {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+// A workspace is a separate task database in a data directory, so one
+// install keeps personal, work and per-repository tasks apart. The default
+// workspace is the database file the data directory was taken from; each
+// other one is a directory of its own under workspaces/, which keeps its
+// backups beside it and apart from the rest.
+
+// DefaultWorkspace names the database in the data directory itself.
+const DefaultWorkspace = "default"
+
+var workspaceName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidateWorkspace rejects names that aren't a safe directory name.
+func ValidateWorkspace(name string) error {
+	if !workspaceName.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// WorkspacePath is the database file of workspace name. defaultPath is
+// the default workspace's file; the others live beside it.
+func WorkspacePath(defaultPath, name string) (string, error) {
+	if name == "" || name == DefaultWorkspace {
+		return defaultPath, nil
+	}
+	if err := ValidateWorkspace(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(defaultPath), "workspaces", name, filepath.Base(defaultPath)), nil
+}
+
+// ListWorkspaces returns the workspaces beside defaultPath that have a
+// database, the default one first even before it has been created.
+func ListWorkspaces(defaultPath string) ([]string, error) {
+	names := []string{DefaultWorkspace}
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(defaultPath), "workspaces"))
+	if errors.Is(err, os.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	var others []string
+	for _, e := range entries {
+		if !e.IsDir() || ValidateWorkspace(e.Name()) != nil {
+			continue
+		}
+		path, _ := WorkspacePath(defaultPath, e.Name())
+		if _, err := os.Stat(path); err == nil {
+			others = append(others, e.Name())
+		}
+	}
+	slices.Sort(others)
+	return append(names, others...), nil
+}
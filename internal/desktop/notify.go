@@ -0,0 +1,59 @@
+// Package desktop runs bossman as a personal workstation app: the HTTP
+// dashboard on localhost, OS notifications when tasks come due or fail,
+// and with the tray build tag a tray icon with the task counts.
+package desktop
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Notifier shows a notification to the user.
+type Notifier func(title, body string) error
+
+// SystemNotifier uses the notifier every desktop already has: notify-send
+// on Linux and the BSDs, osascript on macOS and a PowerShell balloon tip
+// on Windows, so no GUI toolkit is linked in.
+func SystemNotifier() Notifier {
+	return func(title, body string) error {
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("osascript", "-e",
+				fmt.Sprintf("display notification %s with title %s", appleString(body), appleString(title)))
+		case "windows":
+			// the balloon's script outlives it by design, so don't wait
+			cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", balloonScript)
+			cmd.Env = append(os.Environ(), "BOSSMAN_NOTIFY_TITLE="+title, "BOSSMAN_NOTIFY_BODY="+body)
+			if err := cmd.Start(); err != nil {
+				return fmt.Errorf("notify: %w", err)
+			}
+			go cmd.Wait()
+			return nil
+		default:
+			cmd = exec.Command("notify-send", "--app-name=bossman", title, body)
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("notify: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+}
+
+// balloonScript shows the title and body from the environment as a tray
+// balloon, which needs nothing beyond Windows Forms.
+const balloonScript = `Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, $env:BOSSMAN_NOTIFY_TITLE, $env:BOSSMAN_NOTIFY_BODY, 'Info')
+Start-Sleep -Seconds 10
+$icon.Dispose()`
+
+// appleString quotes s for AppleScript.
+func appleString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,129 @@
+//go:build tray
+
+package desktop
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"fyne.io/systray"
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/events"
+)
+
+// TrayAvailable reports whether this binary was built with the tray tag.
+const TrayAvailable = true
+
+func init() {
+	// macOS only runs a tray from the main thread, which is the one
+	// package init runs on
+	runtime.LockOSThread()
+}
+
+// RunTray shows a tray icon titled with the pending and blocked task
+// counts, refreshed on every change on bus and each minute, with menu
+// items to open the dashboard and to quit. It must be called from the
+// main goroutine and blocks until ctx is cancelled or the user quits,
+// when it calls quit.
+func RunTray(ctx context.Context, conn *sqlx.DB, bus *events.Bus, dashboardURL string, open func(string) error, quit func()) {
+	onReady := func() {
+		systray.SetIcon(trayIcon())
+		systray.SetTooltip("bossman")
+		counts := systray.AddMenuItem("", "")
+		counts.Disable()
+		systray.AddSeparator()
+		dashboard := systray.AddMenuItem("Open dashboard", dashboardURL)
+		exit := systray.AddMenuItem("Quit", "stop bossman desktop")
+
+		go func() {
+			sub, unsubscribe := bus.Subscribe()
+			defer unsubscribe()
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			refresh := func() {
+				label, err := trayLabel(ctx, conn)
+				if err != nil {
+					slog.Warn("tray counts", "err", err)
+					return
+				}
+				systray.SetTitle(label)
+				systray.SetTooltip("bossman: " + label)
+				counts.SetTitle(label)
+			}
+			refresh()
+			for {
+				select {
+				case <-ctx.Done():
+					systray.Quit()
+					return
+				case <-ticker.C:
+					refresh()
+				case <-sub:
+					refresh()
+				case <-dashboard.ClickedCh:
+					if err := open(dashboardURL); err != nil {
+						slog.Warn("open dashboard", "url", dashboardURL, "err", err)
+					}
+				case <-exit.ClickedCh:
+					systray.Quit()
+					return
+				}
+			}
+		}()
+	}
+	systray.Run(onReady, quit)
+}
+
+// trayLabel counts the way GET /dashboard/status does.
+func trayLabel(ctx context.Context, conn *sqlx.DB) (string, error) {
+	counts, err := db.CountTasksByStatus(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	blocked, err := db.CountBlockedTasks(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d pending, %d blocked", counts["pending"], blocked), nil
+}
+
+// trayIcon draws a filled circle. Windows wants an .ico, which may carry
+// the PNG as is.
+func trayIcon() []byte {
+	const size = 32
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	fill := color.NRGBA{R: 0x2f, G: 0x6f, B: 0xeb, A: 0xff}
+	for y := range size {
+		for x := range size {
+			dx, dy := x-size/2, y-size/2
+			if dx*dx+dy*dy <= (size/2-2)*(size/2-2) {
+				img.Set(x, y, fill)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	if runtime.GOOS != "windows" {
+		return buf.Bytes()
+	}
+	var ico bytes.Buffer
+	binary.Write(&ico, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+		Width, Height         uint8
+		Colors, Reserved2     uint8
+		Planes, BitCount      uint16
+		Size, Offset          uint32
+	}{Type: 1, Count: 1, Width: size, Height: size, Planes: 1, BitCount: 32, Size: uint32(buf.Len()), Offset: 22})
+	ico.Write(buf.Bytes())
+	return ico.Bytes()
+}
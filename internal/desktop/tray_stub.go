@@ -0,0 +1,19 @@
+//go:build !tray
+
+package desktop
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/events"
+)
+
+// TrayAvailable reports whether this binary was built with the tray tag;
+// without it no GUI toolkit is linked in and RunTray does nothing.
+const TrayAvailable = false
+
+// RunTray is a no-op without the tray build tag.
+func RunTray(ctx context.Context, conn *sqlx.DB, bus *events.Bus, dashboardURL string, open func(string) error, quit func()) {
+}
@@ -0,0 +1,127 @@
+package desktop
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/events"
+)
+
+// maxReminders is how many due tasks get a notification each; more than
+// that in one check collapse into a single summary.
+const maxReminders = 3
+
+// Watcher turns task changes into notifications: a reminder when an open
+// task comes within Lead of its due time, an alert when a task fails, and
+// one when an agent asks a question only a person can answer. Each task is
+// announced once per due time and once per failure.
+type Watcher struct {
+	conn     *sqlx.DB
+	notify   Notifier
+	lead     time.Duration
+	reminded map[string]db.Timestamp // task ID -> due_at it was reminded about
+	failed   map[string]bool
+}
+
+func NewWatcher(conn *sqlx.DB, notify Notifier, lead time.Duration) *Watcher {
+	return &Watcher{
+		conn:     conn,
+		notify:   notify,
+		lead:     lead,
+		reminded: make(map[string]db.Timestamp),
+		failed:   make(map[string]bool),
+	}
+}
+
+// Run checks for due tasks every minute and watches bus for failures
+// until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context, bus *events.Bus) {
+	sub, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	w.remind(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.remind(ctx)
+		case c := <-sub:
+			switch {
+			case c.Entity == "task" && c.Op == "update":
+				w.checkFailed(ctx, c.TaskID)
+			case c.Entity == "task" && c.Op == "insert":
+				w.checkQuestion(ctx, c.TaskID)
+			}
+		}
+	}
+}
+
+func (w *Watcher) remind(ctx context.Context) {
+	now := clock.From(ctx).Now()
+	due, err := db.ListDueTasks(ctx, w.conn, now.Add(w.lead))
+	if err != nil {
+		slog.Error("list due tasks", "err", err)
+		return
+	}
+	var fresh []db.Task
+	for _, t := range due {
+		if !w.reminded[t.ID].Equal(t.DueAt.Time) {
+			w.reminded[t.ID] = *t.DueAt
+			fresh = append(fresh, t)
+		}
+	}
+	if len(fresh) > maxReminders {
+		w.send("Tasks due", fmt.Sprintf("%d tasks are due or overdue", len(fresh)))
+		return
+	}
+	for _, t := range fresh {
+		title := "Task due soon"
+		if !t.DueAt.After(now) {
+			title = "Task overdue"
+		}
+		w.send(title, t.Description)
+	}
+}
+
+func (w *Watcher) checkFailed(ctx context.Context, id string) {
+	t, err := db.GetTask(ctx, w.conn, id)
+	if err != nil {
+		return // deleted since, or not ours to report
+	}
+	if t.Status != "failed" {
+		delete(w.failed, id)
+		return
+	}
+	if w.failed[id] {
+		return
+	}
+	w.failed[id] = true
+	body := t.Description
+	if t.Result != nil && *t.Result != "" {
+		body += ": " + string(*t.Result)
+	}
+	w.send("Task failed", body)
+}
+
+func (w *Watcher) checkQuestion(ctx context.Context, id string) {
+	t, err := db.GetTask(ctx, w.conn, id)
+	if err != nil || t.Kind != db.KindQuestion {
+		return
+	}
+	w.send("Question for you", t.Description)
+}
+
+func (w *Watcher) send(title, body string) {
+	if err := w.notify(title, body); err != nil {
+		slog.Warn("desktop notification", "err", err)
+	}
+}
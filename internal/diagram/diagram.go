@@ -0,0 +1,205 @@
+// Package diagram turns a dependency sketch into tasks: a Mermaid
+// flowchart or a Graphviz DOT digraph whose nodes are task descriptions
+// and whose arrows point from a task to the work it unblocks.
+//
+//	flowchart TD
+//	    design[Design the schema] --> migrate[Write the migration]
+//	    design --> api[Add the endpoint]
+//	    migrate & api --> ship[Ship it]
+//
+// A node's label is its description, or its id when it has none. Styling,
+// subgraph titles and edge labels carry no meaning and are skipped.
+package diagram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// Formats Parse accepts.
+const (
+	Mermaid = "mermaid"
+	DOT     = "dot"
+)
+
+// Plan is a parsed diagram: one fixture task per node, keyed by node id in
+// the order nodes first appear, blocked by the nodes with arrows into it.
+type Plan []db.SeedTask
+
+// Detect names the format of src from its header, or returns "".
+func Detect(src string) string {
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		first := strings.ToLower(strings.Fields(line)[0])
+		switch first {
+		case "flowchart", "graph":
+			if first == "graph" && strings.Contains(line, "{") {
+				return DOT // an undirected DOT graph, which Parse refuses
+			}
+			return Mermaid
+		case "digraph", "strict":
+			return DOT
+		}
+		if strings.HasPrefix(first, "digraph{") {
+			return DOT
+		}
+		return ""
+	}
+	return ""
+}
+
+// Parse reads a diagram in format, or in the format Detect finds when
+// format is empty.
+func Parse(format, src string) (Plan, error) {
+	if format == "" {
+		if format = Detect(src); format == "" {
+			return nil, fmt.Errorf("not a Mermaid flowchart or DOT digraph")
+		}
+	}
+	var g graph
+	var err error
+	switch format {
+	case Mermaid:
+		err = parseMermaid(&g, src)
+	case DOT:
+		err = parseDOT(&g, src)
+	default:
+		return nil, fmt.Errorf("unknown diagram format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(g.order) == 0 {
+		return nil, fmt.Errorf("the diagram has no nodes")
+	}
+	return g.plan()
+}
+
+// Import parses a diagram and adds its tasks and blockers in one
+// transaction, in project when it's set. It returns the new task ids by
+// node id.
+func Import(ctx context.Context, conn *sqlx.DB, format, src, project string) (map[string]string, error) {
+	plan, err := Parse(format, src)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plan {
+		plan[i].Project = project
+	}
+	return db.Seed(ctx, conn, plan)
+}
+
+// graph collects nodes and edges while a parser runs.
+type graph struct {
+	order  []string            // node ids, first appearance first
+	labels map[string]string   // node id -> description
+	after  map[string][]string // node id -> the nodes it waits for
+}
+
+// node declares id, labelling it when label isn't empty. A later label
+// replaces an earlier one, as in both languages.
+func (g *graph) node(id, label string) {
+	if g.labels == nil {
+		g.labels = make(map[string]string)
+		g.after = make(map[string][]string)
+	}
+	if _, ok := g.labels[id]; !ok {
+		g.order = append(g.order, id)
+		g.labels[id] = ""
+	}
+	if label = cleanLabel(label); label != "" {
+		g.labels[id] = label
+	}
+}
+
+// edge records that to can't start until from is done.
+func (g *graph) edge(from, to string) error {
+	if from == to {
+		return fmt.Errorf("%s points at itself", from)
+	}
+	for _, id := range g.after[to] {
+		if id == from {
+			return nil
+		}
+	}
+	g.after[to] = append(g.after[to], from)
+	return nil
+}
+
+func (g *graph) plan() (Plan, error) {
+	if cycle := g.cycle(); cycle != nil {
+		return nil, fmt.Errorf("the diagram has a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	plan := make(Plan, len(g.order))
+	for i, id := range g.order {
+		desc := g.labels[id]
+		if desc == "" {
+			desc = id
+		}
+		plan[i] = db.SeedTask{Key: id, Description: desc, BlockedBy: g.after[id]}
+	}
+	return plan, nil
+}
+
+// cycle returns the nodes of a dependency cycle in arrow order, or nil.
+func (g *graph) cycle() []string {
+	const (
+		unseen = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(g.order))
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range g.after[id] {
+			switch state[dep] {
+			case visiting:
+				// stack runs from id back along blockers; flip it into arrow order
+				start := len(stack) - 1
+				for stack[start] != dep {
+					start--
+				}
+				cycle := []string{}
+				for i := len(stack) - 1; i >= start; i-- {
+					cycle = append(cycle, stack[i])
+				}
+				return append(cycle, stack[len(stack)-1])
+			case unseen:
+				if c := visit(dep); c != nil {
+					return c
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+		return nil
+	}
+	for _, id := range g.order {
+		if state[id] == unseen {
+			if c := visit(id); c != nil {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// cleanLabel folds line breaks, which both languages allow in labels, into
+// spaces.
+func cleanLabel(s string) string {
+	for _, br := range []string{"<br/>", "<br />", "<br>", `\n`, `\l`, `\r`} {
+		s = strings.ReplaceAll(s, br, " ")
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,129 @@
+package diagram
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summary renders a plan as "key=description<blockers" lines.
+func summary(plan Plan) string {
+	var lines []string
+	for _, t := range plan {
+		line := t.Key + "=" + t.Description
+		if len(t.BlockedBy) > 0 {
+			line += "<" + strings.Join(t.BlockedBy, ",")
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{
+			name: "mermaid shapes and chains",
+			src: `flowchart TD
+    %% the release
+    design[Design the schema] --> migrate(Write the migration) --> ship{{Ship it}}
+    design --> api(["Add the <br> endpoint"]):::todo
+    api --> ship; docs>Docs]
+    classDef todo fill:#eee`,
+			want: `design=Design the schema
+migrate=Write the migration<design
+ship=Ship it<migrate,api
+api=Add the endpoint<design
+docs=Docs`,
+		},
+		{
+			name: "mermaid groups, link text and reversed links",
+			src: `graph LR
+    a & b -->|both| c
+    c -- then --> d
+    e <-- d
+    f -.-> e
+    e ==> g
+    g --o h
+    subgraph later
+        h --- i
+    end`,
+			want: `a=a
+b=b
+c=c<a,b
+d=d<c
+e=e<d,f
+f=f
+g=g<e
+h=h<g
+i=i<h`,
+		},
+		{
+			name: "dot",
+			src: `// the release
+digraph release {
+    rankdir=LR
+    node [shape=box]
+    design [label="Design the\nschema"];
+    design -> migrate -> ship
+    design -> { api docs } [color=red]
+    subgraph cluster_0 { label="finish"; api -> "ship" }
+    docs [label=<<b>Write</b> docs>]
+}`,
+			want: `design=Design the schema
+migrate=migrate<design
+ship=ship<migrate,api
+api=api<design
+docs=Write docs<design`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Parse("", tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(plan); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"unknown", "sequenceDiagram\n  a->>b: hi", "not a Mermaid flowchart or DOT digraph"},
+		{"cycle", "flowchart TD\n  a --> b --> c --> a", "cycle: b -> c -> a -> b"},
+		{"self", "digraph { a -> a }", "a points at itself"},
+		{"two-way", "flowchart TD\n  a <--> b", "two-way link"},
+		{"undirected graph", "graph { a -- b }", "undirected graph"},
+		{"undirected edge", "digraph { a -- b }", "undirected edge"},
+		{"unclosed", "digraph { a -> b", "unexpected end of diagram"},
+		{"empty", "flowchart TD\n", "no nodes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("", tt.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	got := []string{
+		Detect("%% plan\nflowchart LR\n a-->b"),
+		Detect("graph TD\n a-->b"),
+		Detect("strict digraph g {}"),
+		Detect("digraph{a->b}"),
+		Detect("pie title Pets"),
+	}
+	want := []string{Mermaid, Mermaid, DOT, DOT, ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Detect = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// dotToken is an id (bare, numeral or quoted) or one of the punctuation
+// tokens { } [ ] ; , = : -> --.
+type dotToken struct {
+	text  string
+	id    bool
+	quote bool // a quoted id, which is never a keyword
+	line  int
+}
+
+func parseDOT(g *graph, src string) error {
+	toks, err := lexDOT(src)
+	if err != nil {
+		return err
+	}
+	p := &dotParser{toks: toks, g: g}
+	return p.graph()
+}
+
+func lexDOT(src string) ([]dotToken, error) {
+	var toks []dotToken
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' && (i == 0 || src[i-1] == '\n'):
+			// a preprocessor line
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case strings.HasPrefix(src[i:], "->") || strings.HasPrefix(src[i:], "--"):
+			toks = append(toks, dotToken{text: src[i : i+2], line: line})
+			i += 2
+		case strings.ContainsRune("{}[];,=:", rune(c)):
+			toks = append(toks, dotToken{text: string(c), line: line})
+			i++
+		case c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) && src[j+1] == '"' {
+					j++
+				} else if src[j] == '\\' && j+1 < len(src) && src[j+1] == '\n' {
+					j++ // a line continuation
+					line++
+					continue
+				}
+				if src[j] == '\n' {
+					line++
+				}
+				b.WriteByte(src[j])
+			}
+			if j == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			toks = append(toks, dotToken{text: b.String(), id: true, quote: true, line: line})
+			i = j + 1
+		case c == '<':
+			// an HTML label: keep its text, drop the markup
+			depth, j := 0, i
+			for ; j < len(src); j++ {
+				if src[j] == '<' {
+					depth++
+				} else if src[j] == '>' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			if j == len(src) {
+				return nil, fmt.Errorf("line %d: unterminated HTML label", line)
+			}
+			toks = append(toks, dotToken{text: stripTags(src[i+1 : j]), id: true, quote: true, line: line})
+			line += strings.Count(src[i:j], "\n")
+			i = j + 1
+		default:
+			j := i
+			for j < len(src) {
+				r := rune(src[j])
+				if r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' ||
+					(r == '-' && j == i) {
+					j++
+					continue
+				}
+				break
+			}
+			if j == i {
+				return nil, fmt.Errorf("line %d: unexpected %q", line, c)
+			}
+			toks = append(toks, dotToken{text: src[i:j], id: true, line: line})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// stripTags drops the tags from an HTML label.
+func stripTags(s string) string {
+	var b strings.Builder
+	in := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			in = true
+			b.WriteByte(' ')
+		case r == '>':
+			in = false
+		case !in:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+type dotParser struct {
+	toks []dotToken
+	pos  int
+	g    *graph
+}
+
+func (p *dotParser) peek() dotToken {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return dotToken{}
+}
+
+func (p *dotParser) next() dotToken {
+	t := p.peek()
+	if p.pos < len(p.toks) {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether t is the keyword kw, which DOT matches
+// case-insensitively.
+func keyword(t dotToken, kw string) bool {
+	return t.id && !t.quote && strings.EqualFold(t.text, kw)
+}
+
+func (p *dotParser) expect(text string) error {
+	t := p.next()
+	if t.id || t.text != text {
+		return p.errorf(t, "want %q", text)
+	}
+	return nil
+}
+
+func (p *dotParser) errorf(t dotToken, format string, args ...any) error {
+	if t.text == "" && !t.id {
+		return fmt.Errorf("unexpected end of diagram: "+format, args...)
+	}
+	return fmt.Errorf("line %d: at %q: "+format, append([]any{t.line, t.text}, args...)...)
+}
+
+func (p *dotParser) graph() error {
+	if keyword(p.peek(), "strict") {
+		p.next()
+	}
+	if t := p.next(); keyword(t, "graph") {
+		return p.errorf(t, "an undirected graph has no order; use digraph and ->")
+	} else if !keyword(t, "digraph") {
+		return p.errorf(t, "want digraph")
+	}
+	if p.peek().id {
+		p.next() // the graph's name
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	if _, err := p.statements(); err != nil {
+		return err
+	}
+	if t := p.peek(); t.id || t.text != "" {
+		return p.errorf(t, "want the end of the diagram")
+	}
+	return nil
+}
+
+// statements parses up to and including the closing brace and returns
+// every node declared or mentioned inside, for edges to a subgraph.
+func (p *dotParser) statements() ([]string, error) {
+	var nodes []string
+	for {
+		t := p.peek()
+		switch {
+		case !t.id && t.text == "}":
+			p.next()
+			return nodes, nil
+		case !t.id && t.text == "":
+			return nil, p.errorf(t, "want }")
+		case !t.id && t.text == ";":
+			p.next()
+			continue
+		}
+		got, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, got...)
+	}
+}
+
+func (p *dotParser) statement() ([]string, error) {
+	t := p.peek()
+	if keyword(t, "graph") || keyword(t, "node") || keyword(t, "edge") {
+		p.next()
+		_, err := p.attributes()
+		return nil, err
+	}
+	if t.id && p.pos+1 < len(p.toks) && p.toks[p.pos+1].text == "=" && !p.toks[p.pos+1].id {
+		p.pos += 3 // a graph attribute such as rankdir=LR
+		return nil, nil
+	}
+
+	prev, single, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	all := prev
+	edges := false
+	for {
+		op := p.peek()
+		if op.id || (op.text != "->" && op.text != "--") {
+			break
+		}
+		if op.text == "--" {
+			return nil, p.errorf(op, "an undirected edge has no order; use ->")
+		}
+		p.next()
+		next, _, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		for _, from := range prev {
+			for _, to := range next {
+				if err := p.g.edge(from, to); err != nil {
+					return nil, err
+				}
+			}
+		}
+		all = append(all, next...)
+		prev = next
+		edges = true
+	}
+	attrs, err := p.attributes()
+	if err != nil {
+		return nil, err
+	}
+	if !edges && single {
+		p.g.node(prev[0], attrs["label"])
+	}
+	return all, nil
+}
+
+// operand reads a node id or a subgraph. single is set for a plain node.
+func (p *dotParser) operand() (nodes []string, single bool, err error) {
+	t := p.next()
+	if keyword(t, "subgraph") {
+		if p.peek().id {
+			p.next()
+		}
+		t = p.next()
+	}
+	if !t.id && t.text == "{" {
+		nodes, err := p.statements()
+		return nodes, false, err
+	}
+	if !t.id || keyword(t, "node") || keyword(t, "edge") || keyword(t, "graph") || keyword(t, "digraph") {
+		return nil, false, p.errorf(t, "want a node")
+	}
+	// a port, as in a:n or a:port:sw, doesn't change the node
+	for !p.peek().id && p.peek().text == ":" {
+		p.next()
+		if !p.next().id {
+			return nil, false, p.errorf(t, "bad port")
+		}
+	}
+	p.g.node(t.text, "")
+	return []string{t.text}, true, nil
+}
+
+// attributes reads any number of [k=v, ...] lists.
+func (p *dotParser) attributes() (map[string]string, error) {
+	attrs := map[string]string{}
+	for !p.peek().id && p.peek().text == "[" {
+		p.next()
+		for {
+			t := p.next()
+			if !t.id && t.text == "]" {
+				break
+			}
+			if !t.id && (t.text == "," || t.text == ";") {
+				continue
+			}
+			if !t.id {
+				return nil, p.errorf(t, "want an attribute")
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			v := p.next()
+			if !v.id {
+				return nil, p.errorf(v, "want a value for %s", t.text)
+			}
+			attrs[strings.ToLower(t.text)] = v.text
+		}
+	}
+	return attrs, nil
+}
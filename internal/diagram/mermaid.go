@@ -0,0 +1,262 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// mermaidSkip are statements that style or group nodes without declaring
+// any.
+var mermaidSkip = []string{"classDef", "class", "style", "linkStyle", "click", "direction", "end"}
+
+// shapes pairs each node shape's opening bracket with its closers, longest
+// first so "([" isn't read as "(".
+var shapes = []struct{ open, close string }{
+	{"([", "])"}, {"[[", "]]"}, {"[(", ")]"}, {"(((", ")))"}, {"((", "))"}, {"{{", "}}"},
+	{"[/", "/]"}, {"[/", `\]`}, {`[\`, `\]`}, {`[\`, "/]"},
+	{"[", "]"}, {"(", ")"}, {"{", "}"}, {">", "]"},
+}
+
+func parseMermaid(g *graph, src string) error {
+	header := false
+	for n, line := range strings.Split(src, "\n") {
+		if i := strings.Index(line, "%%"); i >= 0 {
+			line = line[:i]
+		}
+		for _, stmt := range splitStatements(line) {
+			stmt = strings.TrimSpace(stmt)
+			if stmt == "" {
+				continue
+			}
+			word, _, _ := strings.Cut(stmt, " ")
+			if !header {
+				if word != "flowchart" && word != "graph" {
+					return fmt.Errorf("line %d: want a flowchart header, got %q", n+1, stmt)
+				}
+				header = true
+				continue
+			}
+			if word == "subgraph" || isMermaidSkip(word) {
+				continue
+			}
+			p := &mermaidLine{s: stmt}
+			if err := p.statement(g); err != nil {
+				return fmt.Errorf("line %d: %w", n+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isMermaidSkip(word string) bool {
+	for _, w := range mermaidSkip {
+		if word == w {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStatements splits a line at semicolons outside labels.
+func splitStatements(line string) []string {
+	var out []string
+	depth, quoted, start := 0, false, 0
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case strings.ContainsRune("[({", r):
+			depth++
+		case strings.ContainsRune("])}", r):
+			depth--
+		case r == ';' && depth <= 0:
+			out = append(out, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, line[start:])
+}
+
+// mermaidLine parses one statement: groups of nodes joined by links, as in
+// "a & b --> c[Label] -->|edge text| d".
+type mermaidLine struct {
+	s   string
+	pos int
+}
+
+func (p *mermaidLine) statement(g *graph) error {
+	prev, err := p.group(g)
+	if err != nil {
+		return err
+	}
+	for {
+		p.space()
+		if p.pos == len(p.s) {
+			return nil
+		}
+		reverse, err := p.link()
+		if err != nil {
+			return err
+		}
+		next, err := p.group(g)
+		if err != nil {
+			return err
+		}
+		for _, a := range prev {
+			for _, b := range next {
+				from, to := a, b
+				if reverse {
+					from, to = b, a
+				}
+				if err := g.edge(from, to); err != nil {
+					return err
+				}
+			}
+		}
+		prev = next
+	}
+}
+
+// group reads "a & b & c".
+func (p *mermaidLine) group(g *graph) ([]string, error) {
+	var ids []string
+	for {
+		id, err := p.node(g)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		p.space()
+		if !strings.HasPrefix(p.s[p.pos:], "&") {
+			return ids, nil
+		}
+		p.pos++
+	}
+}
+
+// node reads an id with an optional shaped label and :::class.
+func (p *mermaidLine) node(g *graph) (string, error) {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.s) {
+		r := rune(p.s[p.pos])
+		if r >= 0x80 || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("want a node at %q", p.s[start:])
+	}
+	id := p.s[start:p.pos]
+	label := ""
+	rest := p.s[p.pos:]
+	for _, sh := range shapes {
+		if !strings.HasPrefix(rest, sh.open) {
+			continue
+		}
+		body := rest[len(sh.open):]
+		if strings.HasPrefix(body, `"`) {
+			end := strings.Index(body[1:], `"`)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated label on %s", id)
+			}
+			label = body[1 : end+1]
+			if !strings.HasPrefix(strings.TrimSpace(body[end+2:]), sh.close) {
+				continue
+			}
+			p.pos += len(sh.open) + end + 2
+			p.pos += strings.Index(p.s[p.pos:], sh.close) + len(sh.close)
+			break
+		}
+		end := strings.Index(body, sh.close)
+		if end < 0 {
+			continue
+		}
+		label = body[:end]
+		p.pos += len(sh.open) + end + len(sh.close)
+		break
+	}
+	if strings.HasPrefix(p.s[p.pos:], ":::") {
+		p.pos += 3
+		for p.pos < len(p.s) && !unicode.IsSpace(rune(p.s[p.pos])) && !strings.ContainsRune("&-=.<~", rune(p.s[p.pos])) {
+			p.pos++
+		}
+	}
+	g.node(id, label)
+	return id, nil
+}
+
+// link reads an arrow such as -->, ---, -.->, ==>, --o, <--, "-- text -->"
+// or "-->|text|". reverse is set when it points right to left.
+func (p *mermaidLine) link() (reverse bool, err error) {
+	start := p.pos
+	left := strings.HasPrefix(p.s[p.pos:], "<")
+	if left {
+		p.pos++
+	}
+	if !p.linkBody() {
+		return false, fmt.Errorf("want a link at %q", p.s[start:])
+	}
+	// "-- text -->": a two-character opener followed by text
+	if opener := strings.TrimPrefix(p.s[start:p.pos], "<"); opener == "--" || opener == "==" || opener == "-." {
+		if p.pos < len(p.s) && p.s[p.pos] == ' ' {
+			text := p.s[p.pos:]
+			end := -1
+			for _, closer := range []string{"--", "==", ".-"} {
+				if i := strings.Index(text, closer); i >= 0 && (end < 0 || i < end) {
+					end = i
+				}
+			}
+			// with no closing strokes it was a plain link after all
+			if end >= 0 {
+				p.pos += end
+				p.linkBody()
+			}
+		}
+	}
+	right := false
+	if p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case '>':
+			right = true
+			p.pos++
+		case 'o', 'x':
+			// a circle or cross head, unless it starts the next node's id
+			if p.pos+1 == len(p.s) || p.s[p.pos+1] == ' ' || p.s[p.pos+1] == '|' {
+				right = true
+				p.pos++
+			}
+		}
+	}
+	if left && right {
+		return false, fmt.Errorf("two-way link at %q: pick which task goes first", p.s[start:])
+	}
+	p.space()
+	if strings.HasPrefix(p.s[p.pos:], "|") {
+		end := strings.Index(p.s[p.pos+1:], "|")
+		if end < 0 {
+			return false, fmt.Errorf("unterminated link text at %q", p.s[start:])
+		}
+		p.pos += end + 2
+	}
+	return left, nil
+}
+
+// linkBody consumes a run of link strokes, at least two long.
+func (p *mermaidLine) linkBody() bool {
+	start := p.pos
+	for p.pos < len(p.s) && strings.ContainsRune("-=.~", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.pos-start >= 2
+}
+
+func (p *mermaidLine) space() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
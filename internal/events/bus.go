@@ -0,0 +1,85 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// Bus fans out database changes to in-process subscribers.
+// Watch feeds it by tailing the changes table, which picks up writes
+// from every process sharing the database.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[chan db.Change]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[chan db.Change]struct{})}
+}
+
+// Subscribe returns a channel of changes and a function to unsubscribe.
+// Slow subscribers miss events rather than block the bus; they should
+// re-read the feed from their last seq.
+func (b *Bus) Subscribe() (<-chan db.Change, func()) {
+	ch := make(chan db.Change, 64)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *Bus) Publish(c db.Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
+
+// Watch records the current head of the changes table, then polls it
+// every interval in the background and publishes new rows until ctx is
+// cancelled. Reading the head up front means nothing written after Watch
+// returns can be missed.
+func (b *Bus) Watch(ctx context.Context, conn *sqlx.DB, interval time.Duration) error {
+	last, err := db.LatestChangeSeq(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("read changes head: %w", err)
+	}
+	go b.poll(ctx, conn, interval, last)
+	return nil
+}
+
+func (b *Bus) poll(ctx context.Context, conn *sqlx.DB, interval time.Duration, last int64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changes, err := db.ChangesSince(ctx, conn, last, 0)
+		if err != nil {
+			slog.Error("poll changes", "err", err)
+			continue
+		}
+		for _, c := range changes {
+			b.Publish(c)
+			last = c.Seq
+		}
+	}
+}
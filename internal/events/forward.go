@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+
+	"procdexeh/bossman/internal/mcp"
+)
+
+// TaskURI is how a task is addressed in resource notifications.
+func TaskURI(id string) string {
+	return "bossman://task/" + id
+}
+
+// Forward relays bus changes to MCP sessions until ctx is cancelled:
+// watchers of a task get resources/updated, and everyone hears
+// resources/list_changed when tasks appear or disappear.
+func Forward(ctx context.Context, bus *Bus, sessions *mcp.SessionRegistry) {
+	sub, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-sub:
+			sessions.NotifyWatchers(TaskURI(c.TaskID))
+			if c.Entity == "task" && c.Op != "update" {
+				sessions.Broadcast("notifications/resources/list_changed", nil)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package guard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// Activity kinds the detector tracks.
+const (
+	KindWrite  = "write"
+	KindDelete = "delete"
+)
+
+// Limit is the most events of one kind considered normal within Window.
+type Limit struct {
+	Count  int
+	Window time.Duration
+}
+
+// String is the limit as Set reads it, e.g. 500/1m0s, or 0 when off.
+func (l Limit) String() string {
+	if l.Count == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Count, l.Window)
+}
+
+// Set parses COUNT/WINDOW, e.g. 500/1m, so a Limit can be a flag. A count
+// of 0 turns the limit off, and needs no window.
+func (l *Limit) Set(s string) error {
+	if s == "0" {
+		*l = Limit{}
+		return nil
+	}
+	count, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return fmt.Errorf("limit %q: want COUNT/WINDOW, e.g. 500/1m", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return fmt.Errorf("limit %q: count must be a whole number", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("limit %q: window must be a positive duration", s)
+	}
+	*l = Limit{Count: n, Window: d}
+	return nil
+}
+
+type Config struct {
+	Limits map[string]Limit
+	// AutoReadOnly suspends writes on alert until a human runs `bossman unlock`.
+	AutoReadOnly bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Limits: map[string]Limit{
+			KindWrite:  {Count: 500, Window: time.Minute},
+			KindDelete: {Count: 50, Window: time.Minute},
+		},
+	}
+}
+
+// Alert describes a detected spike.
+type Alert struct {
+	Kind   string        `json:"kind"`
+	Count  int           `json:"count"`
+	Window time.Duration `json:"window"`
+	At     time.Time     `json:"at"`
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("abnormal activity: %d %s operations within %s", a.Count, a.Kind, a.Window)
+}
+
+// Detector counts events in a sliding window per kind and raises an alert
+// when a limit is exceeded. A kind alerts at most once per window.
+type Detector struct {
+	cfg     Config
+	onAlert func(Alert)
+	clock   clock.Clock
+	mu      sync.Mutex
+	events  map[string][]time.Time
+}
+
+func NewDetector(cfg Config, onAlert func(Alert)) *Detector {
+	return &Detector{
+		cfg:     cfg,
+		onAlert: onAlert,
+		clock:   clock.Real{},
+		events:  make(map[string][]time.Time),
+	}
+}
+
+// SetClock replaces the wall clock, for tests and simulations.
+// Must be called before first use.
+func (d *Detector) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+func (d *Detector) Config() Config { return d.cfg }
+
+// Observe records one event of kind and fires onAlert if it tips over the limit.
+func (d *Detector) Observe(kind string) {
+	limit, ok := d.cfg.Limits[kind]
+	if !ok || limit.Count <= 0 {
+		return
+	}
+
+	now := d.clock.Now()
+	d.mu.Lock()
+	events := append(prune(d.events[kind], now.Add(-limit.Window)), now)
+
+	var alert *Alert
+	if len(events) > limit.Count {
+		alert = &Alert{Kind: kind, Count: len(events), Window: limit.Window, At: now}
+		events = events[:0] // start counting afresh so we don't alert on every call
+	}
+	d.events[kind] = events
+	d.mu.Unlock()
+
+	if alert != nil && d.onAlert != nil {
+		d.onAlert(*alert)
+	}
+}
+
+// prune drops leading events older than cutoff; events must be in time order.
+func prune(events []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(events) && events[i].Before(cutoff) {
+		i++
+	}
+	return events[i:]
+}
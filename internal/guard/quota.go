@@ -0,0 +1,120 @@
+package guard
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+)
+
+// QuotaConfig holds soft limits. Crossing them only produces warnings;
+// nothing is rejected.
+type QuotaConfig struct {
+	OpenTasks    int     // pending + in_progress tasks
+	AgentCreates Limit   // tasks created by one agent per window
+	WarnAt       float64 // fraction of a limit at which warnings start
+}
+
+func DefaultQuotaConfig() QuotaConfig {
+	return QuotaConfig{
+		OpenTasks:    1000,
+		AgentCreates: Limit{Count: 200, Window: time.Hour},
+		WarnAt:       0.8,
+	}
+}
+
+// Usage is one quota's current standing.
+type Usage struct {
+	Name     string `json:"name"`
+	Used     int    `json:"used"`
+	Limit    int    `json:"limit"`
+	Window   string `json:"window,omitempty"`
+	Warning  bool   `json:"warning"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+// Quotas tracks rolling per-agent creation counts and evaluates soft limits.
+type Quotas struct {
+	cfg     QuotaConfig
+	clock   clock.Clock
+	mu      sync.Mutex
+	creates map[string][]time.Time
+	warned  map[string]bool
+}
+
+func NewQuotas(cfg QuotaConfig) *Quotas {
+	return &Quotas{
+		cfg:     cfg,
+		clock:   clock.Real{},
+		creates: make(map[string][]time.Time),
+		warned:  make(map[string]bool),
+	}
+}
+
+// SetClock replaces the wall clock, for tests and simulations.
+// Must be called before first use.
+func (q *Quotas) SetClock(c clock.Clock) {
+	q.clock = c
+}
+
+// RecordCreate notes that agent created a task and returns its usage.
+func (q *Quotas) RecordCreate(agent string) Usage {
+	now := q.clock.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	events := append(prune(q.creates[agent], now.Add(-q.cfg.AgentCreates.Window)), now)
+	q.creates[agent] = events
+	return q.usage("agent:"+agent, len(events), q.cfg.AgentCreates.Count, q.cfg.AgentCreates.Window)
+}
+
+// OpenTasks evaluates the open-task quota against a count from the database.
+func (q *Quotas) OpenTasks(open int) Usage {
+	return q.usage("open_tasks", open, q.cfg.OpenTasks, 0)
+}
+
+// Agents returns usage for every agent seen in the current window.
+func (q *Quotas) Agents() []Usage {
+	now := q.clock.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []Usage
+	for agent, events := range q.creates {
+		events = prune(events, now.Add(-q.cfg.AgentCreates.Window))
+		q.creates[agent] = events
+		if len(events) == 0 {
+			continue
+		}
+		out = append(out, q.usage("agent:"+agent, len(events), q.cfg.AgentCreates.Count, q.cfg.AgentCreates.Window))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ShouldWarn is true the first time u enters the warning band; it re-arms
+// once usage drops back below it.
+func (q *Quotas) ShouldWarn(u Usage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !u.Warning {
+		delete(q.warned, u.Name)
+		return false
+	}
+	if q.warned[u.Name] {
+		return false
+	}
+	q.warned[u.Name] = true
+	return true
+}
+
+func (q *Quotas) usage(name string, used, limit int, window time.Duration) Usage {
+	u := Usage{Name: name, Used: used, Limit: limit}
+	if window > 0 {
+		u.Window = window.String()
+	}
+	if limit > 0 {
+		u.Warning = float64(used) >= q.cfg.WarnAt*float64(limit)
+		u.Exceeded = used > limit
+	}
+	return u
+}
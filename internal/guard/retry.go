@@ -0,0 +1,40 @@
+package guard
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryError rejects a call that may succeed later. Callers should wait
+// RetryAfter() before trying again.
+type RetryError struct {
+	Reason  string
+	After   time.Duration
+	Attempt int // consecutive rejections for this caller, starting at 1
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", e.Reason, e.After.Round(time.Millisecond))
+}
+
+func (e *RetryError) RetryAfter() time.Duration { return e.After }
+
+// Backoff computes exponential delays: Base, 2*Base, 4*Base... capped at Max.
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+var DefaultBackoff = Backoff{Base: 250 * time.Millisecond, Max: time.Minute}
+
+// Delay returns the wait for the given attempt, never less than floor.
+func (b Backoff) Delay(attempt int, floor time.Duration) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	d = min(d, b.Max)
+	return max(d, floor)
+}
+
+func (e *RetryError) RetryAttempt() int { return e.Attempt }
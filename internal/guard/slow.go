@@ -0,0 +1,177 @@
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redaction says what slow-call logs hide. Tool arguments under Keys, at
+// any depth, are replaced; query parameters can't be told apart by name,
+// so string parameters longer than MaxQueryString, or holding JSON such as
+// metadata, are replaced instead, which keeps ids and statuses but hides
+// free text.
+type Redaction struct {
+	Keys           []string
+	MaxQueryString int // 0 keeps every parameter
+}
+
+func DefaultRedaction() Redaction {
+	return Redaction{
+		Keys:           []string{"body", "comment", "context", "document", "metadata", "result", "text"},
+		MaxQueryString: 64,
+	}
+}
+
+// Args returns tool arguments with redacted keys' values replaced.
+// Arguments that aren't valid JSON are replaced whole.
+func (r Redaction) Args(args json.RawMessage) json.RawMessage {
+	if len(args) == 0 {
+		return args
+	}
+	var v any
+	if err := json.Unmarshal(args, &v); err != nil {
+		return json.RawMessage(`"[redacted]"`)
+	}
+	out, err := json.Marshal(r.redact(v))
+	if err != nil {
+		return json.RawMessage(`"[redacted]"`)
+	}
+	return out
+}
+
+func (r Redaction) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if slices.Contains(r.Keys, k) {
+				v[k] = "[redacted]"
+			} else {
+				v[k] = r.redact(val)
+			}
+		}
+	case []any:
+		for i, val := range v {
+			v[i] = r.redact(val)
+		}
+	}
+	return v
+}
+
+// Params returns query parameters with long strings and JSON replaced.
+func (r Redaction) Params(args []any) []any {
+	out := make([]any, len(args))
+	for i, a := range args {
+		out[i] = a
+		if r.MaxQueryString <= 0 {
+			continue
+		}
+		var text string
+		switch a := a.(type) {
+		case string:
+			text = a
+		case []byte:
+			text = string(a)
+		default:
+			continue
+		}
+		if n := len(text); n > r.MaxQueryString || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+			out[i] = fmt.Sprintf("[redacted %d bytes]", n)
+		}
+	}
+	return out
+}
+
+// SlowConfig holds the thresholds above which queries and tool calls are
+// logged in full. Zero turns either off.
+type SlowConfig struct {
+	Query  time.Duration
+	Tool   time.Duration
+	Redact Redaction
+}
+
+func DefaultSlowConfig() SlowConfig {
+	return SlowConfig{
+		Query:  250 * time.Millisecond,
+		Tool:   2 * time.Second,
+		Redact: DefaultRedaction(),
+	}
+}
+
+// SlowStats counts slow queries and tool calls since startup, by tool and
+// by the actor (agent and session) that caused them.
+type SlowStats struct {
+	QueryThresholdMS int64          `json:"query_threshold_ms"`
+	ToolThresholdMS  int64          `json:"tool_threshold_ms"`
+	Queries          int            `json:"queries"`
+	Tools            int            `json:"tools"`
+	ByTool           map[string]int `json:"by_tool"`
+	ByActor          map[string]int `json:"by_actor"`
+}
+
+// SlowLog logs queries and tool calls that cross their threshold, with
+// parameters redacted, and counts them so operators can see which agent
+// behaviors hurt the database.
+type SlowLog struct {
+	cfg    SlowConfig
+	logger *slog.Logger
+	mu     sync.Mutex
+	stats  SlowStats
+}
+
+func NewSlowLog(cfg SlowConfig, logger *slog.Logger) *SlowLog {
+	return &SlowLog{
+		cfg:    cfg,
+		logger: logger,
+		stats: SlowStats{
+			QueryThresholdMS: cfg.Query.Milliseconds(),
+			ToolThresholdMS:  cfg.Tool.Milliseconds(),
+			ByTool:           make(map[string]int),
+			ByActor:          make(map[string]int),
+		},
+	}
+}
+
+func (l *SlowLog) Config() SlowConfig {
+	return l.cfg
+}
+
+// Query records a statement the database layer reported as slow; the
+// threshold was applied there.
+func (l *SlowLog) Query(actor, query string, args []any, took time.Duration) {
+	l.mu.Lock()
+	l.stats.Queries++
+	l.stats.ByActor[actor]++
+	l.mu.Unlock()
+	l.logger.Warn("slow query", "took", took.Round(time.Millisecond), "actor", actor,
+		"query", strings.Join(strings.Fields(query), " "), "args", l.cfg.Redact.Params(args))
+}
+
+// Tool records a finished tool call if it ran for the threshold or longer.
+func (l *SlowLog) Tool(actor, tool string, args json.RawMessage, took time.Duration) {
+	if l.cfg.Tool <= 0 || took < l.cfg.Tool {
+		return
+	}
+	l.mu.Lock()
+	l.stats.Tools++
+	l.stats.ByTool[tool]++
+	l.stats.ByActor[actor]++
+	l.mu.Unlock()
+	l.logger.Warn("slow tool call", "took", took.Round(time.Millisecond), "actor", actor,
+		"tool", tool, "args", string(l.cfg.Redact.Args(args)))
+}
+
+// Stats returns a copy of the counters.
+func (l *SlowLog) Stats() SlowStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.stats
+	s.ByTool = maps.Clone(l.stats.ByTool)
+	s.ByActor = maps.Clone(l.stats.ByActor)
+	return s
+}
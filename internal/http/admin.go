@@ -0,0 +1,263 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log/slog"
+	gohttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/guard"
+	"procdexeh/bossman/internal/maintenance"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// Admin serves the /api/v1/admin endpoints: connected MCP sessions with
+// their running tool calls and queue depths, maintenance job states and
+// dead letters, slow query and tool call counts, and replaying a dead
+// letter, cancelling a stuck call, dropping a session, taking a backup or
+// optimizing the database.
+// Every request must carry
+// Token as a bearer token, which is what grants the admin scope; with no
+// token configured the endpoints refuse everything.
+type Admin struct {
+	Token    string
+	Sessions *mcp.SessionRegistry // nil in processes that serve no MCP sessions
+	Jobs     *maintenance.Runner  // nil if maintenance isn't running
+	Slow     *guard.SlowLog       // nil if slow calls aren't tracked
+	DB       *sqlx.DB             // the SQLite database to back up or optimize; nil disables both
+	// BackupDir is where backups go without ?path; empty means beside
+	// the database file.
+	BackupDir string
+}
+
+// AdminTokenEnv names the environment variable holding the admin token.
+const AdminTokenEnv = "BOSSMAN_ADMIN_TOKEN"
+
+// adminCall is a running call with the session it belongs to.
+type adminCall struct {
+	Session string `json:"session"`
+	mcp.CallInfo
+}
+
+func (a *Admin) sessions() []mcp.SessionInfo {
+	if a.Sessions == nil {
+		return []mcp.SessionInfo{}
+	}
+	if s := a.Sessions.Sessions(); s != nil {
+		return s
+	}
+	return []mcp.SessionInfo{}
+}
+
+func (a *Admin) slow() *guard.SlowStats {
+	if a.Slow == nil {
+		return nil
+	}
+	s := a.Slow.Stats()
+	return &s
+}
+
+func (a *Admin) jobs() []maintenance.JobState {
+	if a.Jobs == nil {
+		return []maintenance.JobState{}
+	}
+	return a.Jobs.States()
+}
+
+// Handler returns the admin routes behind the token check.
+func (a *Admin) Handler() gohttp.Handler {
+	mux := gohttp.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/admin", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		writeJSON(w, map[string]any{"sessions": a.sessions(), "jobs": a.jobs(), "slow": a.slow()})
+	})
+
+	mux.HandleFunc("GET /api/v1/admin/sessions", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		writeJSON(w, a.sessions())
+	})
+
+	// every running call across sessions, longest-running first
+	mux.HandleFunc("GET /api/v1/admin/calls", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		calls := []adminCall{}
+		for _, s := range a.sessions() {
+			for _, c := range s.Calls {
+				calls = append(calls, adminCall{Session: s.ID, CallInfo: c})
+			}
+		}
+		for i := 1; i < len(calls); i++ {
+			for j := i; j > 0 && calls[j].DurationMS > calls[j-1].DurationMS; j-- {
+				calls[j], calls[j-1] = calls[j-1], calls[j]
+			}
+		}
+		writeJSON(w, calls)
+	})
+
+	mux.HandleFunc("GET /api/v1/admin/jobs", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		writeJSON(w, a.jobs())
+	})
+
+	// runs that failed every retry; ?resolved=true includes replayed ones
+	mux.HandleFunc("GET /api/v1/admin/dead-letters", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.Jobs == nil {
+			writeJSON(w, []db.DeadLetter{})
+			return
+		}
+		letters, err := a.Jobs.DeadLetters(r.Context(), r.URL.Query().Get("resolved") == "true")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, letters)
+	})
+
+	mux.HandleFunc("POST /api/v1/admin/dead-letters/{id}/replay", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.Jobs == nil {
+			gohttp.Error(w, "maintenance isn't running", gohttp.StatusServiceUnavailable)
+			return
+		}
+		d, err := a.Jobs.Replay(r.Context(), r.PathValue("id"))
+		if errors.Is(err, sql.ErrNoRows) {
+			gohttp.Error(w, "dead letter not found", gohttp.StatusNotFound)
+			return
+		}
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusConflict)
+			return
+		}
+		slog.Info("admin replayed dead letter", "id", d.ID, "resolved", d.ResolvedAt != nil, "from", r.RemoteAddr)
+		writeJSON(w, d)
+	})
+
+	// slow queries and tool calls since startup, by tool and actor
+	mux.HandleFunc("GET /api/v1/admin/slow", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		writeJSON(w, a.slow())
+	})
+
+	// a consistent copy of the database, to ?path or the backups directory
+	mux.HandleFunc("POST /api/v1/admin/backup", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.DB == nil {
+			gohttp.Error(w, "this process has no SQLite database to back up", gohttp.StatusServiceUnavailable)
+			return
+		}
+		var info db.BackupInfo
+		var err error
+		if path := r.URL.Query().Get("path"); path != "" {
+			info, err = db.Backup(r.Context(), a.DB, path)
+		} else {
+			dir := a.BackupDir
+			if dir == "" {
+				dir, err = db.DefaultBackupDir(r.Context(), a.DB)
+			}
+			if err == nil {
+				info, err = db.BackupToDir(r.Context(), a.DB, dir, 0)
+			}
+		}
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusConflict)
+			return
+		}
+		slog.Info("admin took backup", "path", info.Path, "bytes", info.Bytes, "from", r.RemoteAddr)
+		writeJSON(w, info)
+	})
+
+	// ANALYZE and a truncating checkpoint, with VACUUM on ?vacuum=true
+	mux.HandleFunc("POST /api/v1/admin/optimize", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.DB == nil {
+			gohttp.Error(w, "this process has no SQLite database to optimize", gohttp.StatusServiceUnavailable)
+			return
+		}
+		vacuum := r.URL.Query().Get("vacuum") == "true"
+		info, err := db.Optimize(r.Context(), a.DB, db.OptimizeOpts{Vacuum: vacuum, Analyze: true})
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusInternalServerError)
+			return
+		}
+		slog.Info("admin optimized database", "vacuum", vacuum, "bytes_before", info.BytesBefore,
+			"bytes_after", info.BytesAfter, "from", r.RemoteAddr)
+		writeJSON(w, info)
+	})
+
+	mux.HandleFunc("DELETE /api/v1/admin/sessions/{id}", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.Sessions == nil {
+			gohttp.Error(w, mcp.ErrNoSession.Error(), gohttp.StatusNotFound)
+			return
+		}
+		if err := a.Sessions.Disconnect(r.PathValue("id")); err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusNotFound)
+			return
+		}
+		slog.Warn("admin disconnected session", "session", r.PathValue("id"), "from", r.RemoteAddr)
+		writeJSON(w, map[string]string{"disconnected": r.PathValue("id")})
+	})
+
+	mux.HandleFunc("DELETE /api/v1/admin/sessions/{id}/calls/{call}", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.Sessions == nil {
+			gohttp.Error(w, mcp.ErrNoSession.Error(), gohttp.StatusNotFound)
+			return
+		}
+		err := a.Sessions.CancelCall(r.PathValue("id"), r.PathValue("call"))
+		if errors.Is(err, mcp.ErrNoSession) || errors.Is(err, mcp.ErrNoCall) {
+			gohttp.Error(w, err.Error(), gohttp.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		slog.Warn("admin cancelled call", "session", r.PathValue("id"), "call", r.PathValue("call"), "from", r.RemoteAddr)
+		writeJSON(w, map[string]string{"cancelled": r.PathValue("call")})
+	})
+
+	return a.authorize(mux)
+}
+
+func (a *Admin) authorize(next gohttp.Handler) gohttp.Handler {
+	return gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		if a.Token == "" {
+			gohttp.Error(w, "admin endpoints are disabled; set "+AdminTokenEnv, gohttp.StatusForbidden)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer scope="admin"`)
+			gohttp.Error(w, "admin token required", gohttp.StatusUnauthorized)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) != 1 {
+			gohttp.Error(w, "invalid admin token", gohttp.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RegisterAdmin mounts the admin endpoints on the server Run starts.
+func RegisterAdmin(a *Admin) {
+	h := a.Handler()
+	gohttp.Handle("/api/v1/admin", h)
+	gohttp.Handle("/api/v1/admin/", h)
+}
+
+// ServeAdmin serves only the admin endpoints on addr until ctx ends. MCP
+// processes use it, since their sessions live in their own memory.
+func ServeAdmin(ctx context.Context, addr string, a *Admin) error {
+	srv := &gohttp.Server{Addr: addr, Handler: a.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	slog.Info("admin endpoints listening", "addr", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, gohttp.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
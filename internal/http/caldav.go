@@ -0,0 +1,532 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	gohttp "net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// A minimal CalDAV server: one calendar collection holding every task as a
+// VTODO, enough for Apple Reminders and Thunderbird to list tasks and edit
+// their summary, notes, priority, status and due date. New tasks can't be
+// created over CalDAV; bossman ids are assigned server-side.
+const (
+	caldavRoot       = "/caldav/"
+	caldavCollection = "/caldav/tasks/"
+	icalTimeLayout   = "20060102T150405Z"
+)
+
+// VTODO STATUS values for each bossman status, and back.
+var (
+	todoStatus = map[string]string{
+		"pending":     "NEEDS-ACTION",
+		"in_progress": "IN-PROCESS",
+		"completed":   "COMPLETED",
+		"failed":      "CANCELLED",
+	}
+	taskStatus = map[string]string{
+		"NEEDS-ACTION": "pending",
+		"IN-PROCESS":   "in_progress",
+		"COMPLETED":    "completed",
+		"CANCELLED":    "failed",
+	}
+)
+
+func registerCalDAV(conn *sqlx.DB) {
+	gohttp.HandleFunc("/.well-known/caldav", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		gohttp.Redirect(w, r, caldavRoot, gohttp.StatusMovedPermanently)
+	})
+
+	gohttp.HandleFunc(caldavRoot, func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.Header().Set("DAV", "1, calendar-access")
+		switch r.Method {
+		case "OPTIONS":
+			w.Header().Set("Allow", "OPTIONS, GET, PUT, PROPFIND, REPORT")
+			w.WriteHeader(gohttp.StatusOK)
+		case "PROPFIND":
+			caldavPropfind(w, r, conn)
+		case "REPORT":
+			caldavReport(w, r, conn)
+		case "GET":
+			caldavGet(w, r, conn)
+		case "PUT":
+			caldavPut(w, r, conn)
+		default:
+			gohttp.Error(w, "method not allowed", gohttp.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// todoID extracts the task id from /caldav/tasks/<id>.ics.
+func todoID(p string) (string, bool) {
+	dir, file := path.Split(p)
+	if dir != caldavCollection || !strings.HasSuffix(file, ".ics") {
+		return "", false
+	}
+	return strings.TrimSuffix(file, ".ics"), true
+}
+
+func todoHref(id string) string { return caldavCollection + id + ".ics" }
+
+func etag(t *db.Task) string { return `"` + t.UpdatedAt.String() + `"` }
+
+// caldavTasks lists what the collection holds: everything but system tasks.
+func caldavTasks(ctx context.Context, conn *sqlx.DB) ([]db.Task, error) {
+	var tasks []db.Task
+	err := conn.SelectContext(ctx, &tasks,
+		`SELECT * FROM tasks WHERE id != ? AND (parent_id IS NULL OR parent_id != ?) AND archived_at IS NULL
+		 ORDER BY created_at, id`, db.SystemTaskID, db.SystemTaskID)
+	return tasks, err
+}
+
+func caldavPropfind(w gohttp.ResponseWriter, r *gohttp.Request, conn *sqlx.DB) {
+	depth := r.Header.Get("Depth")
+	ms := &multistatus{}
+	switch p := r.URL.Path; {
+	case p == caldavRoot:
+		ms.add(caldavRoot, `<d:resourcetype><d:collection/></d:resourcetype>`+
+			`<d:current-user-principal><d:href>`+caldavRoot+`</d:href></d:current-user-principal>`+
+			`<c:calendar-home-set><d:href>`+caldavRoot+`</d:href></c:calendar-home-set>`)
+		if depth == "1" {
+			if err := collectionProps(r.Context(), conn, ms); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+	case p == caldavCollection:
+		if err := collectionProps(r.Context(), conn, ms); err != nil {
+			writeError(w, err)
+			return
+		}
+		if depth == "1" {
+			tasks, err := caldavTasks(r.Context(), conn)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			for i := range tasks {
+				ms.add(todoHref(tasks[i].ID), resourceProps(&tasks[i], false))
+			}
+		}
+	default:
+		id, ok := todoID(p)
+		if !ok {
+			gohttp.NotFound(w, r)
+			return
+		}
+		task, err := db.GetTask(r.Context(), conn, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			gohttp.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		ms.add(todoHref(id), resourceProps(task, false))
+	}
+	ms.write(w)
+}
+
+func collectionProps(ctx context.Context, conn *sqlx.DB, ms *multistatus) error {
+	// the collection tag changes whenever any task does
+	var ctag sql.NullString
+	if err := conn.GetContext(ctx, &ctag, "SELECT MAX(updated_at) FROM tasks"); err != nil {
+		return err
+	}
+	ms.add(caldavCollection, `<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>`+
+		`<d:displayname>bossman</d:displayname>`+
+		`<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`+
+		`<cs:getctag>`+escapeXML(ctag.String)+`</cs:getctag>`)
+	return nil
+}
+
+func resourceProps(t *db.Task, withData bool) string {
+	props := `<d:getetag>` + escapeXML(etag(t)) + `</d:getetag>` +
+		`<d:getcontenttype>text/calendar; charset=utf-8; component=vtodo</d:getcontenttype>`
+	if withData {
+		props += `<c:calendar-data>` + escapeXML(vtodo(t)) + `</c:calendar-data>`
+	}
+	return props
+}
+
+// caldavReport answers calendar-query (every task) and calendar-multiget
+// (the hrefs listed in the body) with calendar data inline.
+func caldavReport(w gohttp.ResponseWriter, r *gohttp.Request, conn *sqlx.DB) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+		return
+	}
+	root, hrefs, err := parseReport(body)
+	if err != nil {
+		gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+		return
+	}
+
+	ms := &multistatus{}
+	switch root {
+	case "calendar-multiget":
+		for _, href := range hrefs {
+			id, ok := todoID(href)
+			if !ok {
+				ms.missing(href)
+				continue
+			}
+			task, err := db.GetTask(r.Context(), conn, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				ms.missing(href)
+				continue
+			}
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			ms.add(href, resourceProps(task, true))
+		}
+	case "calendar-query":
+		tasks, err := caldavTasks(r.Context(), conn)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		for i := range tasks {
+			ms.add(todoHref(tasks[i].ID), resourceProps(&tasks[i], true))
+		}
+	default:
+		gohttp.Error(w, "unsupported report: "+root, gohttp.StatusForbidden)
+		return
+	}
+	ms.write(w)
+}
+
+// parseReport returns the report's root element name and any hrefs in it.
+func parseReport(body []byte) (string, []string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var root string
+	var hrefs []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return root, hrefs, nil
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("parse report: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+		}
+		if start.Name.Local == "href" {
+			var href string
+			if err := dec.DecodeElement(&href, &start); err != nil {
+				return "", nil, fmt.Errorf("parse report: %w", err)
+			}
+			hrefs = append(hrefs, strings.TrimSpace(href))
+		}
+	}
+}
+
+func caldavGet(w gohttp.ResponseWriter, r *gohttp.Request, conn *sqlx.DB) {
+	id, ok := todoID(r.URL.Path)
+	if !ok {
+		gohttp.NotFound(w, r)
+		return
+	}
+	task, err := db.GetTask(r.Context(), conn, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		gohttp.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", etag(task))
+	io.WriteString(w, vtodo(task))
+}
+
+// caldavPut applies an edited VTODO to an existing task.
+func caldavPut(w gohttp.ResponseWriter, r *gohttp.Request, conn *sqlx.DB) {
+	id, ok := todoID(r.URL.Path)
+	if !ok {
+		gohttp.NotFound(w, r)
+		return
+	}
+	task, err := db.GetTask(r.Context(), conn, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		gohttp.Error(w, "creating tasks over CalDAV is not supported", gohttp.StatusForbidden)
+		return
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != etag(task) {
+		gohttp.Error(w, "task changed since it was fetched", gohttp.StatusPreconditionFailed)
+		return
+	}
+
+	props, err := parseVTODO(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+		return
+	}
+	opts, err := todoUpdate(props, task)
+	if err != nil {
+		gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+		return
+	}
+
+	ctx := db.WithActor(r.Context(), "caldav/"+r.RemoteAddr)
+	// calendar apps tick a todo off in one step and untick it to reopen it;
+	// the start and the completion commit together or not at all
+	if opts.Status != nil {
+		opts.Reopen = true
+	}
+	steps := []db.UpdateOpts{opts}
+	if opts.Status != nil && task.Status == db.StatusPending && *opts.Status == db.StatusCompleted {
+		start := db.StatusInProgress
+		steps = []db.UpdateOpts{{Status: &start}, opts}
+	}
+	if _, err := db.UpdateTaskSteps(ctx, conn, id, steps...); err != nil {
+		writeError(w, err)
+		return
+	}
+	if task, err = db.GetTask(ctx, conn, id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(task))
+	w.WriteHeader(gohttp.StatusNoContent)
+}
+
+// todoUpdate maps the VTODO properties bossman understands onto an update.
+// Properties that are absent leave the field alone, except DUE: a client
+// that drops it has cleared the due date.
+func todoUpdate(props map[string]icalProp, task *db.Task) (db.UpdateOpts, error) {
+	var opts db.UpdateOpts
+	if p, ok := props["SUMMARY"]; ok {
+		opts.Description = &p.value
+	}
+	if p, ok := props["DESCRIPTION"]; ok {
+		opts.Context = &p.value
+	}
+	if p, ok := props["STATUS"]; ok {
+		status, known := taskStatus[strings.ToUpper(p.value)]
+		if !known {
+			return opts, fmt.Errorf("unknown VTODO status: %s", p.value)
+		}
+		opts.Status = &status
+	}
+	if p, ok := props["PRIORITY"]; ok {
+		n, err := strconv.Atoi(p.value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid PRIORITY: %s", p.value)
+		}
+		// 0 means undefined. Clients send PRIORITY back unchanged with every
+		// edit, and nine levels can't round-trip 101 weights, so only a
+		// changed level changes the weight.
+		if n > 0 && n != toICalPriority(task.PriorityWeight) {
+			weight := fromICalPriority(n)
+			opts.Priority = &db.PriorityChange{Weight: &weight}
+		}
+	}
+	due := ""
+	if p, ok := props["DUE"]; ok {
+		t, err := p.time()
+		if err != nil {
+			return opts, fmt.Errorf("invalid DUE: %w", err)
+		}
+		due = db.FormatTime(t)
+	}
+	opts.DueAt = &due
+	return opts, nil
+}
+
+// vtodo renders a task as a one-component iCalendar object.
+func vtodo(t *db.Task) string {
+	var b strings.Builder
+	line := func(name, value string) {
+		// fold at 75 octets as RFC 5545 asks; continuation lines start with a space
+		s := name + ":" + value
+		for len(s) > 75 {
+			cut := 75
+			for cut > 1 && !utf8Start(s[cut]) {
+				cut--
+			}
+			b.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		b.WriteString(s + "\r\n")
+	}
+	stamp := func(ts db.Timestamp) string {
+		return ts.UTC().Format(icalTimeLayout)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//bossman//CalDAV//EN")
+	line("BEGIN", "VTODO")
+	line("UID", t.ID)
+	line("DTSTAMP", stamp(t.UpdatedAt))
+	line("CREATED", stamp(t.CreatedAt))
+	line("LAST-MODIFIED", stamp(t.UpdatedAt))
+	line("SUMMARY", escapeText(t.Description))
+	if t.Context != "" {
+		line("DESCRIPTION", escapeText(string(t.Context)))
+	}
+	line("STATUS", todoStatus[t.Status])
+	line("PRIORITY", strconv.Itoa(toICalPriority(t.PriorityWeight)))
+	if t.DueAt != nil {
+		line("DUE", stamp(*t.DueAt))
+	}
+	if t.CompletedAt != nil && t.Status == "completed" {
+		line("COMPLETED", stamp(*t.CompletedAt))
+	}
+	if t.ParentID != nil {
+		line("RELATED-TO", *t.ParentID)
+	}
+	line("END", "VTODO")
+	line("END", "VCALENDAR")
+	return b.String()
+}
+
+func utf8Start(c byte) bool { return c&0xC0 != 0x80 }
+
+// iCalendar priorities run 1 (highest) to 9; bossman's 1-5 spread across them.
+// iCalendar priorities run 1 (highest) to 9 (lowest); weights 100 to 0.
+func toICalPriority(weight int) int { return 1 + (100-weight)*8/100 }
+
+func fromICalPriority(n int) int { return (9 - min(max(n, 1), 9)) * 100 / 8 }
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+type icalProp struct {
+	params map[string]string
+	value  string
+}
+
+// time parses DATE-TIME and DATE values. Local times with a TZID are read
+// in that zone when it is known; floating times are taken as UTC.
+func (p icalProp) time() (time.Time, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == 8 {
+		return time.Parse("20060102", p.value)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		return time.Parse(icalTimeLayout, p.value)
+	}
+	loc := time.UTC
+	if tz := p.params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	return time.ParseInLocation("20060102T150405", p.value, loc)
+}
+
+// parseVTODO reads the first VTODO's properties. Only single-valued
+// properties are kept; repeated ones keep their last value.
+func parseVTODO(r io.Reader) (map[string]icalProp, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:] // unfold
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	props := make(map[string]icalProp)
+	inTodo, depth := false, 0
+	for _, l := range lines {
+		nameParams, value, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(nameParams, ";")
+		name := strings.ToUpper(parts[0])
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VTODO") && !inTodo:
+			inTodo = true
+			continue
+		case name == "BEGIN" && inTodo:
+			depth++ // nested VALARM and the like
+			continue
+		case name == "END" && inTodo && depth > 0:
+			depth--
+			continue
+		case name == "END" && strings.EqualFold(value, "VTODO") && inTodo:
+			return props, nil
+		}
+		if !inTodo || depth > 0 {
+			continue
+		}
+		p := icalProp{params: make(map[string]string), value: unescapeText(value)}
+		for _, param := range parts[1:] {
+			k, v, _ := strings.Cut(param, "=")
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+		props[name] = p
+	}
+	return nil, errors.New("no VTODO in request body")
+}
+
+// multistatus accumulates WebDAV responses for one 207 reply.
+type multistatus struct {
+	b strings.Builder
+}
+
+func (m *multistatus) add(href, props string) {
+	fmt.Fprintf(&m.b, `<d:response><d:href>%s</d:href><d:propstat><d:prop>%s</d:prop>`+
+		`<d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, escapeXML(href), props)
+}
+
+func (m *multistatus) missing(href string) {
+	fmt.Fprintf(&m.b, `<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`,
+		escapeXML(href))
+}
+
+func (m *multistatus) write(w gohttp.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(gohttp.StatusMultiStatus)
+	io.WriteString(w, xml.Header+
+		`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`+
+		m.b.String()+`</d:multistatus>`)
+}
+
+func escapeXML(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// newTestDB opens a fresh database file for t, closed when t ends, and a
+// context acting as agent-1.
+func newTestDB(t *testing.T) (*sqlx.DB, context.Context) {
+	t.Helper()
+	conn, err := db.InitDB(filepath.Join(t.TempDir(), "bossman.db"), db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, db.WithActor(context.Background(), "agent-1")
+}
+
+// TestCalDAVPutUnchangedPriority PUTs back exactly what GET served, as a
+// calendar app does when only the checkbox moves, and wants the weight
+// left alone even though PRIORITY can't express it exactly.
+func TestCalDAVPutUnchangedPriority(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	task := &db.Task{ID: db.NewTaskID(), Description: "design the schema", Priority: 3}
+	if err := db.InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	weight := 30
+	if _, err := db.UpdateTask(ctx, conn, task.ID, db.UpdateOpts{Priority: &db.PriorityChange{Weight: &weight}}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := db.GetTask(ctx, conn, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := httptest.NewRecorder()
+	caldavGet(get, httptest.NewRequest("GET", todoHref(task.ID), nil), conn)
+	if get.Code != 200 {
+		t.Fatalf("GET: %d %s", get.Code, get.Body)
+	}
+	put := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", todoHref(task.ID), strings.NewReader(get.Body.String()))
+	req.Header.Set("If-Match", get.Header().Get("ETag"))
+	caldavPut(put, req, conn)
+	if put.Code != 204 {
+		t.Fatalf("PUT: %d %s", put.Code, put.Body)
+	}
+
+	after, err := db.GetTask(ctx, conn, task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.PriorityWeight != before.PriorityWeight || after.PriorityLabel != before.PriorityLabel {
+		t.Errorf("priority went from %d (%s) to %d (%s)", before.PriorityWeight, before.PriorityLabel, after.PriorityWeight, after.PriorityLabel)
+	}
+}
+
+// TestCalDAVPutCompleteRefused ticks off an unanswered question, which
+// can't complete, and wants it left pending rather than stuck halfway in
+// in_progress.
+func TestCalDAVPutCompleteRefused(t *testing.T) {
+	conn, ctx := newTestDB(t)
+
+	q := &db.Task{ID: db.NewTaskID(), Description: "which database?", Priority: 3}
+	if err := db.AskQuestion(ctx, conn, q, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	get := httptest.NewRecorder()
+	caldavGet(get, httptest.NewRequest("GET", todoHref(q.ID), nil), conn)
+	if get.Code != 200 {
+		t.Fatalf("GET: %d %s", get.Code, get.Body)
+	}
+	body := strings.Replace(get.Body.String(), "STATUS:NEEDS-ACTION", "STATUS:COMPLETED", 1)
+	put := httptest.NewRecorder()
+	caldavPut(put, httptest.NewRequest("PUT", todoHref(q.ID), strings.NewReader(body)), conn)
+	if put.Code/100 == 2 {
+		t.Fatalf("PUT completed an unanswered question: %d", put.Code)
+	}
+
+	after, err := db.GetTask(ctx, conn, q.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Status != db.StatusPending {
+		t.Errorf("question left %s after a refused PUT", after.Status)
+	}
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	_ "embed"
+	gohttp "net/http"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// DashboardStatus is what the dashboard's badge counts. System tasks are
+// left out; they're always pending between runs.
+type DashboardStatus struct {
+	Pending    int `json:"pending"`
+	Blocked    int `json:"blocked"`
+	InProgress int `json:"in_progress"`
+	Failed     int `json:"failed"`
+	// Questions are waiting for a person to answer; see db.AskQuestion.
+	Questions int `json:"questions"`
+}
+
+// registerDashboard mounts a single-page dashboard at /dashboard, built on
+// GET /tasks and the counts at /dashboard/status.
+func registerDashboard(conn *sqlx.DB) {
+	gohttp.HandleFunc("GET /dashboard", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(dashboardPage)
+	})
+	gohttp.HandleFunc("GET /dashboard/status", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		counts, err := db.CountTasksByStatus(r.Context(), conn)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s := DashboardStatus{
+			Pending:    counts["pending"],
+			InProgress: counts["in_progress"],
+			Failed:     counts["failed"],
+		}
+		if s.Blocked, err = db.CountBlockedTasks(r.Context(), conn); err != nil {
+			writeError(w, err)
+			return
+		}
+		if s.Questions, err = db.CountOpenQuestions(r.Context(), conn); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, s)
+	})
+}
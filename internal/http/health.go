@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// healthTTL is how long /health reuses a check. quick_check reads the
+// whole file, which a monitor polling every few seconds shouldn't cause.
+const healthTTL = time.Minute
+
+// healthCheck runs db.HealthCheck for /health at most once per healthTTL.
+type healthCheck struct {
+	db     *sqlx.DB
+	mu     sync.Mutex
+	at     time.Time
+	report db.HealthReport
+	err    error
+}
+
+// get returns the cached report, running a fresh quick check when it is
+// older than healthTTL. A failed run isn't cached.
+func (h *healthCheck) get(ctx context.Context) (db.HealthReport, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.at.IsZero() && time.Since(h.at) < healthTTL {
+		return h.report, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	report, err := db.HealthCheck(ctx, h.db, db.HealthOpts{})
+	if err != nil {
+		return db.HealthReport{}, err
+	}
+	h.report, h.at = report, time.Now()
+	return report, nil
+}
@@ -0,0 +1,22 @@
+package http
+
+import (
+	_ "embed"
+	gohttp "net/http"
+)
+
+// openapiSpec describes the read API and answering questions. The Go
+// client in pkg/client and the TypeScript client in dist/client are
+// generated from it; regenerate them after changing either the spec or the
+// routes it documents.
+//
+//go:generate go run ../../cmd/genclient -spec openapi.json -root ../..
+//go:embed openapi.json
+var openapiSpec []byte
+
+func registerOpenAPI() {
+	gohttp.HandleFunc("GET /openapi.json", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openapiSpec)
+	})
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"errors"
+	gohttp "net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Probes serves the Kubernetes liveness and readiness checks. /livez only
+// says the process is serving HTTP; /readyz also pings the database and
+// asks Ready, so a draining pod leaves the Service's endpoints without
+// being restarted. A degraded pod stays ready, since reads still work.
+type Probes struct {
+	DB       *sqlx.DB
+	Ready    func() error  // nil error when the process should get traffic
+	Degraded func() string // why writes are paused, if they are; reads still work
+	// LiteFSDir is the LiteFS mount holding the database, if any. LiteFS
+	// writes a .primary file there on replicas, which /readyz reports.
+	LiteFSDir string
+}
+
+type probeStatus struct {
+	Ready     bool   `json:"ready"`
+	Reason    string `json:"reason,omitempty"`
+	Degraded  string `json:"degraded,omitempty"`
+	ReplicaOf string `json:"replica_of,omitempty"`
+}
+
+// RegisterProbes mounts /livez and /readyz on the server Serve starts.
+func RegisterProbes(p *Probes) {
+	gohttp.HandleFunc("GET /livez", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		w.Write([]byte("ok"))
+	})
+	gohttp.HandleFunc("GET /readyz", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		status := probeStatus{Ready: true, ReplicaOf: p.replicaOf()}
+		if p.Degraded != nil {
+			status.Degraded = p.Degraded()
+		}
+		if err := p.check(r.Context()); err != nil {
+			status.Ready, status.Reason = false, err.Error()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(gohttp.StatusServiceUnavailable)
+		}
+		writeJSON(w, status)
+	})
+}
+
+func (p *Probes) check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := p.DB.PingContext(ctx); err != nil {
+		return errors.New("database unreachable: " + err.Error())
+	}
+	if p.Ready != nil {
+		return p.Ready()
+	}
+	return nil
+}
+
+// replicaOf names the LiteFS primary when this node is a replica.
+func (p *Probes) replicaOf() string {
+	if p.LiteFSDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(p.LiteFSDir, ".primary"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	gohttp "net/http"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+)
+
+// AnswerRequest is the body of POST /questions/{id}/answer.
+type AnswerRequest struct {
+	Answer     string `json:"answer"`
+	AnsweredBy string `json:"answered_by,omitempty"`
+}
+
+// registerQuestions serves the questions agents put to people, for the
+// dashboard and anything else a person answers from.
+func registerQuestions(conn *sqlx.DB) {
+	// GET /questions?answered=true
+	gohttp.HandleFunc("GET /questions", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		questions, err := db.ListQuestions(r.Context(), conn, r.URL.Query().Get("answered") == "true")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		out, err := api.Tasks(r.Context(), conn, questions)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, out)
+	})
+
+	gohttp.HandleFunc("POST /questions/{id}/answer", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		var req AnswerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			gohttp.Error(w, "invalid body: "+err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+		if req.Answer == "" {
+			gohttp.Error(w, "missing answer", gohttp.StatusBadRequest)
+			return
+		}
+		id := r.PathValue("id")
+		if req.AnsweredBy == "" {
+			req.AnsweredBy = "http/" + r.RemoteAddr
+		}
+		ctx := db.WithActor(r.Context(), req.AnsweredBy)
+		err := db.AnswerQuestion(ctx, conn, id, req.Answer, req.AnsweredBy)
+		if errors.Is(err, sql.ErrNoRows) {
+			gohttp.Error(w, "question not found", gohttp.StatusNotFound)
+			return
+		}
+		if err != nil {
+			// not a question, or no longer open
+			gohttp.Error(w, err.Error(), gohttp.StatusConflict)
+			return
+		}
+		task, err := db.GetTask(ctx, conn, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		out, err := api.One(ctx, conn, task)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, out)
+	})
+}
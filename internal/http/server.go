@@ -1,26 +1,181 @@
 package http
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
+	"net"
 	gohttp "net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"procdexeh/bossman/internal/api"
 	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/events"
 
 	"github.com/jmoiron/sqlx"
 )
 
 const PORT = ":6969"
 
-func Run(conn *sqlx.DB) {
+// maxWait caps long-poll requests so proxies don't cut them off first.
+const maxWait = 60 * time.Second
+
+// parseWait accepts a Go duration ("30s") or bare seconds ("30").
+func parseWait(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		secs, convErr := strconv.Atoi(raw)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait: %s", raw)
+		}
+		d = time.Duration(secs) * time.Second
+	}
+	return min(max(d, 0), maxWait), nil
+}
+
+// writeError maps err to a status code. Throttled calls get 429 with a
+// Retry-After header (whole seconds, rounded up) so clients can back off;
+// status changes the state machine refuses get 409.
+func writeError(w gohttp.ResponseWriter, err error) {
+	var transition *db.TransitionError
+	if errors.As(err, &transition) {
+		gohttp.Error(w, transition.Error(), gohttp.StatusConflict)
+		return
+	}
+	var retry interface {
+		error
+		RetryAfter() time.Duration
+	}
+	if errors.As(err, &retry) {
+		secs := int(math.Ceil(retry.RetryAfter().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+		w.Header().Set("X-Retry-After-Ms", strconv.FormatInt(retry.RetryAfter().Milliseconds(), 10))
+		w.WriteHeader(gohttp.StatusTooManyRequests)
+		fmt.Fprint(w, retry.Error())
+		return
+	}
+	slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
+	w.WriteHeader(gohttp.StatusInternalServerError)
+	fmt.Fprint(w, "internal server error.")
+}
+
+func writeJSON(w gohttp.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
+	}
+}
+
+// parseFields reads ?fields=id,status; writes a 400 and returns false if invalid.
+func parseFields(w gohttp.ResponseWriter, r *gohttp.Request) ([]string, bool) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, true
+	}
+	fields := strings.Split(raw, ",")
+	if err := api.ValidateFields(fields); err != nil {
+		gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+		return nil, false
+	}
+	return fields, true
+}
+
+// wantsCSV reports whether the client asked for CSV, with ?format=csv or
+// an Accept header that ranks text/csv above application/json.
+func wantsCSV(r *gohttp.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "csv"
+	}
+	csvQ, jsonQ := -1.0, -1.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		media, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(media)) {
+		case "text/csv":
+			csvQ = max(csvQ, q)
+		case "application/json", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+	return csvQ > 0 && csvQ > jsonQ
+}
+
+// Run serves the task routes from store. Search, aggregates, the change
+// feed and CalDAV are SQLite only and are left out on other backends.
+func Run(store db.Store) {
+	registerRoutes(store)
+
+	slog.Info("LISTENING ON", "PORT", PORT)
+	err := gohttp.ListenAndServe(PORT, nil)
+	if err != nil {
+		slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
+	}
+}
+
+// Serve is Run on ln until ctx ends, then waits up to grace for requests
+// in flight before closing their connections.
+func Serve(ctx context.Context, ln net.Listener, store db.Store, grace time.Duration) error {
+	registerRoutes(store)
+	srv := &gohttp.Server{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		if err := srv.Shutdown(shutdown); err != nil {
+			slog.Warn("HTTP shutdown", "err", err)
+			srv.Close()
+		}
+	}()
+	slog.Info("LISTENING ON", "addr", ln.Addr().String())
+	if err := srv.Serve(ln); !errors.Is(err, gohttp.ErrServerClosed) {
+		return err
+	}
+	<-done
+	return nil
+}
+
+func registerRoutes(store db.Store) {
+	conn := db.SQLite(store)
+
 	gohttp.HandleFunc("/", func(w gohttp.ResponseWriter, r *gohttp.Request) {
-		fmt.Println("HELLO HTTP SERVER")
+		slog.Debug("HELLO HTTP SERVER")
 		w.WriteHeader(gohttp.StatusOK)
 		fmt.Fprint(w, "hello")
 	})
 
+	var health *healthCheck
+	if s, ok := store.(*db.SQLStore); ok {
+		health = &healthCheck{db: s.DB}
+	}
 	gohttp.HandleFunc("/health", func(w gohttp.ResponseWriter, r *gohttp.Request) {
 		slog.Info("HEALTH CHECK", "FROM", r.RemoteAddr)
+		if health != nil {
+			report, err := health.get(r.Context())
+			if err != nil {
+				gohttp.Error(w, "health check failed: "+err.Error(), gohttp.StatusServiceUnavailable)
+				return
+			}
+			if !report.OK {
+				gohttp.Error(w, strings.Join(report.Problems(), "\n"), gohttp.StatusServiceUnavailable)
+				return
+			}
+		}
 		w.WriteHeader(gohttp.StatusOK)
 		fmt.Fprint(w, "ok")
 	})
@@ -29,21 +184,262 @@ func Run(conn *sqlx.DB) {
 		task := &db.Task{
 			ID:          db.NewTaskID(),
 			Description: r.RemoteAddr,
+			Priority:    3, // CHECK constraint rejects 0
 		}
-		err := db.InsertTask(conn, task)
+		ctx := db.WithActor(r.Context(), "http/"+r.RemoteAddr)
+		err := store.InsertTask(ctx, task)
 		if err != nil {
-			slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
-			w.WriteHeader(gohttp.StatusInternalServerError)
-			fmt.Fprint(w, "internal server error.")
+			writeError(w, err)
 			return
 		}
 		w.WriteHeader(gohttp.StatusOK)
 		fmt.Fprint(w, "ok")
 	})
 
-	slog.Info("LISTENING ON", "PORT", PORT)
-	err := gohttp.ListenAndServe(PORT, nil)
-	if err != nil {
+	gohttp.HandleFunc("GET /tasks", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		fields, ok := parseFields(w, r)
+		if !ok {
+			return
+		}
+		opts := db.ListOpts{}
+		if s := r.URL.Query().Get("status"); s != "" {
+			opts.Status = &s
+		}
+		if k := r.URL.Query().Get("kind"); k != "" {
+			opts.Kind = &k
+		}
+		if p := r.URL.Query().Get("parent_id"); p != "" {
+			opts.ParentID = &p
+		}
+		if r.URL.Query().Has("assigned_to") {
+			a := r.URL.Query().Get("assigned_to")
+			opts.AssignedTo = &a
+		}
+		if r.URL.Query().Has("project_id") {
+			p := r.URL.Query().Get("project_id")
+			opts.ProjectID = &p
+		}
+		// meta.<key>=<value>; the value is read as JSON when it parses
+		// (42, true, "x"), otherwise as a plain string
+		for k, vals := range r.URL.Query() {
+			key, ok := strings.CutPrefix(k, "meta.")
+			if !ok || len(vals) == 0 {
+				continue
+			}
+			var v any
+			if err := json.Unmarshal([]byte(vals[0]), &v); err != nil {
+				v = vals[0]
+			}
+			if opts.Metadata == nil {
+				opts.Metadata = make(map[string]any)
+			}
+			opts.Metadata[key] = v
+		}
+		for _, t := range r.URL.Query()["tag"] {
+			tag, err := db.NormalizeTag(t)
+			if err != nil {
+				gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+				return
+			}
+			opts.Tags = append(opts.Tags, tag)
+		}
+		if l := r.URL.Query().Get("limit"); l != "" {
+			opts.Limit, _ = strconv.Atoi(l)
+		}
+		opts.OrderBy, opts.SortDir = r.URL.Query().Get("sort"), r.URL.Query().Get("sort_dir")
+		if err := db.ValidateOrder(opts.OrderBy, opts.SortDir); err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+		opts.Cursor = r.URL.Query().Get("cursor")
+		w.Header().Set("Vary", "Accept")
+		if wantsCSV(r) {
+			var columns []string
+			if c := r.URL.Query().Get("columns"); c != "" {
+				columns = strings.Split(c, ",")
+			}
+			if err := db.ValidateReportColumns(columns); err != nil {
+				gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			if _, err := db.WriteTasksCSV(r.Context(), store, w, opts, columns); err != nil {
+				slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
+			}
+			return
+		}
+		// one extra row says whether another page follows
+		limit := opts.Limit
+		if limit > 0 {
+			opts.Limit++
+		}
+		tasks, err := store.QueryTasks(r.Context(), opts)
+		if errors.Is(err, db.ErrInvalidCursor) {
+			gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if limit > 0 && len(tasks) > limit {
+			tasks = tasks[:limit]
+			w.Header().Set("X-Next-Cursor", opts.CursorAfter(&tasks[limit-1]))
+		}
+		out, err := api.FromStore(r.Context(), store, tasks)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if len(fields) > 0 {
+			writeJSON(w, api.ProjectTasks(out, fields))
+			return
+		}
+		writeJSON(w, out)
+	})
+
+	gohttp.HandleFunc("GET /tasks/{id}", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		fields, ok := parseFields(w, r)
+		if !ok {
+			return
+		}
+		task, err := store.GetTask(r.Context(), r.PathValue("id"))
+		if errors.Is(err, sql.ErrNoRows) {
+			gohttp.Error(w, "task not found", gohttp.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		outs, err := api.FromStore(r.Context(), store, []db.Task{*task})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if len(fields) > 0 {
+			writeJSON(w, outs[0].Project(fields))
+			return
+		}
+		writeJSON(w, outs[0])
+	})
+
+	if conn != nil {
+		registerSQLiteRoutes(conn)
+	}
+	registerOpenAPI()
+}
+
+// registerSQLiteRoutes adds the routes built on SQLite-only queries and the
+// change log its triggers keep.
+func registerSQLiteRoutes(conn *sqlx.DB) {
+	bus := events.NewBus()
+	if err := bus.Watch(context.Background(), conn, 500*time.Millisecond); err != nil {
 		slog.Error("HTTP SERVER ERROR", slog.Any("error", err))
 	}
+	registerDashboard(conn)
+
+	// GET /search?q=deploy&kind=task&kind=comment&limit=20
+	gohttp.HandleFunc("GET /search", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		q := r.URL.Query()
+		if strings.TrimSpace(q.Get("q")) == "" {
+			gohttp.Error(w, "missing q", gohttp.StatusBadRequest)
+			return
+		}
+		opts := db.GlobalSearchOpts{Kinds: q["kind"]}
+		if l := q.Get("limit"); l != "" {
+			opts.Limit, _ = strconv.Atoi(l)
+		}
+		hits, err := db.SearchAll(r.Context(), conn, q.Get("q"), opts)
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+		if hits == nil {
+			hits = []db.GlobalHit{}
+		}
+		writeJSON(w, hits)
+	})
+
+	// GET /aggregate?group_by=status,day&metric=count
+	gohttp.HandleFunc("GET /aggregate", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		q := r.URL.Query()
+		opts := db.AggregateOpts{Metric: q.Get("metric")}
+		if g := q.Get("group_by"); g != "" {
+			opts.GroupBy = strings.Split(g, ",")
+		}
+		if s := q.Get("status"); s != "" {
+			opts.Status = &s
+		}
+		rows, err := db.Aggregate(r.Context(), conn, opts)
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+		writeJSON(w, rows)
+	})
+
+	gohttp.HandleFunc("GET /stats", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		stats, err := db.GetStats(r.Context(), conn)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, stats)
+	})
+
+	registerQuestions(conn)
+
+	// GET /api/v1/changes?since=<seq>&wait=30s
+	// Returns as soon as there are changes after since, or empty after wait.
+	gohttp.HandleFunc("GET /api/v1/changes", func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		q := r.URL.Query()
+		var since int64
+		if raw := q.Get("since"); raw != "" {
+			var err error
+			if since, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				gohttp.Error(w, "invalid since: "+raw, gohttp.StatusBadRequest)
+				return
+			}
+		}
+		wait, err := parseWait(q.Get("wait"))
+		if err != nil {
+			gohttp.Error(w, err.Error(), gohttp.StatusBadRequest)
+			return
+		}
+
+		// Subscribe before the first read so nothing slips in between.
+		sub, unsubscribe := bus.Subscribe()
+		defer unsubscribe()
+
+		changes, err := db.ChangesSince(r.Context(), conn, since, 0)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if len(changes) == 0 && wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			select {
+			case <-sub:
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+			if changes, err = db.ChangesSince(r.Context(), conn, since, 0); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+
+		next := since
+		if len(changes) > 0 {
+			next = changes[len(changes)-1].Seq
+		} else {
+			changes = []db.Change{}
+		}
+		writeJSON(w, map[string]any{"changes": changes, "next": next})
+	})
+
+	registerCalDAV(conn)
 }
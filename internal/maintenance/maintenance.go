@@ -0,0 +1,298 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/guard"
+)
+
+// Job is one of bossman's own recurring chores.
+// Each job is backed by a task under db.SystemTaskID.
+type Job struct {
+	TaskID      string
+	Description string
+	Interval    time.Duration
+	Run         func(ctx context.Context, conn *sqlx.DB) (string, error)
+}
+
+// DefaultJobs are the jobs every bossman process runs.
+func DefaultJobs() []Job {
+	return []Job{
+		{
+			TaskID:      "task_system_optimize",
+			Description: "Optimize query planner statistics",
+			Interval:    6 * time.Hour,
+			Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+				if _, err := conn.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+					return "", err
+				}
+				return "optimize ok", nil
+			},
+		},
+		{
+			TaskID:      "task_system_checkpoint",
+			Description: "Checkpoint and truncate the write-ahead log",
+			Interval:    15 * time.Minute,
+			Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+				info, err := db.Checkpoint(ctx, conn)
+				if err != nil {
+					return "", err
+				}
+				if info.Busy {
+					return fmt.Sprintf("checkpointed %d/%d frames; readers kept the log busy", info.Checkpointed, info.LogFrames), nil
+				}
+				return fmt.Sprintf("checkpointed %d/%d frames", info.Checkpointed, info.LogFrames), nil
+			},
+		},
+		{
+			TaskID:      "task_system_checklists",
+			Description: "Generate the day's recurring checklists",
+			Interval:    10 * time.Minute,
+			Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+				n, err := db.RolloverChecklists(ctx, conn)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("generated %d checklists", n), nil
+			},
+		},
+		{
+			TaskID:      "task_system_scratch",
+			Description: "Drop scratch tasks left by sessions that never ended",
+			Interval:    time.Hour,
+			Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+				n, err := db.SweepScratch(ctx, conn, db.ScratchIdle)
+				if err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("dropped %d scratch tasks", n), nil
+			},
+		},
+	}
+}
+
+// BackupJob backs the database up into dir every interval, keeping the
+// newest keep backups.
+func BackupJob(dir string, every time.Duration, keep int) Job {
+	return Job{
+		TaskID:      "task_system_backup",
+		Description: "Back up the database",
+		Interval:    every,
+		Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+			info, err := db.BackupToDir(ctx, conn, dir, keep)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("backed up %d bytes to %s, pruned %d", info.Bytes, info.Path, len(info.Pruned)), nil
+		},
+	}
+}
+
+// RetentionJob purges the finished tasks policy no longer keeps, daily.
+func RetentionJob(policy db.RetentionPolicy) Job {
+	return Job{
+		TaskID:      "task_system_retention",
+		Description: "Purge finished tasks past their retention",
+		Interval:    24 * time.Hour,
+		Run: func(ctx context.Context, conn *sqlx.DB) (string, error) {
+			res, err := db.Purge(ctx, conn, policy, false)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("purged %d tasks, %d comments, %d events, %d changes", len(res.Tasks), res.Comments, res.Events, res.Changes), nil
+		},
+	}
+}
+
+// Runner executes jobs on their intervals and records each run.
+type Runner struct {
+	db     *sqlx.DB
+	jobs   []Job
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	states map[string]*JobState
+}
+
+// JobState is where a job stands, for the admin endpoints. The Last
+// fields are zero until the job first runs in this process.
+type JobState struct {
+	TaskID       string    `json:"task_id"`
+	Description  string    `json:"description"`
+	Interval     string    `json:"interval"`
+	Running      bool      `json:"running"`
+	NextRun      time.Time `json:"next_run,omitzero"`
+	LastStarted  time.Time `json:"last_started,omitzero"`
+	LastFinished time.Time `json:"last_finished,omitzero"`
+	LastOK       bool      `json:"last_ok"`
+	LastDetail   string    `json:"last_detail,omitempty"`
+}
+
+func NewRunner(conn *sqlx.DB, jobs []Job, logger *slog.Logger) *Runner {
+	states := make(map[string]*JobState, len(jobs))
+	for _, j := range jobs {
+		states[j.TaskID] = &JobState{TaskID: j.TaskID, Description: j.Description, Interval: j.Interval.String()}
+	}
+	return &Runner{db: conn, jobs: jobs, logger: logger, states: states}
+}
+
+// States returns a snapshot of every job, in the order they were given.
+func (r *Runner) States() []JobState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]JobState, len(r.jobs))
+	for i, j := range r.jobs {
+		out[i] = *r.states[j.TaskID]
+	}
+	return out
+}
+
+func (r *Runner) update(taskID string, fn func(*JobState)) {
+	r.mu.Lock()
+	if st, ok := r.states[taskID]; ok {
+		fn(st)
+	}
+	r.mu.Unlock()
+}
+
+// Start registers the job tasks and launches one goroutine per job.
+// Goroutines stop when ctx is cancelled.
+func (r *Runner) Start(ctx context.Context) error {
+	for _, j := range r.jobs {
+		if err := db.EnsureSystemTask(ctx, r.db, j.TaskID, j.Description); err != nil {
+			return fmt.Errorf("register %s: %w", j.TaskID, err)
+		}
+	}
+	for _, j := range r.jobs {
+		go r.loop(ctx, j)
+	}
+	return nil
+}
+
+func (r *Runner) loop(ctx context.Context, j Job) {
+	clk := clock.From(ctx)
+	ticker := clk.NewTicker(j.Interval)
+	defer ticker.Stop()
+	next := func() {
+		at := clk.Now().Add(j.Interval)
+		r.update(j.TaskID, func(st *JobState) { st.NextRun = at.UTC() })
+	}
+	next()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			r.RunOnce(ctx, j)
+			next()
+			ticker.Done()
+		}
+	}
+}
+
+// jobAttempts is how often a run is tried before it goes to the
+// dead-letter table.
+const jobAttempts = 3
+
+// RunOnce executes a single job immediately and records the outcome. A
+// job still failing after jobAttempts tries, backing off in between, is
+// recorded as a dead letter for Replay.
+func (r *Runner) RunOnce(ctx context.Context, j Job) {
+	attempts, err := r.run(ctx, j, jobAttempts)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	d := &db.DeadLetter{Kind: db.DeadLetterMaintenance, Source: j.TaskID, Error: err.Error(), Attempts: attempts}
+	if err := db.RecordDeadLetter(ctx, r.db, d); err != nil {
+		r.logger.Error("record dead letter", "task", j.TaskID, "err", err)
+	}
+}
+
+// run tries j up to attempts times and records the last outcome as one
+// maintenance run.
+func (r *Runner) run(ctx context.Context, j Job, attempts int) (int, error) {
+	clk := clock.From(ctx)
+	started := clk.Now()
+	r.update(j.TaskID, func(st *JobState) { st.Running, st.LastStarted = true, started.UTC() })
+	run := &db.MaintenanceRun{
+		TaskID:    j.TaskID,
+		StartedAt: db.FormatTime(started),
+	}
+	detail, err := j.Run(ctx, r.db)
+	n := 1
+	for err != nil && n < attempts {
+		r.logger.Warn("maintenance job failed, retrying", "task", j.TaskID, "attempt", n, "err", err)
+		if !sleep(ctx, guard.DefaultBackoff.Delay(n, time.Second)) {
+			break
+		}
+		n++
+		detail, err = j.Run(ctx, r.db)
+	}
+	finished := clk.Now()
+	run.FinishedAt = db.FormatTime(finished)
+	run.OK = err == nil
+	run.Detail = detail
+	if err != nil {
+		run.Detail = err.Error()
+		r.logger.Error("maintenance job failed", "task", j.TaskID, "attempts", n, "err", err)
+	}
+	r.update(j.TaskID, func(st *JobState) {
+		st.Running, st.LastFinished, st.LastOK, st.LastDetail = false, finished.UTC(), run.OK, run.Detail
+	})
+	if err := db.RecordMaintenanceRun(ctx, r.db, run); err != nil {
+		r.logger.Error("record maintenance run", "task", j.TaskID, "err", err)
+	}
+	return n, err
+}
+
+// sleep waits d, or returns false if ctx ends first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// DeadLetters lists the runs that failed every retry, open ones only
+// unless resolved is set.
+func (r *Runner) DeadLetters(ctx context.Context, resolved bool) ([]db.DeadLetter, error) {
+	return db.ListDeadLetters(ctx, r.db, resolved)
+}
+
+// Replay runs the job behind an open dead letter once more and records the
+// outcome on it: success resolves it, failure keeps it open with the new
+// error. A missing dead letter is sql.ErrNoRows.
+func (r *Runner) Replay(ctx context.Context, id string) (*db.DeadLetter, error) {
+	d, err := db.GetDeadLetter(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.ResolvedAt != nil {
+		return nil, fmt.Errorf("dead letter %s was resolved at %s", id, *d.ResolvedAt)
+	}
+	if d.Kind != db.DeadLetterMaintenance {
+		return nil, fmt.Errorf("can't replay %s dead letters", d.Kind)
+	}
+	i := slices.IndexFunc(r.jobs, func(j Job) bool { return j.TaskID == d.Source })
+	if i < 0 {
+		return nil, fmt.Errorf("no maintenance job %s in this process", d.Source)
+	}
+	_, runErr := r.run(ctx, r.jobs[i], 1)
+	if err := db.ResolveDeadLetter(ctx, r.db, d, runErr); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,140 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Errors from the SessionRegistry admin methods.
+var (
+	ErrNoSession = errors.New("no such session")
+	ErrNoCall    = errors.New("no such call")
+)
+
+// CallInfo is a running tools/call. ID is the request's JSON-RPC id as
+// sent, so a string id keeps its quotes.
+type CallInfo struct {
+	ID         string    `json:"id"`
+	Tool       string    `json:"tool"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// QueueInfo is how full a session's bounded queues are: tool call workers
+// and messages waiting to be written to the client.
+type QueueInfo struct {
+	WorkersBusy   int `json:"workers_busy"`
+	WorkersMax    int `json:"workers_max"`
+	WriteQueue    int `json:"write_queue"`
+	WriteQueueMax int `json:"write_queue_max"`
+}
+
+// SessionInfo describes a connected session for operators.
+type SessionInfo struct {
+	ID              string     `json:"id"`
+	Client          string     `json:"client,omitempty"`
+	ClientVersion   string     `json:"client_version,omitempty"`
+	ProtocolVersion string     `json:"protocol_version,omitempty"`
+	State           string     `json:"state"`
+	ConnectedAt     time.Time  `json:"connected_at"`
+	Calls           []CallInfo `json:"calls"`
+	Queues          QueueInfo  `json:"queues"`
+}
+
+// administered is what a session's server offers the admin methods.
+type administered interface {
+	info() SessionInfo
+	abortCall(id string) bool
+	disconnect()
+}
+
+func (s *Server) info() SessionInfo {
+	s.mu.Lock()
+	info := SessionInfo{State: s.state.String(), ConnectedAt: s.started.UTC()}
+	if s.client != nil {
+		info.Client = s.client.Info.Name
+		info.ClientVersion = s.client.Info.Version
+		info.ProtocolVersion = s.client.ProtocolVersion
+	}
+	s.mu.Unlock()
+	if s.session != nil {
+		info.ID = s.session.ID
+	}
+	info.Calls = s.calls.snapshot()
+	info.Queues = QueueInfo{
+		WorkersBusy:   len(s.workers),
+		WorkersMax:    cap(s.workers),
+		WriteQueue:    len(s.transport.queue),
+		WriteQueueMax: cap(s.transport.queue),
+	}
+	return info
+}
+
+func (s *Server) abortCall(id string) bool {
+	if s.calls.abort(id) {
+		return true
+	}
+	// let operators write a string id without its JSON quotes
+	quoted, _ := json.Marshal(id)
+	return s.calls.abort(string(quoted))
+}
+
+func (s *Server) disconnect() {
+	s.mu.Lock()
+	stop := s.stop
+	s.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+func (r *SessionRegistry) target(id string) (administered, error) {
+	r.mu.Lock()
+	s, ok := r.sessions[id]
+	r.mu.Unlock()
+	if !ok {
+		return nil, ErrNoSession
+	}
+	a, ok := s.notifier.(administered)
+	if !ok {
+		return nil, ErrNoSession
+	}
+	return a, nil
+}
+
+// Sessions describes every connected session, oldest first.
+func (r *SessionRegistry) Sessions() []SessionInfo {
+	var out []SessionInfo
+	for _, s := range r.snapshot() {
+		if a, ok := s.notifier.(administered); ok {
+			out = append(out, a.info())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ConnectedAt.Before(out[j].ConnectedAt) })
+	return out
+}
+
+// CancelCall stops a running tool call. The client gets an error response.
+func (r *SessionRegistry) CancelCall(sessionID, callID string) error {
+	a, err := r.target(sessionID)
+	if err != nil {
+		return err
+	}
+	if !a.abortCall(callID) {
+		return ErrNoCall
+	}
+	return nil
+}
+
+// Disconnect ends a session as if its client had gone away: running calls
+// are cancelled and the server stops reading.
+func (r *SessionRegistry) Disconnect(sessionID string) error {
+	a, err := r.target(sessionID)
+	if err != nil {
+		return err
+	}
+	a.disconnect()
+	return nil
+}
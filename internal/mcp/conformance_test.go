@@ -0,0 +1,297 @@
+package mcp
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHandshake(t *testing.T) {
+	h := newHarness(t, func(s *Server) { s.SetInstructions("be nice") })
+
+	resp := h.call("1", "initialize",
+		`{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"harness","version":"1"}}`)
+	var result InitializeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.ProtocolVersion != "2025-03-26" {
+		t.Errorf("protocolVersion = %q", result.ProtocolVersion)
+	}
+	if result.Capabilities.Tools == nil {
+		t.Error("tools capability missing")
+	}
+	if result.ServerInfo.Name != "bossman" {
+		t.Errorf("serverInfo.name = %q", result.ServerInfo.Name)
+	}
+	if result.Instructions != "be nice" {
+		t.Errorf("instructions = %q", result.Instructions)
+	}
+
+	// Not yet Operating: tools/* are rejected until notifications/initialized.
+	wantErrorCode(t, h.call("2", "tools/list", ""), CodeInvalidRequest)
+
+	h.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if resp := h.call("3", "tools/list", ""); resp.Error != nil {
+		t.Fatalf("tools/list after initialized: %v", resp.Error)
+	}
+
+	wantErrorCode(t, h.call("4", "initialize", `{}`), CodeInvalidRequest)
+
+	if err := h.close(); err != nil {
+		t.Fatalf("Run returned %v on EOF", err)
+	}
+}
+
+func TestToolsRejectedBeforeInitialize(t *testing.T) {
+	h := newHarness(t)
+	wantErrorCode(t, h.call("1", "tools/list", ""), CodeInvalidRequest)
+	wantErrorCode(t, h.call("2", "tools/call", `{"name":"echo"}`), CodeInvalidRequest)
+}
+
+func TestPingInAnyState(t *testing.T) {
+	h := newHarness(t)
+	if resp := h.call("1", "ping", ""); resp.Error != nil || string(resp.Result) != "{}" {
+		t.Fatalf("ping before initialize = %+v", resp)
+	}
+	h.initialize()
+	if resp := h.call("2", "ping", ""); resp.Error != nil || string(resp.Result) != "{}" {
+		t.Fatalf("ping while operating = %+v", resp)
+	}
+}
+
+func TestRequestIDTypeEchoed(t *testing.T) {
+	h := newHarness(t)
+	for _, id := range []string{`7`, `"seven"`, `1.5`} {
+		if resp := h.call(id, "ping", ""); string(resp.ID) != id {
+			t.Errorf("id %s echoed as %s", id, resp.ID)
+		}
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	var result struct {
+		Tools []ToolDefinition `json:"tools"`
+	}
+	if err := json.Unmarshal(h.call("1", "tools/list", "").Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		if len(tool.InputSchema) == 0 {
+			t.Errorf("%s: missing inputSchema", tool.Name)
+		}
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "block,echo,fail" {
+		t.Errorf("tools = %s", got)
+	}
+}
+
+func TestToolCallResults(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	result, env := toolResult(t, h.call("1", "tools/call", `{"name":"echo","arguments":{"a":1}}`))
+	if result.IsError || !env.OK {
+		t.Fatalf("echo failed: %+v", env)
+	}
+	if got, _ := json.Marshal(env.Data); string(got) != `{"a":1}` {
+		t.Errorf("echo data = %s", got)
+	}
+
+	// Execution errors are results with isError, not protocol errors.
+	result, env = toolResult(t, h.call("2", "tools/call", `{"name":"fail"}`))
+	if !result.IsError || env.OK || env.Error == nil || env.Error.Message != "boom" {
+		t.Fatalf("fail result = %+v / %+v", result, env)
+	}
+
+	// Unknown tools and malformed params never reach the handler.
+	wantErrorCode(t, h.call("3", "tools/call", `{"name":"nope"}`), CodeInvalidParams)
+	wantErrorCode(t, h.call("4", "tools/call", `"not an object"`), CodeInvalidParams)
+}
+
+func TestProtocolErrors(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	wantErrorCode(t, h.call("1", "resources/list", ""), CodeMethodNotFound)
+
+	h.send(`{"jsonrpc":"2.0","id":2,"method":`)
+	resp := h.recvResponse()
+	wantErrorCode(t, resp, CodeParseError)
+	if string(resp.ID) != "null" && resp.ID != nil {
+		t.Errorf("parse error id = %s, want null", resp.ID)
+	}
+
+	// The session survives both.
+	if resp := h.call("3", "ping", ""); resp.Error != nil {
+		t.Fatalf("ping after errors: %v", resp.Error)
+	}
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	h := newHarness(t, func(s *Server) { s.SetMaxMessageSize(256) })
+	h.initialize()
+
+	h.send(request("1", "tools/call", `{"name":"echo","arguments":{"pad":"`+strings.Repeat("x", 1024)+`"}}`))
+	wantErrorCode(t, h.recvResponse(), CodeInvalidRequest)
+
+	if resp := h.call("2", "ping", ""); resp.Error != nil {
+		t.Fatalf("ping after oversized message: %v", resp.Error)
+	}
+}
+
+func TestNotificationsGetNoResponse(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+	h.send(`{"jsonrpc":"2.0","method":"notifications/unknown"}`)
+	h.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"never-sent"}}`)
+	h.expectSilence(100 * time.Millisecond)
+}
+
+func TestCancellation(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	h.send(request(`"slow"`, "tools/call", `{"name":"block"}`))
+	<-h.handler.started
+	h.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"slow","reason":"test"}}`)
+
+	// No response for the cancelled call; later requests still work.
+	h.expectSilence(100 * time.Millisecond)
+	if resp := h.call("2", "ping", ""); resp.Error != nil {
+		t.Fatalf("ping after cancel: %v", resp.Error)
+	}
+}
+
+func TestSlowCallDoesNotBlockOthers(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	h.send(request("1", "tools/call", `{"name":"block"}`))
+	<-h.handler.started
+	if resp := h.call("2", "ping", ""); resp.Error != nil {
+		t.Fatalf("ping while a call runs: %v", resp.Error)
+	}
+	if _, env := toolResult(t, h.call("3", "tools/call", `{"name":"echo"}`)); !env.OK {
+		t.Fatal("echo while a call runs failed")
+	}
+
+	close(h.handler.release)
+	resp := h.recvResponse()
+	if string(resp.ID) != "1" {
+		t.Fatalf("got response for %s, want 1", resp.ID)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	h.send(`[` +
+		request("1", "ping", "") + `,` +
+		request("2", "tools/call", `{"name":"echo","arguments":{"n":2}}`) + `,` +
+		`{"jsonrpc":"2.0","method":"notifications/unknown"},` +
+		request("3", "nope", "") +
+		`]`)
+
+	var batch []Response
+	if err := json.Unmarshal(h.recv(), &batch); err != nil {
+		t.Fatalf("batch response is not an array: %v", err)
+	}
+	byID := make(map[string]Response)
+	for _, r := range batch {
+		byID[string(r.ID)] = r
+	}
+	if len(batch) != 3 {
+		t.Fatalf("batch has %d responses, want 3 (notification excluded)", len(batch))
+	}
+	if byID["1"].Error != nil {
+		t.Errorf("ping in batch: %v", byID["1"].Error)
+	}
+	if _, env := toolResult(t, byID["2"]); !env.OK {
+		t.Error("tool call in batch failed")
+	}
+	wantErrorCode(t, byID["3"], CodeMethodNotFound)
+}
+
+// TestBatchInvalidElements wants each bad batch element answered with its
+// own -32600, carrying its ID when it had a usable one, while the valid
+// elements still run.
+func TestBatchInvalidElements(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	h.send(`[` +
+		request("1", "ping", "") + `,` +
+		`1,` +
+		`{"jsonrpc":"2.0","id":2},` +
+		`{"jsonrpc":"1.0","id":3,"method":"ping"},` +
+		`{"jsonrpc":"2.0","id":{"n":4},"method":"ping"}` +
+		`]`)
+
+	var batch []Response
+	if err := json.Unmarshal(h.recv(), &batch); err != nil {
+		t.Fatalf("batch response is not an array: %v", err)
+	}
+	if len(batch) != 5 {
+		t.Fatalf("batch has %d responses, want 5", len(batch))
+	}
+	var nullIDs int
+	for _, r := range batch {
+		switch string(r.ID) {
+		case "1":
+			if r.Error != nil {
+				t.Errorf("ping in batch: %v", r.Error)
+			}
+		case "2", "3":
+			wantErrorCode(t, r, CodeInvalidRequest)
+		case "null":
+			nullIDs++
+			wantErrorCode(t, r, CodeInvalidRequest)
+		default:
+			t.Errorf("unexpected response id %s", r.ID)
+		}
+	}
+	if nullIDs != 2 {
+		t.Errorf("%d responses with a null id, want 2", nullIDs)
+	}
+
+	// a batch of nothing but invalid elements still gets one error each
+	h.send(`[1,2,3]`)
+	if err := json.Unmarshal(h.recv(), &batch); err != nil {
+		t.Fatalf("batch response is not an array: %v", err)
+	}
+	if len(batch) != 3 {
+		t.Fatalf("batch has %d responses, want 3", len(batch))
+	}
+	for _, r := range batch {
+		wantErrorCode(t, r, CodeInvalidRequest)
+	}
+}
+
+func TestShutdownCancelsInflight(t *testing.T) {
+	h := newHarness(t)
+	h.initialize()
+
+	h.send(request("1", "tools/call", `{"name":"block"}`))
+	<-h.handler.started
+	h.cancel()
+
+	select {
+	case err := <-h.done:
+		if err != nil {
+			t.Fatalf("Run = %v", err)
+		}
+		h.done <- err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
@@ -0,0 +1,23 @@
+package mcp
+
+import "context"
+
+// Client is what we learned about the peer during initialize.
+type Client struct {
+	ProtocolVersion string
+	Info            EntityInfo
+	Capabilities    ClientCapabilities
+}
+
+type clientKey struct{}
+
+// WithClient attaches the negotiated client to ctx so tools can adapt to it.
+func WithClient(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFromContext returns the negotiated client, or nil before initialize.
+func ClientFromContext(ctx context.Context) *Client {
+	c, _ := ctx.Value(clientKey{}).(*Client)
+	return c
+}
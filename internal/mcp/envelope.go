@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// Envelope is the one shape every tool's text output takes, so agents can
+// parse all results with a single code path.
+type Envelope struct {
+	OK    bool           `json:"ok"`
+	Data  any            `json:"data,omitempty"`
+	Error *EnvelopeError `json:"error,omitempty"`
+	Meta  *EnvelopeMeta  `json:"meta,omitempty"`
+}
+
+type EnvelopeError struct {
+	Message      string `json:"message"`
+	Code         string `json:"code,omitempty"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
+	Attempt      int    `json:"attempt,omitempty"`
+}
+
+type EnvelopeMeta struct {
+	Count      *int   `json:"count,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// coded is satisfied by errors carrying a stable machine-readable code.
+type coded interface {
+	error
+	ErrorCode() string
+}
+
+// DataResult wraps data in a success envelope. Slices get meta.count
+// automatically; pass meta to add a cursor. Extra content blocks (images,
+// resources) follow the envelope.
+func DataResult(data any, meta *EnvelopeMeta, extra ...ContentBlock) (*ToolResult, error) {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		if meta == nil {
+			meta = &EnvelopeMeta{}
+		}
+		n := v.Len()
+		meta.Count = &n
+		if v.IsNil() {
+			data = []any{} // "data": [] rather than omitted
+		}
+	}
+	text, err := json.Marshal(Envelope{OK: true, Data: data, Meta: meta})
+	if err != nil {
+		return nil, err
+	}
+	return &ToolResult{
+		Content: append([]ContentBlock{TextContent(string(text))}, extra...),
+	}, nil
+}
+
+// ErrorResult wraps a tool execution error in a failure envelope.
+// Throttled calls carry their retry hint both in the envelope and _meta.
+func ErrorResult(err error) *ToolResult {
+	e := &EnvelopeError{Message: err.Error()}
+	var c coded
+	if errors.As(err, &c) {
+		e.Code = c.ErrorCode()
+	}
+
+	result := &ToolResult{IsError: true}
+	if hint, ok := retryData(err); ok {
+		e.RetryAfterMs = hint.RetryAfterMs
+		e.Attempt = hint.Attempt
+		if e.Code == "" {
+			e.Code = "RATE_LIMITED"
+		}
+		result.Meta = map[string]any{"retry": hint}
+	}
+
+	text, _ := json.Marshal(Envelope{OK: false, Error: e})
+	result.Content = []ContentBlock{TextContent(string(text))}
+	return result
+}
@@ -2,6 +2,8 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
+	"time"
 )
 
 // Error Codes
@@ -11,6 +13,9 @@ const (
 	CodeMethodNotFound = -32601
 	CodeInvalidParams  = -32602
 	CodeInternalError  = -32603
+
+	// Server-defined range (-32000 to -32099)
+	CodeResourceNotFound = -32002 // fixed by the MCP spec
 )
 
 // JSON-RPC 2.0 Error Object
@@ -44,3 +49,38 @@ func NewInvalidParams(msg string) *Error {
 func NewInternalError(msg string) *Error {
 	return &Error{Code: CodeInternalError, Message: msg}
 }
+
+// RetryData is the structured hint attached to throttled calls so clients
+// can back off precisely instead of guessing.
+type RetryData struct {
+	RetryAfterMs int64  `json:"retryAfterMs"`
+	Attempt      int    `json:"attempt,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// retryable is satisfied by errors that know when the call may succeed.
+// Declared here so mcp needn't import whichever package produced the error.
+type retryable interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// retryData extracts a RetryData if any error in err's chain is retryable.
+func retryData(err error) (*RetryData, bool) {
+	var r retryable
+	if !errors.As(err, &r) {
+		return nil, false
+	}
+	d := &RetryData{RetryAfterMs: r.RetryAfter().Milliseconds(), Reason: r.Error()}
+	if a, ok := r.(interface{ RetryAttempt() int }); ok {
+		d.Attempt = a.RetryAttempt()
+	}
+	return d, true
+}
+
+// NewMessageTooLarge reports an oversized message. The request was never
+// parsed, so the limit goes in data for the client to adjust.
+func NewMessageTooLarge(e *MessageTooLargeError) *Error {
+	data, _ := json.Marshal(map[string]int{"limit": e.Limit, "size": e.Size})
+	return &Error{Code: CodeInvalidRequest, Message: e.Error(), Data: data}
+}
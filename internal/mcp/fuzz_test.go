@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Seeds shared by the fuzz targets; the in-tree corpus under
+// testdata/fuzz adds inputs the fuzzer found interesting.
+var fuzzSeeds = []string{
+	`{"jsonrpc":"2.0","id":1,"method":"ping"}`,
+	`{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"echo","arguments":{"x":[1,2,{"y":null}]}}}`,
+	`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+	`{"jsonrpc":"2.0","id":"ping-1","result":{}}`,
+	`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"x"}]`,
+	`[]`,
+	`{"jsonrpc":"2.0","id":null,"method":"ping"}`,
+	`{"jsonrpc":"1.0","id":1,"method":"ping"}`,
+	`{"jsonrpc":"2.0","id":{},"method":"ping"}`,
+	`{"jsonrpc":"2.0","id":1}`,
+	strings.Repeat("[", 100) + strings.Repeat("]", 100),
+	`{"jsonrpc":"2.0","id":1,"method":"ping","params":"` + strings.Repeat(`\"[`, 50) + `"}`,
+	"  \t\r\n",
+	"{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\r\n{\"jsonrpc\"",
+	`"just a string"`,
+	`null`,
+}
+
+func FuzzReadMessage(f *testing.F) {
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		tr := NewTransport(bytes.NewReader(data), io.Discard)
+		tr.SetMaxMessageSize(4096)
+
+		// Every line yields one outcome; a read can never stall or panic.
+		lines := bytes.Count(data, []byte{'\n'}) + 1
+		for range lines + 1 {
+			msgs, err := tr.ReadMessage()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var tooLarge *MessageTooLargeError
+				var invalid *Error
+				var syntax *json.SyntaxError
+				var typeErr *json.UnmarshalTypeError
+				if !errors.As(err, &tooLarge) && !errors.As(err, &invalid) &&
+					!errors.As(err, &syntax) && !errors.As(err, &typeErr) {
+					t.Fatalf("unexpected error type %T: %v", err, err)
+				}
+				continue
+			}
+			if len(msgs) == 0 || len(msgs) > MaxBatchSize {
+				t.Fatalf("accepted %d messages", len(msgs))
+			}
+			for _, m := range msgs {
+				if m.invalid != nil {
+					if m.ID != nil && !validID(m.ID) {
+						t.Fatalf("invalid element kept bad id %s", m.ID)
+					}
+					continue
+				}
+				if err := m.validate(); err != nil {
+					t.Fatalf("accepted invalid message %+v: %v", m, err)
+				}
+				// Anything accepted must survive a round trip unchanged.
+				again, err := json.Marshal(m)
+				if err != nil {
+					t.Fatalf("re-marshal: %v", err)
+				}
+				back, err := NewTransport(bytes.NewReader(again), io.Discard).ReadMessage()
+				if err != nil {
+					t.Fatalf("round trip of %s: %v", again, err)
+				}
+				if back[0].Method != m.Method || !bytes.Equal(back[0].ID, m.ID) {
+					t.Fatalf("round trip changed %s into %+v", again, back[0])
+				}
+			}
+		}
+		t.Fatalf("more outcomes than lines in %q", data)
+	})
+}
+
+// FuzzServer feeds arbitrary input to a running server. Whatever arrives,
+// the server must not panic, must exit on EOF, and must only ever write
+// well-formed JSON lines.
+func FuzzServer(f *testing.F) {
+	handshake := `{"jsonrpc":"2.0","id":0,"method":"initialize","params":{}}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n"
+	for _, s := range fuzzSeeds {
+		f.Add([]byte(handshake + s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var out bytes.Buffer
+		handler := newTestHandler()
+		close(handler.release) // block returns at once
+		srv := NewServerWithTransport(handler, NewTransport(bytes.NewReader(data), &out))
+		srv.SetMaxMessageSize(4096)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Run(ctx); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+
+		sc := bufio.NewScanner(&out)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			if !json.Valid(sc.Bytes()) {
+				t.Fatalf("server wrote invalid JSON: %s", sc.Bytes())
+			}
+		}
+	})
+}
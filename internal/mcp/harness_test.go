@@ -0,0 +1,238 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+// testHandler is a ToolHandler with a few behaviours the contract needs:
+// echo returns its arguments, fail returns an execution error, and block
+// waits until cancelled (or released) so cancellation can be observed.
+type testHandler struct {
+	release chan struct{}
+	started chan struct{}
+}
+
+func newTestHandler() *testHandler {
+	return &testHandler{release: make(chan struct{}), started: make(chan struct{}, 16)}
+}
+
+func (h *testHandler) ListTools() []ToolDefinition {
+	schema := json.RawMessage(`{"type":"object","properties":{},"additionalProperties":true}`)
+	return []ToolDefinition{
+		{Name: "block", Description: "waits until cancelled", InputSchema: schema},
+		{Name: "echo", Description: "returns its arguments", InputSchema: schema, Annotations: &ToolAnnotations{ReadOnlyHint: true}},
+		{Name: "fail", Description: "always fails", InputSchema: schema},
+	}
+}
+
+func (h *testHandler) HasTool(name string) bool {
+	return name == "echo" || name == "fail" || name == "block"
+}
+
+func (h *testHandler) CallTool(ctx context.Context, name string, args json.RawMessage) (*ToolResult, error) {
+	switch name {
+	case "echo":
+		var v any
+		if len(args) > 0 {
+			if err := json.Unmarshal(args, &v); err != nil {
+				return nil, err
+			}
+		}
+		return DataResult(v, nil)
+	case "fail":
+		return nil, errors.New("boom")
+	case "block":
+		select {
+		case h.started <- struct{}{}:
+		default:
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-h.release:
+			return DataResult("released", nil)
+		}
+	}
+	return nil, fmt.Errorf("unknown tool: %s", name)
+}
+
+// harness runs a Server over in-memory pipes and speaks raw JSON-RPC lines
+// to it, the same way a stdio client would.
+type harness struct {
+	t       *testing.T
+	handler *testHandler
+	in      *io.PipeWriter
+	lines   chan []byte
+	done    chan error
+	cancel  context.CancelFunc
+}
+
+func newHarness(t *testing.T, configure ...func(*Server)) *harness {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	h := &harness{
+		t:       t,
+		handler: newTestHandler(),
+		in:      inW,
+		lines:   make(chan []byte, 64),
+		done:    make(chan error, 1),
+	}
+	srv := NewServerWithTransport(h.handler, NewTransport(inR, outW))
+	for _, fn := range configure {
+		fn(srv)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go func() {
+		err := srv.Run(ctx)
+		outW.Close()
+		h.done <- err
+	}()
+	go func() {
+		sc := bufio.NewScanner(outR)
+		sc.Buffer(make([]byte, 0, 64*1024), 4<<20)
+		for sc.Scan() {
+			h.lines <- append([]byte(nil), sc.Bytes()...)
+		}
+		close(h.lines)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		inW.Close()
+		select {
+		case <-h.done:
+		case <-time.After(5 * time.Second):
+			t.Error("server did not shut down")
+		}
+	})
+	return h
+}
+
+// send writes one raw line.
+func (h *harness) send(line string) {
+	h.t.Helper()
+	if _, err := io.WriteString(h.in, line+"\n"); err != nil {
+		h.t.Fatalf("write: %v", err)
+	}
+}
+
+// recv returns the next line the server wrote.
+func (h *harness) recv() []byte {
+	h.t.Helper()
+	select {
+	case line, ok := <-h.lines:
+		if !ok {
+			h.t.Fatal("server closed output")
+		}
+		return line
+	case <-time.After(5 * time.Second):
+		h.t.Fatal("timed out waiting for server output")
+	}
+	return nil
+}
+
+func (h *harness) recvResponse() Response {
+	h.t.Helper()
+	var resp Response
+	line := h.recv()
+	if err := json.Unmarshal(line, &resp); err != nil {
+		h.t.Fatalf("decode response %s: %v", line, err)
+	}
+	if resp.JSONRPC != "2.0" {
+		h.t.Fatalf("jsonrpc = %q, want 2.0 in %s", resp.JSONRPC, line)
+	}
+	return resp
+}
+
+// expectSilence asserts the server writes nothing for a short while.
+func (h *harness) expectSilence(d time.Duration) {
+	h.t.Helper()
+	select {
+	case line := <-h.lines:
+		h.t.Fatalf("unexpected output: %s", line)
+	case <-time.After(d):
+	}
+}
+
+// call sends a request and waits for its response.
+func (h *harness) call(id, method, params string) Response {
+	h.t.Helper()
+	h.send(request(id, method, params))
+	resp := h.recvResponse()
+	if string(resp.ID) != id {
+		h.t.Fatalf("response id = %s, want %s", resp.ID, id)
+	}
+	return resp
+}
+
+// initialize performs the full handshake and leaves the server Operating.
+func (h *harness) initialize() {
+	h.t.Helper()
+	resp := h.call("0", "initialize",
+		`{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"harness","version":"1"}}`)
+	if resp.Error != nil {
+		h.t.Fatalf("initialize: %v", resp.Error)
+	}
+	h.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+}
+
+// close ends the session as a client would (EOF) and returns Run's result.
+func (h *harness) close() error {
+	h.t.Helper()
+	h.in.Close()
+	select {
+	case err := <-h.done:
+		h.done <- err // let Cleanup see it too
+		return err
+	case <-time.After(5 * time.Second):
+		h.t.Fatal("server did not exit on EOF")
+	}
+	return nil
+}
+
+func request(id, method, params string) string {
+	if params == "" {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"method":%q}`, id, method)
+	}
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"method":%q,"params":%s}`, id, method, params)
+}
+
+func toolResult(t *testing.T, resp Response) (ToolResult, Envelope) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("protocol error: %v", resp.Error)
+	}
+	var result ToolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("decode tool result: %v", err)
+	}
+	if len(result.Content) == 0 || result.Content[0].Type != "text" {
+		t.Fatalf("first content block is not text: %s", resp.Result)
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &env); err != nil {
+		t.Fatalf("decode envelope: %v", err)
+	}
+	return result, env
+}
+
+func wantErrorCode(t *testing.T, resp Response, code int) {
+	t.Helper()
+	if resp.Error == nil {
+		t.Fatalf("want error %d, got result %s", code, resp.Result)
+	}
+	if resp.Error.Code != code {
+		t.Fatalf("error code = %d (%s), want %d", resp.Error.Code, resp.Error.Message, code)
+	}
+}
@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// inflight tracks running tools/call requests by JSON-RPC ID so
+// notifications/cancelled, the admin endpoints and teardown can reach them.
+type inflight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	tool    string
+	started time.Time
+	cancel  context.CancelFunc
+	aborted atomic.Bool // cancelled by an operator rather than the client
+}
+
+func newInflight() *inflight {
+	return &inflight{calls: make(map[string]*call)}
+}
+
+func (f *inflight) add(id, tool string, cancel context.CancelFunc) *call {
+	c := &call{tool: tool, started: time.Now(), cancel: cancel}
+	f.mu.Lock()
+	f.calls[id] = c
+	f.mu.Unlock()
+	return c
+}
+
+func (f *inflight) remove(id string) {
+	f.mu.Lock()
+	delete(f.calls, id)
+	f.mu.Unlock()
+}
+
+// cancel stops the call with the given ID. Unknown IDs are ignored:
+// the call may have finished already, which the spec says is expected.
+func (f *inflight) cancel(id string) {
+	f.mu.Lock()
+	if c, ok := f.calls[id]; ok {
+		c.cancel()
+		delete(f.calls, id)
+	}
+	f.mu.Unlock()
+}
+
+// abort is cancel on an operator's behalf: the client still gets an error
+// response, since it never asked for the call to stop. Reports whether
+// the call was running.
+func (f *inflight) abort(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.calls[id]
+	if !ok {
+		return false
+	}
+	c.aborted.Store(true)
+	c.cancel()
+	delete(f.calls, id)
+	return true
+}
+
+func (f *inflight) cancelAll() {
+	f.mu.Lock()
+	for id, c := range f.calls {
+		c.cancel()
+		delete(f.calls, id)
+	}
+	f.mu.Unlock()
+}
+
+func (f *inflight) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls)
+}
+
+// snapshot lists the running calls, longest-running first.
+func (f *inflight) snapshot() []CallInfo {
+	f.mu.Lock()
+	out := make([]CallInfo, 0, len(f.calls))
+	for id, c := range f.calls {
+		out = append(out, CallInfo{
+			ID:         id,
+			Tool:       c.tool,
+			StartedAt:  c.started.UTC(),
+			DurationMS: time.Since(c.started).Milliseconds(),
+		})
+	}
+	f.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
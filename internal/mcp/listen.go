@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// pipePrefix starts every Windows named pipe path.
+const pipePrefix = `\\.\pipe\`
+
+// DefaultListenAddr is the local endpoint for sharing one bossman between
+// several clients: a named pipe on Windows, where Unix sockets are missing
+// before Windows 10, and a socket in dir elsewhere.
+func DefaultListenAddr(dir string) string {
+	if runtime.GOOS == "windows" {
+		return "pipe:bossman"
+	}
+	return "unix:" + filepath.Join(dir, "bossman.sock")
+}
+
+// Listen opens a local endpoint for Serve. addr is "unix:PATH" for a Unix
+// domain socket (Windows 10 and later have them too) or "pipe:NAME" (or a
+// full \\.\pipe\NAME path) for a Windows named pipe. A socket file left
+// behind by a process that died is replaced; one still answering is not.
+func Listen(addr string) (net.Listener, error) {
+	kind, path, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "pipe" {
+		return listenPipe(path)
+	}
+	return listenUnix(path)
+}
+
+// parseAddr splits a Listen or Dial address into "unix" or "pipe" and the
+// socket or pipe path.
+func parseAddr(addr string) (kind, path string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		return "unix", filepath.Clean(strings.TrimPrefix(addr, "unix:")), nil
+	case strings.HasPrefix(addr, "pipe:"):
+		return "pipe", pipePrefix + strings.TrimPrefix(addr, "pipe:"), nil
+	case strings.HasPrefix(addr, pipePrefix):
+		return "pipe", addr, nil
+	default:
+		return "", "", fmt.Errorf("address %q: want unix:PATH or pipe:NAME", addr)
+	}
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another bossman", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// Serve runs a session for each connection on ln until ctx is cancelled,
+// then waits for the sessions to finish. newServer builds each session's
+// Server around its transport, configured as for stdio.
+func Serve(ctx context.Context, ln net.Listener, newServer func(*Transport) *Server) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	slog.Info("accepting MCP clients", "addr", ln.Addr())
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if err := newServer(NewTransport(conn, conn)).Run(ctx); err != nil {
+				slog.Warn("session ended", "err", err)
+			}
+		}()
+	}
+}
+
+// Dial connects to an endpoint opened by Listen, for stdio clients that
+// need a bridge to a shared server.
+func Dial(addr string) (io.ReadWriteCloser, error) {
+	kind, path, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "pipe" {
+		return os.OpenFile(path, os.O_RDWR, 0)
+	}
+	return net.Dial("unix", path)
+}
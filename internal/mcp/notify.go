@@ -0,0 +1,58 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotOperating is returned when a notification is attempted before the
+// client has finished initializing or after shutdown.
+var ErrNotOperating = errors.New("server not operating")
+
+// Notifier pushes server-initiated notifications to the client.
+// Subsystems (scheduler, blocker resolution, guards) depend on this rather
+// than on Server so they can run with no client attached.
+type Notifier interface {
+	Notify(method string, params any) error
+}
+
+// NopNotifier drops everything. Used when no MCP session exists (CLI, HTTP).
+type NopNotifier struct{}
+
+func (NopNotifier) Notify(string, any) error { return nil }
+
+// LogMessage sends a notifications/message at the given RFC 5424 level.
+func LogMessage(n Notifier, level string, data any) error {
+	return n.Notify("notifications/message", LogMessageParams{
+		Level:  level,
+		Logger: "bossman",
+		Data:   data,
+	})
+}
+
+type notifierKey struct{}
+
+// WithNotifier attaches n to ctx so tool implementations can emit notifications.
+func WithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// NotifierFromContext returns the notifier on ctx, or a NopNotifier.
+func NotifierFromContext(ctx context.Context) Notifier {
+	if n, ok := ctx.Value(notifierKey{}).(Notifier); ok {
+		return n
+	}
+	return NopNotifier{}
+}
+
+// Notify implements Notifier. Notifications are only sent once the session
+// is operating, per the lifecycle rules in the spec.
+func (s *Server) Notify(method string, params any) error {
+	s.mu.Lock()
+	state := s.state
+	s.mu.Unlock()
+	if state != StateOperating {
+		return ErrNotOperating
+	}
+	return s.transport.WriteNotification(method, params)
+}
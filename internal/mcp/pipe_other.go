@@ -0,0 +1,12 @@
+//go:build !windows
+
+package mcp
+
+import (
+	"errors"
+	"net"
+)
+
+func listenPipe(string) (net.Listener, error) {
+	return nil, errors.New("named pipes are only available on Windows; listen on unix:PATH instead")
+}
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+const pipeBufferSize = 64 << 10
+
+// pipeListener accepts clients on a Windows named pipe. Each connection
+// is its own pipe instance; the next one is created as soon as a client
+// takes the current one, so there is always an instance to connect to.
+type pipeListener struct {
+	name   string
+	mu     sync.Mutex
+	next   windows.Handle // instance waiting for a client, or 0
+	closed bool
+}
+
+func listenPipe(name string) (net.Listener, error) {
+	// the first instance claims the name; a second bossman fails here
+	h, err := createPipe(name, windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	if err != nil {
+		return nil, &os.PathError{Op: "listen", Path: name, Err: err}
+	}
+	return &pipeListener{name: name, next: h}, nil
+}
+
+func createPipe(name string, flags uint32) (windows.Handle, error) {
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	return windows.CreateNamedPipe(path,
+		windows.PIPE_ACCESS_DUPLEX|flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, nil)
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	h, closed := l.next, l.closed
+	l.next = 0
+	l.mu.Unlock()
+	if closed {
+		return nil, net.ErrClosed
+	}
+	if h == 0 {
+		var err error
+		if h, err = createPipe(l.name, 0); err != nil {
+			return nil, err
+		}
+	}
+	if err := windows.ConnectNamedPipe(h, nil); err != nil && err != windows.ERROR_PIPE_CONNECTED {
+		windows.CloseHandle(h)
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		windows.CloseHandle(h)
+		return nil, net.ErrClosed
+	}
+	if next, err := createPipe(l.name, 0); err == nil {
+		l.next = next
+	}
+	return &pipeConn{File: os.NewFile(uintptr(h), l.name), addr: pipeAddr(l.name)}, nil
+}
+
+// Close stops accepting. A blocked Accept is woken by connecting to the
+// pipe ourselves, since closing the handle doesn't interrupt it.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+
+	path, err := windows.UTF16PtrFromString(l.name)
+	if err != nil {
+		return err
+	}
+	if h, err := windows.CreateFile(path, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil,
+		windows.OPEN_EXISTING, 0, 0); err == nil {
+		windows.CloseHandle(h)
+	}
+	l.mu.Lock()
+	if l.next != 0 {
+		windows.CloseHandle(l.next)
+		l.next = 0
+	}
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr { return pipeAddr(l.name) }
+
+type pipeAddr string
+
+func (pipeAddr) Network() string  { return "pipe" }
+func (a pipeAddr) String() string { return string(a) }
+
+// pipeConn is one client's pipe instance. Deadlines aren't supported on
+// synchronous pipe handles; the session doesn't use them.
+type pipeConn struct {
+	*os.File
+	addr pipeAddr
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.addr }
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/guard"
+)
+
+// RateLimit is a token bucket: Rate tokens refill per second, up to Burst.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimit applies to any tool without its own entry.
+var DefaultRateLimit = RateLimit{Rate: 20, Burst: 40}
+
+// DefaultToolRateLimits are tighter limits for tools a runaway loop is
+// most likely to hammer.
+var DefaultToolRateLimits = map[string]RateLimit{
+	"create_task": {Rate: 5, Burst: 50},
+	"delete_task": {Rate: 2, Burst: 20},
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps one bucket per tool for a single session. Repeated
+// rejections grow the suggested wait exponentially so loops slow down.
+type RateLimiter struct {
+	def     RateLimit
+	perTool map[string]RateLimit
+	backoff guard.Backoff
+	clock   clock.Clock
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	strikes map[string]int // consecutive rejections per tool
+}
+
+func NewRateLimiter(def RateLimit, perTool map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		def:     def,
+		perTool: perTool,
+		backoff: guard.DefaultBackoff,
+		clock:   clock.Real{},
+		buckets: make(map[string]*bucket),
+		strikes: make(map[string]int),
+	}
+}
+
+// SetClock replaces the wall clock, for tests and simulations.
+// Must be called before first use.
+func (l *RateLimiter) SetClock(c clock.Clock) {
+	l.clock = c
+}
+
+// Allow takes a token for tool, or returns a *guard.RetryError saying how
+// long to wait.
+func (l *RateLimiter) Allow(tool string) error {
+	limit, ok := l.perTool[tool]
+	if !ok {
+		limit = l.def
+	}
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	now := l.clock.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[tool]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[tool] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		delete(l.strikes, tool)
+		return nil
+	}
+
+	l.strikes[tool]++
+	refill := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return &guard.RetryError{
+		Reason:  "rate limit exceeded for " + tool,
+		After:   l.backoff.Delay(l.strikes[tool], refill),
+		Attempt: l.strikes[tool],
+	}
+}
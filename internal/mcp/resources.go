@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ResourceHandler is implemented by handlers that also serve resources.
+// The server advertises the resources capability only when its handler
+// implements it.
+type ResourceHandler interface {
+	ListResources(ctx context.Context) ([]Resource, error)
+	ResourceTemplates() []ResourceTemplate
+	// ReadResource returns ErrResourceNotFound for URIs it doesn't know.
+	ReadResource(ctx context.Context, uri string) ([]ResourceContents, error)
+}
+
+// ErrResourceNotFound maps to the spec's resource-not-found error.
+var ErrResourceNotFound = errors.New("resource not found")
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+}
+
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ResourcesCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+func (s *Server) resources() (ResourceHandler, bool) {
+	rh, ok := s.handler.(ResourceHandler)
+	return rh, ok
+}
+
+func (s *Server) handleResourcesList(ctx context.Context, req Request) *Response {
+	rh, _ := s.resources()
+	list, err := rh.ListResources(ctx)
+	if err != nil {
+		r := NewErrorResponse(req.ID, NewInternalError(err.Error()))
+		return &r
+	}
+	if list == nil {
+		list = []Resource{}
+	}
+	return marshalResponse(req.ID, struct {
+		Resources []Resource `json:"resources"`
+	}{list})
+}
+
+func (s *Server) handleResourceTemplatesList(req Request) *Response {
+	rh, _ := s.resources()
+	templates := rh.ResourceTemplates()
+	if templates == nil {
+		templates = []ResourceTemplate{}
+	}
+	return marshalResponse(req.ID, struct {
+		ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	}{templates})
+}
+
+func (s *Server) handleResourcesRead(ctx context.Context, req Request) *Response {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		r := NewErrorResponse(req.ID, NewInvalidParams("uri is required"))
+		return &r
+	}
+	rh, _ := s.resources()
+	contents, err := rh.ReadResource(ctx, params.URI)
+	if errors.Is(err, ErrResourceNotFound) {
+		r := NewErrorResponse(req.ID, &Error{
+			Code:    CodeResourceNotFound,
+			Message: "resource not found: " + params.URI,
+			Data:    mustJSON(map[string]string{"uri": params.URI}),
+		})
+		return &r
+	}
+	if err != nil {
+		r := NewErrorResponse(req.ID, NewInternalError(err.Error()))
+		return &r
+	}
+	return marshalResponse(req.ID, struct {
+		Contents []ResourceContents `json:"contents"`
+	}{contents})
+}
+
+func marshalResponse(id json.RawMessage, v any) *Response {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r := NewErrorResponse(id, NewInternalError(err.Error()))
+		return &r
+	}
+	r := NewResponse(id, data)
+	return &r
+}
+
+func mustJSON(v any) json.RawMessage {
+	data, _ := json.Marshal(v)
+	return data
+}
@@ -3,10 +3,15 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // ToolHandler is the boundary between protocol and business logic.
@@ -21,16 +26,45 @@ type ToolHandler interface {
 // It manages the state machine (Created -> Initializing -> Operating -> Shutdown)
 // and dispatches JSON-RPC requests to the appropriate handler.
 type Server struct {
-	transport *Transport
-	handler   ToolHandler
-	state     ServerState
-	inflight  map[string]context.CancelFunc // tracks in-progress requests for cancellation
-	mu        sync.Mutex                    // guards state and inflight
+	transport    *Transport
+	handler      ToolHandler
+	state        ServerState
+	client       *Client       // set by initialize, read-only afterwards
+	instructions string        // sent to clients in InitializeResult
+	limiter      *RateLimiter  // nil disables rate limiting
+	pingInterval time.Duration // zero disables keepalive pings
+	idleTimeout  time.Duration // zero disables idle shutdown
+	canIdleStop  func() bool   // vetoes idle shutdown, e.g. while work is in progress
+	pingSeq      int
+	sessions     *SessionRegistry // nil when running standalone
+	session      *Session
+	calls        *inflight          // running tools/call requests, for cancellation
+	workers      chan struct{}      // semaphore bounding concurrent tool calls
+	work         sync.WaitGroup     // running tool call goroutines
+	started      time.Time          // when Run began
+	stop         context.CancelFunc // ends Run, for the admin disconnect
+	mu           sync.Mutex         // guards state, client and pingSeq
 }
 
+// DefaultMaxConcurrency bounds how many tool calls run at once per session.
+const DefaultMaxConcurrency = 8
+
 func (s *Server) handleInitialize(req Request) *Response {
+	var params InitializeParams
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			r := NewErrorResponse(req.ID, NewInvalidParams(err.Error()))
+			return &r
+		}
+	}
+
 	s.mu.Lock()
 	s.state = StateInitializing
+	s.client = &Client{
+		ProtocolVersion: params.ProtocolVersion,
+		Info:            params.ClientInfo,
+		Capabilities:    params.Capabilities,
+	}
 	s.mu.Unlock()
 
 	result := InitializeResult{
@@ -43,6 +77,10 @@ func (s *Server) handleInitialize(req Request) *Response {
 			Name:    "bossman",
 			Version: "0.1.0",
 		},
+		Instructions: s.instructions,
+	}
+	if _, ok := s.resources(); ok {
+		result.Capabilities.Resources = &ResourcesCapability{ListChanged: true}
 	}
 
 	data, err := json.Marshal(result)
@@ -72,46 +110,59 @@ func (s *Server) handleToolsList(req Request) *Response {
 	return &r
 }
 
-func (s *Server) handleToolsCall(req Request) *Response {
+func (s *Server) handleToolsCall(ctx context.Context, req Request) *Response {
 	var params ToolCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		r := NewErrorResponse(req.ID, NewInvalidParams(err.Error()))
 		return &r
 	}
 
-	// Cancellable context: stored in inflight so notifications/cancelled can stop this request.
-	ctx, cancel := context.WithCancel(context.Background())
-	key := string(req.ID)
-
-	s.mu.Lock()
-	s.inflight[key] = cancel
-	s.mu.Unlock()
-
 	if !s.handler.HasTool(params.Name) {
-		cancel()
-		s.mu.Lock()
-		delete(s.inflight, key)
-		s.mu.Unlock()
 		r := NewErrorResponse(req.ID, NewInvalidParams("unknown tool: "+params.Name))
 		return &r
 	}
 
-	result, err := s.handler.CallTool(ctx, params.Name, params.Arguments)
+	// Derived from the run context so teardown reaches every call, and
+	// stored in inflight so notifications/cancelled can stop this one.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	key := string(req.ID)
+	running := s.calls.add(key, params.Name, cancel)
+	defer s.calls.remove(key)
 
-	// Cleanup: remove from inflight before cancelling to avoid a redundant cancel
-	// from a racing notifications/cancelled.
 	s.mu.Lock()
-	delete(s.inflight, key)
+	if s.client != nil {
+		ctx = WithClient(ctx, s.client)
+	}
 	s.mu.Unlock()
-	cancel() // release context resources even on the normal path
+	ctx = WithNotifier(ctx, s)
+	if s.session != nil {
+		ctx = WithSession(ctx, s.session)
+	}
+
+	var result *ToolResult
+	var err error
+	if s.limiter != nil {
+		err = s.limiter.Allow(params.Name)
+	}
+	if err == nil {
+		result, err = s.handler.CallTool(ctx, params.Name, params.Arguments)
+	}
+
+	// Cancelled requests get no response, per spec. A call an operator
+	// cancelled still owes the client one.
+	if running.aborted.Load() {
+		r := NewErrorResponse(req.ID, NewInternalError("cancelled by an administrator"))
+		return &r
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
 
 	// Tool errors are execution errors, not protocol errors.
 	// They go in result with isError:true — the tool ran but failed.
 	if err != nil {
-		result = &ToolResult{
-			Content: []ContentBlock{{Type: "text", Text: err.Error()}},
-			IsError: true,
-		}
+		result = ErrorResult(err)
 	}
 
 	data, err := json.Marshal(result)
@@ -139,19 +190,16 @@ func (s *Server) handleNotification(req Request) {
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			return // malformed cancel — spec says ignore silently
 		}
-		key := string(params.RequestID)
-		s.mu.Lock()
-		if cancel, ok := s.inflight[key]; ok {
-			cancel()
-			delete(s.inflight, key)
-		}
-		s.mu.Unlock()
+		s.calls.cancel(string(params.RequestID))
 	}
 }
 
 // dispatch routes a request to its handler after checking the state machine.
 // Returns nil for notifications (no response needed).
-func (s *Server) dispatch(req Request) *Response {
+func (s *Server) dispatch(ctx context.Context, req Request) *Response {
+	if req.IsResponse() {
+		return nil // reply to our keepalive ping; arrival already counted as activity
+	}
 	if req.IsNotification() {
 		s.handleNotification(req)
 		return nil
@@ -161,7 +209,13 @@ func (s *Server) dispatch(req Request) *Response {
 	s.mu.Lock()
 	state := s.state
 	s.mu.Unlock()
+	return s.route(ctx, req, state)
+}
 
+// route handles a request against a state snapshot. Async calls take the
+// snapshot on the read loop so they see the state as of arrival, not as of
+// whenever a worker picks them up.
+func (s *Server) route(ctx context.Context, req Request, state ServerState) *Response {
 	switch req.Method {
 	case "initialize":
 		if state != StateCreated {
@@ -183,7 +237,24 @@ func (s *Server) dispatch(req Request) *Response {
 			r := NewErrorResponse(req.ID, NewInvalidRequest("server not initialized"))
 			return &r
 		}
-		return s.handleToolsCall(req)
+		return s.handleToolsCall(ctx, req)
+	case "resources/list", "resources/templates/list", "resources/read":
+		if _, ok := s.resources(); !ok {
+			r := NewErrorResponse(req.ID, NewMethodNotFound(req.Method))
+			return &r
+		}
+		if state != StateOperating {
+			r := NewErrorResponse(req.ID, NewInvalidRequest("server not initialized"))
+			return &r
+		}
+		switch req.Method {
+		case "resources/list":
+			return s.handleResourcesList(ctx, req)
+		case "resources/templates/list":
+			return s.handleResourceTemplatesList(req)
+		default:
+			return s.handleResourcesRead(ctx, req)
+		}
 	default:
 		r := NewErrorResponse(req.ID, NewMethodNotFound(req.Method))
 		return &r
@@ -191,58 +262,314 @@ func (s *Server) dispatch(req Request) *Response {
 }
 
 func NewServer(handler ToolHandler) *Server {
+	return NewServerWithTransport(handler, NewTransport(os.Stdin, os.Stdout))
+}
+
+// NewServerWithTransport serves over t instead of stdio; tests drive the
+// server through in-memory pipes this way.
+func NewServerWithTransport(handler ToolHandler, t *Transport) *Server {
 	return &Server{
-		transport: NewTransport(os.Stdin, os.Stdout),
+		transport: t,
 		handler:   handler,
 		state:     StateCreated,
-		inflight:  make(map[string]context.CancelFunc),
+		calls:     newInflight(),
+		workers:   make(chan struct{}, DefaultMaxConcurrency),
+	}
+}
+
+// SetInstructions sets the usage guidance sent to clients on initialize.
+// Must be called before Run.
+func (s *Server) SetInstructions(text string) {
+	s.instructions = text
+}
+
+// SetMaxMessageSize bounds the size of a single incoming message in bytes.
+// Must be called before Run.
+func (s *Server) SetMaxMessageSize(n int) {
+	s.transport.SetMaxMessageSize(n)
+}
+
+// SetRateLimiter enables per-tool rate limiting for this session.
+// Must be called before Run.
+func (s *Server) SetRateLimiter(l *RateLimiter) {
+	s.limiter = l
+}
+
+// Client returns what the peer sent in initialize, or nil if it hasn't yet.
+func (s *Server) Client() *Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.client
+}
+
+// SetSessions registers this server with reg for the duration of Run, so
+// broadcasts reach it. Must be called before Run.
+func (s *Server) SetSessions(reg *SessionRegistry) {
+	s.sessions = reg
+}
+
+// SetKeepalive makes the server ping the client every interval once
+// operating. Zero disables pings. Must be called before Run.
+func (s *Server) SetKeepalive(interval time.Duration) {
+	s.pingInterval = interval
+}
+
+// SetIdleTimeout shuts the server down when no client message has arrived
+// for timeout and canStop (if non-nil) agrees. Zero disables it.
+// Must be called before Run.
+func (s *Server) SetIdleTimeout(timeout time.Duration, canStop func() bool) {
+	s.idleTimeout = timeout
+	s.canIdleStop = canStop
+}
+
+func (s *Server) sendPing() error {
+	s.mu.Lock()
+	operating := s.state == StateOperating
+	s.pingSeq++
+	id := fmt.Sprintf(`"ping-%d"`, s.pingSeq)
+	s.mu.Unlock()
+	if !operating {
+		return nil
+	}
+	return s.transport.WriteRequest(Request{JSONRPC: "2.0", ID: json.RawMessage(id), Method: "ping"})
+}
+
+// SetMaxConcurrency bounds how many tool calls may run at once.
+// Must be called before Run.
+func (s *Server) SetMaxConcurrency(n int) {
+	if n > 0 {
+		s.workers = make(chan struct{}, n)
 	}
 }
 
-// Run is the main loop. Reads messages from stdin, dispatches, writes responses to stdout.
-// Returns nil on clean shutdown (stdin EOF), error if the transport breaks.
-func (s *Server) Run() error {
+func (s *Server) currentState() ServerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *Server) setState(state ServerState) {
+	s.mu.Lock()
+	s.state = state
+	s.mu.Unlock()
+}
+
+type readResult struct {
+	msgs []Request
+	err  error
+}
+
+// Run serves one session until stdin closes, the idle timeout fires, ctx is
+// cancelled, or writing to the client fails. Returns nil on clean shutdown.
+//
+// Teardown is ordered: stop accepting messages, let running tool calls
+// finish (or cancel them if ctx was cancelled), flush queued writes, return.
+func (s *Server) Run(ctx context.Context) error {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
-	for {
-		msgs, err := s.transport.ReadMessage()
-		if err == io.EOF {
-			s.mu.Lock()
-			s.state = StateShutdown
-			s.mu.Unlock()
-			return nil
-		}
-		if err != nil {
-			logger.Error("parse error", "err", err)
-			// null ID: we couldn't parse the request, so we don't know the ID
-			resp := NewErrorResponse(nil, NewParseError(err.Error()))
-			if writeErr := s.transport.WriteResponse(resp); writeErr != nil {
-				return writeErr
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	s.mu.Lock()
+	s.started, s.stop = time.Now(), stop
+	s.mu.Unlock()
+
+	if s.sessions != nil {
+		s.session = s.sessions.Register(s)
+		defer s.sessions.Unregister(s.session.ID)
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(s.transport.RunWriter)
+
+	// The reader is deliberately outside the group: a blocking read on
+	// stdin can't be interrupted, and waiting on it would stall idle
+	// shutdown. It exits on EOF or when done is closed.
+	reads := make(chan readResult)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			msgs, err := s.transport.ReadMessage()
+			select {
+			case reads <- readResult{msgs, err}:
+			case <-done:
+				return
+			}
+			if err == io.EOF {
+				return
 			}
-			continue
 		}
+	}()
 
-		if len(msgs) == 1 {
-			resp := s.dispatch(msgs[0])
-			if resp != nil {
-				if err := s.transport.WriteResponse(*resp); err != nil {
-					return err
-				}
+	loopErr := s.loop(gctx, logger, reads)
+
+	s.setState(StateShutdown)
+	if gctx.Err() != nil {
+		s.calls.cancelAll()
+	}
+	s.work.Wait()
+	s.transport.CloseWrites()
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return loopErr
+}
+
+func (s *Server) loop(ctx context.Context, logger *slog.Logger, reads <-chan readResult) error {
+	var pings, idleChecks <-chan time.Time
+	if s.pingInterval > 0 {
+		t := time.NewTicker(s.pingInterval)
+		defer t.Stop()
+		pings = t.C
+	}
+	if s.idleTimeout > 0 {
+		t := time.NewTicker(max(s.idleTimeout/4, time.Second))
+		defer t.Stop()
+		idleChecks = t.C
+	}
+	lastActivity := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-pings:
+			if err := s.sendPing(); err != nil {
+				return err
+			}
+		case <-idleChecks:
+			if time.Since(lastActivity) < s.idleTimeout || s.calls.len() > 0 {
+				continue
 			}
-		} else {
-			// Batch: collect responses, skip nil (notifications), write as JSON array
-			var responses []Response
-			for _, msg := range msgs {
-				resp := s.dispatch(msg)
-				if resp != nil {
-					responses = append(responses, *resp)
+			if s.canIdleStop != nil && !s.canIdleStop() {
+				continue
+			}
+			logger.Info("idle timeout, shutting down", "idle", time.Since(lastActivity).Round(time.Second))
+			return nil
+		case rr := <-reads:
+			lastActivity = time.Now()
+			if err := s.handleRead(ctx, logger, rr.msgs, rr.err); err != nil {
+				if err == io.EOF {
+					return nil
 				}
+				return err
 			}
-			if len(responses) > 0 {
-				if err := s.transport.WriteBatchResponse(responses); err != nil {
-					return err
+		}
+	}
+}
+
+// isAsync reports whether req runs on a worker rather than the read loop.
+// Only tool calls are slow enough to need it; everything else (including
+// state transitions) stays in order on the loop.
+func isAsync(req Request) bool {
+	return req.Method == "tools/call" && !req.IsNotification() && !req.IsResponse()
+}
+
+// spawn runs fn on a worker once a slot is free. Blocking here is the
+// backpressure that keeps goroutine count bounded. Returns false, without
+// running fn, if ctx ends first.
+func (s *Server) spawn(ctx context.Context, fn func()) bool {
+	select {
+	case s.workers <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+	s.work.Add(1)
+	go func() {
+		defer s.work.Done()
+		defer func() { <-s.workers }()
+		fn()
+	}()
+	return true
+}
+
+// handleRead processes one read from the transport. Returns io.EOF on
+// clean shutdown, any other error if writing to the client failed.
+func (s *Server) handleRead(ctx context.Context, logger *slog.Logger, msgs []Request, err error) error {
+	if err == io.EOF {
+		return io.EOF
+	}
+	var tooLarge *MessageTooLargeError
+	if errors.As(err, &tooLarge) {
+		// The line was discarded; tell the client and keep the session alive.
+		logger.Warn("message too large", "size", tooLarge.Size, "limit", tooLarge.Limit)
+		return s.transport.WriteResponse(NewErrorResponse(nil, NewMessageTooLarge(tooLarge)))
+	}
+	var invalid *Error
+	if errors.As(err, &invalid) {
+		logger.Warn("invalid message", "err", invalid.Message)
+		return s.transport.WriteResponse(NewErrorResponse(nil, invalid))
+	}
+	if err != nil {
+		logger.Error("parse error", "err", err)
+		// null ID: we couldn't parse the request, so we don't know the ID
+		return s.transport.WriteResponse(NewErrorResponse(nil, NewParseError(err.Error())))
+	}
+
+	if len(msgs) == 1 {
+		msg := msgs[0]
+		if msg.invalid != nil {
+			logger.Warn("invalid message", "err", msg.invalid.Message)
+			return s.transport.WriteResponse(NewErrorResponse(msg.ID, msg.invalid))
+		}
+		if isAsync(msg) {
+			state := s.currentState()
+			s.spawn(ctx, func() {
+				if resp := s.route(ctx, msg, state); resp != nil {
+					if err := s.transport.WriteResponse(*resp); err != nil {
+						logger.Debug("write response", "err", err)
+					}
 				}
+			})
+			return nil
+		}
+		if resp := s.dispatch(ctx, msg); resp != nil {
+			return s.transport.WriteResponse(*resp)
+		}
+		return nil
+	}
+
+	// Batch: tool calls fan out to workers, everything else runs in order
+	// here. One collector waits for the lot and writes a single array,
+	// skipping nil (notifications, cancelled calls).
+	responses := make([]*Response, len(msgs))
+	var batch sync.WaitGroup
+	for i, msg := range msgs {
+		if msg.invalid != nil {
+			logger.Warn("invalid message in batch", "err", msg.invalid.Message)
+			resp := NewErrorResponse(msg.ID, msg.invalid)
+			responses[i] = &resp
+			continue
+		}
+		if isAsync(msg) {
+			batch.Add(1)
+			state := s.currentState()
+			if !s.spawn(ctx, func() {
+				defer batch.Done()
+				responses[i] = s.route(ctx, msg, state)
+			}) {
+				batch.Done()
 			}
+			continue
 		}
+		responses[i] = s.dispatch(ctx, msg)
 	}
+
+	s.work.Add(1)
+	go func() {
+		defer s.work.Done()
+		batch.Wait()
+		var out []Response
+		for _, r := range responses {
+			if r != nil {
+				out = append(out, *r)
+			}
+		}
+		if len(out) > 0 {
+			if err := s.transport.WriteBatchResponse(out); err != nil {
+				logger.Debug("write batch response", "err", err)
+			}
+		}
+	}()
+	return nil
 }
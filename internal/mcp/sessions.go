@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/rs/xid"
+)
+
+// Session is one connected client as seen by the registry.
+type Session struct {
+	ID       string
+	notifier Notifier
+	mu       sync.Mutex
+	watched  map[string]bool // resource URIs this session wants updates for
+}
+
+func (s *Session) Subscribe(uri string) {
+	s.mu.Lock()
+	s.watched[uri] = true
+	s.mu.Unlock()
+}
+
+func (s *Session) Unsubscribe(uri string) {
+	s.mu.Lock()
+	delete(s.watched, uri)
+	s.mu.Unlock()
+}
+
+func (s *Session) Watching(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watched[uri]
+}
+
+// SessionRegistry tracks connected sessions and fans notifications out to
+// them. Today stdio gives us one session per process; the registry is the
+// seam multi-session transports plug into.
+type SessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	onEnd    []func(id string)
+}
+
+func NewSessionRegistry() *SessionRegistry {
+	return &SessionRegistry{sessions: make(map[string]*Session)}
+}
+
+func (r *SessionRegistry) Register(n Notifier) *Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &Session{
+		// unique across processes and restarts, since session-owned rows
+		// in a shared database outlive the process
+		ID:       "session_" + xid.New().String(),
+		notifier: n,
+		watched:  make(map[string]bool),
+	}
+	r.sessions[s.ID] = s
+	return s
+}
+
+func (r *SessionRegistry) Unregister(id string) {
+	r.mu.Lock()
+	delete(r.sessions, id)
+	hooks := r.onEnd
+	r.mu.Unlock()
+	for _, fn := range hooks {
+		fn(id)
+	}
+}
+
+// OnEnd registers fn to run with a session's ID once it has ended.
+func (r *SessionRegistry) OnEnd(fn func(id string)) {
+	r.mu.Lock()
+	r.onEnd = append(r.onEnd, fn)
+	r.mu.Unlock()
+}
+
+func (r *SessionRegistry) snapshot() []*Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Broadcast sends to every session.
+func (r *SessionRegistry) Broadcast(method string, params any) {
+	for _, s := range r.snapshot() {
+		r.send(s, method, params)
+	}
+}
+
+// Notify implements Notifier by broadcasting, so background subsystems
+// reach every client when several share one process.
+func (r *SessionRegistry) Notify(method string, params any) error {
+	r.Broadcast(method, params)
+	return nil
+}
+
+// NotifyWatchers sends notifications/resources/updated for uri to the
+// sessions subscribed to it.
+func (r *SessionRegistry) NotifyWatchers(uri string) {
+	for _, s := range r.snapshot() {
+		if s.Watching(uri) {
+			r.send(s, "notifications/resources/updated", map[string]string{"uri": uri})
+		}
+	}
+}
+
+// send drops notifications for sessions not yet operating; anything else
+// is logged and ignored so one broken session can't stall the rest.
+func (r *SessionRegistry) send(s *Session, method string, params any) {
+	err := s.notifier.Notify(method, params)
+	if err != nil && err != ErrNotOperating {
+		slog.Warn("notify session", "session", s.ID, "method", method, "err", err)
+	}
+}
+
+type sessionKey struct{}
+
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext returns the calling session, or nil outside a session.
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
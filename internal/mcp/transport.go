@@ -3,75 +3,267 @@ package mcp
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"sync"
 )
 
+// DefaultMaxMessageSize bounds a single incoming line.
+const DefaultMaxMessageSize = 1 << 20
+
+// MessageTooLargeError is returned by ReadMessage when a line exceeds the
+// limit. The oversized line has been discarded, so reading can continue.
+type MessageTooLargeError struct {
+	Size  int
+	Limit int
+}
+
+func (e *MessageTooLargeError) Error() string {
+	return fmt.Sprintf("message of %d bytes exceeds the %d byte limit", e.Size, e.Limit)
+}
+
+// ErrTransportClosed is returned by writes after CloseWrites.
+var ErrTransportClosed = errors.New("transport closed")
+
+// writeQueueSize bounds how many outgoing messages may be waiting on a slow
+// client before writers block.
+const writeQueueSize = 64
+
+// Transport reads newline-delimited JSON-RPC and writes through a bounded
+// queue drained by RunWriter, so no caller ever holds a lock across a
+// blocking write to the client.
 type Transport struct {
-	scanner *bufio.Scanner
+	reader  *bufio.Reader
+	maxSize int
 	writer  io.Writer
-	mu      sync.Mutex
+
+	mu      sync.RWMutex // guards closed against concurrent enqueue
+	closed  bool
+	queue   chan []byte
+	stopped chan struct{} // closed when RunWriter returns
+	err     error         // first write error; read only after stopped
 }
 
 func NewTransport(r io.Reader, w io.Writer) *Transport {
-	s := bufio.NewScanner(r)
-	s.Buffer(make([]byte, 0, 1<<20), 1<<20)
-	return &Transport{scanner: s, writer: w}
+	return &Transport{
+		reader:  bufio.NewReaderSize(r, 64<<10),
+		maxSize: DefaultMaxMessageSize,
+		writer:  w,
+		queue:   make(chan []byte, writeQueueSize),
+		stopped: make(chan struct{}),
+	}
 }
 
-func (t *Transport) ReadMessage() ([]Request, error) {
-	if !t.scanner.Scan() {
-		if err := t.scanner.Err(); err != nil {
+// RunWriter drains the write queue until CloseWrites, then returns.
+// On a write error it stops and returns the error; pending and future
+// writes fail with the same error.
+func (t *Transport) RunWriter() error {
+	defer close(t.stopped)
+	for data := range t.queue {
+		if _, err := t.writer.Write(data); err != nil {
+			t.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseWrites stops accepting writes. RunWriter returns once the queue is flushed.
+func (t *Transport) CloseWrites() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+}
+
+func (t *Transport) enqueue(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrTransportClosed
+	}
+	select {
+	case t.queue <- data:
+		return nil
+	case <-t.stopped:
+		return t.err
+	}
+}
+
+// SetMaxMessageSize changes the per-line limit. Must be called before reading.
+func (t *Transport) SetMaxMessageSize(n int) {
+	if n > 0 {
+		t.maxSize = n
+	}
+}
+
+// readLine returns the next newline-terminated line without the terminator.
+// Lines over maxSize are drained and reported as *MessageTooLargeError.
+func (t *Transport) readLine() ([]byte, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := t.reader.ReadSlice('\n')
+		size += len(chunk)
+		if size <= t.maxSize+1 { // +1 for the newline itself
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && size > 0 {
+			break // final line without a trailing newline
+		}
+		if err != nil {
 			return nil, err
 		}
-		return nil, io.EOF
+		break
+	}
+	if size > t.maxSize+1 {
+		return nil, &MessageTooLargeError{Size: size, Limit: t.maxSize}
+	}
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// Structural limits on a message, beyond its size in bytes.
+const (
+	MaxBatchSize    = 100
+	maxNestingDepth = 64
+)
+
+// ReadMessage reads one line and decodes it as a request or batch. Syntax
+// errors come back as plain errors (-32700); well-formed JSON that isn't a
+// valid JSON-RPC message comes back as *Error (-32600). Neither ends the
+// session. In a batch only the bad elements are invalid: they come back
+// marked, for the server to answer one by one beside the rest.
+func (t *Transport) ReadMessage() ([]Request, error) {
+	data, err := t.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDepth(data, maxNestingDepth); err != nil {
+		return nil, err
 	}
-	data := t.scanner.Bytes()
 
 	for _, b := range data {
 		switch b {
 		case ' ', '\t', '\n', '\r':
 			continue
 		case '[':
-			var batch []Request
-			if err := json.Unmarshal(data, &batch); err != nil {
+			var elems []json.RawMessage
+			if err := json.Unmarshal(data, &elems); err != nil {
 				return nil, err
 			}
+			if len(elems) == 0 {
+				return nil, NewInvalidRequest("empty batch")
+			}
+			if len(elems) > MaxBatchSize {
+				return nil, NewInvalidRequest(fmt.Sprintf("batch of %d exceeds the limit of %d", len(elems), MaxBatchSize))
+			}
+			batch := make([]Request, len(elems))
+			for i, elem := range elems {
+				batch[i] = batchRequest(elem)
+			}
 			return batch, nil
 		default:
 			var req Request
 			if err := json.Unmarshal(data, &req); err != nil {
 				return nil, err
 			}
+			if err := req.validate(); err != nil {
+				return nil, err
+			}
 			return []Request{req}, nil
 		}
 	}
 	return nil, NewParseError("empty message")
 }
 
-func (t *Transport) WriteResponse(resp Response) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// batchRequest decodes one batch element. One that isn't a valid request
+// keeps its ID only when that is a valid ID, so the error answers it.
+func batchRequest(elem json.RawMessage) Request {
+	var req Request
+	if err := json.Unmarshal(elem, &req); err != nil {
+		return Request{JSONRPC: "2.0", invalid: NewInvalidRequest("batch element is not a request object")}
+	}
+	if err := req.validate(); err != nil {
+		invalid := Request{JSONRPC: "2.0", invalid: NewInvalidRequest(err.Error())}
+		if req.ID != nil && validID(req.ID) {
+			invalid.ID = req.ID
+		}
+		return invalid
+	}
+	return req
+}
 
-	data, err := json.Marshal(resp)
-	if err != nil {
-		return err
+// checkDepth rejects JSON nested deeper than limit before it is decoded.
+// It only tracks brackets outside strings; syntax is left to the decoder.
+func checkDepth(data []byte, limit int) error {
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch b {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > limit {
+				return NewInvalidRequest(fmt.Sprintf("message nested deeper than %d levels", limit))
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
 	}
-	data = append(data, '\n')
-	_, err = t.writer.Write(data)
-	return err
+	return nil
+}
+
+func (t *Transport) WriteResponse(resp Response) error {
+	return t.enqueue(resp)
 }
 
 func (t *Transport) WriteBatchResponse(responses []Response) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	return t.enqueue(responses)
+}
 
-	data, err := json.Marshal(responses)
-	if err != nil {
-		return err
-	}
+// WriteRequest sends a server-initiated request such as ping.
+func (t *Transport) WriteRequest(req Request) error {
+	return t.enqueue(req)
+}
 
-	data = append(data, '\n')
-	_, err = t.writer.Write(data)
-	return err
+// WriteNotification sends a server-initiated notification. Safe to call from
+// any goroutine; writes are serialised with responses.
+func (t *Transport) WriteNotification(method string, params any) error {
+	n := Notification{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		n.Params = data
+	}
+	return t.enqueue(n)
 }
@@ -1,19 +1,58 @@
 package mcp
 
-import "encoding/json"
+import (
+	"encoding/base64"
+	"encoding/json"
+)
 
 // Request is a JSON-RPC 2.0 request or notification.
-// ID is nil for notifications.
+// ID is nil for notifications. Result and Error are only set when the
+// client answers a request the server sent (e.g. ping).
 type Request struct {
 	JSONRPC string          `json:"jsonrpc"`
 	ID      json.RawMessage `json:"id,omitempty"`
-	Method  string          `json:"method"`
+	Method  string          `json:"method,omitempty"`
 	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+
+	// invalid is set on a batch element that isn't a valid request; the
+	// server answers it with this error and runs the rest of the batch.
+	invalid *Error
 }
 
 // IsNotification returns true if this message has no ID (notification).
 func (r *Request) IsNotification() bool { return r.ID == nil }
 
+// IsResponse returns true if this message answers a server-sent request.
+func (r *Request) IsResponse() bool {
+	return r.Method == "" && r.ID != nil && (r.Result != nil || r.Error != nil)
+}
+
+// validate checks the envelope fields JSON-RPC requires; params are left
+// to the method handlers.
+func (r *Request) validate() error {
+	if r.JSONRPC != "2.0" {
+		return NewInvalidRequest(`jsonrpc must be "2.0"`)
+	}
+	if r.ID != nil && !validID(r.ID) {
+		return NewInvalidRequest("id must be a string or number")
+	}
+	if r.Method == "" && !r.IsResponse() {
+		return NewInvalidRequest("method is required")
+	}
+	return nil
+}
+
+// validID reports whether id is a JSON string or number.
+func validID(id json.RawMessage) bool {
+	switch id[0] {
+	case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return true
+	}
+	return false
+}
+
 // Response is a JSON-RPC 2.0 response.
 type Response struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -32,6 +71,20 @@ func NewErrorResponse(id json.RawMessage, e *Error) Response {
 	return Response{JSONRPC: "2.0", ID: id, Error: e}
 }
 
+// Notification is a JSON-RPC 2.0 notification sent from server to client.
+type Notification struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// LogMessageParams is the payload of notifications/message.
+type LogMessageParams struct {
+	Level  string `json:"level"`
+	Logger string `json:"logger,omitempty"`
+	Data   any    `json:"data"`
+}
+
 type ServerState int
 
 const (
@@ -41,36 +94,160 @@ const (
 	StateShutdown
 )
 
+func (s ServerState) String() string {
+	switch s {
+	case StateCreated:
+		return "created"
+	case StateInitializing:
+		return "initializing"
+	case StateOperating:
+		return "operating"
+	case StateShutdown:
+		return "shutdown"
+	}
+	return "unknown"
+}
+
 type EntityInfo struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
 }
 
 type Capabilities struct {
-	Tools   *struct{} `json:"tools,omitempty"`
-	Logging *struct{} `json:"logging,omitempty"`
+	Tools     *struct{}            `json:"tools,omitempty"`
+	Logging   *struct{}            `json:"logging,omitempty"`
+	Resources *ResourcesCapability `json:"resources,omitempty"`
+}
+
+// ClientCapabilities is what the client advertised in initialize.
+// Unknown capabilities are kept raw under Experimental.
+type ClientCapabilities struct {
+	Roots        *RootsCapability           `json:"roots,omitempty"`
+	Sampling     *struct{}                  `json:"sampling,omitempty"`
+	Experimental map[string]json.RawMessage `json:"experimental,omitempty"`
+}
+
+type RootsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type InitializeParams struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ClientCapabilities `json:"capabilities"`
+	ClientInfo      EntityInfo         `json:"clientInfo"`
 }
 
 type InitializeResult struct {
 	ProtocolVersion string       `json:"protocolVersion"`
 	Capabilities    Capabilities `json:"capabilities"`
 	ServerInfo      EntityInfo   `json:"serverInfo"`
+	Instructions    string       `json:"instructions,omitempty"`
 }
 
 type ToolDefinition struct {
-	Name        string          `json:"name"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema json.RawMessage  `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+	Meta        *ToolMeta        `json:"_meta,omitempty"`
+}
+
+// ToolMeta carries bossman extensions to a tool definition.
+type ToolMeta struct {
+	Examples []ToolExample `json:"examples,omitempty"`
+}
+
+// ToolExample is a known-good invocation and the result it produces.
+type ToolExample struct {
 	Description string          `json:"description"`
-	InputSchema json.RawMessage `json:"inputSchema"`
+	Arguments   json.RawMessage `json:"arguments"`
+	Output      json.RawMessage `json:"output,omitempty"`
 }
 
+// ToolAnnotations are behavioural hints. Per spec, a tool without
+// annotations is assumed to modify state.
+type ToolAnnotations struct {
+	ReadOnlyHint    bool `json:"readOnlyHint,omitempty"`
+	DestructiveHint bool `json:"destructiveHint,omitempty"`
+}
+
+// ReadOnly reports whether the tool promises not to modify state.
+func (d ToolDefinition) ReadOnly() bool {
+	return d.Annotations != nil && d.Annotations.ReadOnlyHint
+}
+
+// Destructive reports whether the tool may delete or overwrite data.
+func (d ToolDefinition) Destructive() bool {
+	return d.Annotations != nil && d.Annotations.DestructiveHint
+}
+
+// Content block types.
+const (
+	ContentText         = "text"
+	ContentImage        = "image"
+	ContentResource     = "resource"
+	ContentResourceLink = "resource_link"
+)
+
+// ContentBlock is one item of tool output. Which fields are meaningful
+// depends on Type; use the constructors below rather than building by hand.
 type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type        string            `json:"type"`
+	Text        string            `json:"text,omitempty"`
+	Data        string            `json:"data,omitempty"` // base64, for images
+	MimeType    string            `json:"mimeType,omitempty"`
+	URI         string            `json:"uri,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Resource    *ResourceContents `json:"resource,omitempty"`
+}
+
+// MarshalJSON keeps "text" present on text blocks even when empty,
+// since omitempty would otherwise produce an invalid block.
+func (c ContentBlock) MarshalJSON() ([]byte, error) {
+	type plain ContentBlock
+	if c.Type == ContentText {
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}{c.Type, c.Text})
+	}
+	return json.Marshal(plain(c))
+}
+
+// ResourceContents is an embedded resource: either Text or base64 Blob.
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+func TextContent(text string) ContentBlock {
+	return ContentBlock{Type: ContentText, Text: text}
+}
+
+func ImageContent(data []byte, mimeType string) ContentBlock {
+	return ContentBlock{
+		Type:     ContentImage,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// ResourceLinkContent points at something the client can fetch itself.
+func ResourceLinkContent(uri, name, mimeType string) ContentBlock {
+	return ContentBlock{Type: ContentResourceLink, URI: uri, Name: name, MimeType: mimeType}
+}
+
+func EmbeddedResourceContent(r ResourceContents) ContentBlock {
+	return ContentBlock{Type: ContentResource, Resource: &r}
 }
 
 type ToolResult struct {
 	Content []ContentBlock `json:"content"`
 	IsError bool           `json:"isError"`
+	Meta    map[string]any `json:"_meta,omitempty"`
 }
 
 type ToolCallParams struct {
@@ -0,0 +1,308 @@
+// Package taskwarrior converts between bossman tasks and TaskWarrior's JSON
+// export format (`task export` / `task import`).
+//
+// TaskWarrior has no parent/child links, free-form context, results or
+// metadata, so those travel as user-defined attributes (bossmanid,
+// bossmanparent, bossmancontext, bossmanresult, bossmanmetadata holding the
+// JSON as a string); TaskWarrior keeps unknown attributes on
+// import, which lets a round trip through it preserve them.
+package taskwarrior
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// timeLayout is TaskWarrior's compact ISO 8601 form.
+const timeLayout = "20060102T150405Z"
+
+// namespace seeds the name-based UUIDs exported tasks get, so exporting the
+// same database twice yields the same UUIDs.
+var namespace = uuid.MustParse("6b1d6f0e-6a43-4b55-9d0c-1c0b2f4d9a11")
+
+type task struct {
+	UUID        string       `json:"uuid"`
+	Description string       `json:"description"`
+	Status      string       `json:"status"`
+	Entry       string       `json:"entry"`
+	Modified    string       `json:"modified,omitempty"`
+	Start       string       `json:"start,omitempty"`
+	End         string       `json:"end,omitempty"`
+	Due         string       `json:"due,omitempty"`
+	Project     string       `json:"project,omitempty"`
+	Priority    string       `json:"priority,omitempty"`
+	Tags        []string     `json:"tags,omitempty"`
+	Depends     depends      `json:"depends,omitempty"`
+	Annotations []annotation `json:"annotations,omitempty"`
+
+	BossmanID      string `json:"bossmanid,omitempty"`
+	BossmanParent  string `json:"bossmanparent,omitempty"`
+	BossmanContext string `json:"bossmancontext,omitempty"`
+	BossmanResult  string `json:"bossmanresult,omitempty"`
+	BossmanMeta    string `json:"bossmanmetadata,omitempty"`
+}
+
+type annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// depends is written as an array (TaskWarrior 2.6+) and read from either
+// that or the older comma-separated string.
+type depends []string
+
+func (d *depends) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*d = nil
+		for _, u := range strings.Split(s, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				*d = append(*d, u)
+			}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("depends: want a string or array of UUIDs")
+	}
+	*d = list
+	return nil
+}
+
+// Export writes every non-system task as a TaskWarrior JSON array, or only
+// the tasks of projectID when it is set.
+func Export(ctx context.Context, conn *sqlx.DB, w io.Writer, projectID string) (int, error) {
+	bundles, err := db.LoadBundles(ctx, conn, projectID)
+	if err != nil {
+		return 0, err
+	}
+	uuidOf := func(id string) string {
+		return uuid.NewSHA1(namespace, []byte(id)).String()
+	}
+
+	out := make([]task, len(bundles))
+	for i, b := range bundles {
+		t := b.Task
+		tw := task{
+			UUID:           uuidOf(t.ID),
+			Description:    t.Description,
+			Entry:          toTW(t.CreatedAt),
+			Modified:       toTW(t.UpdatedAt),
+			Priority:       exportPriority(t.Priority),
+			Project:        b.Project,
+			Tags:           b.Tags,
+			BossmanID:      t.ID,
+			BossmanContext: string(t.Context),
+		}
+		if t.ParentID != nil {
+			tw.BossmanParent = uuidOf(*t.ParentID)
+		}
+		if t.Result != nil {
+			tw.BossmanResult = string(*t.Result)
+		}
+		if t.Metadata != nil {
+			tw.BossmanMeta = *t.Metadata
+		}
+		if t.StartedAt != nil {
+			tw.Start = toTW(*t.StartedAt)
+		}
+		if t.DueAt != nil {
+			tw.Due = toTW(*t.DueAt)
+		}
+		switch t.Status {
+		case "completed":
+			tw.Status = "completed"
+		case "failed":
+			// TaskWarrior has no failed state; deleted is the closest
+			// "closed without being done"
+			tw.Status = "deleted"
+		default:
+			tw.Status = "pending" // in_progress shows as a started pending task
+		}
+		if tw.Status != "pending" {
+			tw.End = tw.Modified
+			if t.CompletedAt != nil {
+				tw.End = toTW(*t.CompletedAt)
+			}
+		}
+		for _, dep := range b.BlockedBy {
+			tw.Depends = append(tw.Depends, uuidOf(dep))
+		}
+		for _, c := range b.Comments {
+			at, _ := db.ParseTimestamp(c.CreatedAt) // stored, so in TimeLayout
+			tw.Annotations = append(tw.Annotations, annotation{Entry: toTW(at), Description: c.Body})
+		}
+		out[i] = tw
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return len(out), enc.Encode(out)
+}
+
+// Import reads a TaskWarrior JSON export (an array, or one object per line
+// as older versions write) and adds its tasks. Dependencies on tasks that
+// aren't in the file are dropped. Tasks keep their bossmanid when they have
+// one, so importing into the database they came from fails rather than
+// duplicating them.
+func Import(ctx context.Context, conn *sqlx.DB, r io.Reader) (int, error) {
+	tasks, err := decode(r)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make(map[string]string, len(tasks))
+	for _, tw := range tasks {
+		if tw.UUID == "" {
+			return 0, fmt.Errorf("task %q has no uuid", tw.Description)
+		}
+		ids[tw.UUID] = tw.BossmanID
+		if ids[tw.UUID] == "" {
+			ids[tw.UUID] = db.NewTaskID()
+		}
+	}
+
+	bundles := make([]db.TaskBundle, 0, len(tasks))
+	for _, tw := range tasks {
+		t := db.Task{
+			ID:          ids[tw.UUID],
+			Description: tw.Description,
+			Context:     db.Sensitive(tw.BossmanContext),
+			Priority:    importPriority(tw.Priority),
+			Status:      importStatus(tw),
+			CreatedAt:   fromTW(tw.Entry),
+			UpdatedAt:   fromTW(tw.Modified),
+		}
+		if parent, ok := ids[tw.BossmanParent]; ok {
+			t.ParentID = &parent
+		}
+		if tw.BossmanResult != "" {
+			result := db.Sensitive(tw.BossmanResult)
+			t.Result = &result
+		}
+		if tw.BossmanMeta != "" {
+			meta, err := db.ValidateMetadata(json.RawMessage(tw.BossmanMeta))
+			if err != nil {
+				return 0, fmt.Errorf("task %s: %w", tw.UUID, err)
+			}
+			t.Metadata = &meta
+		}
+		if d := fromTW(tw.Due); !d.IsZero() {
+			t.DueAt = &d
+		}
+		if s := fromTW(tw.Start); !s.IsZero() {
+			t.StartedAt = &s
+		}
+		if e := fromTW(tw.End); !e.IsZero() && (t.Status == "completed" || t.Status == "failed") {
+			t.CompletedAt = &e
+		}
+
+		b := db.TaskBundle{Task: t, Project: tw.Project, Tags: tw.Tags}
+		for _, dep := range tw.Depends {
+			if id, ok := ids[dep]; ok {
+				b.BlockedBy = append(b.BlockedBy, id)
+			}
+		}
+		for _, a := range tw.Annotations {
+			c := db.Comment{Author: "taskwarrior", Body: a.Description}
+			if at := fromTW(a.Entry); !at.IsZero() {
+				c.CreatedAt = at.String()
+			}
+			b.Comments = append(b.Comments, c)
+		}
+		bundles = append(bundles, b)
+	}
+
+	if err := db.ImportBundles(ctx, conn, bundles); err != nil {
+		return 0, fmt.Errorf("import: %w", err)
+	}
+	return len(bundles), nil
+}
+
+func decode(r io.Reader) ([]task, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	var tasks []task
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &tasks); err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+		return tasks, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var t task
+		if err := dec.Decode(&t); err != nil {
+			return nil, fmt.Errorf("decode task %d: %w", len(tasks)+1, err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+func importStatus(tw task) string {
+	switch tw.Status {
+	case "completed":
+		return "completed"
+	case "deleted":
+		return "failed"
+	}
+	if tw.Start != "" && tw.End == "" {
+		return "in_progress"
+	}
+	return "pending"
+}
+
+// Priorities map H/M/L onto 1/2/4, leaving 3 as bossman's unset default.
+func exportPriority(p int) string {
+	switch {
+	case p <= 1:
+		return "H"
+	case p == 2:
+		return "M"
+	case p >= 4:
+		return "L"
+	}
+	return ""
+}
+
+func importPriority(p string) int {
+	switch strings.ToUpper(p) {
+	case "H":
+		return 1
+	case "M":
+		return 2
+	case "L":
+		return 4
+	}
+	return 3
+}
+
+func toTW(t db.Timestamp) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// fromTW returns the zero time for a missing or unparseable time, which
+// ImportBundles fills with now.
+func fromTW(s string) db.Timestamp {
+	for _, layout := range []string{timeLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return db.TimestampOf(t)
+		}
+	}
+	return db.Timestamp{}
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) aggregateTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		GroupBy   []string `json:"group_by"`
+		Metric    string   `json:"metric"`
+		Status    *string  `json:"status"`
+		ProjectID *string  `json:"project_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	rows, err := db.Aggregate(ctx, r.conn(ctx), db.AggregateOpts{
+		GroupBy: params.GroupBy,
+		Metric:  params.Metric,
+		Status:  params.Status,
+
+		ProjectID: params.ProjectID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aggregate: %w", err)
+	}
+	return resultJSON(rows)
+}
+
+func (r *Registry) getStatistics(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	stats, err := db.GetStats(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get statistics: %w", err)
+	}
+	return resultJSON(stats)
+}
+
+func (r *Registry) registerAggregateTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "aggregate_tasks",
+		Description: "Count tasks (or sum their estimates) grouped by one or more dimensions, for charts and reports",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "group_by": {
+                    "type": "array",
+                    "description": "Dimensions to group by, in order",
+                    "items": {
+                        "type": "string",
+                        "enum": ["status", "priority", "parent", "day", "completed_day", "tag", "assignee", "project"]
+                    }
+                },
+                "metric": {
+                    "type": "string",
+                    "description": "Value computed per group (default count); estimate_minutes sums estimates",
+                    "enum": ["count", "estimate_minutes"]
+                },
+                "status": {
+                    "type": "string",
+                    "description": "Only include tasks with this status",
+                    "enum": ["pending", "in_progress", "completed", "failed"]
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only include tasks in this project; empty string for tasks outside any project"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.aggregateTasks)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_statistics",
+		Description: "Summarize the task list in one call: counts by status and (open tasks) by priority, blocked versus ready pending tasks, average seconds from start to completion, and the oldest pending task",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getStatistics)
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) archiveCompleted(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		OlderThan string  `json:"older_than"`
+		ProjectID *string `json:"project_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.OlderThan == "" {
+		params.OlderThan = "168h"
+	}
+	// a duration counts back from now, anything else is a time or date
+	var before time.Time
+	if d, err := time.ParseDuration(params.OlderThan); err == nil {
+		before = clock.From(ctx).Now().Add(-d)
+	} else if ts, err := db.ParseTimestamp(params.OlderThan); err == nil {
+		before = ts.Time
+	} else {
+		return nil, err
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := db.ArchiveCompleted(ctx, r.conn(ctx), db.ArchiveOpts{Before: before, ProjectID: projectID})
+	if err != nil {
+		return nil, fmt.Errorf("archive tasks: %w", err)
+	}
+	ids := make([]string, len(archived))
+	for i, t := range archived {
+		ids[i] = t.ID
+	}
+	return resultJSON(map[string]any{"archived": len(ids), "ids": ids, "before": db.FormatTime(before)})
+}
+
+func (r *Registry) listArchivedTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ParentID  *string  `json:"parent_id"`
+		Tags      []string `json:"tags"`
+		ProjectID *string  `json:"project_id"`
+		Limit     int      `json:"limit"`
+		Cursor    string   `json:"cursor"`
+		Fields    []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	for i, tag := range params.Tags {
+		norm, err := db.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Tags[i] = norm
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	// archiving stamps updated_at, so this is most recently archived first
+	opts := db.ListOpts{
+		Archived:  true,
+		Session:   sessionID(ctx),
+		ParentID:  params.ParentID,
+		Tags:      params.Tags,
+		ProjectID: projectID,
+		OrderBy:   db.OrderUpdatedAt,
+		SortDir:   "desc",
+		Cursor:    params.Cursor,
+	}
+	if params.Limit > 0 {
+		opts.Limit = params.Limit + 1
+	}
+	tasks, err := db.QueryTasks(ctx, r.conn(ctx), opts)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return nil, fmt.Errorf("invalid cursor: pass the next_cursor of a previous list_archived_tasks result")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query archived tasks: %w", err)
+	}
+	var next string
+	if params.Limit > 0 && len(tasks) > params.Limit {
+		tasks = tasks[:params.Limit]
+		next = opts.CursorAfter(&tasks[len(tasks)-1])
+	}
+	return r.tasksPage(ctx, tasks, params.Fields, next)
+}
+
+func (r *Registry) registerArchiveTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "archive_completed",
+		Description: "Move tasks completed a while ago out of list_tasks and other default listings, keeping them, their history and their IDs. A parent is archived only with its whole subtree; reopening an archived task brings it back",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "older_than": {
+                    "type": "string",
+                    "description": "Archive tasks completed before this: a duration back from now such as 720h, RFC 3339, or a date (default 168h)"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only this project's tasks (ID or name); empty string for tasks outside any project"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.archiveCompleted)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_archived_tasks",
+		Description: "Browse archived tasks, most recently archived first. get_task still fetches an archived task by ID",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "parent_id": {
+                    "type": "string",
+                    "description": "Filter by parent task ID"
+                },
+                "tags": {
+                    "type": "array",
+                    "description": "Only tasks carrying every one of these tags",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only tasks in this project (ID or name); empty string for tasks outside any project"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of tasks to return; when more match, meta.next_cursor continues the listing"
+                },
+                "cursor": {
+                    "type": "string",
+                    "description": "meta.next_cursor from the previous page, with the same filters"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listArchivedTasks)
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) assignTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID       string `json:"id"`
+		Assignee string `json:"assignee"`
+		Force    bool   `json:"force"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	// default to whoever is connected, the same name list_my_tasks matches
+	if params.Assignee == "" {
+		params.Assignee = agentName(ctx)
+	}
+	return r.setAssignee(ctx, params.ID, params.Assignee, params.Force)
+}
+
+func (r *Registry) unassignTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return r.setAssignee(ctx, params.ID, "", true)
+}
+
+func (r *Registry) setAssignee(ctx context.Context, id, assignee string, force bool) (*mcp.ToolResult, error) {
+	err := db.AssignTask(ctx, r.conn(ctx), id, assignee, force)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("assign task: %w", err)
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("get updated task: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) listMyTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Status *string  `json:"status"`
+		Limit  int      `json:"limit"`
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	me := agentName(ctx)
+	tasks, err := db.QueryTasks(ctx, r.conn(ctx), db.ListOpts{
+		Status:     params.Status,
+		AssignedTo: &me,
+		Limit:      params.Limit,
+		Session:    sessionID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) registerAssignTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "assign_task",
+		Description: "Assign a task to a worker so others know it is taken. Fails if someone else already holds it, or if it would put the worker over the capacity declared with set_capacity, unless force is set",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "assignee": {
+                    "type": "string",
+                    "description": "Worker name (default: the connected client's name)"
+                },
+                "force": {
+                    "type": "boolean",
+                    "description": "Take the task even if it is assigned to someone else or the worker is at capacity"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.assignTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "unassign_task",
+		Description: "Clear a task's assignee so another worker can pick it up",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.unassignTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_my_tasks",
+		Description: "List tasks assigned to the connected client",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "description": "Filter by status",
+                    "enum": ["pending", "in_progress", "completed", "failed"]
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of tasks to return"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listMyTasks)
+}
@@ -0,0 +1,317 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+const attachmentScheme = "bossman://attachment/"
+
+// maxResourceRead bounds how much of a path attachment resources/read
+// will load into a response.
+const maxResourceRead = 10 << 20
+
+// listedResources is how many recent attachments resources/list shows;
+// older ones stay readable by URI.
+const listedResources = 100
+
+func attachmentURI(id string) string {
+	return attachmentScheme + id
+}
+
+// attachmentInfo is an attachment as tools report it, with the URI clients
+// pass to resources/read.
+type attachmentInfo struct {
+	db.Attachment
+	URI string `json:"uri"`
+}
+
+func (r *Registry) attachToTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID   string  `json:"task_id"`
+		Name     string  `json:"name"`
+		MimeType string  `json:"mime_type"`
+		Text     *string `json:"text"`
+		Base64   *string `json:"base64"`
+		Path     *string `json:"path"`
+		URL      *string `json:"url"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	sources := 0
+	for _, s := range []*string{params.Text, params.Base64, params.Path, params.URL} {
+		if s != nil {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return nil, fmt.Errorf("give exactly one of text, base64, path or url")
+	}
+
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("check task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	a := db.Attachment{TaskID: params.TaskID, Name: params.Name, MimeType: params.MimeType, Author: agentName(ctx)}
+	var defaultName, defaultType string
+	switch {
+	case params.Text != nil:
+		a.Data = []byte(*params.Text)
+		defaultType = "text/plain"
+	case params.Base64 != nil:
+		data, err := base64.StdEncoding.DecodeString(*params.Base64)
+		if err != nil {
+			return nil, fmt.Errorf("base64: %w", err)
+		}
+		a.Data = data
+		defaultType = "application/octet-stream"
+	case params.Path != nil:
+		abs, err := filepath.Abs(*params.Path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", abs)
+		}
+		a.Path, a.Size = &abs, info.Size()
+		defaultName = filepath.Base(abs)
+		defaultType = typeByExtension(filepath.Ext(abs), "application/octet-stream")
+	case params.URL != nil:
+		u, err := url.Parse(*params.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid url: %s", *params.URL)
+		}
+		a.URL = params.URL
+		defaultName = u.Host + u.Path
+		defaultType = typeByExtension(path.Ext(u.Path), "text/uri-list")
+	}
+	if len(a.Data) > db.MaxAttachmentBytes {
+		return nil, fmt.Errorf("inline attachments are limited to %d bytes; attach a path or url instead", db.MaxAttachmentBytes)
+	}
+	if a.Name == "" {
+		a.Name = defaultName
+	}
+	if a.Name == "" {
+		return nil, fmt.Errorf("name is required for text and base64 attachments")
+	}
+	if a.MimeType == "" {
+		a.MimeType = defaultType
+	}
+
+	if err := db.AddAttachment(ctx, r.conn(ctx), &a); err != nil {
+		return nil, fmt.Errorf("add attachment: %w", err)
+	}
+	a.Data = nil
+	return resultJSON(attachmentInfo{a, attachmentURI(a.ID)})
+}
+
+// typeByExtension takes the extension from filepath.Ext for local files,
+// whose separators vary by platform, and path.Ext for URLs.
+func typeByExtension(ext, fallback string) string {
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return fallback
+}
+
+func (r *Registry) listAttachments(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("check task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	list, err := db.ListAttachments(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+	out := make([]attachmentInfo, len(list))
+	for i, a := range list {
+		out[i] = attachmentInfo{a, attachmentURI(a.ID)}
+	}
+	return resultJSON(out)
+}
+
+// ListResources implements mcp.ResourceHandler with the week plan and the
+// newest attachments.
+func (r *Registry) ListResources(ctx context.Context) ([]mcp.Resource, error) {
+	list, err := db.RecentAttachments(ctx, r.conn(ctx), listedResources)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]mcp.Resource, len(list)+1)
+	out[0] = mcp.Resource{
+		URI:         weekPlanURI,
+		Name:        "Week plan",
+		Description: "The current plan_week draft, or what it would pick now",
+		MimeType:    "text/markdown",
+	}
+	for i, a := range list {
+		out[i+1] = mcp.Resource{
+			URI:         attachmentURI(a.ID),
+			Name:        a.Name,
+			Description: "Attachment of task " + a.TaskID,
+			MimeType:    a.MimeType,
+			Size:        a.Size,
+		}
+	}
+	return out, nil
+}
+
+func (r *Registry) ResourceTemplates() []mcp.ResourceTemplate {
+	return []mcp.ResourceTemplate{{
+		URITemplate: attachmentScheme + "{id}",
+		Name:        "Task attachment",
+		Description: "An artifact attached to a task; ids come from list_attachments",
+	}}
+}
+
+// ReadResource implements mcp.ResourceHandler. Inline data and files are
+// returned as text for textual types and base64 otherwise; URL attachments
+// return the URL itself.
+func (r *Registry) ReadResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	if uri == weekPlanURI {
+		return r.readWeekPlan(ctx)
+	}
+	id, ok := strings.CutPrefix(uri, attachmentScheme)
+	if !ok {
+		return nil, mcp.ErrResourceNotFound
+	}
+	a, err := db.GetAttachment(ctx, r.conn(ctx), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, mcp.ErrResourceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if a.URL != nil {
+		return []mcp.ResourceContents{{URI: uri, MimeType: "text/uri-list", Text: *a.URL}}, nil
+	}
+	data := a.Data
+	if a.Path != nil {
+		if data, err = readCapped(*a.Path, maxResourceRead); err != nil {
+			return nil, err
+		}
+	}
+	contents := mcp.ResourceContents{URI: uri, MimeType: a.MimeType}
+	if isTextual(a.MimeType) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = base64.StdEncoding.EncodeToString(data)
+	}
+	return []mcp.ResourceContents{contents}, nil
+}
+
+func readCapped(name string, limit int64) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, limit)
+	}
+	return data, nil
+}
+
+func isTextual(mimeType string) bool {
+	base, _, _ := strings.Cut(mimeType, ";")
+	base = strings.TrimSpace(base)
+	return strings.HasPrefix(base, "text/") || base == "application/json" ||
+		strings.HasSuffix(base, "+json") || strings.HasSuffix(base, "+xml") || base == "application/xml"
+}
+
+func (r *Registry) registerAttachmentTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "attach_to_task",
+		Description: "Attach an artifact to a task: small inline text or base64 content, or a reference to a local file or URL. Attachments are readable as MCP resources at the returned uri",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "name": {
+                    "type": "string",
+                    "description": "Display name (default: the file name or URL)"
+                },
+                "mime_type": {
+                    "type": "string",
+                    "description": "MIME type (default: guessed from the name, text/plain for text)"
+                },
+                "text": {
+                    "type": "string",
+                    "description": "Inline text content, up to 1 MiB"
+                },
+                "base64": {
+                    "type": "string",
+                    "description": "Inline binary content, base64-encoded, up to 1 MiB decoded"
+                },
+                "path": {
+                    "type": "string",
+                    "description": "Local file to reference; it is read when the resource is fetched, not copied"
+                },
+                "url": {
+                    "type": "string",
+                    "description": "URL to reference, such as a pull request or build log"
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+	}, r.attachToTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_attachments",
+		Description: "List a task's attachments, oldest first, with the resource uri of each",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listAttachments)
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
+	"procdexeh/bossman/internal/api"
 	"procdexeh/bossman/internal/db"
 	"procdexeh/bossman/internal/mcp"
 )
@@ -20,7 +22,7 @@ func (r *Registry) addBlocker(ctx context.Context, args json.RawMessage) (*mcp.T
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	if err := db.AddBlocker(ctx, r.db, params.TaskID, params.BlockedByID); err != nil {
+	if err := db.AddBlocker(ctx, r.conn(ctx), params.TaskID, params.BlockedByID); err != nil {
 		return nil, fmt.Errorf("add blocker: %w", err)
 	}
 
@@ -40,7 +42,7 @@ func (r *Registry) removeBlocker(ctx context.Context, args json.RawMessage) (*mc
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	err := db.RemoveBlocker(ctx, r.db, params.TaskID, params.BlockedByID)
+	err := db.RemoveBlocker(ctx, r.conn(ctx), params.TaskID, params.BlockedByID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("blocker not found: %s -> %s", params.TaskID, params.BlockedByID)
 	}
@@ -63,12 +65,157 @@ func (r *Registry) getBlockers(ctx context.Context, args json.RawMessage) (*mcp.
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
 
-	tasks, err := db.GetBlockers(ctx, r.db, params.TaskID)
+	tasks, err := db.GetBlockers(ctx, r.conn(ctx), params.TaskID)
 	if err != nil {
 		return nil, fmt.Errorf("get blockers: %w", err)
 	}
 
-	return resultJSON(tasks)
+	return r.tasksResult(ctx, tasks, nil)
+}
+
+func (r *Registry) getBlocking(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID     string   `json:"task_id"`
+		Transitive bool     `json:"transitive"`
+		Fields     []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	if ok, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID); err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	} else if !ok {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	tasks, err := db.GetBlocking(ctx, r.conn(ctx), params.TaskID, params.Transitive)
+	if err != nil {
+		return nil, fmt.Errorf("get blocking: %w", err)
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) getDependencyChain(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string   `json:"task_id"`
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+
+	chain, err := db.GetDependencyChain(ctx, r.conn(ctx), params.TaskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get dependency chain: %w", err)
+	}
+	tasks := make([]db.Task, len(chain))
+	for i := range chain {
+		tasks[i] = chain[i].Task
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, fmt.Errorf("get dependency chain: %w", err)
+	}
+	nodes := make([]map[string]any, len(outs))
+	open := 0
+	for i := range outs {
+		if nodes[i], err = treeNode(outs[i], params.Fields); err != nil {
+			return nil, err
+		}
+		nodes[i]["depth"] = chain[i].Depth
+		if chain[i].Status == "pending" || chain[i].Status == "in_progress" {
+			open++
+		}
+	}
+	return resultJSON(map[string]any{
+		"chain": nodes,
+		"open":  open,
+	})
+}
+
+func (r *Registry) getCriticalPath(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID *string  `json:"project_id"`
+		Fields    []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := db.GetCriticalPath(ctx, r.conn(ctx), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get critical path: %w", err)
+	}
+	tasks, err := api.Tasks(ctx, r.conn(ctx), path.Tasks)
+	if err != nil {
+		return nil, fmt.Errorf("get critical path: %w", err)
+	}
+	out := map[string]any{"total_minutes": path.TotalMinutes}
+	if len(params.Fields) > 0 {
+		out["tasks"] = api.ProjectTasks(tasks, params.Fields)
+	} else {
+		out["tasks"] = tasks
+	}
+	return resultJSON(out)
+}
+
+func (r *Registry) exportDependencyGraph(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	edges, err := db.ListBlockerEdges(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list blockers: %w", err)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	seen := make(map[string]bool)
+	node := func(id string) error {
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		t, err := db.GetTask(ctx, r.conn(ctx), id)
+		if err != nil {
+			return fmt.Errorf("get task %s: %w", id, err)
+		}
+		label := strings.ReplaceAll(t.Description, `"`, "#quot;")
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", id, label)
+		return nil
+	}
+	for _, e := range edges {
+		if err := node(e.BlockedByID); err != nil {
+			return nil, err
+		}
+		if err := node(e.TaskID); err != nil {
+			return nil, err
+		}
+	}
+	// arrows point the way work flows: blocker first
+	for _, e := range edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", e.BlockedByID, e.TaskID)
+	}
+
+	summary := map[string]int{"tasks": len(seen), "dependencies": len(edges)}
+	return mcp.DataResult(summary, nil, mcp.EmbeddedResourceContent(mcp.ResourceContents{
+		URI:      "bossman://graph/dependencies.mmd",
+		MimeType: "text/vnd.mermaid",
+		Text:     b.String(),
+	}))
 }
 
 func (r *Registry) registerBlockerTools() {
@@ -110,6 +257,7 @@ func (r *Registry) registerBlockerTools() {
             "required": ["task_id", "blocked_by_id"],
             "additionalProperties": false
         }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
 	}, r.removeBlocker)
 
 	r.register(mcp.ToolDefinition{
@@ -126,5 +274,95 @@ func (r *Registry) registerBlockerTools() {
             "required": ["task_id"],
             "additionalProperties": false
         }`),
+		Annotations: readOnly,
 	}, r.getBlockers)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_blocking",
+		Description: "List the tasks waiting on a given task, most urgent first. Check this before failing or delaying a task to see what it holds up",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "The task whose dependents to list"
+                },
+                "transitive": {
+                    "type": "boolean",
+                    "description": "Also include tasks that wait on it through other tasks (default: direct dependents only)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getBlocking)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_dependency_chain",
+		Description: "List everything a task waits on, directly or through other blockers, finished or not, in an order the work can be done: deepest blockers first, each with its depth",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "The task whose blockers to trace"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getDependencyChain)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_critical_path",
+		Description: "Find the longest chain of open tasks each blocking the next, by estimated minutes: the work that sets how soon everything can finish. Tasks come in the order they must be done",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Only consider tasks in this project (ID or name; \"\" for tasks outside any project). Default: all tasks"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getCriticalPath)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "export_dependency_graph",
+		Description: "Render all task dependencies as a Mermaid flowchart",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.exportDependencyGraph)
 }
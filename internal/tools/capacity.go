@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) setCapacity(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Assignee    string   `json:"assignee"`
+		HoursPerDay *float64 `json:"hours_per_day"`
+		TasksPerDay *int     `json:"tasks_per_day"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.Assignee == "" {
+		params.Assignee = agentName(ctx)
+	}
+	c := db.Capacity{Assignee: params.Assignee, TasksPerDay: params.TasksPerDay}
+	if params.HoursPerDay != nil {
+		minutes := int(math.Round(*params.HoursPerDay * 60))
+		c.MinutesPerDay = &minutes
+	}
+	if err := db.SetCapacity(ctx, r.conn(ctx), c); err != nil {
+		return nil, fmt.Errorf("set capacity: %w", err)
+	}
+	usage, err := db.GetUtilization(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get utilization: %w", err)
+	}
+	for _, u := range usage {
+		if u.Assignee == params.Assignee {
+			return resultJSON(u)
+		}
+	}
+	return resultJSON(db.Utilization{Assignee: params.Assignee})
+}
+
+func (r *Registry) getUtilization(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	usage, err := db.GetUtilization(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get utilization: %w", err)
+	}
+	if params.Assignee != "" {
+		kept := usage[:0]
+		for _, u := range usage {
+			if u.Assignee == params.Assignee {
+				kept = append(kept, u)
+			}
+		}
+		usage = kept
+	}
+	return resultJSON(usage)
+}
+
+func (r *Registry) registerCapacityTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "set_capacity",
+		Description: "Declare how much a worker can take on per working day, in hours of estimated work, in tasks, or both. assign_task refuses work that would go past a five-day week of it unless forced, plan_week fills a week up to it, and reports show utilization against it. Give neither limit to remove the declaration",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string",
+                    "description": "Worker name (default: the connected client's name)"
+                },
+                "hours_per_day": {
+                    "type": "number",
+                    "description": "Hours of estimated work per day; tasks without an estimate count as one hour"
+                },
+                "tasks_per_day": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "description": "Open tasks per day"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.setCapacity)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_utilization",
+		Description: "Show each worker's open assigned work against a week of their declared capacity, busiest first. percent is the higher of the hours and task ratios, 0 without a declared capacity",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string",
+                    "description": "Only this worker"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getUtilization)
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) createChecklist(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Name         string   `json:"name"`
+		Items        []string `json:"items"`
+		Hour         *int     `json:"hour"`
+		SkipWeekends bool     `json:"skip_weekends"`
+		CarryOver    bool     `json:"carry_over"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Name) == "" {
+		return nil, fmt.Errorf("checklist name must not be empty")
+	}
+	if len(params.Items) == 0 {
+		return nil, fmt.Errorf("checklist needs at least one item")
+	}
+
+	c := db.Checklist{
+		Name:         params.Name,
+		Items:        params.Items,
+		Hour:         6,
+		SkipWeekends: params.SkipWeekends,
+		CarryOver:    params.CarryOver,
+	}
+	if params.Hour != nil {
+		c.Hour = *params.Hour
+	}
+	if err := db.CreateChecklist(ctx, r.conn(ctx), &c); err != nil {
+		return nil, fmt.Errorf("create checklist: %w", err)
+	}
+	return resultJSON(c)
+}
+
+func (r *Registry) listChecklists(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	lists, err := db.ListChecklists(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list checklists: %w", err)
+	}
+	return resultJSON(lists)
+}
+
+func (r *Registry) deleteChecklist(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	err := db.DeleteChecklist(ctx, r.conn(ctx), params.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("checklist not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delete checklist: %w", err)
+	}
+	return resultJSON(map[string]string{"deleted": params.ID})
+}
+
+// runChecklist generates today's run now, ignoring the hour and weekend
+// settings; the scheduled rollover then leaves the day alone.
+func (r *Registry) runChecklist(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	c, err := db.GetChecklist(ctx, r.conn(ctx), params.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("checklist not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get checklist: %w", err)
+	}
+	day := clock.From(ctx).Now().In(time.Local).Format("2006-01-02")
+	run, err := db.GenerateChecklist(ctx, r.conn(ctx), c, day)
+	if err != nil {
+		return nil, fmt.Errorf("generate checklist: %w", err)
+	}
+	return resultJSON(run)
+}
+
+func (r *Registry) registerChecklistTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "create_checklist",
+		Description: "Define a daily recurring checklist: each morning its items are created as subtasks of a new parent task tagged checklist",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "description": "Checklist name; each day's parent task is \"<name> (<date>)\""
+                },
+                "items": {
+                    "type": "array",
+                    "description": "Task descriptions to create every day, in order",
+                    "items": {
+                        "type": "string"
+                    },
+                    "minItems": 1
+                },
+                "hour": {
+                    "type": "integer",
+                    "description": "Local hour from which the day's run is generated (default 6)",
+                    "minimum": 0,
+                    "maximum": 23
+                },
+                "skip_weekends": {
+                    "type": "boolean",
+                    "description": "Don't generate runs on Saturday and Sunday"
+                },
+                "carry_over": {
+                    "type": "boolean",
+                    "description": "Move the previous run's unfinished items to the new run, tagged carried-over"
+                }
+            },
+            "required": ["name", "items"],
+            "additionalProperties": false
+        }`),
+	}, r.createChecklist)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_checklists",
+		Description: "List recurring checklists",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listChecklists)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "delete_checklist",
+		Description: "Stop a recurring checklist. Tasks it already generated are kept",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Checklist ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
+	}, r.deleteChecklist)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "run_checklist",
+		Description: "Generate today's run of a checklist now instead of waiting for its hour. Does nothing if today already has one",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Checklist ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.runChecklist)
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) addComment(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+		Body   string `json:"body"`
+		Author string `json:"author"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Body) == "" {
+		return nil, fmt.Errorf("comment body must not be empty")
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	// default the author to whoever is connected
+	if params.Author == "" {
+		params.Author = agentName(ctx)
+	}
+	comment := db.Comment{TaskID: params.TaskID, Author: params.Author, Body: params.Body}
+	if err := db.AddComment(ctx, r.conn(ctx), &comment); err != nil {
+		return nil, fmt.Errorf("add comment: %w", err)
+	}
+	return resultJSON(comment)
+}
+
+func (r *Registry) listComments(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	comments, err := db.ListComments(ctx, r.conn(ctx), params.TaskID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	return resultJSON(comments)
+}
+
+func (r *Registry) registerCommentTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "add_comment",
+		Description: "Append a progress note to a task without touching its context",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "body": {
+                    "type": "string",
+                    "description": "Comment text"
+                },
+                "author": {
+                    "type": "string",
+                    "description": "Who wrote it (default: the connected client's name)"
+                }
+            },
+            "required": ["task_id", "body"],
+            "additionalProperties": false
+        }`),
+	}, r.addComment)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_comments",
+		Description: "List a task's comments, oldest first",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of comments to return",
+                    "minimum": 1
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listComments)
+}
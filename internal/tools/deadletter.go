@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// Replayer reruns the automation behind a dead letter;
+// maintenance.Runner is one.
+type Replayer interface {
+	Replay(ctx context.Context, id string) (*db.DeadLetter, error)
+}
+
+func (r *Registry) listDeadLetters(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Resolved bool `json:"resolved"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	letters, err := db.ListDeadLetters(ctx, r.conn(ctx), params.Resolved)
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	return resultJSON(letters)
+}
+
+func (r *Registry) replayDeadLetter(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if r.replayer == nil {
+		return nil, fmt.Errorf("this process runs no automations to replay")
+	}
+	d, err := r.replayer.Replay(ctx, params.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("dead letter not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	return resultJSON(d)
+}
+
+func (r *Registry) registerDeadLetterTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "list_dead_letters",
+		Description: "List automated runs (maintenance jobs) that failed every retry, newest first, with the last error. They stay listed until replay_dead_letter succeeds",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "resolved": {
+                    "type": "boolean",
+                    "description": "Include dead letters already replayed successfully"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listDeadLetters)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "replay_dead_letter",
+		Description: "Run the automation behind a dead letter once more. Success resolves it; failure keeps it open with the new error and one more attempt counted",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Dead letter ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.replayDeadLetter)
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/guard"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// kindBusy counts SQLite busy errors; too many in a window means another
+// process is starving us of the write lock.
+const kindBusy = "busy"
+
+// busyLimit is the busy storm that puts bossman into degraded mode.
+var busyLimit = guard.Limit{Count: 20, Window: 30 * time.Second}
+
+// recoveryAttempts is how often recovery is tried before giving up.
+const recoveryAttempts = 5
+
+// degradation is why and since when writes are paused.
+type degradation struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// SetFatalHandler is called with the last error once recovery has failed
+// recoveryAttempts times; bossman can't usefully keep serving then.
+func (r *Registry) SetFatalHandler(fn func(error)) {
+	r.fatal = fn
+}
+
+// observeError watches tool errors for signs of database trouble.
+func (r *Registry) observeError(err error) {
+	switch {
+	case db.IsCorrupt(err):
+		r.degrade("database corruption: " + err.Error())
+	case db.IsBusy(err):
+		r.busy.Observe(kindBusy)
+	}
+}
+
+func (r *Registry) onBusyStorm(a guard.Alert) {
+	r.degrade(fmt.Sprintf("database busy: %d lock timeouts within %s", a.Count, a.Window))
+}
+
+// degrade pauses writes, tells the client, and starts recovery. Reads keep
+// working, so agents can still plan while bossman heals.
+func (r *Registry) degrade(reason string) {
+	d := &degradation{Reason: reason, Since: r.clock.Now()}
+	if !r.degraded.CompareAndSwap(nil, d) {
+		return
+	}
+	slog.Warn("degraded mode: writes paused", "reason", reason)
+	r.notify("warning", map[string]any{
+		"degraded": true,
+		"reason":   reason,
+		"message":  "bossman is degraded; reads work but writes are refused until it recovers",
+	})
+	go r.recover(d)
+}
+
+// recover retries db.Recover with backoff, leaving degraded mode on the
+// first success and handing over to the fatal handler after the last try.
+func (r *Registry) recover(d *degradation) {
+	ctx := clock.With(context.Background(), r.clock)
+	var err error
+	for attempt := 1; attempt <= recoveryAttempts; attempt++ {
+		time.Sleep(guard.DefaultBackoff.Delay(attempt, time.Second))
+		if err = db.Recover(ctx, r.db); err == nil {
+			r.degraded.CompareAndSwap(d, nil)
+			slog.Info("recovered from degraded mode", "attempt", attempt, "reason", d.Reason)
+			r.notify("info", map[string]any{"degraded": false, "message": "bossman recovered; writes are accepted again"})
+			return
+		}
+		slog.Warn("recovery failed", "attempt", attempt, "err", err)
+	}
+	slog.Error("giving up on recovery", "reason", d.Reason, "err", err)
+	r.notify("error", map[string]any{
+		"degraded": true,
+		"reason":   d.Reason,
+		"message":  fmt.Sprintf("bossman could not recover the database and is shutting down: %v", err),
+	})
+	if r.fatal != nil {
+		r.fatal(fmt.Errorf("database unrecoverable (%s): %w", d.Reason, err))
+	}
+}
+
+// degradedError refuses a write while degraded. It is retryable, so
+// clients are told when to come back.
+func (d *degradation) degradedError() error {
+	return &guard.RetryError{
+		Reason:  "bossman is degraded (" + d.Reason + "); writes are paused while it recovers",
+		After:   5 * time.Second,
+		Attempt: 1,
+	}
+}
+
+func (r *Registry) notify(level string, data any) {
+	if err := mcp.LogMessage(r.notifier, level, data); err != nil {
+		slog.Debug("notify client", "err", err)
+	}
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/diagram"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) importDiagram(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Diagram   string   `json:"diagram"`
+		Format    string   `json:"format"`
+		ProjectID *string  `json:"project_id"`
+		Fields    []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	project := ""
+	if projectID != nil && *projectID != "" {
+		p, err := db.GetProject(ctx, r.conn(ctx), *projectID)
+		if err != nil {
+			return nil, fmt.Errorf("get project: %w", err)
+		}
+		project = p.Name
+	}
+	plan, err := diagram.Parse(params.Format, params.Diagram)
+	if err != nil {
+		return nil, fmt.Errorf("parse diagram: %w", err)
+	}
+	for i := range plan {
+		plan[i].Project = project
+	}
+	ids, err := db.Seed(ctx, r.conn(ctx), plan)
+	if err != nil {
+		return nil, fmt.Errorf("import diagram: %w", err)
+	}
+	r.afterCreate(ctx)
+
+	// in diagram order, so the result reads like the sketch
+	tasks := make([]db.Task, 0, len(plan))
+	for _, t := range plan {
+		task, err := db.GetTask(ctx, r.conn(ctx), ids[t.Key])
+		if err != nil {
+			return nil, fmt.Errorf("get created task: %w", err)
+		}
+		tasks = append(tasks, *task)
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) registerDiagramTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "import_diagram",
+		Description: "Create tasks from a dependency sketch: a Mermaid flowchart or DOT digraph whose nodes are task descriptions (the label, or the node id without one) and whose arrows point from a task to the work it unblocks. Everything is created in one go, or nothing if the diagram is malformed or has a cycle",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "diagram": {
+                    "type": "string",
+                    "description": "Diagram source, e.g. flowchart TD; design[Design the schema] --> migrate[Write the migration]"
+                },
+                "format": {
+                    "type": "string",
+                    "enum": ["mermaid", "dot"],
+                    "description": "Diagram language (default: detected from the header)"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Project ID or name for the new tasks"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["diagram"],
+            "additionalProperties": false
+        }`),
+	}, r.importDiagram)
+}
@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"procdexeh/bossman/internal/guard"
+)
+
+// drain counts running tool calls so shutdown can wait for them. Once
+// closed it admits no new calls.
+type drain struct {
+	mu      sync.Mutex
+	closed  bool
+	running int
+	idle    chan struct{} // closed when the last call leaves after close
+}
+
+func (d *drain) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	d.running++
+	return true
+}
+
+func (d *drain) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running--
+	if d.closed && d.running == 0 {
+		close(d.idle)
+	}
+}
+
+// Drain stops the registry taking new tool calls and waits until the
+// running ones finish or ctx ends. New calls get a retryable error, so a
+// client can try again against whichever replica replaces this one.
+func (r *Registry) Drain(ctx context.Context) error {
+	d := &r.drain
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		d.idle = make(chan struct{})
+		if d.running == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Draining reports whether Drain has been called.
+func (r *Registry) Draining() bool {
+	r.drain.mu.Lock()
+	defer r.drain.mu.Unlock()
+	return r.drain.closed
+}
+
+// Degraded returns why writes are paused, or "" while the database is
+// healthy.
+func (r *Registry) Degraded() string {
+	if d := r.degraded.Load(); d != nil {
+		return d.Reason
+	}
+	return ""
+}
+
+func drainingError() error {
+	return &guard.RetryError{
+		Reason:  "bossman is shutting down",
+		After:   time.Second,
+		Attempt: 1,
+	}
+}
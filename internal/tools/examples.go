@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"encoding/json"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// Example payloads are built from the real Go types so a renamed field
+// shows up here at compile time, and register checks every example's
+// arguments against the tool's schema.
+
+var sampleTask = api.Task{
+	ID:             "task_d0c1example00000000",
+	Description:    "Fix parser bug",
+	Context:        "Crash on empty input; see parser.go:42",
+	Priority:       "high",
+	PriorityWeight: 75,
+	Status:         "pending",
+	CreatedAt:      "2025-01-01T09:00:00.000Z",
+	UpdatedAt:      "2025-01-01T09:00:00.000Z",
+	Tags:           []string{"backend"},
+	AgeSeconds:     120,
+	ChildrenCount:  0,
+}
+
+func sampleTaskWith(mutate func(*api.Task)) api.Task {
+	t := sampleTask
+	mutate(&t)
+	return t
+}
+
+func example(description string, args any, data any) mcp.ToolExample {
+	ex := mcp.ToolExample{Description: description, Arguments: mustJSON(args)}
+	if data != nil {
+		ex.Output = mustJSON(mcp.Envelope{OK: true, Data: data})
+	}
+	return ex
+}
+
+func mustJSON(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type args = map[string]any
+
+var toolExamples = map[string][]mcp.ToolExample{
+	"create_task": {
+		example("create a top-level task",
+			args{"description": "Fix parser bug", "priority": "high", "context": "Crash on empty input; see parser.go:42"},
+			sampleTask),
+	},
+	"list_tasks": {
+		example("pending backend work, ids and descriptions only",
+			args{"status": "pending", "tags": []string{"backend"}, "fields": []string{"id", "description"}},
+			[]map[string]any{{"id": sampleTask.ID, "description": sampleTask.Description}}),
+	},
+	"get_task": {
+		example("fetch one task", args{"id": sampleTask.ID}, sampleTask),
+	},
+	"update_task": {
+		example("start work on a task",
+			args{"id": sampleTask.ID, "status": "in_progress"},
+			sampleTaskWith(func(t *api.Task) {
+				t.Status = "in_progress"
+				t.StartedAt = "2025-01-01T09:02:00.000Z"
+				t.UpdatedAt = t.StartedAt
+			})),
+		example("record the outcome",
+			args{"id": sampleTask.ID, "status": "completed", "result": "Guarded empty input; added regression test"},
+			nil),
+	},
+	"add_blocker": {
+		example("task waits on another task",
+			args{"task_id": sampleTask.ID, "blocked_by_id": "task_d0c1example00000001"},
+			nil),
+	},
+	"add_comment": {
+		example("leave a progress note",
+			args{"task_id": sampleTask.ID, "body": "Reproduced; root cause is the empty-slice index"},
+			db.Comment{
+				ID:        "comment_d0c1example00000000",
+				TaskID:    sampleTask.ID,
+				Author:    "agent-1",
+				Body:      "Reproduced; root cause is the empty-slice index",
+				CreatedAt: "2025-01-01T09:05:00.000Z",
+			}),
+	},
+	"search_tasks": {
+		example("find work mentioning the parser",
+			args{"query": "parser crash", "fields": []string{"id", "status"}},
+			[]map[string]any{{
+				"task":    map[string]any{"id": sampleTask.ID, "status": sampleTask.Status},
+				"snippet": "[Crash] on empty input; see [parser].go:42",
+				"rank":    -1.7,
+			}}),
+	},
+	"add_tag": {
+		example("tag a task", args{"task_id": sampleTask.ID, "tag": "backend"}, sampleTask),
+	},
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) exportTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID *string `json:"project_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	var project string
+	if projectID != nil {
+		project = *projectID
+	}
+
+	doc, err := db.ExportAll(ctx, r.conn(ctx), project)
+	if err != nil {
+		return nil, fmt.Errorf("export tasks: %w", err)
+	}
+	body, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	summary := map[string]int{"tasks": len(doc.Tasks), "version": doc.Version}
+	return mcp.DataResult(summary, nil, mcp.EmbeddedResourceContent(mcp.ResourceContents{
+		URI:      "bossman://export/tasks.json",
+		MimeType: "application/json",
+		Text:     string(body),
+	}))
+}
+
+func (r *Registry) importTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Document db.ExportDocument `json:"document"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	res, err := db.ImportAll(ctx, r.conn(ctx), &params.Document)
+	if err != nil {
+		return nil, fmt.Errorf("import tasks: %w", err)
+	}
+	return resultJSON(res)
+}
+
+func (r *Registry) registerExportTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "export_tasks",
+		Description: "Export the task graph (tasks with their projects, tags, blockers and comments) as a versioned JSON document that import_tasks or `bossman import -format json` reads back",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Only export this project's tasks (ID or name)"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.exportTasks)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "import_tasks",
+		Description: "Import a document produced by export_tasks. Task IDs that already exist get fresh ones, with parents and blockers following; existing tasks are never changed",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "document": {
+                    "type": "object",
+                    "description": "The export document, with format, version and tasks",
+                    "additionalProperties": true
+                }
+            },
+            "required": ["document"],
+            "additionalProperties": false
+        }`),
+	}, r.importTasks)
+}
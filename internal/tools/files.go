@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) findTasksByFile(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Paths         []string `json:"paths"`
+		IncludeClosed bool     `json:"include_closed"`
+		ProjectID     *string  `json:"project_id"`
+		Limit         int      `json:"limit"`
+		Fields        []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if len(params.Paths) == 0 {
+		return nil, fmt.Errorf("give at least one path")
+	}
+	for _, p := range params.Paths {
+		if _, err := db.NormalizeFilePath(p); err != nil {
+			return nil, err
+		}
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := db.QueryTasks(ctx, r.conn(ctx), db.ListOpts{
+		Files:     params.Paths,
+		Open:      !params.IncludeClosed,
+		ProjectID: projectID,
+		Limit:     params.Limit,
+		Session:   sessionID(ctx),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find tasks: %w", err)
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) registerFileTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "find_tasks_by_file",
+		Description: "Find the open tasks touching files you're about to edit: a task matches when it lists one of the paths, something under a directory you give, or a directory holding a file you give. Set a task's files with create_task or update_task",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "paths": {
+                    "type": "array",
+                    "description": "Repo-relative files or directories, e.g. internal/db or internal/db/db.go",
+                    "items": {
+                        "type": "string"
+                    },
+                    "minItems": 1
+                },
+                "include_closed": {
+                    "type": "boolean",
+                    "description": "Include completed and failed tasks (default: open tasks only)"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only tasks in this project (ID or name)"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of tasks to return"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["paths"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.findTasksByFile)
+}
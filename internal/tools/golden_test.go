@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"procdexeh/bossman/internal/db"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	conn, err := db.InitDB(filepath.Join(t.TempDir(), "bossman.db"), db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewRegistry(conn)
+}
+
+// TestToolSchemasGolden pins the tools/list payload. Any change to a tool's
+// name, description, schema, annotations or examples shows up as a diff;
+// run `go test ./internal/tools -update` to accept it.
+func TestToolSchemasGolden(t *testing.T) {
+	r := newTestRegistry(t)
+	for _, def := range r.ListTools() {
+		t.Run(def.Name, func(t *testing.T) {
+			// re-indent so schema whitespace in Go source doesn't matter
+			raw, err := json.Marshal(def)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := json.Indent(&got, raw, "", "  "); err != nil {
+				t.Fatal(err)
+			}
+			got.WriteByte('\n')
+
+			path := filepath.Join("testdata", "tools", def.Name+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create)", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("tool definition changed; run with -update if intended\n--- got\n%s\n--- want\n%s", got.Bytes(), want)
+			}
+		})
+	}
+}
+
+// TestGoldenFilesHaveTools catches golden files left behind by a removed tool.
+func TestGoldenFilesHaveTools(t *testing.T) {
+	r := newTestRegistry(t)
+	files, err := filepath.Glob(filepath.Join("testdata", "tools", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		name = name[:len(name)-len(".json")]
+		if !r.HasTool(name) {
+			t.Errorf("%s has no registered tool", f)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// taskEvent is the wire shape of an audit row; stored values are already JSON.
+type taskEvent struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	Op        string          `json:"op"`
+	Old       json.RawMessage `json:"old,omitempty"`
+	New       json.RawMessage `json:"new,omitempty"`
+	Actor     string          `json:"actor"`
+	CreatedAt string          `json:"created_at"`
+}
+
+func rawJSON(s *string) json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	return json.RawMessage(*s)
+}
+
+func (r *Registry) getTaskHistory(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	events, err := db.GetTaskEvents(ctx, r.conn(ctx), params.TaskID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("get task history: %w", err)
+	}
+	// deleted tasks keep their history, so only an empty log means unknown
+	if len(events) == 0 {
+		exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+		if err != nil {
+			return nil, fmt.Errorf("get task: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("task not found: %s", params.TaskID)
+		}
+	}
+
+	return resultJSON(wireEvents(events))
+}
+
+// wireEvents converts audit rows to their wire shape.
+func wireEvents(events []db.TaskEvent) []taskEvent {
+	out := make([]taskEvent, len(events))
+	for i, e := range events {
+		out[i] = taskEvent{
+			ID:        e.ID,
+			Entity:    e.Entity,
+			Op:        e.Op,
+			Old:       rawJSON(e.OldValue),
+			New:       rawJSON(e.NewValue),
+			Actor:     e.Actor,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return out
+}
+
+func (r *Registry) registerHistoryTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "get_task_history",
+		Description: "Audit log of every change to a task, its blockers and tags, newest first, with old/new values and the acting client",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID (history survives deletion)"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of events to return (default 100)",
+                    "minimum": 1
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getTaskHistory)
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) listLocks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	locks, err := db.ListLocks(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list locks: %w", err)
+	}
+	return resultJSON(locks)
+}
+
+func (r *Registry) releaseLock(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Resource string `json:"resource"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	lock, err := db.ReleaseLock(ctx, r.conn(ctx), params.Resource)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("resource is not locked: %s", params.Resource)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("release lock: %w", err)
+	}
+	return resultJSON(map[string]any{"released": lock})
+}
+
+func (r *Registry) registerLockTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "list_locks",
+		Description: "List held resource locks: which task holds each shared resource and for whom. Tasks take the resources they declare when set in_progress or assigned, and free them when completed, failed or unassigned",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listLocks)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "release_lock",
+		Description: "Force-release a resource lock, e.g. one left behind by a worker that stopped without finishing its task",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "resource": {
+                    "type": "string",
+                    "description": "Resource name"
+                }
+            },
+            "required": ["resource"],
+            "additionalProperties": false
+        }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
+	}, r.releaseLock)
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) suggestParallel(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Workers         int      `json:"workers"`
+		ProjectID       *string  `json:"project_id"`
+		IncludeAssigned bool     `json:"include_assigned"`
+		Fields          []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	if len(params.Fields) == 0 {
+		params.Fields = []string{"id", "description", "priority", "estimate_minutes"}
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := db.SuggestParallel(ctx, r.conn(ctx), db.ParallelOpts{
+		Workers:         params.Workers,
+		ProjectID:       projectID,
+		IncludeAssigned: params.IncludeAssigned,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("suggest parallel: %w", err)
+	}
+
+	type group struct {
+		Worker int              `json:"worker"`
+		Tasks  []map[string]any `json:"tasks"`
+	}
+	out := struct {
+		Groups     []group `json:"groups"`
+		ReadyTasks int     `json:"ready_tasks"`
+	}{Groups: []group{}}
+	for _, g := range groups {
+		tasks, err := api.Tasks(ctx, r.conn(ctx), g.Tasks)
+		if err != nil {
+			return nil, err
+		}
+		out.Groups = append(out.Groups, group{Worker: g.Worker, Tasks: api.ProjectTasks(tasks, params.Fields)})
+		out.ReadyTasks += len(g.Tasks)
+	}
+	return resultJSON(out)
+}
+
+func (r *Registry) getReadyTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID       *string  `json:"project_id"`
+		IncludeAssigned bool     `json:"include_assigned"`
+		Limit           int      `json:"limit"`
+		Fields          []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := db.GetReadyTasks(ctx, r.conn(ctx), db.ParallelOpts{
+		ProjectID:       projectID,
+		IncludeAssigned: params.IncludeAssigned,
+	}, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("get ready tasks: %w", err)
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) claimNextTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID *string  `json:"project_id"`
+		Fields    []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := db.ClaimNextTask(ctx, r.conn(ctx), agentName(ctx), db.ParallelOpts{ProjectID: projectID})
+	if err != nil {
+		return nil, fmt.Errorf("claim task: %w", err)
+	}
+	if task == nil {
+		return resultJSON(nil)
+	}
+	return r.taskResult(ctx, task, params.Fields)
+}
+
+func (r *Registry) registerParallelTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "get_ready_tasks",
+		Description: "List the tasks that can be started right now, most urgent first: pending, every blocker completed, no open subtasks, parent not failed, work window open. Call this to decide what to do next",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Only consider tasks in this project (ID or name)"
+                },
+                "include_assigned": {
+                    "type": "boolean",
+                    "description": "Also include tasks already assigned to someone (default: only unassigned tasks)"
+                },
+                "limit": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "description": "Return at most this many tasks (default: all)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getReadyTasks)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "claim_next_task",
+		Description: "Take the most urgent task get_ready_tasks would offer you: it is assigned to you and started in one step, so agents claiming at the same time never get the same task. Returns no data when nothing is ready",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Only claim a task in this project (ID or name)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.claimNextTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "suggest_parallel",
+		Description: "Split the ready tasks (pending, unblocked, no open subtasks) into up to N groups that different workers can take at the same time. Tasks feeding the same downstream work stay in one group",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "workers": {
+                    "type": "integer",
+                    "description": "Number of workers to plan for; fewer groups come back if the work doesn't split that far",
+                    "minimum": 1
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only consider tasks in this project (ID or name)"
+                },
+                "include_assigned": {
+                    "type": "boolean",
+                    "description": "Also include tasks already assigned to someone (default: only unassigned tasks)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Task fields to return (default: id, description, priority, estimate_minutes)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["workers"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.suggestParallel)
+}
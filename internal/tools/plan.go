@@ -0,0 +1,218 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// weekPlanURI serves the current week plan, or a fresh one when none was
+// made, as Markdown.
+const weekPlanURI = "plan://week"
+
+func (r *Registry) planWeek(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		WeekOf          string  `json:"week_of"`
+		Assignee        string  `json:"assignee"`
+		ProjectID       *string `json:"project_id"`
+		CapacityMinutes int     `json:"capacity_minutes"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	opts := db.PlanOpts{Assignee: params.Assignee, CapacityMinutes: params.CapacityMinutes}
+	if params.WeekOf != "" {
+		weekOf, err := db.ParseTime(params.WeekOf)
+		if err != nil {
+			return nil, err
+		}
+		opts.WeekOf, _ = time.Parse(db.TimeLayout, weekOf)
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	opts.ProjectID = projectID
+
+	plan, err := db.PlanWeek(ctx, r.conn(ctx), opts)
+	if err != nil {
+		return nil, fmt.Errorf("plan week: %w", err)
+	}
+	if err := db.SaveWeekPlan(ctx, r.conn(ctx), &plan); err != nil {
+		return nil, fmt.Errorf("save week plan: %w", err)
+	}
+	return weekPlanResult(plan)
+}
+
+func (r *Registry) editWeekPlan(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	plan, err := db.EditWeekPlan(ctx, r.conn(ctx), params.Add, params.Remove)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no week plan yet: run plan_week first")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("edit week plan: %w", err)
+	}
+	return weekPlanResult(plan)
+}
+
+func (r *Registry) commitWeekPlan(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	res, err := db.CommitWeekPlan(ctx, r.conn(ctx), params.Assignee)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no week plan yet: run plan_week first")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("commit week plan: %w", err)
+	}
+	return resultJSON(res)
+}
+
+// weekPlanResult returns the plan as data plus the Markdown a person would
+// review.
+func weekPlanResult(plan db.WeekPlan) (*mcp.ToolResult, error) {
+	return mcp.DataResult(plan, nil, mcp.TextContent(renderWeekPlan(plan)))
+}
+
+// readWeekPlan serves weekPlanURI. Without a saved plan it shows what
+// plan_week would pick with the defaults, without saving it.
+func (r *Registry) readWeekPlan(ctx context.Context) ([]mcp.ResourceContents, error) {
+	plan, err := db.GetWeekPlan(ctx, r.conn(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		plan, err = db.PlanWeek(ctx, r.conn(ctx), db.PlanOpts{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{{URI: weekPlanURI, MimeType: "text/markdown", Text: renderWeekPlan(plan)}}, nil
+}
+
+func renderWeekPlan(plan db.WeekPlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Week of %s\n\n", reportDate(plan.WeekStart))
+	switch {
+	case plan.CommittedAt != "":
+		fmt.Fprintf(&b, "_Committed to %s at %s._\n\n", plan.Assignee, plan.CommittedAt)
+	case plan.Assignee != "":
+		fmt.Fprintf(&b, "_Draft for %s; edit with edit_week_plan, then commit_week_plan._\n\n", plan.Assignee)
+	default:
+		b.WriteString("_Draft; edit with edit_week_plan, then commit_week_plan with an assignee._\n\n")
+	}
+	fmt.Fprintf(&b, "Planned %s of %s capacity", planHours(plan.PlannedMinutes), planHours(plan.CapacityMinutes))
+	if plan.CapacityTasks > 0 {
+		fmt.Fprintf(&b, ", %d of %d tasks", len(plan.Items), plan.CapacityTasks)
+	}
+	b.WriteString(".\n")
+	if len(plan.Items) == 0 {
+		b.WriteString("\nNothing to plan.\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	for i, item := range plan.Items {
+		estimate := planHours(item.EstimateMinutes)
+		if !item.Estimated {
+			estimate += " (no estimate)"
+		}
+		fmt.Fprintf(&b, "%d. %s (`%s`), %s: %s\n", i+1, item.Description, item.TaskID, estimate, strings.Join(item.Reasons, ", "))
+	}
+	return b.String()
+}
+
+func reportDate(stored string) string {
+	t, err := time.Parse(time.RFC3339Nano, stored)
+	if err != nil {
+		return stored
+	}
+	return t.Format(time.DateOnly)
+}
+
+func planHours(minutes int) string {
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+func (r *Registry) registerPlanTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "plan_week",
+		Description: "Draft a week's work: pick pending tasks by priority, due dates and deadline risk until the capacity is used, blockers before the tasks they block. The draft replaces any earlier one and is readable as the plan://week resource; adjust it with edit_week_plan and make it real with commit_week_plan",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "week_of": {
+                    "type": "string",
+                    "description": "Any day of the week to plan, RFC 3339 or YYYY-MM-DD (default: the coming week, or this one on a Monday)"
+                },
+                "assignee": {
+                    "type": "string",
+                    "description": "Who the week is for; their assigned tasks are candidates too, and commit_week_plan assigns to them"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only plan tasks in this project (ID or name)"
+                },
+                "capacity_minutes": {
+                    "type": "integer",
+                    "minimum": 1,
+                    "description": "Minutes of work available in the week (default: a week of the assignee's declared capacity, else 2400, five 8-hour days)"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.planWeek)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "edit_week_plan",
+		Description: "Add tasks to or drop tasks from the current week plan before committing it. Capacity isn't enforced on edits; compare planned_minutes with capacity_minutes",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "add": {
+                    "type": "array",
+                    "description": "IDs of open tasks to add",
+                    "items": {"type": "string"}
+                },
+                "remove": {
+                    "type": "array",
+                    "description": "IDs of tasks to drop",
+                    "items": {"type": "string"}
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.editWeekPlan)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "commit_week_plan",
+		Description: "Assign every task in the current week plan in one step. Tasks finished, deleted or taken by someone else since planning, or over the assignee's capacity, are skipped and listed with the reason",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "assignee": {
+                    "type": "string",
+                    "description": "Who takes the tasks (default: the plan's assignee)"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.commitWeekPlan)
+}
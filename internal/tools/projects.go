@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// resolveProject turns a project ID or name from tool arguments into an ID.
+// A nil or empty ref passes through unchanged, meaning "no project".
+func (r *Registry) resolveProject(ctx context.Context, ref *string) (*string, error) {
+	if ref == nil || *ref == "" {
+		return ref, nil
+	}
+	p, err := db.GetProject(ctx, r.conn(ctx), *ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project not found: %s", *ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	return &p.ID, nil
+}
+
+func (r *Registry) createProject(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	p := db.Project{Name: params.Name, Description: params.Description}
+	if err := db.CreateProject(ctx, r.conn(ctx), &p); err != nil {
+		return nil, fmt.Errorf("create project: %w", err)
+	}
+	return resultJSON(p)
+}
+
+func (r *Registry) listProjects(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	projects, err := db.ListProjects(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	return resultJSON(projects)
+}
+
+func (r *Registry) getPriorityScale(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID *string `json:"project_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if projectID != nil && *projectID == "" {
+		projectID = nil
+	}
+	levels, err := db.GetPriorityScale(ctx, r.conn(ctx), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get priority scale: %w", err)
+	}
+	return resultJSON(levels)
+}
+
+func (r *Registry) setPriorityScale(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ProjectID string             `json:"project_id"`
+		Levels    []db.PriorityLevel `json:"levels"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	projectID, err := r.resolveProject(ctx, &params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if *projectID == "" {
+		return nil, fmt.Errorf("project_id is required")
+	}
+	if err := db.SetPriorityScale(ctx, r.conn(ctx), *projectID, params.Levels); err != nil {
+		return nil, fmt.Errorf("set priority scale: %w", err)
+	}
+	levels, err := db.GetPriorityScale(ctx, r.conn(ctx), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("get priority scale: %w", err)
+	}
+	return resultJSON(levels)
+}
+
+func (r *Registry) registerProjectTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "create_project",
+		Description: "Create a project to group the tasks of one initiative. Tasks join it via project_id on create_task or update_task; subtasks inherit their parent's project",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "description": "Unique project name; tools accept it wherever a project ID is expected"
+                },
+                "description": {
+                    "type": "string",
+                    "description": "What the project is for"
+                }
+            },
+            "required": ["name"],
+            "additionalProperties": false
+        }`),
+	}, r.createProject)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_projects",
+		Description: "List projects with their open and total task counts",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listProjects)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_priority_scale",
+		Description: "List the priority labels a project's tasks can take, highest weight first. Without a project, or for a project without its own scale, this is the default: critical 100, high 75, normal 50, low 25, someday 0",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Project ID or name (default: the scale for tasks outside any project)"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getPriorityScale)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "set_priority_scale",
+		Description: "Replace a project's priority labels and their weights. Tasks whose label is on the new scale take its weight; an empty list restores the default scale",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "project_id": {
+                    "type": "string",
+                    "description": "Project ID or name"
+                },
+                "levels": {
+                    "type": "array",
+                    "description": "The new scale; labels are case-insensitive and must be unique",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "label": {
+                                "type": "string",
+                                "description": "Priority label, e.g. p0 or blocker"
+                            },
+                            "weight": {
+                                "type": "integer",
+                                "description": "Sort weight 0-100; higher is more urgent",
+                                "minimum": 0,
+                                "maximum": 100
+                            }
+                        },
+                        "required": ["label", "weight"],
+                        "additionalProperties": false
+                    }
+                }
+            },
+            "required": ["project_id", "levels"],
+            "additionalProperties": false
+        }`),
+	}, r.setPriorityScale)
+}
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) askQuestion(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Question  string   `json:"question"`
+		Context   string   `json:"context"`
+		Blocks    []string `json:"blocks"`
+		Priority  *string  `json:"priority"`
+		DueAt     *string  `json:"due_at"`
+		ProjectID *string  `json:"project_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	q := &db.Task{
+		ID:          db.NewTaskID(),
+		Description: params.Question,
+		Context:     db.Sensitive(params.Context),
+	}
+	if projectID != nil && *projectID != "" {
+		q.ProjectID = projectID
+	}
+	if params.Priority != nil {
+		q.PriorityLabel = *params.Priority
+	}
+	if params.DueAt != nil {
+		due, err := db.ParseTimestamp(*params.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		q.DueAt = &due
+	}
+	for _, id := range params.Blocks {
+		if ok, err := db.TaskExists(ctx, r.conn(ctx), id); err != nil {
+			return nil, fmt.Errorf("check blocked task: %w", err)
+		} else if !ok {
+			return nil, fmt.Errorf("task not found: %s", id)
+		}
+	}
+	if err := db.AskQuestion(ctx, r.conn(ctx), q, params.Blocks); err != nil {
+		return nil, fmt.Errorf("ask question: %w", err)
+	}
+	r.afterCreate(ctx)
+
+	created, err := db.GetTask(ctx, r.conn(ctx), q.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get created question: %w", err)
+	}
+	return r.taskResult(ctx, created, nil)
+}
+
+func (r *Registry) answerQuestion(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID     string `json:"id"`
+		Answer string `json:"answer"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	err := db.AnswerQuestion(ctx, r.conn(ctx), params.ID, params.Answer, agentName(ctx))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("answer question: %w", err)
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), params.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get answered question: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) listQuestions(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Answered bool `json:"answered"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	questions, err := db.ListQuestions(ctx, r.conn(ctx), params.Answered)
+	if err != nil {
+		return nil, fmt.Errorf("list questions: %w", err)
+	}
+	return r.tasksResult(ctx, questions, nil)
+}
+
+func (r *Registry) registerQuestionTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "ask_question",
+		Description: "Escalate a decision to a person instead of guessing. The question shows on the dashboard and in desktop notifications, is never handed out as ready work, and keeps the tasks in blocks waiting until it's answered; they then find the answer on their blocker (get_blockers)",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "question": {
+                    "type": "string",
+                    "description": "The question, phrased so it can be answered without reading the code"
+                },
+                "context": {
+                    "type": "string",
+                    "description": "Background, options considered and what each answer would mean"
+                },
+                "blocks": {
+                    "type": "array",
+                    "description": "IDs of tasks that can't go on until the question is answered",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "priority": {
+                    "type": "string",
+                    "description": "Priority label from the project's scale (default: normal)"
+                },
+                "due_at": {
+                    "type": "string",
+                    "description": "When an answer is needed by: RFC 3339 time or YYYY-MM-DD"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Project ID or name"
+                }
+            },
+            "required": ["question"],
+            "additionalProperties": false
+        }`),
+	}, r.askQuestion)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "answer_question",
+		Description: "Answer an open question. It completes with the answer, releasing the tasks it blocked",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Question task ID"
+                },
+                "answer": {
+                    "type": "string",
+                    "description": "The decision, for the agents waiting on it"
+                }
+            },
+            "required": ["id", "answer"],
+            "additionalProperties": false
+        }`),
+	}, r.answerQuestion)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_questions",
+		Description: "List questions waiting for a person, most urgent first",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "answered": {
+                    "type": "boolean",
+                    "description": "List answered questions instead, latest first"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listQuestions)
+}
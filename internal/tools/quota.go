@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/guard"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// agentName identifies the caller by the clientInfo it sent in initialize.
+func agentName(ctx context.Context) string {
+	if c := mcp.ClientFromContext(ctx); c != nil && c.Info.Name != "" {
+		return c.Info.Name
+	}
+	return "unknown"
+}
+
+// actor identifies the caller in the audit log: client name plus session.
+func actor(ctx context.Context) string {
+	if s := mcp.SessionFromContext(ctx); s != nil {
+		return agentName(ctx) + "/" + s.ID
+	}
+	return agentName(ctx)
+}
+
+// sessionID is the caller's MCP session, which owns its scratch tasks,
+// or "" outside one.
+func sessionID(ctx context.Context) string {
+	if s := mcp.SessionFromContext(ctx); s != nil {
+		return s.ID
+	}
+	return ""
+}
+
+// afterCreate updates soft quotas and warns the client on the first crossing.
+// Quota bookkeeping never fails the create that triggered it.
+func (r *Registry) afterCreate(ctx context.Context) {
+	usages := []guard.Usage{r.quotas.RecordCreate(agentName(ctx))}
+	if open, err := db.CountOpenTasks(ctx, r.conn(ctx)); err != nil {
+		slog.Error("count open tasks", "err", err)
+	} else {
+		usages = append(usages, r.quotas.OpenTasks(open))
+	}
+
+	notifier := mcp.NotifierFromContext(ctx)
+	for _, u := range usages {
+		if !r.quotas.ShouldWarn(u) {
+			continue
+		}
+		slog.Warn("soft quota approaching", "quota", u.Name, "used", u.Used, "limit", u.Limit)
+		if err := mcp.LogMessage(notifier, "warning", map[string]any{
+			"message": "soft quota approaching limit",
+			"quota":   u,
+		}); err != nil {
+			slog.Debug("notify quota", "err", err)
+		}
+	}
+}
+
+func (r *Registry) quotaStatus(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	open, err := db.CountOpenTasks(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("count open tasks: %w", err)
+	}
+	return resultJSON(map[string]any{
+		"open_tasks": r.quotas.OpenTasks(open),
+		"agents":     r.quotas.Agents(),
+	})
+}
+
+// SetQuotaConfig replaces the soft quota thresholds.
+func (r *Registry) SetQuotaConfig(cfg guard.QuotaConfig) {
+	r.quotas = guard.NewQuotas(cfg)
+	r.quotas.SetClock(r.clock)
+}
+
+func (r *Registry) registerQuotaTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "quota_status",
+		Description: "Show soft quota usage: open tasks and per-agent creation counts over the rolling window",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.quotaStatus)
+}
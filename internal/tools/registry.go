@@ -4,24 +4,64 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/guard"
 	"procdexeh/bossman/internal/mcp"
 )
 
 // signature every tool implementation must match
 type toolFunc func(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error)
 
+// annotation shared by every tool that only reads
+var readOnly = &mcp.ToolAnnotations{ReadOnlyHint: true}
+
 // registry holds tool definitions and their implementations
 // it implements mcp.ToolHandler
 type Registry struct {
-	db    *sqlx.DB
-	tools map[string]registeredTool
+	db       *sqlx.DB
+	tools    map[string]registeredTool
+	anomaly  *guard.Detector
+	quotas   *guard.Quotas
+	slow     *guard.SlowLog
+	busy     *guard.Detector
+	degraded atomic.Pointer[degradation] // nil while the database is healthy
+	fatal    func(error)
+	drain    drain
+	notifier mcp.Notifier
+	clock    clock.Clock
+	replayer Replayer // nil until SetReplayer
+	// backupDir is where backup_database writes when given no path; empty
+	// means beside the database file.
+	backupDir  string
+	workspaces *workspaceSet // nil until SetWorkspaces
 }
 
+// register compiles the tool's schema and attaches its examples. A bad
+// schema or an example the schema rejects is a programming error.
 func (r *Registry) register(def mcp.ToolDefinition, fn toolFunc) {
-	r.tools[def.Name] = registeredTool{def: def, invoke: fn}
+	s, err := compileSchema(def.InputSchema)
+	if err != nil {
+		panic(fmt.Sprintf("tool %s: bad input schema: %v", def.Name, err))
+	}
+	if examples := toolExamples[def.Name]; len(examples) > 0 {
+		for _, ex := range examples {
+			if err := s.validate(ex.Arguments); err != nil {
+				panic(fmt.Sprintf("tool %s: example %q: %v", def.Name, ex.Description, err))
+			}
+		}
+		def.Meta = &mcp.ToolMeta{Examples: examples}
+		def.Description += "\nExample: " + string(examples[0].Arguments)
+	}
+	r.tools[def.Name] = registeredTool{def: def, schema: s, invoke: fn}
 }
 
 func (r *Registry) ListTools() []mcp.ToolDefinition {
@@ -29,6 +69,8 @@ func (r *Registry) ListTools() []mcp.ToolDefinition {
 	for _, t := range r.tools {
 		defs = append(defs, t.def)
 	}
+	// stable order keeps tools/list diffable across runs
+	slices.SortFunc(defs, func(a, b mcp.ToolDefinition) int { return strings.Compare(a.Name, b.Name) })
 	return defs
 }
 
@@ -37,7 +79,50 @@ func (r *Registry) CallTool(ctx context.Context, name string, args json.RawMessa
 	if !ok {
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
-	return took.invoke(ctx, args)
+	if err := took.schema.validate(args); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !r.drain.enter() {
+		return nil, drainingError()
+	}
+	defer r.drain.leave()
+	ctx = clock.With(ctx, r.clock)
+	ctx = db.WithActor(ctx, actor(ctx))
+	ctx = r.withConn(ctx)
+	if r.slow != nil {
+		start := time.Now()
+		defer func() { r.slow.Tool(db.ActorFromContext(ctx), name, args, time.Since(start)) }()
+	}
+	if took.def.ReadOnly() {
+		result, err := took.invoke(ctx, args)
+		if err != nil {
+			r.observeError(err)
+		}
+		return result, err
+	}
+	if d := r.degraded.Load(); d != nil {
+		return nil, d.degradedError()
+	}
+
+	reason, locked, err := db.GetSetting(ctx, r.db, db.SettingReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("check read-only: %w", err)
+	}
+	if locked {
+		return nil, fmt.Errorf("bossman is read-only (%s); a human must run `bossman unlock`", reason)
+	}
+
+	result, err := took.invoke(ctx, args)
+	if err != nil {
+		r.observeError(err)
+	} else {
+		kind := guard.KindWrite
+		if took.def.Destructive() {
+			kind = guard.KindDelete
+		}
+		r.anomaly.Observe(kind)
+	}
+	return result, err
 }
 
 func (r *Registry) HasTool(name string) bool {
@@ -45,17 +130,100 @@ func (r *Registry) HasTool(name string) bool {
 	return ok
 }
 
+// SetAnomalyConfig replaces the spike detector thresholds.
+func (r *Registry) SetAnomalyConfig(cfg guard.Config) {
+	r.anomaly = guard.NewDetector(cfg, r.onAnomaly)
+	r.anomaly.SetClock(r.clock)
+}
+
+// SetSlowLog logs and counts tool calls slower than its threshold.
+func (r *Registry) SetSlowLog(l *guard.SlowLog) {
+	r.slow = l
+}
+
+// SetClock makes every tool call, and the guards watching them, run on c.
+func (r *Registry) SetClock(c clock.Clock) {
+	r.clock = c
+	r.anomaly.SetClock(c)
+	r.busy.SetClock(c)
+	r.quotas.SetClock(c)
+}
+
+// SetReplayer lets replay_dead_letter rerun failed automations.
+func (r *Registry) SetReplayer(rep Replayer) {
+	r.replayer = rep
+}
+
+// SetBackupDir sets where backup_database writes when given no path.
+func (r *Registry) SetBackupDir(dir string) {
+	r.backupDir = dir
+}
+
+// SetNotifier gives background subsystems a way to reach the client.
+func (r *Registry) SetNotifier(n mcp.Notifier) {
+	r.notifier = n
+}
+
+// onAnomaly runs outside any request, so it uses a fresh context for the write.
+func (r *Registry) onAnomaly(a guard.Alert) {
+	slog.Warn(a.String(), "kind", a.Kind, "count", a.Count, "window", a.Window)
+	if err := mcp.LogMessage(r.notifier, "warning", a); err != nil {
+		slog.Debug("notify anomaly", "err", err)
+	}
+	if !r.anomaly.Config().AutoReadOnly {
+		return
+	}
+	if err := db.SetSetting(clock.With(context.Background(), r.clock), r.db, db.SettingReadOnly, a.String()); err != nil {
+		slog.Error("enable read-only mode", "err", err)
+	}
+}
+
 type registeredTool struct {
 	def    mcp.ToolDefinition
+	schema *schema
 	invoke toolFunc
 }
 
 func NewRegistry(db *sqlx.DB) *Registry {
 	r := &Registry{
-		db:    db,
-		tools: make(map[string]registeredTool),
+		db:       db,
+		tools:    make(map[string]registeredTool),
+		notifier: mcp.NopNotifier{},
+		clock:    clock.Real{},
 	}
+	r.busy = guard.NewDetector(guard.Config{Limits: map[string]guard.Limit{kindBusy: busyLimit}}, r.onBusyStorm)
+	r.SetAnomalyConfig(guard.DefaultConfig())
+	r.SetQuotaConfig(guard.DefaultQuotaConfig())
 	r.registerTaskTools()
 	r.registerBlockerTools()
+	r.registerSystemTools()
+	r.registerQuotaTools()
+	r.registerAggregateTools()
+	r.registerWatchTools()
+	r.registerTagTools()
+	r.registerCommentTools()
+	r.registerHistoryTools()
+	r.registerSearchTools()
+	r.registerTimeTools()
+	r.registerAssignTools()
+	r.registerChecklistTools()
+	r.registerProjectTools()
+	r.registerReviewTools()
+	r.registerAttachmentTools()
+	r.registerParallelTools()
+	r.registerExportTools()
+	r.registerLockTools()
+	r.registerReportTools()
+	r.registerWindowTools()
+	r.registerTreeTools()
+	r.registerPlanTools()
+	r.registerCapacityTools()
+	r.registerDeadLetterTools()
+	r.registerQuestionTools()
+	r.registerDiagramTools()
+	r.registerScratchTools()
+	r.registerFileTools()
+	r.registerArchiveTools()
+	r.registerWorkspaceTools()
 	return r
 }
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// exportCSV takes list_tasks' filters and returns the matching tasks as a
+// CSV resource for spreadsheets.
+func (r *Registry) exportCSV(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Status     *string        `json:"status"`
+		ParentID   *string        `json:"parent_id"`
+		Tags       []string       `json:"tags"`
+		AssignedTo *string        `json:"assigned_to"`
+		ProjectID  *string        `json:"project_id"`
+		Metadata   map[string]any `json:"metadata"`
+		Limit      int            `json:"limit"`
+		Columns    []string       `json:"columns"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	for i, tag := range params.Tags {
+		norm, err := db.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Tags[i] = norm
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	n, err := db.WriteTasksCSV(ctx, &db.SQLStore{DB: r.conn(ctx)}, &b, db.ListOpts{
+		Status:     params.Status,
+		ParentID:   params.ParentID,
+		Tags:       params.Tags,
+		AssignedTo: params.AssignedTo,
+		ProjectID:  projectID,
+		Metadata:   params.Metadata,
+		Limit:      params.Limit,
+	}, params.Columns)
+	if err != nil {
+		return nil, fmt.Errorf("export csv: %w", err)
+	}
+	return mcp.DataResult(map[string]int{"rows": n}, nil, mcp.EmbeddedResourceContent(mcp.ResourceContents{
+		URI:      "bossman://export/tasks.csv",
+		MimeType: "text/csv",
+		Text:     b.String(),
+	}))
+}
+
+// generateReport returns the Markdown digest as a text block, ready to
+// paste, after a short data part saying what it covers.
+func (r *Registry) generateReport(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Since     string  `json:"since"`
+		ProjectID *string `json:"project_id"`
+		GroupBy   string  `json:"group_by"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.Since == "" {
+		params.Since = "24h"
+	}
+	// a duration counts back from now, anything else is a time or date
+	var since string
+	if d, err := time.ParseDuration(params.Since); err == nil {
+		since = db.FormatTime(clock.From(ctx).Now().Add(-d))
+	} else if since, err = db.ParseTime(params.Since); err != nil {
+		return nil, err
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	if err := db.GenerateReport(ctx, r.conn(ctx), &b, db.ReportOpts{
+		Since:     since,
+		ProjectID: projectID,
+		GroupBy:   params.GroupBy,
+	}); err != nil {
+		return nil, fmt.Errorf("generate report: %w", err)
+	}
+	return mcp.DataResult(map[string]string{"since": since, "format": "markdown"}, nil, mcp.TextContent(b.String()))
+}
+
+func (r *Registry) registerReportTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "export_csv",
+		Description: "Export tasks matching list_tasks-style filters as CSV, with a header row, for spreadsheets",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "description": "Filter by status",
+                    "enum": ["pending", "in_progress", "completed", "failed"]
+                },
+                "parent_id": {
+                    "type": "string",
+                    "description": "Filter by parent task ID"
+                },
+                "tags": {
+                    "type": "array",
+                    "description": "Only tasks carrying every one of these tags",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "assigned_to": {
+                    "type": "string",
+                    "description": "Only tasks assigned to this worker; empty string for unassigned tasks"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only tasks in this project (ID or name); empty string for tasks outside any project"
+                },
+                "metadata": {
+                    "type": "object",
+                    "description": "Only tasks whose metadata has each key equal to the given value, as in list_tasks",
+                    "additionalProperties": true
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of rows"
+                },
+                "columns": {
+                    "type": "array",
+                    "description": "Columns in order (default: all). Tags are joined with ;",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "metadata", "review_status", "reviewer", "revision", "tags"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.exportCSV)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "generate_report",
+		Description: "Write a Markdown status digest for standups or PR descriptions: open tasks at risk of missing a due date downstream, each worker's open work against their capacity, tasks completed since a time, in-progress work with its age, and blocked tasks with what they wait on, under a heading per project or tag",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "since": {
+                    "type": "string",
+                    "description": "Report completions after this time: RFC 3339, a date, or a duration back from now such as 24h (default 24h)"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only this project's tasks (ID or name); empty string for tasks outside any project"
+                },
+                "group_by": {
+                    "type": "string",
+                    "description": "Heading per project (default) or per tag; a task with several tags is listed under each",
+                    "enum": ["project", "tag"]
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.generateReport)
+}
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) requestReview(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID       string `json:"id"`
+		Reviewer string `json:"reviewer"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return r.reviewResult(ctx, params.ID, db.RequestReview(ctx, r.conn(ctx), params.ID, params.Reviewer))
+}
+
+func (r *Registry) approveTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID      string `json:"id"`
+		Comment string `json:"comment"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return r.reviewResult(ctx, params.ID, db.ApproveTask(ctx, r.conn(ctx), params.ID, agentName(ctx), params.Comment))
+}
+
+func (r *Registry) sendBackTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID      string `json:"id"`
+		Comment string `json:"comment"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return r.reviewResult(ctx, params.ID, db.SendBack(ctx, r.conn(ctx), params.ID, agentName(ctx), params.Comment))
+}
+
+// reviewResult maps a review transition's error and returns the task.
+func (r *Registry) reviewResult(ctx context.Context, id string, err error) (*mcp.ToolResult, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("review task: %w", err)
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("get updated task: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) listReviewQueue(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Reviewer *string  `json:"reviewer"`
+		Limit    int      `json:"limit"`
+		Fields   []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	requested := db.ReviewRequested
+	tasks, err := db.QueryTasks(ctx, r.conn(ctx), db.ListOpts{ReviewStatus: &requested})
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+	// a named reviewer also sees the unclaimed reviews
+	if params.Reviewer != nil {
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if t.Reviewer == nil || *t.Reviewer == *params.Reviewer {
+				kept = append(kept, t)
+			}
+		}
+		tasks = kept
+	}
+	if params.Limit > 0 && len(tasks) > params.Limit {
+		tasks = tasks[:params.Limit]
+	}
+	return r.tasksResult(ctx, tasks, params.Fields)
+}
+
+func (r *Registry) registerReviewTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "request_review",
+		Description: "Queue a completed task for review by a human or reviewer agent",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID; the task must be completed"
+                },
+                "reviewer": {
+                    "type": "string",
+                    "description": "Who should review it (default: anyone)"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.requestReview)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "approve_task",
+		Description: "Approve a task waiting for review. The connected client is recorded as reviewer",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "comment": {
+                    "type": "string",
+                    "description": "Optional review note, added as a comment"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.approveTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "send_back_task",
+		Description: "Reject a task waiting for review: it returns to pending with your comment and its revision increases. Completing it again re-queues it for review",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "comment": {
+                    "type": "string",
+                    "description": "What needs to change, added as a comment"
+                }
+            },
+            "required": ["id", "comment"],
+            "additionalProperties": false
+        }`),
+	}, r.sendBackTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_review_queue",
+		Description: "List tasks waiting for review",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "reviewer": {
+                    "type": "string",
+                    "description": "Only reviews for this reviewer, plus those left to anyone"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of tasks to return"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listReviewQueue)
+}
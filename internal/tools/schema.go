@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// schema is the subset of JSON Schema our inputSchemas use. The same
+// compiled schema checks incoming arguments and the examples published in
+// tools/list, so an example can never drift from what the tool accepts.
+type schema struct {
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+}
+
+func compileSchema(raw json.RawMessage) (*schema, error) {
+	var s schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// validate checks a tools/call arguments object. Absent arguments count
+// as an empty object.
+func (s *schema) validate(args json.RawMessage) error {
+	if len(bytes.TrimSpace(args)) == 0 || bytes.Equal(bytes.TrimSpace(args), []byte("null")) {
+		args = json.RawMessage("{}")
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return s.check("", v)
+}
+
+func (s *schema) check(path string, v any) error {
+	if v == nil {
+		// null means "not set" for our optional pointer params
+		return nil
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return typeError(path, "object")
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: required", join(path, name))
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unknown property", join(path, k))
+				}
+				continue
+			}
+			if err := prop.check(join(path, k), obj[k]); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return typeError(path, "array")
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				if err := s.Items.check(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return typeError(path, "string")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return typeError(path, "boolean")
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			return typeError(path, s.Type)
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return typeError(path, s.Type)
+		}
+		if s.Type == "integer" && f != math.Trunc(f) {
+			return typeError(path, "integer")
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: must be >= %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("%s: must be <= %v", path, *s.Maximum)
+		}
+	}
+	if len(s.Enum) > 0 && !s.allows(v) {
+		return fmt.Errorf("%s: must be one of %s", path, s.enumList())
+	}
+	return nil
+}
+
+func (s *schema) allows(v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		v = f
+	}
+	return slices.Contains(s.Enum, v)
+}
+
+func (s *schema) enumList() string {
+	parts := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func typeError(path, want string) error {
+	if path == "" {
+		path = "arguments"
+	}
+	return fmt.Errorf("%s: must be %s", path, want)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) promoteTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	err := db.PromoteTask(ctx, r.conn(ctx), params.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("promote task: %w", err)
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), params.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get promoted task: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+// EndSession drops the scratch tasks of a session that has ended. Wire it
+// to mcp.SessionRegistry.OnEnd.
+func (r *Registry) EndSession(id string) {
+	// the session may have left scratch tasks in every workspace it used
+	for _, conn := range r.sessionConns(id) {
+		n, err := db.DropSessionTasks(clock.With(context.Background(), r.clock), conn, id)
+		if err != nil {
+			slog.Error("drop scratch tasks", "session", id, "err", err)
+			continue
+		}
+		if n > 0 {
+			slog.Info("dropped scratch tasks", "session", id, "count", n)
+		}
+	}
+}
+
+func (r *Registry) registerScratchTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "promote_task",
+		Description: "Keep a scratch task: it and its scratch subtasks join the shared backlog, visible to everyone and no longer deleted when your session ends",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Scratch task ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.promoteTask)
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+type searchHit struct {
+	Task    any     `json:"task"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+func (r *Registry) searchTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Query  string   `json:"query"`
+		Status *string  `json:"status"`
+		Limit  int      `json:"limit"`
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("query must not be empty")
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+
+	hits, err := db.SearchTasks(ctx, r.conn(ctx), params.Query, db.SearchOpts{
+		Status: params.Status,
+		Limit:  params.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search tasks: %w", err)
+	}
+	tasks := make([]db.Task, len(hits))
+	for i, h := range hits {
+		tasks[i] = h.Task
+	}
+	converted, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]searchHit, len(hits))
+	for i, h := range hits {
+		out[i] = searchHit{Task: converted[i], Snippet: h.Snippet, Rank: h.Rank}
+		if len(params.Fields) > 0 {
+			out[i].Task = converted[i].Project(params.Fields)
+		}
+	}
+	return resultJSON(out)
+}
+
+func (r *Registry) search(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Query string   `json:"query"`
+		Kinds []string `json:"kinds"`
+		Limit int      `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, fmt.Errorf("query must not be empty")
+	}
+
+	hits, err := db.SearchAll(ctx, r.conn(ctx), params.Query, db.GlobalSearchOpts{
+		Kinds: params.Kinds,
+		Limit: params.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	return resultJSON(hits)
+}
+
+func (r *Registry) registerSearchTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "search_tasks",
+		Description: "Full-text search over task description, context and result, best matches first",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "query": {
+                    "type": "string",
+                    "description": "Words to find; every word must match, prefixes count (pars finds parser)"
+                },
+                "status": {
+                    "type": "string",
+                    "description": "Only tasks in this status",
+                    "enum": ["pending", "in_progress", "completed", "failed"]
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of hits (default 20)",
+                    "minimum": 1
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these task fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["query"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.searchTasks)
+	r.register(mcp.ToolDefinition{
+		Name:        "search",
+		Description: "Search tasks, comments, attachment names and project names at once. Each hit says its kind and id (plus task_id for comments and attachments), best matches first",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "query": {
+                    "type": "string",
+                    "description": "Words to find; every word must match"
+                },
+                "kinds": {
+                    "type": "array",
+                    "description": "Only these kinds of result (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["task", "comment", "attachment", "project"]
+                    }
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of hits (default 20)",
+                    "minimum": 1
+                }
+            },
+            "required": ["query"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.search)
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"procdexeh/bossman/internal/clock"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// maxAdvance keeps a typo like "9999h" from spinning through years of ticks.
+const maxAdvance = 366 * 24 * time.Hour
+
+// EnableSimulation runs the registry on a virtual clock and exposes the
+// debug tools that drive it. Only for test and evaluation setups.
+func (r *Registry) EnableSimulation(fake *clock.Fake) {
+	r.SetClock(fake)
+	r.registerSimulationTools(fake)
+}
+
+func (r *Registry) registerSimulationTools(fake *clock.Fake) {
+	r.register(mcp.ToolDefinition{
+		Name:        "advance_time",
+		Description: "Simulation only: move virtual time forward, running every scheduled job that comes due on the way",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "duration": {
+                    "type": "string",
+                    "description": "How far to advance, as a Go duration such as 90m or 72h"
+                }
+            },
+            "required": ["duration"],
+            "additionalProperties": false
+        }`),
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+		var params struct {
+			Duration string `json:"duration"`
+		}
+		if err := json.Unmarshal(args, &params); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		d, err := time.ParseDuration(params.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		if d <= 0 || d > maxAdvance {
+			return nil, fmt.Errorf("duration must be positive and at most %s", maxAdvance)
+		}
+		ticks, err := fake.Run(ctx, d)
+		if err != nil {
+			return nil, fmt.Errorf("advance time: %w", err)
+		}
+		return resultJSON(map[string]any{"now": db.FormatTime(fake.Now()), "ticks": ticks})
+	})
+
+	r.register(mcp.ToolDefinition{
+		Name:        "get_time",
+		Description: "Simulation only: report the current virtual time",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, func(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+		return resultJSON(map[string]string{"now": db.FormatTime(fake.Now())})
+	})
+}
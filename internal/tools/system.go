@@ -0,0 +1,163 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) getMaintenanceHistory(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	system, err := db.IsSystemTask(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !system {
+		return nil, fmt.Errorf("not a system task: %s", params.TaskID)
+	}
+
+	runs, err := db.GetMaintenanceRuns(ctx, r.conn(ctx), params.TaskID, params.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("get maintenance runs: %w", err)
+	}
+	return resultJSON(runs)
+}
+
+func (r *Registry) backupDatabase(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.Path != "" {
+		info, err := db.Backup(ctx, r.conn(ctx), params.Path)
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+		return resultJSON(info)
+	}
+	dir := r.backupDir
+	if ws := r.workspaces; ws != nil && ws.workspace(ctx) != ws.started {
+		// another workspace's backups stay beside it, apart from the rest
+		dir = ""
+	}
+	if dir == "" {
+		var err error
+		if dir, err = db.DefaultBackupDir(ctx, r.conn(ctx)); err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+	}
+	info, err := db.BackupToDir(ctx, r.conn(ctx), dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	return resultJSON(info)
+}
+
+func (r *Registry) optimizeDatabase(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Vacuum bool `json:"vacuum"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	info, err := db.Optimize(ctx, r.conn(ctx), db.OptimizeOpts{Vacuum: params.Vacuum, Analyze: true})
+	if err != nil {
+		return nil, fmt.Errorf("optimize database: %w", err)
+	}
+	return resultJSON(info)
+}
+
+func (r *Registry) checkDatabase(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Full bool `json:"full"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	report, err := db.HealthCheck(ctx, r.conn(ctx), db.HealthOpts{Full: params.Full})
+	if err != nil {
+		return nil, fmt.Errorf("check database: %w", err)
+	}
+	return resultJSON(report)
+}
+
+func (r *Registry) registerSystemTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "get_maintenance_history",
+		Description: "List recent runs of a bossman maintenance job (children of " + db.SystemTaskID + ")",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "System task ID of the maintenance job"
+                },
+                "limit": {
+                    "type": "integer",
+                    "description": "Maximum number of runs to return (default 20)"
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getMaintenanceHistory)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "backup_database",
+		Description: "Write a consistent copy of the database while it stays in use, e.g. before a risky bulk change. Without path it goes to the backups directory under a timestamped name",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "path": {
+                    "type": "string",
+                    "description": "File to write the backup to; must not exist yet"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.backupDatabase)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "optimize_database",
+		Description: "Refresh the query planner's statistics and truncate the write-ahead log, optionally compacting the database file too. Reports the file sizes before and after",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "vacuum": {
+                    "type": "boolean",
+                    "description": "Also VACUUM to reclaim free pages; blocks writes while it runs and needs free disk the size of the database"
+                }
+            },
+            "additionalProperties": false
+        }`),
+	}, r.optimizeDatabase)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "check_database",
+		Description: "Check the database file for corruption and look for blockers and subtasks pointing at tasks that no longer exist. ok is false when anything turned up",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "full": {
+                    "type": "boolean",
+                    "description": "Run PRAGMA integrity_check, which also verifies every index, instead of the faster quick_check"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.checkDatabase)
+}
@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+type tagParams struct {
+	TaskID string `json:"task_id"`
+	Tag    string `json:"tag"`
+}
+
+func (r *Registry) decodeTagParams(args json.RawMessage) (tagParams, error) {
+	var params tagParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return params, fmt.Errorf("invalid arguments: %w", err)
+	}
+	tag, err := db.NormalizeTag(params.Tag)
+	if err != nil {
+		return params, err
+	}
+	params.Tag = tag
+	return params, nil
+}
+
+func (r *Registry) addTag(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	params, err := r.decodeTagParams(args)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+	if err := db.AddTag(ctx, r.conn(ctx), params.TaskID, params.Tag); err != nil {
+		return nil, fmt.Errorf("add tag: %w", err)
+	}
+	return r.taggedTask(ctx, params.TaskID)
+}
+
+func (r *Registry) removeTag(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	params, err := r.decodeTagParams(args)
+	if err != nil {
+		return nil, err
+	}
+	err = db.RemoveTag(ctx, r.conn(ctx), params.TaskID, params.Tag)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("tag %q not found on task %s", params.Tag, params.TaskID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("remove tag: %w", err)
+	}
+	return r.taggedTask(ctx, params.TaskID)
+}
+
+// taggedTask returns the task after a tag change so callers see its tags.
+func (r *Registry) taggedTask(ctx context.Context, id string) (*mcp.ToolResult, error) {
+	task, err := db.GetTask(ctx, r.conn(ctx), id)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) listTags(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	tags, err := db.ListTags(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	return resultJSON(tags)
+}
+
+func (r *Registry) registerTagTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "add_tag",
+		Description: "Attach a tag to a task (tags are lowercased; adding an existing tag is a no-op)",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "tag": {
+                    "type": "string",
+                    "description": "Tag to attach, at most 64 characters"
+                }
+            },
+            "required": ["task_id", "tag"],
+            "additionalProperties": false
+        }`),
+	}, r.addTag)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "remove_tag",
+		Description: "Detach a tag from a task",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "tag": {
+                    "type": "string",
+                    "description": "Tag to detach"
+                }
+            },
+            "required": ["task_id", "tag"],
+            "additionalProperties": false
+        }`),
+	}, r.removeTag)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_tags",
+		Description: "List every tag in use with the number of tasks carrying it",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listTags)
+}
@@ -6,121 +6,536 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"procdexeh/bossman/internal/api"
 	"procdexeh/bossman/internal/db"
 	"procdexeh/bossman/internal/mcp"
 )
 
+// resultJSON wraps v in the standard envelope.
 func resultJSON(v any) (*mcp.ToolResult, error) {
-	data, err := json.Marshal(v)
+	return mcp.DataResult(v, nil)
+}
+
+// tasksResult converts rows to the public model, optionally projected.
+func (r *Registry) tasksResult(ctx context.Context, tasks []db.Task, fields []string) (*mcp.ToolResult, error) {
+	out, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) > 0 {
+		return resultJSON(api.ProjectTasks(out, fields))
+	}
+	return resultJSON(out)
+}
+
+// tasksPage is tasksResult for one page of a listing, with meta.next_cursor
+// set when there is more.
+func (r *Registry) tasksPage(ctx context.Context, tasks []db.Task, fields []string, next string) (*mcp.ToolResult, error) {
+	out, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, err
+	}
+	var meta *mcp.EnvelopeMeta
+	if next != "" {
+		meta = &mcp.EnvelopeMeta{NextCursor: next}
+	}
+	if len(fields) > 0 {
+		return mcp.DataResult(api.ProjectTasks(out, fields), meta)
+	}
+	return mcp.DataResult(out, meta)
+}
+
+// taskResult converts one row to the public model, optionally projected.
+func (r *Registry) taskResult(ctx context.Context, task *db.Task, fields []string) (*mcp.ToolResult, error) {
+	out, err := api.One(ctx, r.conn(ctx), task)
 	if err != nil {
 		return nil, err
 	}
-	return &mcp.ToolResult{
-		Content: []mcp.ContentBlock{{Type: "text", Text: string(data)}},
-	}, nil
+	if len(fields) > 0 {
+		return resultJSON(out.Project(fields))
+	}
+	return resultJSON(out)
 }
 
 func (r *Registry) listTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
 	var params struct {
-		Status   *string `json:"status"`
-		ParentID *string `json:"parent_id"`
-		Limit    int     `json:"limit"`
+		Status        *string        `json:"status"`
+		Kind          *string        `json:"kind"`
+		Scratch       bool           `json:"scratch"`
+		ParentID      *string        `json:"parent_id"`
+		Tags          []string       `json:"tags"`
+		AssignedTo    *string        `json:"assigned_to"`
+		ProjectID     *string        `json:"project_id"`
+		Metadata      map[string]any `json:"metadata"`
+		CreatedAfter  string         `json:"created_after"`
+		CreatedBefore string         `json:"created_before"`
+		UpdatedAfter  string         `json:"updated_after"`
+		Text          string         `json:"text"`
+		Limit         int            `json:"limit"`
+		Sort          string         `json:"sort"`
+		SortDir       string         `json:"sort_dir"`
+		Cursor        string         `json:"cursor"`
+		Fields        []string       `json:"fields"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
-	tasks, err := db.QueryTasks(ctx, r.db, db.ListOpts{
-		Status:   params.Status,
-		ParentID: params.ParentID,
-		Limit:    params.Limit,
-	})
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	var bounds [3]time.Time
+	for i, s := range []string{params.CreatedAfter, params.CreatedBefore, params.UpdatedAfter} {
+		if s == "" {
+			continue
+		}
+		ts, err := db.ParseTimestamp(s)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = ts.Time
+	}
+	for i, tag := range params.Tags {
+		norm, err := db.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		params.Tags[i] = norm
+	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	opts := db.ListOpts{
+		Status:        params.Status,
+		Kind:          params.Kind,
+		Scratch:       params.Scratch,
+		Session:       sessionID(ctx),
+		ParentID:      params.ParentID,
+		Tags:          params.Tags,
+		AssignedTo:    params.AssignedTo,
+		ProjectID:     projectID,
+		Metadata:      params.Metadata,
+		CreatedAfter:  bounds[0],
+		CreatedBefore: bounds[1],
+		UpdatedAfter:  bounds[2],
+		Text:          params.Text,
+		OrderBy:       params.Sort,
+		SortDir:       params.SortDir,
+		Cursor:        params.Cursor,
+	}
+	// one extra row says whether another page follows
+	if params.Limit > 0 {
+		opts.Limit = params.Limit + 1
+	}
+	tasks, err := db.QueryTasks(ctx, r.conn(ctx), opts)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return nil, fmt.Errorf("invalid cursor: pass the next_cursor of a previous list_tasks result with the same sort")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("query tasks: %w", err)
 	}
-	return resultJSON(tasks)
+	var next string
+	if params.Limit > 0 && len(tasks) > params.Limit {
+		tasks = tasks[:params.Limit]
+		next = opts.CursorAfter(&tasks[len(tasks)-1])
+	}
+	return r.tasksPage(ctx, tasks, params.Fields, next)
 }
 
 func (r *Registry) getTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
 	var params struct {
-		ID string `json:"id"`
+		ID      string   `json:"id"`
+		Fields  []string `json:"fields"`
+		Include []string `json:"include"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
-	task, err := db.GetTask(ctx, r.db, params.ID)
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), params.ID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("task not found: %s", params.ID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get task: %w", err)
 	}
-	return resultJSON(task)
+	if len(params.Include) == 0 {
+		return r.taskResult(ctx, task, params.Fields)
+	}
+
+	out, err := api.One(ctx, r.conn(ctx), task)
+	if err != nil {
+		return nil, err
+	}
+	node, err := treeNode(out, params.Fields)
+	if err != nil {
+		return nil, err
+	}
+	related := func(tasks []db.Task) (any, error) {
+		outs, err := api.Tasks(ctx, r.conn(ctx), tasks)
+		if err != nil {
+			return nil, err
+		}
+		if len(params.Fields) > 0 {
+			return api.ProjectTasks(outs, params.Fields), nil
+		}
+		return outs, nil
+	}
+	for _, inc := range params.Include {
+		var v any
+		switch inc {
+		case "children":
+			var children []db.Task
+			if children, err = db.QueryTasks(ctx, r.conn(ctx), db.ListOpts{ParentID: &task.ID, Session: sessionID(ctx)}); err == nil {
+				v, err = related(children)
+			}
+		case "blockers":
+			var blockers []db.Task
+			if blockers, err = db.GetBlockers(ctx, r.conn(ctx), task.ID); err == nil {
+				v, err = related(blockers)
+			}
+		case "blocking":
+			var blocking []db.Task
+			if blocking, err = db.GetBlocking(ctx, r.conn(ctx), task.ID, false); err == nil {
+				v, err = related(blocking)
+			}
+		case "comments":
+			var comments []db.Comment
+			if comments, err = db.ListComments(ctx, r.conn(ctx), task.ID, 0); err == nil && comments == nil {
+				comments = []db.Comment{}
+			}
+			v = comments
+		case "history":
+			var events []db.TaskEvent
+			if events, err = db.GetTaskEvents(ctx, r.conn(ctx), task.ID, 0); err == nil {
+				v = wireEvents(events)
+			}
+		default:
+			return nil, fmt.Errorf("unknown include: %s", inc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get task %s: %w", inc, err)
+		}
+		node[inc] = v
+	}
+	return resultJSON(node)
+}
+
+// maxGetTasks is how many IDs one get_tasks call takes.
+const maxGetTasks = 100
+
+func (r *Registry) getTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		IDs    []string `json:"ids"`
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	if len(params.IDs) > maxGetTasks {
+		return nil, fmt.Errorf("%d ids; get_tasks takes at most %d per call", len(params.IDs), maxGetTasks)
+	}
+	tasks, missing, err := db.GetTasksByIDs(ctx, r.conn(ctx), params.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("get tasks: %w", err)
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, fmt.Errorf("get tasks: %w", err)
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+	if len(params.Fields) > 0 {
+		return resultJSON(map[string]any{"tasks": api.ProjectTasks(outs, params.Fields), "missing": missing})
+	}
+	return resultJSON(map[string]any{"tasks": outs, "missing": missing})
 }
 
 func (r *Registry) deleteTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
 	var params struct {
-		ID string `json:"id"`
+		ID     string          `json:"id"`
+		Policy db.DeletePolicy `json:"policy"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
-	err := db.DeleteTask(ctx, r.db, params.ID)
+	if params.Policy == "" {
+		params.Policy = db.DeleteForbid
+	}
+	if system, err := db.IsSystemTask(ctx, r.conn(ctx), params.ID); err != nil {
+		return nil, fmt.Errorf("delete task: %w", err)
+	} else if system {
+		return nil, fmt.Errorf("cannot delete system task: %s", params.ID)
+	}
+	res, err := db.DeleteTask(ctx, r.conn(ctx), params.ID, params.Policy)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("task not found: %s", params.ID)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("delete task: %w", err)
 	}
-	return resultJSON(map[string]string{"deleted": params.ID})
+	return resultJSON(map[string]any{
+		"deleted":             params.ID,
+		"policy":              params.Policy,
+		"descendants_deleted": res.DescendantsDeleted,
+		"children_orphaned":   res.ChildrenOrphaned,
+	})
+}
+
+func (r *Registry) moveTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID       string `json:"id"`
+		ParentID string `json:"parent_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	for _, id := range []string{params.ID, params.ParentID} {
+		if id == "" {
+			continue
+		}
+		if system, err := db.IsSystemTask(ctx, r.conn(ctx), id); err != nil {
+			return nil, fmt.Errorf("move task: %w", err)
+		} else if system {
+			return nil, fmt.Errorf("cannot move system task or move a task under one: %s", id)
+		}
+	}
+	var parentID *string
+	if params.ParentID != "" {
+		parentID = &params.ParentID
+	}
+	err := db.ReparentTask(ctx, r.conn(ctx), params.ID, parentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("move task: %w", err)
+	}
+	task, err := db.GetTask(ctx, r.conn(ctx), params.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get moved task: %w", err)
+	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) mergeTasks(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		SurvivorID  string   `json:"survivor_id"`
+		DuplicateID string   `json:"duplicate_id"`
+		Fields      []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	for _, id := range []string{params.SurvivorID, params.DuplicateID} {
+		if system, err := db.IsSystemTask(ctx, r.conn(ctx), id); err != nil {
+			return nil, fmt.Errorf("merge tasks: %w", err)
+		} else if system {
+			return nil, fmt.Errorf("cannot merge system task: %s", id)
+		}
+	}
+	res, err := db.MergeTasks(ctx, r.conn(ctx), params.SurvivorID, params.DuplicateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s or %s", params.SurvivorID, params.DuplicateID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("merge tasks: %w", err)
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), []db.Task{*res.Survivor, *res.Duplicate})
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]any{"children_moved": res.ChildrenMoved, "blockers_moved": res.BlockersMoved}
+	if len(res.CyclesDropped) > 0 {
+		out["cycles_dropped"] = res.CyclesDropped
+	}
+	if len(params.Fields) > 0 {
+		projected := api.ProjectTasks(outs, params.Fields)
+		out["survivor"], out["duplicate"] = projected[0], projected[1]
+	} else {
+		out["survivor"], out["duplicate"] = outs[0], outs[1]
+	}
+	return resultJSON(out)
 }
 
 func (r *Registry) createTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
 	var params struct {
-		Description string  `json:"description"`
-		ParentID    *string `json:"parent_id"`
-		Priority    *int    `json:"priority"`
-		Context     *string `json:"context"`
+		Description     string          `json:"description"`
+		ParentID        *string         `json:"parent_id"`
+		Priority        *string         `json:"priority"`
+		Context         *string         `json:"context"`
+		EstimateMinutes *int            `json:"estimate_minutes"`
+		DueAt           *string         `json:"due_at"`
+		ProjectID       *string         `json:"project_id"`
+		Metadata        json.RawMessage `json:"metadata"`
+		Resources       []string        `json:"resources"`
+		Files           []string        `json:"files"`
+		BlockedBy       []string        `json:"blocked_by"`
+		Scratch         bool            `json:"scratch"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range params.Resources {
+		if _, err := db.NormalizeResource(res); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range params.Files {
+		if _, err := db.NormalizeFilePath(f); err != nil {
+			return nil, err
+		}
+	}
 	task := &db.Task{
 		ID:          db.NewTaskID(),
 		Description: params.Description,
 		ParentID:    params.ParentID,
-		Priority:    3, // default; CHECK constraint rejects 0
+
+		EstimateMinutes: params.EstimateMinutes,
+	}
+	if projectID != nil && *projectID != "" {
+		task.ProjectID = projectID
+	}
+	if params.Scratch {
+		session := sessionID(ctx)
+		if session == "" {
+			return nil, fmt.Errorf("scratch tasks belong to an MCP session, and this call has none")
+		}
+		task.SessionID = &session
+	}
+	if len(params.Metadata) > 0 && string(params.Metadata) != "null" {
+		meta, err := db.ValidateMetadata(params.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		task.Metadata = &meta
 	}
 	if params.Priority != nil {
-		task.Priority = *params.Priority
+		task.PriorityLabel = *params.Priority
 	}
 	if params.Context != nil {
-		task.Context = *params.Context
+		task.Context = db.Sensitive(*params.Context)
 	}
-	if err := db.InsertTask(ctx, r.db, task); err != nil {
+	if params.DueAt != nil {
+		due, err := db.ParseTimestamp(*params.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		task.DueAt = &due
+	}
+	for _, id := range params.BlockedBy {
+		if ok, err := db.TaskExists(ctx, r.conn(ctx), id); err != nil {
+			return nil, fmt.Errorf("check blocker: %w", err)
+		} else if !ok {
+			return nil, fmt.Errorf("blocker not found: %s", id)
+		}
+	}
+	err = db.CreateTask(ctx, r.conn(ctx), task, db.CreateOpts{
+		Resources: params.Resources,
+		Files:     params.Files,
+		BlockedBy: params.BlockedBy,
+	})
+	if err != nil {
 		return nil, fmt.Errorf("insert task: %w", err)
 	}
-	return resultJSON(task)
+	r.afterCreate(ctx)
+
+	// Re-read so defaults filled in by the database (status, timestamps) show up
+	created, err := db.GetTask(ctx, r.conn(ctx), task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get created task: %w", err)
+	}
+	return r.taskResult(ctx, created, nil)
 }
 
 func (r *Registry) updateTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
 	var params struct {
-		ID          string  `json:"id"`
-		Description *string `json:"description"`
-		Priority    *int    `json:"priority"`
-		Status      *string `json:"status"`
-		Context     *string `json:"context"`
-		Result      *string `json:"result"`
+		ID              string          `json:"id"`
+		Description     *string         `json:"description"`
+		Priority        *string         `json:"priority"`
+		PriorityWeight  *int            `json:"priority_weight"`
+		Status          *string         `json:"status"`
+		Reopen          bool            `json:"reopen"`
+		Context         *string         `json:"context"`
+		Result          *string         `json:"result"`
+		EstimateMinutes *int            `json:"estimate_minutes"`
+		DueAt           *string         `json:"due_at"`
+		ProjectID       *string         `json:"project_id"`
+		Metadata        json.RawMessage `json:"metadata"`
+		Resources       *[]string       `json:"resources"`
+		Files           *[]string       `json:"files"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
+	projectID, err := r.resolveProject(ctx, params.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	var resources []string
+	if params.Resources != nil {
+		resources = append([]string{}, *params.Resources...)
+	}
+	var files []string
+	if params.Files != nil {
+		files = append([]string{}, *params.Files...)
+	}
+	var metadata *string
+	if len(params.Metadata) > 0 && string(params.Metadata) != "null" {
+		meta, err := db.ValidateMetadata(params.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata = &meta
+	}
+	if params.DueAt != nil && *params.DueAt != "" {
+		due, err := db.ParseTime(*params.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		params.DueAt = &due
+	}
+
+	var priority *db.PriorityChange
+	switch {
+	case params.Priority != nil && params.PriorityWeight != nil:
+		return nil, fmt.Errorf("give priority or priority_weight, not both")
+	case params.Priority != nil:
+		priority = &db.PriorityChange{Label: *params.Priority}
+	case params.PriorityWeight != nil:
+		priority = &db.PriorityChange{Weight: params.PriorityWeight}
+	}
 
-	err := db.UpdateTask(ctx, r.db, params.ID, db.UpdateOpts{
+	task, err := db.UpdateTask(ctx, r.conn(ctx), params.ID, db.UpdateOpts{
 		Description: params.Description,
-		Priority:    params.Priority,
+		Priority:    priority,
 		Status:      params.Status,
+		Reopen:      params.Reopen,
 		Context:     params.Context,
 		Result:      params.Result,
+
+		EstimateMinutes: params.EstimateMinutes,
+		DueAt:           params.DueAt,
+		ProjectID:       projectID,
+		Metadata:        metadata,
+		Resources:       resources,
+		Files:           files,
 	})
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("task not found: %s", params.ID)
@@ -128,14 +543,172 @@ func (r *Registry) updateTask(ctx context.Context, args json.RawMessage) (*mcp.T
 	if err != nil {
 		return nil, fmt.Errorf("update task: %w", err)
 	}
+	return r.taskResult(ctx, task, nil)
+}
+
+func (r *Registry) updateTasksStatus(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		IDs    []string `json:"ids"`
+		Filter *struct {
+			SubtreeOf *string  `json:"subtree_of"`
+			Status    *string  `json:"status"`
+			ParentID  *string  `json:"parent_id"`
+			ProjectID *string  `json:"project_id"`
+			Tags      []string `json:"tags"`
+		} `json:"filter"`
+		Status string `json:"status"`
+		Reopen bool   `json:"reopen"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	ids := params.IDs
+	switch f := params.Filter; {
+	case (f == nil) == (len(ids) == 0):
+		return nil, fmt.Errorf("give either ids or filter")
+	case f != nil:
+		if f.SubtreeOf == nil && f.Status == nil && f.ParentID == nil && f.ProjectID == nil && len(f.Tags) == 0 {
+			return nil, fmt.Errorf("filter needs at least one condition")
+		}
+		for i, tag := range f.Tags {
+			norm, err := db.NormalizeTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			f.Tags[i] = norm
+		}
+		projectID, err := r.resolveProject(ctx, f.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := db.QueryTasks(ctx, r.conn(ctx), db.ListOpts{
+			Status:    f.Status,
+			ParentID:  f.ParentID,
+			ProjectID: projectID,
+			Tags:      f.Tags,
+			Session:   sessionID(ctx),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("query tasks: %w", err)
+		}
+		var inSubtree map[string]bool
+		if f.SubtreeOf != nil {
+			subtree, err := db.GetSubtree(ctx, r.conn(ctx), *f.SubtreeOf, 0)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("task not found: %s", *f.SubtreeOf)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("get subtree: %w", err)
+			}
+			inSubtree = make(map[string]bool, len(subtree))
+			for _, t := range subtree {
+				inSubtree[t.ID] = true
+			}
+		}
+		for _, t := range tasks {
+			if inSubtree == nil || inSubtree[t.ID] {
+				ids = append(ids, t.ID)
+			}
+		}
+	}
 
-	// Return the updated task so the client sees the current state
-	task, err := db.GetTask(ctx, r.db, params.ID)
+	outcomes, err := db.UpdateTasksStatus(ctx, r.conn(ctx), ids, params.Status, params.Reopen)
+	if err != nil {
+		return nil, fmt.Errorf("update tasks: %w", err)
+	}
+	var updated int
+	for _, o := range outcomes {
+		if o.OK {
+			updated++
+		}
+	}
+	return resultJSON(map[string]any{"updated": updated, "failed": len(outcomes) - updated, "results": outcomes})
+}
+
+func (r *Registry) completeTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID     string   `json:"id"`
+		Result *string  `json:"result"`
+		Fields []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	task, unblocked, err := db.CompleteTask(ctx, r.conn(ctx), params.ID, params.Result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("complete task: %w", err)
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), append([]db.Task{*task}, unblocked...))
 	if err != nil {
-		return nil, fmt.Errorf("get updated task: %w", err)
+		return nil, err
+	}
+	if len(params.Fields) > 0 {
+		projected := api.ProjectTasks(outs, params.Fields)
+		return resultJSON(map[string]any{"task": projected[0], "unblocked": projected[1:]})
 	}
+	return resultJSON(map[string]any{"task": outs[0], "unblocked": outs[1:]})
+}
 
-	return resultJSON(task)
+func (r *Registry) failTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID        string   `json:"id"`
+		Reason    string   `json:"reason"`
+		Retryable bool     `json:"retryable"`
+		Fields    []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(params.Reason) == "" {
+		return nil, fmt.Errorf("reason must not be empty")
+	}
+	task, err := db.FailTask(ctx, r.conn(ctx), params.ID, params.Reason, params.Retryable)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail task: %w", err)
+	}
+	return r.taskResult(ctx, task, params.Fields)
+}
+
+func (r *Registry) reopenTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID                string   `json:"id"`
+		ReblockDependents bool     `json:"reblock_dependents"`
+		Fields            []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	task, reblocked, err := db.ReopenTask(ctx, r.conn(ctx), params.ID, params.ReblockDependents)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reopen task: %w", err)
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), append([]db.Task{*task}, reblocked...))
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Fields) > 0 {
+		projected := api.ProjectTasks(outs, params.Fields)
+		return resultJSON(map[string]any{"task": projected[0], "reblocked": projected[1:]})
+	}
+	return resultJSON(map[string]any{"task": outs[0], "reblocked": outs[1:]})
 }
 
 func (r *Registry) registerTaskTools() {
@@ -154,14 +727,55 @@ func (r *Registry) registerTaskTools() {
                     "description": "Parent task ID for subtasks"
                 },
                 "priority": {
-                    "type": "integer",
-                    "description": "Priority 1-5 (1 is highest)",
-                    "minimum": 1,
-                    "maximum": 5
+                    "type": "string",
+                    "description": "Priority label from the project's scale; the default scale is critical, high, normal, low, someday (default: normal)"
                 },
                 "context": {
                     "type": "string",
                     "description": "Additional context or notes"
+                },
+                "estimate_minutes": {
+                    "type": "integer",
+                    "description": "Estimated effort in minutes",
+                    "minimum": 0
+                },
+                "due_at": {
+                    "type": "string",
+                    "description": "Due date: RFC 3339 time or YYYY-MM-DD"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Project ID or name (default: the parent's project, if any)"
+                },
+                "metadata": {
+                    "type": "object",
+                    "description": "Free-form JSON object for structured data such as repo, branch or PR URL",
+                    "additionalProperties": true
+                },
+                "resources": {
+                    "type": "array",
+                    "description": "Shared resources the task locks while in progress, e.g. staging-env or deploy; a task whose resource another task holds is not ready",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "files": {
+                    "type": "array",
+                    "description": "Repo-relative files or directories the task touches, e.g. internal/db/db.go or internal/http; other agents find the task with find_tasks_by_file",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "blocked_by": {
+                    "type": "array",
+                    "description": "IDs of existing tasks that must complete before this one; the task and its blockers are created together or not at all",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "scratch": {
+                    "type": "boolean",
+                    "description": "Track a micro-step of your own: only your session sees it, it's never handed out as ready work, and it's deleted when your session ends unless you promote_task it. Subtasks of a scratch task are scratch too"
                 }
             },
             "required": ["description"],
@@ -180,35 +794,149 @@ func (r *Registry) registerTaskTools() {
                     "description": "Filter by status",
                     "enum": ["pending", "in_progress", "completed", "failed"]
                 },
+                "kind": {
+                    "type": "string",
+                    "description": "Only ordinary tasks, or only questions put to a person (see ask_question)",
+                    "enum": ["task", "question"]
+                },
+                "scratch": {
+                    "type": "boolean",
+                    "description": "Only your own scratch tasks (other sessions' are never listed)"
+                },
                 "parent_id": {
                     "type": "string",
                     "description": "Filter by parent task ID"
                 },
+                "tags": {
+                    "type": "array",
+                    "description": "Only tasks carrying every one of these tags",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "assigned_to": {
+                    "type": "string",
+                    "description": "Only tasks assigned to this worker; empty string for unassigned tasks"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Only tasks in this project (ID or name); empty string for tasks outside any project"
+                },
+                "metadata": {
+                    "type": "object",
+                    "description": "Only tasks whose metadata has each key equal to the given string, number or boolean (null: key absent). Dotted keys reach nested objects, e.g. {\"ci.status\": \"green\"}",
+                    "additionalProperties": true
+                },
+                "created_after": {
+                    "type": "string",
+                    "description": "Only tasks created after this time: RFC 3339 time or YYYY-MM-DD"
+                },
+                "created_before": {
+                    "type": "string",
+                    "description": "Only tasks created before this time: RFC 3339 time or YYYY-MM-DD"
+                },
+                "updated_after": {
+                    "type": "string",
+                    "description": "Only tasks changed after this time, e.g. when you last checked: RFC 3339 time or YYYY-MM-DD"
+                },
+                "text": {
+                    "type": "string",
+                    "description": "Only tasks whose description contains this text, ignoring case"
+                },
                 "limit": {
                     "type": "integer",
-                    "description": "Maximum number of tasks to return"
+                    "description": "Maximum number of tasks to return; when more match, meta.next_cursor continues the listing"
+                },
+                "sort": {
+                    "type": "string",
+                    "description": "Order by priority (default; newest first among equals), created_at, updated_at or due_at (tasks without one last)",
+                    "enum": ["priority", "created_at", "updated_at", "due_at"]
+                },
+                "sort_dir": {
+                    "type": "string",
+                    "description": "Sort direction (default: desc for priority, asc otherwise)",
+                    "enum": ["asc", "desc"]
+                },
+                "cursor": {
+                    "type": "string",
+                    "description": "meta.next_cursor from the previous page, with the same filters and sort"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
                 }
             },
             "additionalProperties": false
         }`),
+		Annotations: readOnly,
 	}, r.listTasks)
 
 	r.register(mcp.ToolDefinition{
 		Name:        "get_task",
-		Description: "Get a task by ID",
+		Description: "Get a task by ID, optionally with its subtasks, blockers, dependents, comments and history in the same call",
 		InputSchema: json.RawMessage(`{
             "type": "object",
             "properties": {
                 "id": {
                     "type": "string",
                     "description": "Task ID"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields, for the task and any included tasks (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                },
+                "include": {
+                    "type": "array",
+                    "description": "Related records to return beside the task's fields, under the same names: children (direct subtasks), blockers (tasks it waits on), blocking (tasks waiting on it), comments (oldest first), history (audit events, newest first, up to 100)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["children", "blockers", "blocking", "comments", "history"]
+                    }
                 }
             },
             "required": ["id"],
             "additionalProperties": false
         }`),
+		Annotations: readOnly,
 	}, r.getTask)
 
+	r.register(mcp.ToolDefinition{
+		Name:        "get_tasks",
+		Description: "Get up to 100 tasks by ID in one call, in the order given. IDs that don't exist are listed in missing instead of failing the call",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "description": "Task IDs, at most 100",
+                    "items": {
+                        "type": "string"
+                    },
+                    "minItems": 1
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["ids"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getTasks)
+
 	r.register(mcp.ToolDefinition{
 		Name:        "update_task",
 		Description: "Update fields on an existing task",
@@ -224,16 +952,24 @@ func (r *Registry) registerTaskTools() {
                     "description": "Updated task description"
                 },
                 "priority": {
+                    "type": "string",
+                    "description": "New priority label from the task's project scale (see get_priority_scale)"
+                },
+                "priority_weight": {
                     "type": "integer",
-                    "description": "Priority 1-5 (1 is highest)",
-                    "minimum": 1,
-                    "maximum": 5
+                    "description": "New priority as an exact weight 0-100, higher first; labelled with the nearest level. Use instead of priority",
+                    "minimum": 0,
+                    "maximum": 100
                 },
                 "status": {
                     "type": "string",
-                    "description": "Task status",
+                    "description": "New status: pending goes to in_progress or failed, in_progress to completed, failed or back to pending. Anything else fails with INVALID_TRANSITION; started_at and completed_at are set for you",
                     "enum": ["pending", "in_progress", "completed", "failed"]
                 },
+                "reopen": {
+                    "type": "boolean",
+                    "description": "Allow status to take a completed or failed task back to pending or in_progress"
+                },
                 "context": {
                     "type": "string",
                     "description": "Additional context or notes"
@@ -241,6 +977,38 @@ func (r *Registry) registerTaskTools() {
                 "result": {
                     "type": "string",
                     "description": "Task result or outcome"
+                },
+                "estimate_minutes": {
+                    "type": "integer",
+                    "description": "Estimated effort in minutes",
+                    "minimum": 0
+                },
+                "due_at": {
+                    "type": "string",
+                    "description": "Due date: RFC 3339 time or YYYY-MM-DD; empty string clears it"
+                },
+                "project_id": {
+                    "type": "string",
+                    "description": "Move the task to this project (ID or name); empty string removes it from its project. Subtasks stay where they are"
+                },
+                "metadata": {
+                    "type": "object",
+                    "description": "Keys to merge into the task's metadata; a key set to null is removed",
+                    "additionalProperties": true
+                },
+                "resources": {
+                    "type": "array",
+                    "description": "Replace the shared resources the task locks while in progress; [] clears them",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "files": {
+                    "type": "array",
+                    "description": "Replace the repo-relative files or directories the task touches; [] clears them",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             },
             "required": ["id"],
@@ -248,19 +1016,222 @@ func (r *Registry) registerTaskTools() {
         }`),
 	}, r.updateTask)
 
+	r.register(mcp.ToolDefinition{
+		Name:        "update_tasks_status",
+		Description: "Move many tasks to one status in a single transaction, such as marking a whole subtree failed. Each task is checked against the status rules on its own: the ones that can't move are reported in results and left alone while the rest change",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "description": "Task IDs to update; give this or filter",
+                    "items": {
+                        "type": "string"
+                    },
+                    "minItems": 1
+                },
+                "filter": {
+                    "type": "object",
+                    "description": "Update every task matching all of these instead of listing ids",
+                    "properties": {
+                        "subtree_of": {
+                            "type": "string",
+                            "description": "This task and all its descendants"
+                        },
+                        "status": {
+                            "type": "string",
+                            "description": "Only tasks currently in this status",
+                            "enum": ["pending", "in_progress", "completed", "failed"]
+                        },
+                        "parent_id": {
+                            "type": "string",
+                            "description": "Direct subtasks of this task"
+                        },
+                        "project_id": {
+                            "type": "string",
+                            "description": "Tasks in this project (ID or name); empty string for tasks outside any project"
+                        },
+                        "tags": {
+                            "type": "array",
+                            "description": "Tasks carrying every one of these tags",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "additionalProperties": false
+                },
+                "status": {
+                    "type": "string",
+                    "description": "Status to move every task to, under the same rules as update_task",
+                    "enum": ["pending", "in_progress", "completed", "failed"]
+                },
+                "reopen": {
+                    "type": "boolean",
+                    "description": "Allow completed or failed tasks to go back to pending or in_progress"
+                }
+            },
+            "required": ["status"],
+            "additionalProperties": false
+        }`),
+	}, r.updateTasksStatus)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "complete_task",
+		Description: "Mark an in-progress task completed with its result. unblocked lists the tasks that were waiting on it and are ready now, so you know what work this opened up",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "result": {
+                    "type": "string",
+                    "description": "Task result or outcome"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.completeTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "fail_task",
+		Description: "Record a failed attempt at a task: the reason becomes its result and attempts goes up by one. A retryable failure puts it back to pending and unassigned so it's handed out again; otherwise it stays failed. Check attempts before retrying again",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "reason": {
+                    "type": "string",
+                    "description": "What went wrong"
+                },
+                "retryable": {
+                    "type": "boolean",
+                    "description": "The failure is worth another try, e.g. a flaky test or a timeout (default: false)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["id", "reason"],
+            "additionalProperties": false
+        }`),
+	}, r.failTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "reopen_task",
+		Description: "Take a completed or failed task back to pending when it turns out not to be done. Its result and completed_at are cleared; get_task_history keeps the old result",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "reblock_dependents": {
+                    "type": "boolean",
+                    "description": "Also move the tasks waiting on this one that already started back to pending; they are listed in reblocked"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+	}, r.reopenTask)
+
 	r.register(mcp.ToolDefinition{
 		Name:        "delete_task",
-		Description: "Delete a task by ID",
+		Description: "Delete a task by ID, choosing what happens to its subtasks",
 		InputSchema: json.RawMessage(`{
             "type": "object",
             "properties": {
                 "id": {
                     "type": "string",
                     "description": "Task ID"
+                },
+                "policy": {
+                    "type": "string",
+                    "enum": ["forbid-if-children", "cascade", "orphan"],
+                    "description": "forbid-if-children (default) refuses when the task has subtasks; cascade deletes all descendants; orphan makes the children top-level tasks"
                 }
             },
             "required": ["id"],
             "additionalProperties": false
         }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
 	}, r.deleteTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "move_task",
+		Description: "Move a task, with its subtasks, under another parent or to the top level",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                },
+                "parent_id": {
+                    "type": "string",
+                    "description": "New parent task ID; empty string moves the task to the top level"
+                }
+            },
+            "required": ["id", "parent_id"],
+            "additionalProperties": false
+        }`),
+	}, r.moveTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "merge_tasks",
+		Description: "Merge a duplicate task into the one to keep: its subtasks and blockers move to the survivor and its context is appended to the survivor's. Blocker edges that would close a cycle are dropped and listed in cycles_dropped. The duplicate keeps its status and is archived with merged_into naming the survivor in its metadata, and both histories record the merge",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "survivor_id": {
+                    "type": "string",
+                    "description": "Task to keep"
+                },
+                "duplicate_id": {
+                    "type": "string",
+                    "description": "Task to fold into it"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["survivor_id", "duplicate_id"],
+            "additionalProperties": false
+        }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
+	}, r.mergeTasks)
 }
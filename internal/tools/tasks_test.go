@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"procdexeh/bossman/internal/db"
+)
+
+// TestListTasksRepeatedTag filters on one tag spelled twice and wants the
+// tagged task, not nothing: the filter counts each distinct tag once.
+func TestListTasksRepeatedTag(t *testing.T) {
+	r := newTestRegistry(t)
+	ctx := db.WithActor(context.Background(), "agent-1")
+
+	task := &db.Task{ID: db.NewTaskID(), Description: "design the schema"}
+	if err := db.InsertTask(ctx, r.db, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CallTool(ctx, "add_tag", json.RawMessage(`{"task_id": "`+task.ID+`", "tag": "backend"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tags := range []string{`["backend"]`, `["backend", "Backend"]`, `["backend", "backend"]`} {
+		res, err := r.CallTool(ctx, "list_tasks", json.RawMessage(`{"tags": `+tags+`, "fields": ["id"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var env struct {
+			Data []map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(res.Content[0].Text), &env); err != nil {
+			t.Fatal(err)
+		}
+		got := env.Data
+		if len(got) != 1 || got[0]["id"] != task.ID {
+			t.Errorf("tags %s: got %v, want %s", tags, got, task.ID)
+		}
+	}
+}
+
+// TestUpdateTasksStatusRepeatedTag is the same for update_tasks_status's
+// filter, which goes through the same query.
+func TestUpdateTasksStatusRepeatedTag(t *testing.T) {
+	r := newTestRegistry(t)
+	ctx := db.WithActor(context.Background(), "agent-1")
+
+	task := &db.Task{ID: db.NewTaskID(), Description: "design the schema"}
+	if err := db.InsertTask(ctx, r.db, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CallTool(ctx, "add_tag", json.RawMessage(`{"task_id": "`+task.ID+`", "tag": "backend"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := r.CallTool(ctx, "update_tasks_status", json.RawMessage(`{"filter": {"tags": ["backend", "backend"]}, "status": "in_progress"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env struct {
+		Data struct {
+			Updated int `json:"updated"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(res.Content[0].Text), &env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Data.Updated != 1 {
+		t.Errorf("updated %d tasks, want 1: %s", env.Data.Updated, res.Content[0].Text)
+	}
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) startWork(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.TaskID)
+	}
+
+	entry, err := db.StartWork(ctx, r.conn(ctx), params.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("start work: %w", err)
+	}
+	return resultJSON(entry)
+}
+
+func (r *Registry) stopWork(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		TaskID string `json:"task_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	entry, err := db.StopWork(ctx, r.conn(ctx), params.TaskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no work in progress on %s by %s", params.TaskID, db.ActorFromContext(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stop work: %w", err)
+	}
+	return resultJSON(entry)
+}
+
+func (r *Registry) timeReport(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		GroupBy []string `json:"group_by"`
+		Since   string   `json:"since"`
+		Until   string   `json:"until"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	rows, err := db.TimeReport(ctx, r.conn(ctx), db.TimeReportOpts{
+		GroupBy: params.GroupBy,
+		Since:   params.Since,
+		Until:   params.Until,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("time report: %w", err)
+	}
+	return resultJSON(rows)
+}
+
+func (r *Registry) registerTimeTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "start_work",
+		Description: "Start the clock on a task for the connected client; stop it with stop_work",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+	}, r.startWork)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "stop_work",
+		Description: "Stop the connected client's running clock on a task and record the duration",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "task_id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["task_id"],
+            "additionalProperties": false
+        }`),
+	}, r.stopWork)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "time_report",
+		Description: "Total seconds of tracked work, grouped by day, tag, task or actor. Running clocks count up to now",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "group_by": {
+                    "type": "array",
+                    "description": "Dimensions to group by, in order. An entry counts toward the day it started",
+                    "items": {
+                        "type": "string",
+                        "enum": ["actor", "day", "tag", "task"]
+                    }
+                },
+                "since": {
+                    "type": "string",
+                    "description": "Only entries started at or after this time (e.g. 2025-01-01 or 2025-01-01T09:00:00.000Z)"
+                },
+                "until": {
+                    "type": "string",
+                    "description": "Only entries started before this time"
+                }
+            },
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.timeReport)
+}
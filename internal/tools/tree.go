@@ -0,0 +1,267 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/api"
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) getTaskTree(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID       string   `json:"id"`
+		MaxDepth int      `json:"max_depth"`
+		Fields   []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.GetSubtree(ctx, r.conn(ctx), params.ID, params.MaxDepth)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get task tree: %w", err)
+	}
+	tasks := make([]db.Task, len(rows))
+	for i := range rows {
+		tasks[i] = rows[i].Task
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, fmt.Errorf("get task tree: %w", err)
+	}
+
+	// rows come parents first, so every parent's node exists before its children's
+	nodes := make(map[string]map[string]any, len(rows))
+	for i, row := range rows {
+		node, err := treeNode(outs[i], params.Fields)
+		if err != nil {
+			return nil, err
+		}
+		node["depth"] = row.Depth
+		node["children"] = []map[string]any{}
+		nodes[row.ID] = node
+		if i > 0 {
+			parent := nodes[*row.ParentID]
+			parent["children"] = append(parent["children"].([]map[string]any), node)
+		}
+	}
+	return resultJSON(map[string]any{
+		"tree":        nodes[params.ID],
+		"descendants": len(rows) - 1,
+	})
+}
+
+// treeNode is a task as a JSON object, so depth and children can sit
+// beside its fields.
+func treeNode(t api.Task, fields []string) (map[string]any, error) {
+	if len(fields) > 0 {
+		return t.Project(fields), nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	var node map[string]any
+	err = json.Unmarshal(data, &node)
+	return node, err
+}
+
+func (r *Registry) decomposeTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ParentID string `json:"parent_id"`
+		Subtasks []struct {
+			Description     string  `json:"description"`
+			Context         *string `json:"context"`
+			Priority        *string `json:"priority"`
+			EstimateMinutes *int    `json:"estimate_minutes"`
+			DueAt           *string `json:"due_at"`
+			After           []int   `json:"after"`
+		} `json:"subtasks"`
+		Order       string   `json:"order"`
+		StartParent bool     `json:"start_parent"`
+		Fields      []string `json:"fields"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if err := api.ValidateFields(params.Fields); err != nil {
+		return nil, err
+	}
+	opts := db.DecomposeOpts{Start: params.StartParent}
+	switch params.Order {
+	case "", "parallel":
+	case "sequential":
+		opts.Sequential = true
+	default:
+		return nil, fmt.Errorf("invalid order %q: use sequential or parallel", params.Order)
+	}
+	subtasks := make([]*db.Task, len(params.Subtasks))
+	for i, spec := range params.Subtasks {
+		task := &db.Task{
+			ID:              db.NewTaskID(),
+			Description:     spec.Description,
+			EstimateMinutes: spec.EstimateMinutes,
+		}
+		if spec.Priority != nil {
+			task.PriorityLabel = *spec.Priority
+		}
+		if spec.Context != nil {
+			task.Context = db.Sensitive(*spec.Context)
+		}
+		if spec.DueAt != nil {
+			due, err := db.ParseTimestamp(*spec.DueAt)
+			if err != nil {
+				return nil, fmt.Errorf("subtask %d: %w", i, err)
+			}
+			task.DueAt = &due
+		}
+		if spec.After != nil {
+			if opts.After == nil {
+				opts.After = make([][]int, len(params.Subtasks))
+			}
+			opts.After[i] = spec.After
+		}
+		subtasks[i] = task
+	}
+
+	parent, err := db.DecomposeTask(ctx, r.conn(ctx), params.ParentID, subtasks, opts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task not found: %s", params.ParentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decompose task: %w", err)
+	}
+	r.afterCreate(ctx)
+
+	tasks := make([]db.Task, 0, len(subtasks)+1)
+	tasks = append(tasks, *parent)
+	for _, sub := range subtasks {
+		tasks = append(tasks, *sub)
+	}
+	outs, err := api.Tasks(ctx, r.conn(ctx), tasks)
+	if err != nil {
+		return nil, fmt.Errorf("decompose task: %w", err)
+	}
+	if len(params.Fields) > 0 {
+		projected := api.ProjectTasks(outs, params.Fields)
+		return resultJSON(map[string]any{"parent": projected[0], "subtasks": projected[1:]})
+	}
+	return resultJSON(map[string]any{"parent": outs[0], "subtasks": outs[1:]})
+}
+
+func (r *Registry) registerTreeTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "get_task_tree",
+		Description: "Get a task with all its subtasks, nested: each node has its depth below the root and a children array in creation order. One call instead of walking list_tasks by parent_id",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Root task ID"
+                },
+                "max_depth": {
+                    "type": "integer",
+                    "minimum": 0,
+                    "description": "Stop this many levels below the root (default: the whole tree)"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.getTaskTree)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "decompose_task",
+		Description: "Break a task into subtasks in one step: create them all under the parent, wire blockers between them from the order hint, and optionally start the parent. Nothing is created unless all of it succeeds",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "parent_id": {
+                    "type": "string",
+                    "description": "Task to break down"
+                },
+                "subtasks": {
+                    "type": "array",
+                    "description": "Subtasks to create, in order",
+                    "minItems": 1,
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "description": {
+                                "type": "string",
+                                "description": "Subtask description"
+                            },
+                            "context": {
+                                "type": "string",
+                                "description": "Additional context or notes"
+                            },
+                            "priority": {
+                                "type": "string",
+                                "description": "Priority label from the project's scale (default: normal)"
+                            },
+                            "estimate_minutes": {
+                                "type": "integer",
+                                "description": "Estimated effort in minutes",
+                                "minimum": 0
+                            },
+                            "due_at": {
+                                "type": "string",
+                                "description": "Due date: RFC 3339 time or YYYY-MM-DD"
+                            },
+                            "after": {
+                                "type": "array",
+                                "description": "0-based indexes of earlier subtasks in this list that must complete first; overrides order for this subtask, and [] makes it wait on none",
+                                "items": {
+                                    "type": "integer",
+                                    "minimum": 0
+                                }
+                            }
+                        },
+                        "required": ["description"],
+                        "additionalProperties": false
+                    }
+                },
+                "order": {
+                    "type": "string",
+                    "enum": ["sequential", "parallel"],
+                    "description": "sequential blocks each subtask on the one before it; parallel (default) leaves them all ready at once"
+                },
+                "start_parent": {
+                    "type": "boolean",
+                    "description": "Move the parent to in_progress if it is pending"
+                },
+                "fields": {
+                    "type": "array",
+                    "description": "Only return these fields for each task (default: all)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["id", "parent_id", "description", "context", "priority", "status", "result", "created_at", "started_at", "completed_at", "updated_at", "archived_at", "estimate_minutes", "assigned_to", "due_at", "project_id", "priority_weight", "metadata", "review_status", "reviewer", "revision", "attempts", "kind", "answer", "answered_by", "scratch", "tags", "resources", "files", "time_spent_seconds", "subtree_estimate_minutes", "remaining_estimate_minutes", "implied_deadline", "latest_start", "at_risk", "is_blocked", "age_seconds", "children_count"]
+                    }
+                }
+            },
+            "required": ["parent_id", "subtasks"],
+            "additionalProperties": false
+        }`),
+	}, r.decomposeTask)
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/events"
+	"procdexeh/bossman/internal/mcp"
+)
+
+var errNoSession = errors.New("watching requires an MCP session")
+
+func (r *Registry) watchTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	session := mcp.SessionFromContext(ctx)
+	if session == nil {
+		return nil, errNoSession
+	}
+	exists, err := db.TaskExists(ctx, r.conn(ctx), params.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get task: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("task not found: %s", params.ID)
+	}
+
+	uri := events.TaskURI(params.ID)
+	session.Subscribe(uri)
+	return resultJSON(map[string]string{"uri": uri, "status": "watching"})
+}
+
+func (r *Registry) unwatchTask(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	session := mcp.SessionFromContext(ctx)
+	if session == nil {
+		return nil, errNoSession
+	}
+
+	uri := events.TaskURI(params.ID)
+	session.Unsubscribe(uri)
+	return resultJSON(map[string]string{"uri": uri, "status": "unwatched"})
+}
+
+func (r *Registry) registerWatchTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "watch_task",
+		Description: "Receive notifications/resources/updated whenever a task changes",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.watchTask)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "unwatch_task",
+		Description: "Stop receiving change notifications for a task",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Task ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.unwatchTask)
+}
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+func (r *Registry) createWorkWindow(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Tag      string   `json:"tag"`
+		Resource string   `json:"resource"`
+		Days     []string `json:"days"`
+		Start    string   `json:"start"`
+		End      string   `json:"end"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	w := db.WorkWindow{Days: params.Days, Start: params.Start, End: params.End}
+	switch {
+	case params.Tag != "" && params.Resource == "":
+		w.Kind, w.Name = db.WindowTag, params.Tag
+	case params.Resource != "" && params.Tag == "":
+		w.Kind, w.Name = db.WindowResource, params.Resource
+	default:
+		return nil, fmt.Errorf("give exactly one of tag or resource")
+	}
+	if len(w.Days) == 0 {
+		w.Days = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	if err := db.CreateWorkWindow(ctx, r.conn(ctx), &w); err != nil {
+		return nil, fmt.Errorf("create work window: %w", err)
+	}
+	return resultJSON(w)
+}
+
+func (r *Registry) listWorkWindows(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	windows, err := db.ListWorkWindows(ctx, r.conn(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list work windows: %w", err)
+	}
+	return resultJSON(windows)
+}
+
+func (r *Registry) deleteWorkWindow(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	err := db.DeleteWorkWindow(ctx, r.conn(ctx), params.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("work window not found: %s", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("delete work window: %w", err)
+	}
+	return resultJSON(map[string]string{"deleted": params.ID})
+}
+
+func (r *Registry) registerWindowTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "create_work_window",
+		Description: "Only let tasks with a tag or resource start inside a time window, e.g. deploys 09:00-17:00 on weekdays. Several windows for the same name add up; a task outside its windows is left out of ready work until one opens",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "tag": {
+                    "type": "string",
+                    "description": "Apply to tasks carrying this tag"
+                },
+                "resource": {
+                    "type": "string",
+                    "description": "Apply to tasks needing this resource"
+                },
+                "days": {
+                    "type": "array",
+                    "description": "Days the window opens (default: mon to fri)",
+                    "items": {
+                        "type": "string",
+                        "enum": ["mon", "tue", "wed", "thu", "fri", "sat", "sun"]
+                    }
+                },
+                "start": {
+                    "type": "string",
+                    "description": "Opening time, HH:MM local"
+                },
+                "end": {
+                    "type": "string",
+                    "description": "Closing time, HH:MM local; at or before start means the next day"
+                }
+            },
+            "required": ["start", "end"],
+            "additionalProperties": false
+        }`),
+	}, r.createWorkWindow)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "list_work_windows",
+		Description: "List work windows",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listWorkWindows)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "delete_work_window",
+		Description: "Remove a work window",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string",
+                    "description": "Work window ID"
+                }
+            },
+            "required": ["id"],
+            "additionalProperties": false
+        }`),
+		Annotations: &mcp.ToolAnnotations{DestructiveHint: true},
+	}, r.deleteWorkWindow)
+}
@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// workspaceSet is what switch_workspace chooses from: the workspaces
+// beside the database the process opened, each opened on first use and
+// kept open until the process exits. A switch moves only the session that
+// asked, so clients sharing a -listen server keep their own workspaces.
+type workspaceSet struct {
+	defaultPath string // the default workspace's database file
+	started     string // the workspace of Registry.db
+	opts        db.Options
+
+	mu       sync.Mutex
+	open     map[string]*sqlx.DB
+	sessions map[string]string // session ID -> workspace, once switched
+}
+
+// SetWorkspaces lets sessions switch between the workspaces beside
+// defaultPath; current is the one the registry's database is. Without it
+// the workspace tools refuse.
+func (r *Registry) SetWorkspaces(defaultPath, current string, opts db.Options) {
+	if current == "" {
+		current = db.DefaultWorkspace
+	}
+	r.workspaces = &workspaceSet{
+		defaultPath: defaultPath,
+		started:     current,
+		opts:        opts,
+		open:        map[string]*sqlx.DB{current: r.db},
+		sessions:    make(map[string]string),
+	}
+}
+
+// CloseWorkspaces closes the databases sessions switched to.
+func (r *Registry) CloseWorkspaces() {
+	ws := r.workspaces
+	if ws == nil {
+		return
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for name, conn := range ws.open {
+		if conn != r.db {
+			conn.Close()
+		}
+		delete(ws.open, name)
+	}
+}
+
+// connKey holds the database a tool call started on, so a switch made by
+// a concurrent call doesn't move it part way.
+type connKey struct{}
+
+// withConn pins ctx to the session's current workspace for one call.
+func (r *Registry) withConn(ctx context.Context) context.Context {
+	if r.workspaces == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, connKey{}, r.sessionConn(ctx))
+}
+
+// conn is the database of the calling session's workspace.
+func (r *Registry) conn(ctx context.Context) *sqlx.DB {
+	if conn, ok := ctx.Value(connKey{}).(*sqlx.DB); ok {
+		return conn
+	}
+	return r.sessionConn(ctx)
+}
+
+func (r *Registry) sessionConn(ctx context.Context) *sqlx.DB {
+	ws := r.workspaces
+	if ws == nil {
+		return r.db
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if name, ok := ws.sessions[sessionID(ctx)]; ok {
+		if conn := ws.open[name]; conn != nil {
+			return conn
+		}
+	}
+	return r.db
+}
+
+// workspace names the calling session's workspace.
+func (ws *workspaceSet) workspace(ctx context.Context) string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if name, ok := ws.sessions[sessionID(ctx)]; ok {
+		return name
+	}
+	return ws.started
+}
+
+// sessionConns are the databases a session may have written to, for
+// cleaning up after it.
+func (r *Registry) sessionConns(id string) []*sqlx.DB {
+	ws := r.workspaces
+	if ws == nil {
+		return []*sqlx.DB{r.db}
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.sessions, id)
+	conns := make([]*sqlx.DB, 0, len(ws.open))
+	for _, conn := range ws.open {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+var errNoWorkspaces = errors.New("workspaces need a database file; this server was started -ephemeral or embedded")
+
+func (r *Registry) listWorkspaces(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	ws := r.workspaces
+	if ws == nil {
+		return nil, errNoWorkspaces
+	}
+	names, err := db.ListWorkspaces(ws.defaultPath)
+	if err != nil {
+		return nil, err
+	}
+	current := ws.workspace(ctx)
+	type workspace struct {
+		Name    string `json:"name"`
+		Path    string `json:"path"`
+		Current bool   `json:"current,omitempty"`
+	}
+	out := make([]workspace, 0, len(names))
+	for _, name := range names {
+		path, _ := db.WorkspacePath(ws.defaultPath, name)
+		out = append(out, workspace{Name: name, Path: path, Current: name == current})
+	}
+	return resultJSON(map[string]any{"current": current, "workspaces": out})
+}
+
+func (r *Registry) switchWorkspace(ctx context.Context, args json.RawMessage) (*mcp.ToolResult, error) {
+	var params struct {
+		Name   string `json:"name"`
+		Create bool   `json:"create"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	ws := r.workspaces
+	if ws == nil {
+		return nil, errNoWorkspaces
+	}
+	params.Name = cmp.Or(params.Name, db.DefaultWorkspace)
+	path, err := db.WorkspacePath(ws.defaultPath, params.Name)
+	if err != nil {
+		return nil, err
+	}
+	ws.mu.Lock()
+	conn, created := ws.open[params.Name], false
+	ws.mu.Unlock()
+	if conn == nil {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if !params.Create {
+				return nil, fmt.Errorf("workspace %q doesn't exist; list_workspaces shows the others, or pass create: true to start it", params.Name)
+			}
+			created = true
+		}
+		// opening may migrate the database; other sessions' calls take
+		// ws.mu to find their workspace, so don't make them wait on it
+		if conn, err = db.InitDB(path, ws.opts); err != nil {
+			return nil, fmt.Errorf("open workspace %s: %w", params.Name, err)
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if open := ws.open[params.Name]; open == nil {
+		ws.open[params.Name] = conn
+	} else if open != conn {
+		// another session opened it meanwhile
+		conn.Close()
+	}
+	if params.Name == ws.started {
+		delete(ws.sessions, sessionID(ctx))
+	} else {
+		ws.sessions[sessionID(ctx)] = params.Name
+	}
+	return resultJSON(map[string]any{"workspace": params.Name, "path": path, "created": created})
+}
+
+func (r *Registry) registerWorkspaceTools() {
+	r.register(mcp.ToolDefinition{
+		Name:        "list_workspaces",
+		Description: "List the workspaces: separate task databases, such as personal, work or one per repository. current is the one your calls use",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {},
+            "additionalProperties": false
+        }`),
+		Annotations: readOnly,
+	}, r.listWorkspaces)
+
+	r.register(mcp.ToolDefinition{
+		Name:        "switch_workspace",
+		Description: "Point your following calls at another workspace's tasks. Only your session switches; other clients keep theirs",
+		InputSchema: json.RawMessage(`{
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "description": "Workspace name, \"default\" for the main database"
+                },
+                "create": {
+                    "type": "boolean",
+                    "description": "Start the workspace if it doesn't exist yet"
+                }
+            },
+            "required": ["name"],
+            "additionalProperties": false
+        }`),
+	}, r.switchWorkspace)
+}
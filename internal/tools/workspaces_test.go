@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"procdexeh/bossman/internal/db"
+	"procdexeh/bossman/internal/mcp"
+)
+
+// TestSwitchWorkspacePerSession switches one session to a new workspace
+// and wants another session still on the default one, reading its tasks.
+func TestSwitchWorkspacePerSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bossman.db")
+	conn, err := db.InitDB(path, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	r := NewRegistry(conn)
+	r.SetWorkspaces(path, "", db.Options{})
+	t.Cleanup(r.CloseWorkspaces)
+
+	ctx := db.WithActor(context.Background(), "agent-1")
+	a := mcp.WithSession(ctx, &mcp.Session{ID: "session-a"})
+	b := mcp.WithSession(ctx, &mcp.Session{ID: "session-b"})
+
+	task := &db.Task{ID: db.NewTaskID(), Description: "design the schema"}
+	if err := db.InsertTask(ctx, conn, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CallTool(a, "switch_workspace", json.RawMessage(`{"name": "work", "create": true}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name    string
+		ctx     context.Context
+		current string
+		tasks   int
+	}{
+		{"switched", a, "work", 0},
+		{"other", b, db.DefaultWorkspace, 1},
+	} {
+		res, err := r.CallTool(c.ctx, "list_workspaces", json.RawMessage(`{}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ws struct {
+			Data struct {
+				Current string `json:"current"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(res.Content[0].Text), &ws); err != nil {
+			t.Fatal(err)
+		}
+		if ws.Data.Current != c.current {
+			t.Errorf("%s session: current workspace %q, want %q", c.name, ws.Data.Current, c.current)
+		}
+
+		res, err = r.CallTool(c.ctx, "list_tasks", json.RawMessage(`{"fields": ["id"]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tasks struct {
+			Data []map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(res.Content[0].Text), &tasks); err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks.Data) != c.tasks {
+			t.Errorf("%s session: listed %v, want %d tasks", c.name, tasks.Data, c.tasks)
+		}
+	}
+}